@@ -0,0 +1,21 @@
+CREATE TABLE `alertrules` (
+`alertruleid` VARCHAR(36) NOT NULL,
+`accountid` VARCHAR(36) NOT NULL,
+`botid` VARCHAR(36) DEFAULT NULL,
+`kind` VARCHAR(32) NOT NULL,
+`threshold` DOUBLE NOT NULL DEFAULT 0,
+`window` INTEGER NOT NULL DEFAULT 0,
+`webhook` VARCHAR(512) DEFAULT NULL,
+`notifycallback` TINYINT(1) NOT NULL DEFAULT 1,
+`createat` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+`updateat` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+`deleteat` DATETIME DEFAULT NULL,
+PRIMARY KEY (`alertruleid`),
+INDEX `accountid_index` (`accountid`),
+INDEX `botid_index` (`botid`),
+INDEX `kind_index` (`kind`),
+INDEX `createat_index` (`createat`),
+INDEX `updateat_index` (`updateat`),
+INDEX `deleteat_index` (`deleteat`)
+)
+CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
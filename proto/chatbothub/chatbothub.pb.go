@@ -532,6 +532,10 @@ type BotsInfo struct {
 	MomentFilterInfo     string   `protobuf:"bytes,10,opt,name=momentFilterInfo,proto3" json:"momentFilterInfo,omitempty"`
 	BotId                string   `protobuf:"bytes,11,opt,name=botId,proto3" json:"botId,omitempty"`
 	ScanUrl              string   `protobuf:"bytes,12,opt,name=scanUrl,proto3" json:"scanUrl,omitempty"`
+	ActionQueueInfo      string   `protobuf:"bytes,13,opt,name=actionQueueInfo,proto3" json:"actionQueueInfo,omitempty"`
+	ProtocolVersion      int32    `protobuf:"varint,14,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	SupportedActions     []string `protobuf:"bytes,15,rep,name=supportedActions,proto3" json:"supportedActions,omitempty"`
+	SupportedEvents      []string `protobuf:"bytes,16,rep,name=supportedEvents,proto3" json:"supportedEvents,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -646,6 +650,34 @@ func (m *BotsInfo) GetScanUrl() string {
 	return ""
 }
 
+func (m *BotsInfo) GetActionQueueInfo() string {
+	if m != nil {
+		return m.ActionQueueInfo
+	}
+	return ""
+}
+
+func (m *BotsInfo) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *BotsInfo) GetSupportedActions() []string {
+	if m != nil {
+		return m.SupportedActions
+	}
+	return nil
+}
+
+func (m *BotsInfo) GetSupportedEvents() []string {
+	if m != nil {
+		return m.SupportedEvents
+	}
+	return nil
+}
+
 type BotLoginRequest struct {
 	ClientId             string   `protobuf:"bytes,1,opt,name=clientId,proto3" json:"clientId,omitempty"`
 	ClientType           string   `protobuf:"bytes,2,opt,name=clientType,proto3" json:"clientType,omitempty"`
@@ -654,6 +686,7 @@ type BotLoginRequest struct {
 	NotifyUrl            string   `protobuf:"bytes,5,opt,name=notifyUrl,proto3" json:"notifyUrl,omitempty"`
 	LoginInfo            string   `protobuf:"bytes,6,opt,name=loginInfo,proto3" json:"loginInfo,omitempty"`
 	BotId                string   `protobuf:"bytes,7,opt,name=botId,proto3" json:"botId,omitempty"`
+	Region               string   `protobuf:"bytes,8,opt,name=region,proto3" json:"region,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -733,6 +766,13 @@ func (m *BotLoginRequest) GetBotId() string {
 	return ""
 }
 
+func (m *BotLoginRequest) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
 type BotLogoutRequest struct {
 	BotId                string   `protobuf:"bytes,1,opt,name=botId,proto3" json:"botId,omitempty"`
 	ClientId             string   `protobuf:"bytes,2,opt,name=clientId,proto3" json:"clientId,omitempty"`
@@ -1127,119 +1167,851 @@ type StreamingCtrlRequest struct {
 	XXX_sizecache        int32                `json:"-"`
 }
 
-func (m *StreamingCtrlRequest) Reset()         { *m = StreamingCtrlRequest{} }
-func (m *StreamingCtrlRequest) String() string { return proto.CompactTextString(m) }
-func (*StreamingCtrlRequest) ProtoMessage()    {}
-func (*StreamingCtrlRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_0b1f640cec0d9d68, []int{18}
+func (m *StreamingCtrlRequest) Reset()         { *m = StreamingCtrlRequest{} }
+func (m *StreamingCtrlRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamingCtrlRequest) ProtoMessage()    {}
+func (*StreamingCtrlRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{18}
+}
+
+func (m *StreamingCtrlRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamingCtrlRequest.Unmarshal(m, b)
+}
+func (m *StreamingCtrlRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamingCtrlRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamingCtrlRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamingCtrlRequest.Merge(m, src)
+}
+func (m *StreamingCtrlRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamingCtrlRequest.Size(m)
+}
+func (m *StreamingCtrlRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamingCtrlRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamingCtrlRequest proto.InternalMessageInfo
+
+func (m *StreamingCtrlRequest) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *StreamingCtrlRequest) GetClientType() string {
+	if m != nil {
+		return m.ClientType
+	}
+	return ""
+}
+
+func (m *StreamingCtrlRequest) GetResources() []*StreamingResource {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+type StreamingResource struct {
+	BotId                string   `protobuf:"bytes,1,opt,name=botId,proto3" json:"botId,omitempty"`
+	ResourceType         int32    `protobuf:"varint,2,opt,name=resourceType,proto3" json:"resourceType,omitempty"`
+	ActionType           int32    `protobuf:"varint,3,opt,name=actionType,proto3" json:"actionType,omitempty"`
+	Chatusers            []string `protobuf:"bytes,4,rep,name=chatusers,proto3" json:"chatusers,omitempty"`
+	Chatgroups           []string `protobuf:"bytes,5,rep,name=chatgroups,proto3" json:"chatgroups,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamingResource) Reset()         { *m = StreamingResource{} }
+func (m *StreamingResource) String() string { return proto.CompactTextString(m) }
+func (*StreamingResource) ProtoMessage()    {}
+func (*StreamingResource) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{19}
+}
+
+func (m *StreamingResource) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamingResource.Unmarshal(m, b)
+}
+func (m *StreamingResource) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamingResource.Marshal(b, m, deterministic)
+}
+func (m *StreamingResource) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamingResource.Merge(m, src)
+}
+func (m *StreamingResource) XXX_Size() int {
+	return xxx_messageInfo_StreamingResource.Size(m)
+}
+func (m *StreamingResource) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamingResource.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamingResource proto.InternalMessageInfo
+
+func (m *StreamingResource) GetBotId() string {
+	if m != nil {
+		return m.BotId
+	}
+	return ""
+}
+
+func (m *StreamingResource) GetResourceType() int32 {
+	if m != nil {
+		return m.ResourceType
+	}
+	return 0
+}
+
+func (m *StreamingResource) GetActionType() int32 {
+	if m != nil {
+		return m.ActionType
+	}
+	return 0
+}
+
+func (m *StreamingResource) GetChatusers() []string {
+	if m != nil {
+		return m.Chatusers
+	}
+	return nil
+}
+
+func (m *StreamingResource) GetChatgroups() []string {
+	if m != nil {
+		return m.Chatgroups
+	}
+	return nil
+}
+
+type DrainHubRequest struct {
+	Target               string   `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Timeout              int64    `protobuf:"varint,2,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainHubRequest) Reset()         { *m = DrainHubRequest{} }
+func (m *DrainHubRequest) String() string { return proto.CompactTextString(m) }
+func (*DrainHubRequest) ProtoMessage()    {}
+func (*DrainHubRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{20}
+}
+
+func (m *DrainHubRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainHubRequest.Unmarshal(m, b)
+}
+func (m *DrainHubRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainHubRequest.Marshal(b, m, deterministic)
+}
+func (m *DrainHubRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainHubRequest.Merge(m, src)
+}
+func (m *DrainHubRequest) XXX_Size() int {
+	return xxx_messageInfo_DrainHubRequest.Size(m)
+}
+func (m *DrainHubRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainHubRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainHubRequest proto.InternalMessageInfo
+
+func (m *DrainHubRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *DrainHubRequest) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+type DrainStatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainStatusRequest) Reset()         { *m = DrainStatusRequest{} }
+func (m *DrainStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*DrainStatusRequest) ProtoMessage()    {}
+func (*DrainStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{21}
+}
+
+func (m *DrainStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainStatusRequest.Unmarshal(m, b)
+}
+func (m *DrainStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *DrainStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainStatusRequest.Merge(m, src)
+}
+func (m *DrainStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_DrainStatusRequest.Size(m)
+}
+func (m *DrainStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainStatusRequest proto.InternalMessageInfo
+
+type DrainStatusReply struct {
+	Draining             bool     `protobuf:"varint,1,opt,name=draining,proto3" json:"draining,omitempty"`
+	Phase                string   `protobuf:"bytes,2,opt,name=phase,proto3" json:"phase,omitempty"`
+	Target               string   `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	StartAt              int64    `protobuf:"varint,4,opt,name=startAt,proto3" json:"startAt,omitempty"`
+	Deadline             int64    `protobuf:"varint,5,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	Bots                 int32    `protobuf:"varint,6,opt,name=bots,proto3" json:"bots,omitempty"`
+	InflightActions      int32    `protobuf:"varint,7,opt,name=inflightActions,proto3" json:"inflightActions,omitempty"`
+	PendingMqSends       int32    `protobuf:"varint,8,opt,name=pendingMqSends,proto3" json:"pendingMqSends,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainStatusReply) Reset()         { *m = DrainStatusReply{} }
+func (m *DrainStatusReply) String() string { return proto.CompactTextString(m) }
+func (*DrainStatusReply) ProtoMessage()    {}
+func (*DrainStatusReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{22}
+}
+
+func (m *DrainStatusReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainStatusReply.Unmarshal(m, b)
+}
+func (m *DrainStatusReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainStatusReply.Marshal(b, m, deterministic)
+}
+func (m *DrainStatusReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainStatusReply.Merge(m, src)
+}
+func (m *DrainStatusReply) XXX_Size() int {
+	return xxx_messageInfo_DrainStatusReply.Size(m)
+}
+func (m *DrainStatusReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainStatusReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainStatusReply proto.InternalMessageInfo
+
+func (m *DrainStatusReply) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+func (m *DrainStatusReply) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *DrainStatusReply) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *DrainStatusReply) GetStartAt() int64 {
+	if m != nil {
+		return m.StartAt
+	}
+	return 0
+}
+
+func (m *DrainStatusReply) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
+}
+
+func (m *DrainStatusReply) GetBots() int32 {
+	if m != nil {
+		return m.Bots
+	}
+	return 0
+}
+
+func (m *DrainStatusReply) GetInflightActions() int32 {
+	if m != nil {
+		return m.InflightActions
+	}
+	return 0
+}
+
+func (m *DrainStatusReply) GetPendingMqSends() int32 {
+	if m != nil {
+		return m.PendingMqSends
+	}
+	return 0
+}
+
+type ClientAdminRequest struct {
+	ClientId             string   `protobuf:"bytes,1,opt,name=clientId,proto3" json:"clientId,omitempty"`
+	Target               string   `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	LogLevel             string   `protobuf:"bytes,3,opt,name=logLevel,proto3" json:"logLevel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ClientAdminRequest) Reset()         { *m = ClientAdminRequest{} }
+func (m *ClientAdminRequest) String() string { return proto.CompactTextString(m) }
+func (*ClientAdminRequest) ProtoMessage()    {}
+func (*ClientAdminRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{23}
+}
+
+func (m *ClientAdminRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClientAdminRequest.Unmarshal(m, b)
+}
+func (m *ClientAdminRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClientAdminRequest.Marshal(b, m, deterministic)
+}
+func (m *ClientAdminRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClientAdminRequest.Merge(m, src)
+}
+func (m *ClientAdminRequest) XXX_Size() int {
+	return xxx_messageInfo_ClientAdminRequest.Size(m)
+}
+func (m *ClientAdminRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClientAdminRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClientAdminRequest proto.InternalMessageInfo
+
+func (m *ClientAdminRequest) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *ClientAdminRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *ClientAdminRequest) GetLogLevel() string {
+	if m != nil {
+		return m.LogLevel
+	}
+	return ""
+}
+
+type PingSample struct {
+	At                   int64    `protobuf:"varint,1,opt,name=at,proto3" json:"at,omitempty"`
+	Latency              int64    `protobuf:"varint,2,opt,name=latency,proto3" json:"latency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PingSample) Reset()         { *m = PingSample{} }
+func (m *PingSample) String() string { return proto.CompactTextString(m) }
+func (*PingSample) ProtoMessage()    {}
+func (*PingSample) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{24}
+}
+
+func (m *PingSample) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingSample.Unmarshal(m, b)
+}
+func (m *PingSample) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingSample.Marshal(b, m, deterministic)
+}
+func (m *PingSample) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingSample.Merge(m, src)
+}
+func (m *PingSample) XXX_Size() int {
+	return xxx_messageInfo_PingSample.Size(m)
+}
+func (m *PingSample) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingSample.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingSample proto.InternalMessageInfo
+
+func (m *PingSample) GetAt() int64 {
+	if m != nil {
+		return m.At
+	}
+	return 0
+}
+
+func (m *PingSample) GetLatency() int64 {
+	if m != nil {
+		return m.Latency
+	}
+	return 0
+}
+
+type TunnelSendError struct {
+	At                   int64    `protobuf:"varint,1,opt,name=at,proto3" json:"at,omitempty"`
+	EventType            string   `protobuf:"bytes,2,opt,name=eventType,proto3" json:"eventType,omitempty"`
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TunnelSendError) Reset()         { *m = TunnelSendError{} }
+func (m *TunnelSendError) String() string { return proto.CompactTextString(m) }
+func (*TunnelSendError) ProtoMessage()    {}
+func (*TunnelSendError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{25}
+}
+
+func (m *TunnelSendError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TunnelSendError.Unmarshal(m, b)
+}
+func (m *TunnelSendError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TunnelSendError.Marshal(b, m, deterministic)
+}
+func (m *TunnelSendError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TunnelSendError.Merge(m, src)
+}
+func (m *TunnelSendError) XXX_Size() int {
+	return xxx_messageInfo_TunnelSendError.Size(m)
+}
+func (m *TunnelSendError) XXX_DiscardUnknown() {
+	xxx_messageInfo_TunnelSendError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TunnelSendError proto.InternalMessageInfo
+
+func (m *TunnelSendError) GetAt() int64 {
+	if m != nil {
+		return m.At
+	}
+	return 0
+}
+
+func (m *TunnelSendError) GetEventType() string {
+	if m != nil {
+		return m.EventType
+	}
+	return ""
+}
+
+func (m *TunnelSendError) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type InflightAction struct {
+	ActionRequestId      string   `protobuf:"bytes,1,opt,name=actionRequestId,proto3" json:"actionRequestId,omitempty"`
+	ActionType           string   `protobuf:"bytes,2,opt,name=actionType,proto3" json:"actionType,omitempty"`
+	SentAt               int64    `protobuf:"varint,3,opt,name=sentAt,proto3" json:"sentAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InflightAction) Reset()         { *m = InflightAction{} }
+func (m *InflightAction) String() string { return proto.CompactTextString(m) }
+func (*InflightAction) ProtoMessage()    {}
+func (*InflightAction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{26}
+}
+
+func (m *InflightAction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InflightAction.Unmarshal(m, b)
+}
+func (m *InflightAction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InflightAction.Marshal(b, m, deterministic)
+}
+func (m *InflightAction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InflightAction.Merge(m, src)
+}
+func (m *InflightAction) XXX_Size() int {
+	return xxx_messageInfo_InflightAction.Size(m)
+}
+func (m *InflightAction) XXX_DiscardUnknown() {
+	xxx_messageInfo_InflightAction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InflightAction proto.InternalMessageInfo
+
+func (m *InflightAction) GetActionRequestId() string {
+	if m != nil {
+		return m.ActionRequestId
+	}
+	return ""
+}
+
+func (m *InflightAction) GetActionType() string {
+	if m != nil {
+		return m.ActionType
+	}
+	return ""
+}
+
+func (m *InflightAction) GetSentAt() int64 {
+	if m != nil {
+		return m.SentAt
+	}
+	return 0
+}
+
+type ClientEvent struct {
+	At                   int64    `protobuf:"varint,1,opt,name=at,proto3" json:"at,omitempty"`
+	Direction            string   `protobuf:"bytes,2,opt,name=direction,proto3" json:"direction,omitempty"`
+	EventType            string   `protobuf:"bytes,3,opt,name=eventType,proto3" json:"eventType,omitempty"`
+	Body                 string   `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ClientEvent) Reset()         { *m = ClientEvent{} }
+func (m *ClientEvent) String() string { return proto.CompactTextString(m) }
+func (*ClientEvent) ProtoMessage()    {}
+func (*ClientEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{27}
+}
+
+func (m *ClientEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClientEvent.Unmarshal(m, b)
+}
+func (m *ClientEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClientEvent.Marshal(b, m, deterministic)
+}
+func (m *ClientEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClientEvent.Merge(m, src)
+}
+func (m *ClientEvent) XXX_Size() int {
+	return xxx_messageInfo_ClientEvent.Size(m)
+}
+func (m *ClientEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClientEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClientEvent proto.InternalMessageInfo
+
+func (m *ClientEvent) GetAt() int64 {
+	if m != nil {
+		return m.At
+	}
+	return 0
+}
+
+func (m *ClientEvent) GetDirection() string {
+	if m != nil {
+		return m.Direction
+	}
+	return ""
+}
+
+func (m *ClientEvent) GetEventType() string {
+	if m != nil {
+		return m.EventType
+	}
+	return ""
+}
+
+func (m *ClientEvent) GetBody() string {
+	if m != nil {
+		return m.Body
+	}
+	return ""
+}
+
+type ClientInspection struct {
+	ClientError          *OperationReply    `protobuf:"bytes,1,opt,name=clientError,proto3" json:"clientError,omitempty"`
+	BotsInfo             *BotsInfo          `protobuf:"bytes,2,opt,name=botsInfo,proto3" json:"botsInfo,omitempty"`
+	HubInstance          string             `protobuf:"bytes,3,opt,name=hubInstance,proto3" json:"hubInstance,omitempty"`
+	FilterId             string             `protobuf:"bytes,4,opt,name=filterId,proto3" json:"filterId,omitempty"`
+	MomentFilterId       string             `protobuf:"bytes,5,opt,name=momentFilterId,proto3" json:"momentFilterId,omitempty"`
+	Pings                []*PingSample      `protobuf:"bytes,6,rep,name=pings,proto3" json:"pings,omitempty"`
+	SendErrors           []*TunnelSendError `protobuf:"bytes,7,rep,name=sendErrors,proto3" json:"sendErrors,omitempty"`
+	InflightActions      []*InflightAction  `protobuf:"bytes,8,rep,name=inflightActions,proto3" json:"inflightActions,omitempty"`
+	RecentEvents         []*ClientEvent     `protobuf:"bytes,9,rep,name=recentEvents,proto3" json:"recentEvents,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ClientInspection) Reset()         { *m = ClientInspection{} }
+func (m *ClientInspection) String() string { return proto.CompactTextString(m) }
+func (*ClientInspection) ProtoMessage()    {}
+func (*ClientInspection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{28}
+}
+
+func (m *ClientInspection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClientInspection.Unmarshal(m, b)
+}
+func (m *ClientInspection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClientInspection.Marshal(b, m, deterministic)
+}
+func (m *ClientInspection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClientInspection.Merge(m, src)
+}
+func (m *ClientInspection) XXX_Size() int {
+	return xxx_messageInfo_ClientInspection.Size(m)
+}
+func (m *ClientInspection) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClientInspection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClientInspection proto.InternalMessageInfo
+
+func (m *ClientInspection) GetClientError() *OperationReply {
+	if m != nil {
+		return m.ClientError
+	}
+	return nil
+}
+
+func (m *ClientInspection) GetBotsInfo() *BotsInfo {
+	if m != nil {
+		return m.BotsInfo
+	}
+	return nil
+}
+
+func (m *ClientInspection) GetHubInstance() string {
+	if m != nil {
+		return m.HubInstance
+	}
+	return ""
+}
+
+func (m *ClientInspection) GetFilterId() string {
+	if m != nil {
+		return m.FilterId
+	}
+	return ""
+}
+
+func (m *ClientInspection) GetMomentFilterId() string {
+	if m != nil {
+		return m.MomentFilterId
+	}
+	return ""
+}
+
+func (m *ClientInspection) GetPings() []*PingSample {
+	if m != nil {
+		return m.Pings
+	}
+	return nil
+}
+
+func (m *ClientInspection) GetSendErrors() []*TunnelSendError {
+	if m != nil {
+		return m.SendErrors
+	}
+	return nil
+}
+
+func (m *ClientInspection) GetInflightActions() []*InflightAction {
+	if m != nil {
+		return m.InflightActions
+	}
+	return nil
+}
+
+func (m *ClientInspection) GetRecentEvents() []*ClientEvent {
+	if m != nil {
+		return m.RecentEvents
+	}
+	return nil
+}
+
+type BotLogsRequest struct {
+	BotId                string   `protobuf:"bytes,1,opt,name=botId,proto3" json:"botId,omitempty"`
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	Limit                int32    `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StreamingCtrlRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StreamingCtrlRequest.Unmarshal(m, b)
+func (m *BotLogsRequest) Reset()         { *m = BotLogsRequest{} }
+func (m *BotLogsRequest) String() string { return proto.CompactTextString(m) }
+func (*BotLogsRequest) ProtoMessage()    {}
+func (*BotLogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{29}
 }
-func (m *StreamingCtrlRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StreamingCtrlRequest.Marshal(b, m, deterministic)
+
+func (m *BotLogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BotLogsRequest.Unmarshal(m, b)
 }
-func (m *StreamingCtrlRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StreamingCtrlRequest.Merge(m, src)
+func (m *BotLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BotLogsRequest.Marshal(b, m, deterministic)
 }
-func (m *StreamingCtrlRequest) XXX_Size() int {
-	return xxx_messageInfo_StreamingCtrlRequest.Size(m)
+func (m *BotLogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BotLogsRequest.Merge(m, src)
 }
-func (m *StreamingCtrlRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_StreamingCtrlRequest.DiscardUnknown(m)
+func (m *BotLogsRequest) XXX_Size() int {
+	return xxx_messageInfo_BotLogsRequest.Size(m)
+}
+func (m *BotLogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BotLogsRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StreamingCtrlRequest proto.InternalMessageInfo
+var xxx_messageInfo_BotLogsRequest proto.InternalMessageInfo
 
-func (m *StreamingCtrlRequest) GetClientId() string {
+func (m *BotLogsRequest) GetBotId() string {
 	if m != nil {
-		return m.ClientId
+		return m.BotId
 	}
 	return ""
 }
 
-func (m *StreamingCtrlRequest) GetClientType() string {
+func (m *BotLogsRequest) GetLevel() string {
 	if m != nil {
-		return m.ClientType
+		return m.Level
 	}
 	return ""
 }
 
-func (m *StreamingCtrlRequest) GetResources() []*StreamingResource {
+func (m *BotLogsRequest) GetLimit() int32 {
 	if m != nil {
-		return m.Resources
+		return m.Limit
 	}
-	return nil
+	return 0
 }
 
-type StreamingResource struct {
-	BotId                string   `protobuf:"bytes,1,opt,name=botId,proto3" json:"botId,omitempty"`
-	ResourceType         int32    `protobuf:"varint,2,opt,name=resourceType,proto3" json:"resourceType,omitempty"`
-	ActionType           int32    `protobuf:"varint,3,opt,name=actionType,proto3" json:"actionType,omitempty"`
-	Chatusers            []string `protobuf:"bytes,4,rep,name=chatusers,proto3" json:"chatusers,omitempty"`
-	Chatgroups           []string `protobuf:"bytes,5,rep,name=chatgroups,proto3" json:"chatgroups,omitempty"`
+type LogLine struct {
+	At                   int64    `protobuf:"varint,1,opt,name=at,proto3" json:"at,omitempty"`
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	Package              string   `protobuf:"bytes,3,opt,name=package,proto3" json:"package,omitempty"`
+	Message              string   `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Fields               string   `protobuf:"bytes,6,opt,name=fields,proto3" json:"fields,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StreamingResource) Reset()         { *m = StreamingResource{} }
-func (m *StreamingResource) String() string { return proto.CompactTextString(m) }
-func (*StreamingResource) ProtoMessage()    {}
-func (*StreamingResource) Descriptor() ([]byte, []int) {
-	return fileDescriptor_0b1f640cec0d9d68, []int{19}
+func (m *LogLine) Reset()         { *m = LogLine{} }
+func (m *LogLine) String() string { return proto.CompactTextString(m) }
+func (*LogLine) ProtoMessage()    {}
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{30}
 }
 
-func (m *StreamingResource) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StreamingResource.Unmarshal(m, b)
+func (m *LogLine) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogLine.Unmarshal(m, b)
 }
-func (m *StreamingResource) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StreamingResource.Marshal(b, m, deterministic)
+func (m *LogLine) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogLine.Marshal(b, m, deterministic)
 }
-func (m *StreamingResource) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StreamingResource.Merge(m, src)
+func (m *LogLine) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogLine.Merge(m, src)
 }
-func (m *StreamingResource) XXX_Size() int {
-	return xxx_messageInfo_StreamingResource.Size(m)
+func (m *LogLine) XXX_Size() int {
+	return xxx_messageInfo_LogLine.Size(m)
 }
-func (m *StreamingResource) XXX_DiscardUnknown() {
-	xxx_messageInfo_StreamingResource.DiscardUnknown(m)
+func (m *LogLine) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogLine.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StreamingResource proto.InternalMessageInfo
+var xxx_messageInfo_LogLine proto.InternalMessageInfo
 
-func (m *StreamingResource) GetBotId() string {
+func (m *LogLine) GetAt() int64 {
 	if m != nil {
-		return m.BotId
+		return m.At
+	}
+	return 0
+}
+
+func (m *LogLine) GetLevel() string {
+	if m != nil {
+		return m.Level
 	}
 	return ""
 }
 
-func (m *StreamingResource) GetResourceType() int32 {
+func (m *LogLine) GetPackage() string {
 	if m != nil {
-		return m.ResourceType
+		return m.Package
 	}
-	return 0
+	return ""
 }
 
-func (m *StreamingResource) GetActionType() int32 {
+func (m *LogLine) GetMessage() string {
 	if m != nil {
-		return m.ActionType
+		return m.Message
 	}
-	return 0
+	return ""
 }
 
-func (m *StreamingResource) GetChatusers() []string {
+func (m *LogLine) GetError() string {
 	if m != nil {
-		return m.Chatusers
+		return m.Error
+	}
+	return ""
+}
+
+func (m *LogLine) GetFields() string {
+	if m != nil {
+		return m.Fields
+	}
+	return ""
+}
+
+type BotLogsReply struct {
+	ClientError          *OperationReply `protobuf:"bytes,1,opt,name=clientError,proto3" json:"clientError,omitempty"`
+	Lines                []*LogLine      `protobuf:"bytes,2,rep,name=lines,proto3" json:"lines,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *BotLogsReply) Reset()         { *m = BotLogsReply{} }
+func (m *BotLogsReply) String() string { return proto.CompactTextString(m) }
+func (*BotLogsReply) ProtoMessage()    {}
+func (*BotLogsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b1f640cec0d9d68, []int{31}
+}
+
+func (m *BotLogsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BotLogsReply.Unmarshal(m, b)
+}
+func (m *BotLogsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BotLogsReply.Marshal(b, m, deterministic)
+}
+func (m *BotLogsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BotLogsReply.Merge(m, src)
+}
+func (m *BotLogsReply) XXX_Size() int {
+	return xxx_messageInfo_BotLogsReply.Size(m)
+}
+func (m *BotLogsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_BotLogsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BotLogsReply proto.InternalMessageInfo
+
+func (m *BotLogsReply) GetClientError() *OperationReply {
+	if m != nil {
+		return m.ClientError
 	}
 	return nil
 }
 
-func (m *StreamingResource) GetChatgroups() []string {
+func (m *BotLogsReply) GetLines() []*LogLine {
 	if m != nil {
-		return m.Chatgroups
+		return m.Lines
 	}
 	return nil
 }
@@ -1265,85 +2037,139 @@ func init() {
 	proto.RegisterType((*FilterFillReply)(nil), "chatbothub.FilterFillReply")
 	proto.RegisterType((*StreamingCtrlRequest)(nil), "chatbothub.StreamingCtrlRequest")
 	proto.RegisterType((*StreamingResource)(nil), "chatbothub.StreamingResource")
+	proto.RegisterType((*DrainHubRequest)(nil), "chatbothub.DrainHubRequest")
+	proto.RegisterType((*DrainStatusRequest)(nil), "chatbothub.DrainStatusRequest")
+	proto.RegisterType((*DrainStatusReply)(nil), "chatbothub.DrainStatusReply")
+	proto.RegisterType((*ClientAdminRequest)(nil), "chatbothub.ClientAdminRequest")
+	proto.RegisterType((*PingSample)(nil), "chatbothub.PingSample")
+	proto.RegisterType((*TunnelSendError)(nil), "chatbothub.TunnelSendError")
+	proto.RegisterType((*InflightAction)(nil), "chatbothub.InflightAction")
+	proto.RegisterType((*ClientEvent)(nil), "chatbothub.ClientEvent")
+	proto.RegisterType((*ClientInspection)(nil), "chatbothub.ClientInspection")
+	proto.RegisterType((*BotLogsRequest)(nil), "chatbothub.BotLogsRequest")
+	proto.RegisterType((*LogLine)(nil), "chatbothub.LogLine")
+	proto.RegisterType((*BotLogsReply)(nil), "chatbothub.BotLogsReply")
 }
 
 func init() { proto.RegisterFile("chatbothub.proto", fileDescriptor_0b1f640cec0d9d68) }
 
 var fileDescriptor_0b1f640cec0d9d68 = []byte{
-	// 1168 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0x4f, 0x6f, 0xdb, 0x36,
-	0x14, 0xaf, 0xac, 0x38, 0xb1, 0x9f, 0x93, 0x26, 0x65, 0xd3, 0x4e, 0x73, 0xd2, 0x2e, 0x10, 0x06,
-	0x2c, 0xd8, 0x80, 0x20, 0x48, 0x8f, 0x5d, 0x07, 0xd4, 0x69, 0xda, 0x65, 0x4b, 0xdb, 0x40, 0x49,
-	0xb7, 0xe3, 0x40, 0xdb, 0x8c, 0x2c, 0x4c, 0x16, 0x3d, 0x92, 0x6a, 0x6a, 0x60, 0xd7, 0x9d, 0x07,
-	0xec, 0x6b, 0xec, 0xd3, 0xec, 0xd6, 0xcf, 0xb0, 0xf3, 0x3e, 0xc0, 0x40, 0x52, 0x14, 0x29, 0xd9,
-	0x89, 0xbb, 0x66, 0x37, 0xbd, 0x3f, 0x7c, 0x7c, 0xbf, 0x1f, 0x1f, 0xdf, 0xa3, 0x60, 0x63, 0x30,
-	0xc2, 0xa2, 0x4f, 0xc5, 0x28, 0xef, 0xef, 0x4d, 0x18, 0x15, 0x14, 0x81, 0xd5, 0x84, 0xcf, 0x60,
-	0xa3, 0x47, 0xc5, 0xf3, 0x24, 0x15, 0x84, 0x45, 0xe4, 0x97, 0x9c, 0x70, 0x81, 0x36, 0xa1, 0xd9,
-	0xa7, 0xe2, 0x78, 0x18, 0x78, 0x3b, 0xde, 0x6e, 0x3b, 0xd2, 0x02, 0xea, 0x42, 0xeb, 0x42, 0xb9,
-	0x1d, 0x0f, 0x83, 0x86, 0x32, 0x94, 0x72, 0xf8, 0x9b, 0x07, 0x77, 0x75, 0x8c, 0x43, 0x46, 0xb0,
-	0x20, 0x26, 0x92, 0xbb, 0xc6, 0xab, 0xae, 0x41, 0x0f, 0x01, 0xf4, 0xf7, 0xf9, 0x74, 0x42, 0x8a,
-	0x88, 0x8e, 0xc6, 0xda, 0x5f, 0xe1, 0x31, 0x09, 0x7c, 0xd7, 0x2e, 0x35, 0x08, 0xc1, 0x52, 0x9f,
-	0x0e, 0xa7, 0xc1, 0x92, 0xb2, 0xa8, 0xef, 0xf0, 0x0c, 0xee, 0xe8, 0x34, 0x5e, 0x91, 0x77, 0xe2,
-	0x43, 0x92, 0x08, 0x61, 0x35, 0x23, 0xef, 0x0a, 0xfc, 0x25, 0xb0, 0x8a, 0x2e, 0x7c, 0x04, 0xed,
-	0x1e, 0xc3, 0xd9, 0x60, 0x74, 0x8e, 0x63, 0xb4, 0x01, 0xfe, 0xf7, 0x64, 0x5a, 0xc4, 0x91, 0x9f,
-	0x92, 0xad, 0x1f, 0x70, 0x9a, 0x1b, 0x08, 0x5a, 0x08, 0xdf, 0xc2, 0xdd, 0x88, 0xe6, 0x82, 0x30,
-	0xbd, 0xd4, 0xe4, 0xf2, 0x05, 0xf8, 0x02, 0xc7, 0x6a, 0x79, 0xe7, 0xe0, 0xde, 0x9e, 0x73, 0x34,
-	0xe5, 0x16, 0x91, 0xf4, 0x90, 0x49, 0x33, 0xb5, 0xde, 0xb2, 0x6d, 0xe4, 0x0a, 0x20, 0xbf, 0x76,
-	0x12, 0xbf, 0xc2, 0xea, 0xd1, 0x5b, 0x92, 0x95, 0xe0, 0xb7, 0xa1, 0x4d, 0xa4, 0xac, 0x48, 0xd6,
-	0x59, 0x5b, 0x45, 0xc9, 0x61, 0xc3, 0x72, 0x28, 0xa3, 0x0f, 0xd2, 0x84, 0x64, 0xc2, 0x46, 0x37,
-	0xb2, 0x3c, 0x13, 0xfd, 0xad, 0xc2, 0x69, 0xe6, 0x1d, 0x4d, 0xf8, 0xde, 0x03, 0x28, 0xb6, 0x9f,
-	0xa4, 0xd3, 0x8f, 0xd8, 0x7c, 0x07, 0x3a, 0x7d, 0x2a, 0x0e, 0xab, 0xfb, 0xbb, 0x2a, 0xf4, 0x39,
-	0xac, 0x95, 0xa2, 0x93, 0x45, 0x55, 0x69, 0x4b, 0xb8, 0x59, 0x2b, 0xe1, 0x12, 0xda, 0xf2, 0xb5,
-	0xd0, 0x56, 0x66, 0xa0, 0x3d, 0x81, 0x4e, 0x8f, 0x0a, 0x6e, 0x78, 0xbd, 0x0f, 0xcb, 0x29, 0x8d,
-	0x93, 0x8c, 0x07, 0xde, 0x8e, 0xbf, 0xdb, 0x8e, 0x0a, 0x49, 0xea, 0xd5, 0x5e, 0x3c, 0x68, 0x68,
-	0xbd, 0x96, 0xc2, 0x27, 0xd0, 0xd6, 0xcb, 0x25, 0x2f, 0xfb, 0xd0, 0xea, 0x53, 0xc1, 0x8f, 0xb3,
-	0x0b, 0xaa, 0x96, 0x77, 0x0e, 0x36, 0x2b, 0xa5, 0x50, 0xd8, 0xa2, 0xd2, 0x2b, 0x7c, 0xdf, 0x80,
-	0x96, 0x51, 0x57, 0x60, 0x78, 0xd7, 0xc2, 0x68, 0xd4, 0x61, 0x48, 0xd2, 0x33, 0x7b, 0x9f, 0xd4,
-	0x37, 0x0a, 0x60, 0x85, 0x0b, 0xcc, 0xc4, 0x53, 0xa1, 0xc8, 0xf4, 0x23, 0x23, 0xca, 0x9d, 0x52,
-	0xcc, 0xc5, 0x69, 0x92, 0xc5, 0x8a, 0x49, 0x3f, 0x2a, 0x65, 0x49, 0xb1, 0xc2, 0x5c, 0x30, 0xa9,
-	0x05, 0x79, 0xe4, 0xea, 0x43, 0x61, 0xd3, 0x2c, 0x5a, 0x85, 0x64, 0x87, 0x0b, 0x2c, 0x72, 0x1e,
-	0xb4, 0x76, 0xbc, 0xdd, 0x66, 0x54, 0x48, 0xf6, 0xae, 0xab, 0x65, 0x6d, 0xf7, 0xae, 0xab, 0x75,
-	0x5f, 0xc2, 0xc6, 0x98, 0x8e, 0x49, 0x66, 0x2e, 0xa5, 0xf4, 0x02, 0xe5, 0x35, 0xa3, 0xb7, 0x47,
-	0xdf, 0x71, 0x8f, 0x5e, 0x62, 0x1c, 0xe0, 0xec, 0x0d, 0x4b, 0x83, 0x55, 0xa5, 0x37, 0x62, 0xf8,
-	0x97, 0x07, 0xeb, 0x3d, 0x2a, 0x4e, 0x64, 0x92, 0x4e, 0xcb, 0xf8, 0x68, 0x86, 0x4b, 0x5e, 0x7c,
-	0x97, 0x97, 0x2e, 0xb4, 0x26, 0x98, 0xf3, 0x4b, 0xca, 0x86, 0x45, 0xc5, 0x96, 0xb2, 0xe4, 0x2c,
-	0xa3, 0x22, 0xb9, 0x98, 0xca, 0xec, 0x74, 0xc1, 0x5a, 0x45, 0x95, 0xd1, 0xe5, 0x3a, 0xa3, 0x25,
-	0xda, 0x15, 0x07, 0x6d, 0xd1, 0xd5, 0x4f, 0x68, 0x4c, 0x73, 0xb1, 0xb0, 0xab, 0x97, 0x48, 0x1b,
-	0x55, 0xa4, 0xe1, 0x37, 0x70, 0xfb, 0xf5, 0x84, 0x30, 0x2c, 0x12, 0x9a, 0xe9, 0xc2, 0x45, 0xb0,
-	0x34, 0xa0, 0x43, 0x7d, 0x97, 0x9b, 0x91, 0xfa, 0x96, 0xcc, 0x8e, 0x09, 0xe7, 0x38, 0x36, 0x64,
-	0x18, 0x31, 0xfc, 0x09, 0xd6, 0x2c, 0xb1, 0x72, 0xf9, 0x06, 0xf8, 0x63, 0x1e, 0x9b, 0xe6, 0x39,
-	0xe6, 0x31, 0xfa, 0x1a, 0x3a, 0x7a, 0xbb, 0x23, 0xc6, 0x28, 0x53, 0x01, 0x3a, 0x07, 0x5d, 0xf7,
-	0x32, 0x54, 0x33, 0x88, 0x5c, 0xf7, 0xf0, 0x0f, 0x4f, 0xe1, 0x7c, 0x3a, 0xd0, 0x76, 0x8d, 0x73,
-	0x17, 0xd6, 0xb1, 0xab, 0x28, 0x11, 0xd7, 0xd5, 0xf6, 0xa4, 0x1a, 0xee, 0x49, 0x3d, 0x04, 0xd0,
-	0x8e, 0xea, 0x7c, 0x8b, 0xb9, 0x63, 0x35, 0xd6, 0xde, 0xb3, 0xd3, 0xc7, 0xd1, 0x84, 0xff, 0x78,
-	0x70, 0xdb, 0x49, 0x4a, 0xe2, 0xfe, 0xf0, 0x94, 0x64, 0x99, 0xe6, 0x83, 0x01, 0xe1, 0x5c, 0x25,
-	0xd5, 0x8a, 0x8c, 0x68, 0xb8, 0xf3, 0x2d, 0x77, 0x73, 0x06, 0x60, 0x9d, 0xcf, 0xe6, 0x7f, 0xe2,
-	0xb3, 0x56, 0xda, 0xcb, 0x33, 0xa5, 0xed, 0x16, 0xcb, 0x4a, 0xad, 0x58, 0xde, 0x98, 0xd1, 0xfb,
-	0x3c, 0x49, 0xd3, 0xeb, 0x6b, 0x4e, 0x76, 0x01, 0x9a, 0xb3, 0x81, 0x29, 0x98, 0x42, 0x2a, 0x01,
-	0xf9, 0xce, 0x44, 0xff, 0x0a, 0xd6, 0xdd, 0xb0, 0x92, 0x4d, 0x87, 0x23, 0xaf, 0xc2, 0x51, 0xf8,
-	0xbb, 0x07, 0x9b, 0x67, 0x82, 0x11, 0x3c, 0x4e, 0xb2, 0xf8, 0x50, 0xb0, 0xf4, 0xff, 0xb8, 0xcf,
-	0x8f, 0xa1, 0xcd, 0x88, 0xce, 0x90, 0x07, 0xbe, 0xea, 0xd6, 0x0f, 0x5c, 0x42, 0xcb, 0x0d, 0xa3,
-	0xc2, 0x2b, 0xb2, 0xfe, 0xe1, 0x9f, 0x1e, 0xdc, 0x99, 0x71, 0xb8, 0x82, 0x96, 0x10, 0x56, 0xcd,
-	0xc2, 0x32, 0x95, 0x66, 0x54, 0xd1, 0xcd, 0x29, 0xce, 0x66, 0xa5, 0x38, 0xb7, 0xa1, 0x2d, 0x53,
-	0xcb, 0x39, 0x61, 0x3c, 0x58, 0x52, 0x13, 0xc8, 0x2a, 0x14, 0xd4, 0x11, 0x16, 0x31, 0xa3, 0xf9,
-	0x84, 0x07, 0x4d, 0x65, 0x76, 0x34, 0x07, 0x7f, 0xb7, 0x00, 0x0e, 0x47, 0x58, 0xf4, 0xa8, 0xf8,
-	0x36, 0xef, 0xa3, 0x23, 0xe8, 0xa8, 0x61, 0x7e, 0x9e, 0x67, 0x19, 0x49, 0x51, 0xe0, 0xa2, 0x76,
-	0x1f, 0x19, 0xdd, 0xfb, 0x73, 0x2c, 0x93, 0x74, 0x1a, 0xde, 0xda, 0xf5, 0xf6, 0x3d, 0xf4, 0x18,
-	0x56, 0x5e, 0x10, 0x19, 0x93, 0xa3, 0x4f, 0xea, 0x63, 0xce, 0x44, 0xb8, 0x37, 0x6b, 0x50, 0x01,
-	0xd0, 0x33, 0x35, 0xf7, 0x54, 0x0f, 0x41, 0x5b, 0x35, 0x27, 0xb7, 0x65, 0x77, 0x3f, 0x9d, 0x6f,
-	0xd4, 0x51, 0x5e, 0xa8, 0xe9, 0xab, 0xfb, 0x21, 0xda, 0x9e, 0xf5, 0xb4, 0x6d, 0xb2, 0x7b, 0xcd,
-	0x65, 0x09, 0x6f, 0xa1, 0x63, 0xf5, 0x0a, 0x38, 0x1b, 0xe5, 0x62, 0x48, 0x2f, 0xb3, 0x1b, 0x85,
-	0xd2, 0x39, 0xe9, 0x36, 0x31, 0x13, 0xa8, 0xd2, 0xd2, 0xaa, 0x81, 0xaa, 0xbd, 0xa5, 0x0c, 0xa4,
-	0x6f, 0xc9, 0x4c, 0xa0, 0xca, 0xcb, 0x7e, 0x41, 0x46, 0x2f, 0xd5, 0x20, 0x7c, 0xe9, 0x0c, 0xd4,
-	0x1b, 0x86, 0x5b, 0x75, 0xff, 0x09, 0xd0, 0x67, 0xae, 0xf7, 0x9c, 0xbf, 0x85, 0x85, 0xd4, 0x83,
-	0x7d, 0xdb, 0xa3, 0x07, 0xb3, 0xc1, 0x9c, 0x37, 0xff, 0xe2, 0xcc, 0xdc, 0xc7, 0x79, 0x35, 0xb3,
-	0x39, 0xcf, 0xf6, 0x05, 0xe1, 0xbe, 0x33, 0x99, 0xc9, 0x1e, 0x35, 0x2f, 0x33, 0xa7, 0x25, 0x76,
-	0xb7, 0xae, 0x32, 0xeb, 0x58, 0x27, 0xb0, 0xf9, 0x23, 0xe9, 0x9f, 0x8d, 0x28, 0x13, 0x87, 0x58,
-	0xaa, 0xf9, 0x84, 0x66, 0x9c, 0xa0, 0x2b, 0xae, 0xd8, 0x42, 0xce, 0xd6, 0xcb, 0xee, 0x73, 0xc3,
-	0x5b, 0xfc, 0x1a, 0xd6, 0x2a, 0xad, 0x15, 0xed, 0xcc, 0x6d, 0x82, 0x4e, 0xd7, 0xbd, 0x3e, 0xb7,
-	0xde, 0x3e, 0x6c, 0x65, 0x44, 0xec, 0x8d, 0xf0, 0xe5, 0xcf, 0x97, 0x89, 0x18, 0x5d, 0x26, 0xd9,
-	0xd0, 0xf1, 0xef, 0xad, 0xdb, 0x46, 0x74, 0x2a, 0xff, 0x5a, 0x4f, 0xbd, 0xfe, 0xb2, 0xfa, 0x7d,
-	0x7d, 0xf4, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x40, 0x8d, 0xd4, 0x6d, 0xd2, 0x0e, 0x00, 0x00,
+	// 1831 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0x4f, 0x6f, 0xdb, 0xc8,
+	0x15, 0x0f, 0x45, 0xcb, 0x96, 0x9e, 0x1c, 0x4b, 0x99, 0x78, 0xb3, 0xac, 0xe2, 0x4d, 0x8d, 0x41,
+	0xb1, 0x75, 0xff, 0x20, 0x08, 0xbc, 0x40, 0x2f, 0xe9, 0x16, 0x88, 0xec, 0x24, 0xeb, 0x56, 0xd9,
+	0x64, 0xa9, 0x64, 0x7b, 0x2c, 0x28, 0x71, 0x4c, 0x11, 0x4b, 0xcd, 0x70, 0x39, 0xc3, 0x78, 0x0d,
+	0xf4, 0x54, 0x60, 0xcf, 0x45, 0xfb, 0x35, 0xfa, 0x55, 0x7a, 0x2e, 0xfa, 0x15, 0x7a, 0xef, 0xa1,
+	0xc7, 0x62, 0x66, 0x38, 0xe4, 0x90, 0x94, 0x2d, 0x67, 0xbd, 0x37, 0xbe, 0x37, 0x6f, 0x1e, 0xdf,
+	0xfb, 0xbd, 0xbf, 0x24, 0x8c, 0x16, 0xcb, 0x40, 0xcc, 0x99, 0x58, 0xe6, 0xf3, 0xc7, 0x69, 0xc6,
+	0x04, 0x43, 0x50, 0x71, 0xf0, 0x29, 0x8c, 0x26, 0x4c, 0xbc, 0x88, 0x13, 0x41, 0x32, 0x9f, 0x7c,
+	0x9b, 0x13, 0x2e, 0xd0, 0x3e, 0x74, 0xe7, 0x4c, 0x9c, 0x85, 0x9e, 0x73, 0xe8, 0x1c, 0xf5, 0x7d,
+	0x4d, 0xa0, 0x31, 0xf4, 0xce, 0x95, 0xd8, 0x59, 0xe8, 0x75, 0xd4, 0x41, 0x49, 0xe3, 0xef, 0x1d,
+	0xb8, 0xaf, 0x75, 0x9c, 0x64, 0x24, 0x10, 0xc4, 0x68, 0xb2, 0xef, 0x38, 0xf5, 0x3b, 0xe8, 0x11,
+	0x80, 0x7e, 0x7e, 0x7b, 0x99, 0x92, 0x42, 0xa3, 0xc5, 0xa9, 0xce, 0xbf, 0x0c, 0x56, 0xc4, 0x73,
+	0xed, 0x73, 0xc9, 0x41, 0x08, 0xb6, 0xe6, 0x2c, 0xbc, 0xf4, 0xb6, 0xd4, 0x89, 0x7a, 0xc6, 0x33,
+	0xb8, 0xa7, 0xcd, 0xf8, 0x92, 0x7c, 0x27, 0x6e, 0x62, 0x04, 0x86, 0x5d, 0x4a, 0xbe, 0x13, 0x2f,
+	0xea, 0x8e, 0xd5, 0x78, 0xf8, 0x33, 0xe8, 0x4f, 0xb2, 0x80, 0x2e, 0x96, 0x6f, 0x83, 0x08, 0x8d,
+	0xc0, 0xfd, 0x03, 0xb9, 0x2c, 0xf4, 0xc8, 0x47, 0x89, 0xd6, 0xd7, 0x41, 0x92, 0x1b, 0x17, 0x34,
+	0x81, 0xdf, 0xc3, 0x7d, 0x9f, 0xe5, 0x82, 0x64, 0xfa, 0xaa, 0xb1, 0xe5, 0xe7, 0xe0, 0x8a, 0x20,
+	0x52, 0xd7, 0x07, 0xc7, 0x1f, 0x3d, 0xb6, 0x42, 0x53, 0xbe, 0xc2, 0x97, 0x12, 0xd2, 0xe8, 0x8c,
+	0xe5, 0xb6, 0x51, 0x25, 0x5d, 0x73, 0xc8, 0x6d, 0x44, 0xe2, 0xcf, 0xb0, 0xfb, 0xfc, 0x3d, 0xa1,
+	0xa5, 0xf3, 0x07, 0xd0, 0x27, 0x92, 0x56, 0x20, 0x6b, 0xab, 0x2b, 0x46, 0x89, 0x61, 0xa7, 0xc2,
+	0x50, 0x6a, 0x5f, 0x24, 0x31, 0xa1, 0xa2, 0xd2, 0x6e, 0x68, 0x19, 0x13, 0xfd, 0xac, 0xd4, 0x69,
+	0xe4, 0x2d, 0x0e, 0xfe, 0xb7, 0x03, 0x50, 0xbc, 0x3e, 0x4d, 0x2e, 0x7f, 0xc0, 0xcb, 0x0f, 0x61,
+	0x30, 0x67, 0xe2, 0xa4, 0xfe, 0x7e, 0x9b, 0x85, 0x7e, 0x06, 0x77, 0x4b, 0xd2, 0xb2, 0xa2, 0xce,
+	0xac, 0x52, 0xb8, 0xdb, 0x48, 0xe1, 0xd2, 0xb5, 0xed, 0x6b, 0x5d, 0xdb, 0x69, 0xb9, 0xf6, 0x39,
+	0x0c, 0x26, 0x4c, 0x70, 0x83, 0xeb, 0x03, 0xd8, 0x4e, 0x58, 0x14, 0x53, 0xee, 0x39, 0x87, 0xee,
+	0x51, 0xdf, 0x2f, 0x28, 0xc9, 0x57, 0xef, 0xe2, 0x5e, 0x47, 0xf3, 0x35, 0x85, 0x3f, 0x87, 0xbe,
+	0xbe, 0x2e, 0x71, 0x79, 0x02, 0xbd, 0x39, 0x13, 0xfc, 0x8c, 0x9e, 0x33, 0x75, 0x7d, 0x70, 0xbc,
+	0x5f, 0x4b, 0x85, 0xe2, 0xcc, 0x2f, 0xa5, 0xf0, 0x5f, 0xb6, 0xa0, 0x67, 0xd8, 0x35, 0x37, 0x9c,
+	0x6b, 0xdd, 0xe8, 0x34, 0xdd, 0x90, 0xa0, 0xd3, 0xaa, 0x9e, 0xd4, 0x33, 0xf2, 0x60, 0x87, 0x8b,
+	0x20, 0x13, 0xcf, 0x84, 0x02, 0xd3, 0xf5, 0x0d, 0x29, 0xdf, 0x94, 0x04, 0x5c, 0xbc, 0x89, 0x69,
+	0xa4, 0x90, 0x74, 0xfd, 0x92, 0x96, 0x10, 0x2b, 0x9f, 0x0b, 0x24, 0x35, 0x21, 0x43, 0xae, 0x1e,
+	0x94, 0x6f, 0x1a, 0xc5, 0x8a, 0x21, 0xd1, 0xe1, 0x22, 0x10, 0x39, 0xf7, 0x7a, 0x87, 0xce, 0x51,
+	0xd7, 0x2f, 0xa8, 0xaa, 0xd6, 0xd5, 0xb5, 0xbe, 0x5d, 0xeb, 0xea, 0xde, 0x2f, 0x61, 0xb4, 0x62,
+	0x2b, 0x42, 0xc5, 0x8b, 0x4a, 0x0a, 0x94, 0x54, 0x8b, 0x5f, 0x85, 0x7e, 0x60, 0x87, 0x5e, 0xfa,
+	0xb8, 0x08, 0xe8, 0xbb, 0x2c, 0xf1, 0x76, 0x15, 0xdf, 0x90, 0xe8, 0x08, 0x86, 0xc1, 0x42, 0xc4,
+	0x8c, 0x7e, 0x95, 0x93, 0x9c, 0x28, 0xd5, 0x77, 0x95, 0x44, 0x93, 0x2d, 0x25, 0x55, 0x03, 0x5d,
+	0xb0, 0xe4, 0x6b, 0x92, 0xf1, 0x98, 0x51, 0x6f, 0x4f, 0xb9, 0xd1, 0x64, 0x4b, 0x7b, 0x79, 0x9e,
+	0xa6, 0x2c, 0x13, 0x24, 0x7c, 0xa6, 0xb4, 0x70, 0x6f, 0xa8, 0xf2, 0xa1, 0xc5, 0x97, 0x5a, 0x4b,
+	0x9e, 0xaa, 0x1d, 0xee, 0x8d, 0x94, 0x68, 0x93, 0x8d, 0xff, 0xe3, 0xc0, 0x70, 0xc2, 0xc4, 0x54,
+	0xc2, 0x69, 0x35, 0xb7, 0x1f, 0x9c, 0x0b, 0x65, 0x04, 0x5d, 0x3b, 0x82, 0x63, 0xe8, 0xa5, 0x01,
+	0xe7, 0x17, 0x2c, 0x0b, 0x8b, 0xda, 0x2a, 0x69, 0x19, 0x5d, 0xca, 0x44, 0x7c, 0x7e, 0x29, 0x71,
+	0xd4, 0xa5, 0x55, 0x31, 0xea, 0xb1, 0xdf, 0x6e, 0xc6, 0xbe, 0x8c, 0xcb, 0x8e, 0x1d, 0x97, 0x07,
+	0xb0, 0x9d, 0x91, 0x48, 0x42, 0xd9, 0x53, 0xec, 0x82, 0x2a, 0xe6, 0xd2, 0x94, 0x45, 0x2c, 0x17,
+	0x1b, 0xe7, 0x52, 0x89, 0x40, 0xa7, 0x8e, 0x00, 0xfe, 0x1d, 0xec, 0xbd, 0x4e, 0x49, 0x16, 0x48,
+	0xa4, 0x75, 0xe9, 0x21, 0xd8, 0x5a, 0xb0, 0x50, 0x77, 0xa3, 0xae, 0xaf, 0x9e, 0x65, 0x6e, 0xac,
+	0x08, 0xe7, 0x41, 0x64, 0x40, 0x32, 0x24, 0xfe, 0x13, 0xdc, 0xad, 0x00, 0x97, 0xd7, 0x47, 0xe0,
+	0xae, 0x78, 0x64, 0xda, 0xff, 0x8a, 0x47, 0xe8, 0xb7, 0x30, 0xd0, 0xaf, 0x7b, 0x9e, 0x65, 0x2c,
+	0x53, 0x0a, 0x06, 0xc7, 0x63, 0xbb, 0x9c, 0xeb, 0x16, 0xf8, 0xb6, 0x38, 0xfe, 0xbb, 0xa3, 0xfc,
+	0xd4, 0xb9, 0x60, 0xfc, 0x2c, 0x33, 0xb2, 0x60, 0x94, 0x1e, 0x37, 0xd9, 0x55, 0x04, 0x3b, 0x76,
+	0x04, 0x1f, 0x01, 0x68, 0x41, 0x15, 0xf7, 0x62, 0x72, 0x56, 0x9c, 0xea, 0x7c, 0x52, 0xcd, 0x4f,
+	0x8b, 0x83, 0xff, 0xeb, 0xc0, 0x9e, 0x65, 0x94, 0xf4, 0xfb, 0xe6, 0x26, 0xc9, 0x42, 0xcb, 0x17,
+	0x0b, 0xc2, 0xb9, 0x32, 0xaa, 0xe7, 0x1b, 0xd2, 0x60, 0xe7, 0x56, 0xd8, 0xad, 0x19, 0xe1, 0x4d,
+	0x3c, 0xbb, 0x1f, 0x84, 0x67, 0x23, 0xe5, 0xb7, 0x5b, 0x29, 0x6f, 0x27, 0xcb, 0x4e, 0x23, 0x59,
+	0xde, 0x99, 0xe5, 0xe1, 0x45, 0x9c, 0x24, 0xd7, 0xe7, 0x9c, 0xec, 0x63, 0x2c, 0xcf, 0x16, 0x26,
+	0x61, 0x0a, 0xaa, 0x74, 0xc8, 0xb5, 0x76, 0x92, 0x5f, 0xc1, 0xd0, 0x56, 0x2b, 0xd1, 0xb4, 0x30,
+	0x72, 0x6a, 0x18, 0xe1, 0xbf, 0x3a, 0xb0, 0x3f, 0x13, 0x19, 0x09, 0x56, 0x31, 0x8d, 0x4e, 0x44,
+	0x96, 0xfc, 0x18, 0x75, 0xfe, 0x14, 0xfa, 0x19, 0xd1, 0x16, 0x72, 0xcf, 0x55, 0xf3, 0xe6, 0x13,
+	0x1b, 0xd0, 0xf2, 0x85, 0x7e, 0x21, 0xe5, 0x57, 0xf2, 0xf8, 0x1f, 0x0e, 0xdc, 0x6b, 0x09, 0x5c,
+	0x01, 0x0b, 0x86, 0x5d, 0x73, 0xb1, 0x34, 0xa5, 0xeb, 0xd7, 0x78, 0x6b, 0x92, 0xb3, 0x5b, 0x4b,
+	0xce, 0x03, 0xe8, 0x4b, 0xd3, 0x72, 0x4e, 0x32, 0xee, 0x6d, 0xa9, 0x46, 0x58, 0x31, 0x94, 0xab,
+	0xcb, 0x40, 0x44, 0x19, 0xcb, 0x53, 0xee, 0x75, 0xd5, 0xb1, 0xc5, 0xc1, 0x27, 0x30, 0x3c, 0xcd,
+	0x82, 0x98, 0x7e, 0x91, 0xcf, 0xad, 0x49, 0x2d, 0x82, 0x2c, 0x22, 0xa2, 0xb0, 0xb5, 0xa0, 0x64,
+	0x10, 0x44, 0xbc, 0x22, 0x2c, 0x17, 0xca, 0x4e, 0xd7, 0x37, 0x24, 0xde, 0x07, 0xa4, 0x94, 0xcc,
+	0xd4, 0x70, 0x2a, 0xf4, 0xe0, 0xff, 0x39, 0x30, 0xaa, 0xb1, 0x65, 0x24, 0xc7, 0xd0, 0x0b, 0x25,
+	0x4f, 0x0e, 0x48, 0x1d, 0xca, 0x92, 0x96, 0x18, 0xa5, 0xcb, 0x80, 0x97, 0x8b, 0xa1, 0x22, 0x2c,
+	0x73, 0xdc, 0xa6, 0x39, 0x57, 0x0f, 0xe1, 0x90, 0x04, 0x61, 0x12, 0x53, 0x62, 0x86, 0xb0, 0xa1,
+	0x75, 0xc2, 0x09, 0xae, 0x32, 0xbd, 0xeb, 0xab, 0x67, 0x59, 0xab, 0x31, 0x3d, 0x4f, 0xe2, 0x68,
+	0x29, 0xcc, 0xec, 0xd9, 0xd1, 0x63, 0xaa, 0xc1, 0x46, 0x9f, 0xc2, 0x5e, 0x4a, 0x68, 0x18, 0xd3,
+	0xe8, 0xd5, 0xb7, 0x33, 0x42, 0x43, 0x33, 0x96, 0x1b, 0x5c, 0x1c, 0x02, 0xd2, 0xbb, 0xd5, 0xb3,
+	0x70, 0x75, 0xb3, 0xd1, 0x53, 0x79, 0xd9, 0xa9, 0x79, 0x29, 0x17, 0x0a, 0x16, 0x4d, 0xc9, 0x7b,
+	0x92, 0x98, 0xe5, 0xd2, 0xd0, 0xf8, 0x37, 0x00, 0x72, 0xb1, 0x98, 0x05, 0xab, 0x34, 0x21, 0x68,
+	0x0f, 0x3a, 0x81, 0x0e, 0x99, 0xeb, 0x77, 0x02, 0x85, 0x4f, 0x12, 0x08, 0x42, 0x17, 0x97, 0x26,
+	0x5c, 0x05, 0x89, 0xdf, 0xc1, 0xf0, 0x6d, 0x4e, 0x29, 0x49, 0xa4, 0xb1, 0xba, 0x0d, 0x34, 0x2f,
+	0xd7, 0x16, 0xd1, 0x4e, 0x73, 0x11, 0xdd, 0x87, 0x2e, 0x91, 0xd7, 0xcc, 0x1c, 0x54, 0x04, 0xce,
+	0x60, 0xef, 0xac, 0x86, 0xd7, 0x07, 0x34, 0xc1, 0x7a, 0x92, 0x77, 0x5a, 0x1d, 0x58, 0xf6, 0x0f,
+	0x09, 0xa7, 0x4e, 0x02, 0xd7, 0x2f, 0x28, 0xbc, 0x82, 0x81, 0x06, 0x5a, 0x4d, 0xfc, 0x75, 0x6e,
+	0x84, 0x71, 0x46, 0x94, 0x1e, 0xe3, 0x46, 0xc9, 0xa8, 0x3b, 0xe9, 0x5e, 0xb5, 0x6d, 0xdb, 0x9f,
+	0x4b, 0xff, 0x72, 0x61, 0x54, 0x2c, 0xd6, 0x94, 0xa7, 0x85, 0x9a, 0x46, 0x03, 0x76, 0x3e, 0xac,
+	0x01, 0xdb, 0xab, 0xad, 0x9e, 0x85, 0x1b, 0x56, 0x5b, 0xb9, 0xf2, 0x2f, 0xf3, 0xf9, 0x19, 0xe5,
+	0x22, 0xa0, 0x0b, 0x63, 0xb8, 0xcd, 0xaa, 0x7d, 0xef, 0x6c, 0x35, 0x3e, 0xe0, 0x3e, 0x85, 0xbd,
+	0xda, 0x06, 0x68, 0x36, 0xfe, 0x06, 0x17, 0xfd, 0x1a, 0xba, 0x69, 0x4c, 0x23, 0x59, 0x29, 0xb2,
+	0xff, 0x3d, 0xb0, 0x8d, 0xaa, 0xb2, 0xce, 0xd7, 0x42, 0xe8, 0x29, 0x00, 0x37, 0xc9, 0x24, 0xab,
+	0x47, 0x5e, 0x79, 0x68, 0x5f, 0x69, 0x24, 0x9c, 0x6f, 0x89, 0xa3, 0xd3, 0x76, 0xfd, 0xf5, 0x0e,
+	0xdd, 0x26, 0x88, 0xf5, 0xdc, 0x6a, 0xd7, 0xe6, 0x53, 0xd9, 0x4b, 0x17, 0x26, 0x15, 0xb8, 0xd7,
+	0x57, 0x2a, 0x3e, 0xb6, 0x55, 0x58, 0xa9, 0xe2, 0xd7, 0x84, 0xb1, 0xaf, 0x06, 0xf8, 0x94, 0x45,
+	0xfc, 0xfa, 0x39, 0x26, 0xf7, 0x07, 0x55, 0x8b, 0x66, 0x7f, 0x90, 0x84, 0xe2, 0xc6, 0xab, 0x58,
+	0x14, 0xdd, 0x59, 0x13, 0xf8, 0x6f, 0x0e, 0xec, 0x4c, 0x59, 0x34, 0x95, 0x6d, 0xa7, 0x99, 0x98,
+	0xeb, 0xf5, 0x78, 0xb0, 0x93, 0x06, 0x8b, 0x6f, 0xe4, 0x5e, 0xa5, 0xa3, 0x6a, 0x48, 0x7b, 0xe3,
+	0xda, 0xaa, 0x6d, 0x5c, 0x55, 0x2d, 0x76, 0xad, 0x5a, 0x94, 0xf5, 0x72, 0x1e, 0x93, 0x24, 0xe4,
+	0xc5, 0x48, 0x2f, 0x28, 0x7c, 0x01, 0xbb, 0xa5, 0x9f, 0xb2, 0x1d, 0xdf, 0x2e, 0x77, 0x7f, 0x21,
+	0xfd, 0xa6, 0x44, 0x7f, 0xba, 0x0d, 0x8e, 0xef, 0xdb, 0xf7, 0x0a, 0xcf, 0x7d, 0x2d, 0x71, 0xfc,
+	0x3d, 0x00, 0x9c, 0x2c, 0x03, 0x31, 0x61, 0xe2, 0x8b, 0x7c, 0x8e, 0x9e, 0xc3, 0x40, 0x21, 0xaf,
+	0xd3, 0x02, 0x79, 0xf6, 0x4d, 0xfb, 0x73, 0x7c, 0xfc, 0x60, 0xcd, 0x49, 0x9a, 0x5c, 0xe2, 0x3b,
+	0x47, 0xce, 0x13, 0x07, 0x3d, 0x85, 0x9d, 0x97, 0x44, 0xea, 0xe4, 0xe8, 0xe3, 0x66, 0xd5, 0x18,
+	0x0d, 0x1f, 0xb5, 0x0f, 0x94, 0x02, 0x74, 0x0a, 0x3d, 0xb3, 0xab, 0xa2, 0x87, 0x0d, 0x21, 0xfb,
+	0x93, 0x61, 0xfc, 0x93, 0xf5, 0x87, 0x5a, 0xcb, 0x4b, 0xe8, 0x97, 0x7b, 0x37, 0x3a, 0x68, 0x4b,
+	0x56, 0xeb, 0xf8, 0xf8, 0x1a, 0x5c, 0xf1, 0x1d, 0x74, 0xa6, 0xbe, 0x97, 0x67, 0xcb, 0x5c, 0x84,
+	0xec, 0x82, 0xde, 0x4a, 0x95, 0xb6, 0xa9, 0x68, 0xc2, 0x4d, 0x45, 0xb5, 0xd5, 0x79, 0x3c, 0xbe,
+	0xe2, 0xd4, 0x56, 0xa4, 0x7b, 0x42, 0x4b, 0x51, 0xed, 0x1f, 0xd8, 0x06, 0x8b, 0x5e, 0xa9, 0x0f,
+	0xb1, 0x57, 0x56, 0x8b, 0xb9, 0xa5, 0xba, 0x5d, 0xfb, 0xef, 0x19, 0xfa, 0xa9, 0x2d, 0xbd, 0xe6,
+	0xbf, 0xda, 0x46, 0xe8, 0xa1, 0xfa, 0x0b, 0x86, 0x3e, 0x69, 0x2b, 0xb3, 0xfe, 0x8e, 0x6d, 0xb6,
+	0xcc, 0xfe, 0x8d, 0x55, 0xb7, 0x6c, 0xcd, 0x0f, 0xae, 0x0d, 0xea, 0x7e, 0x0f, 0x50, 0xed, 0xc2,
+	0xeb, 0x2c, 0xb3, 0x56, 0xef, 0xf1, 0xc3, 0xab, 0x8e, 0xb5, 0xae, 0x29, 0xec, 0xff, 0x91, 0xcc,
+	0x67, 0x4b, 0x96, 0x89, 0x93, 0x40, 0xb2, 0x79, 0xca, 0xa8, 0x5c, 0xb0, 0xd6, 0x97, 0xd8, 0x46,
+	0xcc, 0x86, 0xe5, 0x96, 0x7b, 0xcb, 0x2a, 0x7e, 0x0d, 0x77, 0x6b, 0x2b, 0x3c, 0x3a, 0x5c, 0xbb,
+	0x6c, 0x5b, 0xdb, 0xfd, 0xc6, 0xfc, 0xef, 0x99, 0xa5, 0xb6, 0x5e, 0xd9, 0x8d, 0x55, 0x77, 0x7c,
+	0xd0, 0x3a, 0xb4, 0x76, 0x55, 0x7c, 0x07, 0xbd, 0x81, 0xbd, 0x97, 0x44, 0x58, 0x07, 0xe8, 0xd1,
+	0x95, 0x37, 0x6e, 0xa4, 0xf1, 0xf8, 0x9f, 0x1d, 0x18, 0x56, 0x7d, 0x50, 0xad, 0x87, 0xd2, 0xff,
+	0x62, 0x9d, 0xd0, 0x03, 0xaa, 0xfe, 0x92, 0xf6, 0x22, 0x39, 0x3e, 0x68, 0x9f, 0x57, 0xfb, 0x08,
+	0xbe, 0x83, 0x5e, 0xc3, 0xd0, 0x27, 0x0b, 0x46, 0xe9, 0xcd, 0x55, 0x5e, 0x0f, 0xe8, 0x57, 0x70,
+	0x6f, 0x46, 0x0a, 0x55, 0xd3, 0x62, 0xfd, 0xbc, 0xa5, 0xca, 0x53, 0x00, 0xdd, 0xba, 0xe5, 0x30,
+	0x42, 0xe3, 0x76, 0xb7, 0x2b, 0x21, 0xf5, 0xd6, 0x9e, 0x29, 0x2d, 0x93, 0x27, 0xf0, 0x90, 0x12,
+	0xf1, 0x78, 0x19, 0x5c, 0x7c, 0x73, 0x11, 0x8b, 0xe5, 0x45, 0x4c, 0x43, 0x4b, 0x7a, 0x62, 0x41,
+	0xfd, 0x26, 0x63, 0x82, 0xbd, 0x71, 0xe6, 0xdb, 0xea, 0xd7, 0xd3, 0x67, 0xff, 0x1f, 0x00, 0x31,
+	0x18, 0xac, 0xfd, 0xe6, 0x17, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1377,6 +2203,8 @@ type ChatBotHubClient interface {
 	StreamingTunnel(ctx context.Context, opts ...grpc.CallOption) (ChatBotHub_StreamingTunnelClient, error)
 	// streaming rpc
 	StreamingCtrl(ctx context.Context, in *StreamingCtrlRequest, opts ...grpc.CallOption) (*OperationReply, error)
+	DrainHub(ctx context.Context, in *DrainHubRequest, opts ...grpc.CallOption) (*DrainStatusReply, error)
+	GetDrainStatus(ctx context.Context, in *DrainStatusRequest, opts ...grpc.CallOption) (*DrainStatusReply, error)
 }
 
 type chatBotHubClient struct {
@@ -1566,6 +2394,24 @@ func (c *chatBotHubClient) StreamingCtrl(ctx context.Context, in *StreamingCtrlR
 	return out, nil
 }
 
+func (c *chatBotHubClient) DrainHub(ctx context.Context, in *DrainHubRequest, opts ...grpc.CallOption) (*DrainStatusReply, error) {
+	out := new(DrainStatusReply)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHub/DrainHub", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatBotHubClient) GetDrainStatus(ctx context.Context, in *DrainStatusRequest, opts ...grpc.CallOption) (*DrainStatusReply, error) {
+	out := new(DrainStatusReply)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHub/GetDrainStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatBotHubServer is the server API for ChatBotHub service.
 type ChatBotHubServer interface {
 	// bots only use eventtunnel to communicate
@@ -1587,6 +2433,8 @@ type ChatBotHubServer interface {
 	StreamingTunnel(ChatBotHub_StreamingTunnelServer) error
 	// streaming rpc
 	StreamingCtrl(context.Context, *StreamingCtrlRequest) (*OperationReply, error)
+	DrainHub(context.Context, *DrainHubRequest) (*DrainStatusReply, error)
+	GetDrainStatus(context.Context, *DrainStatusRequest) (*DrainStatusReply, error)
 }
 
 // UnimplementedChatBotHubServer can be embedded to have forward compatible implementations.
@@ -1638,6 +2486,12 @@ func (*UnimplementedChatBotHubServer) StreamingTunnel(srv ChatBotHub_StreamingTu
 func (*UnimplementedChatBotHubServer) StreamingCtrl(ctx context.Context, req *StreamingCtrlRequest) (*OperationReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StreamingCtrl not implemented")
 }
+func (*UnimplementedChatBotHubServer) DrainHub(ctx context.Context, req *DrainHubRequest) (*DrainStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainHub not implemented")
+}
+func (*UnimplementedChatBotHubServer) GetDrainStatus(ctx context.Context, req *DrainStatusRequest) (*DrainStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDrainStatus not implemented")
+}
 
 func RegisterChatBotHubServer(s *grpc.Server, srv ChatBotHubServer) {
 	s.RegisterService(&_ChatBotHub_serviceDesc, srv)
@@ -1929,6 +2783,42 @@ func _ChatBotHub_StreamingCtrl_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatBotHub_DrainHub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainHubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubServer).DrainHub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHub/DrainHub",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubServer).DrainHub(ctx, req.(*DrainHubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatBotHub_GetDrainStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubServer).GetDrainStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHub/GetDrainStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubServer).GetDrainStatus(ctx, req.(*DrainStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ChatBotHub_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "chatbothub.ChatBotHub",
 	HandlerType: (*ChatBotHubServer)(nil),
@@ -1985,6 +2875,14 @@ var _ChatBotHub_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StreamingCtrl",
 			Handler:    _ChatBotHub_StreamingCtrl_Handler,
 		},
+		{
+			MethodName: "DrainHub",
+			Handler:    _ChatBotHub_DrainHub_Handler,
+		},
+		{
+			MethodName: "GetDrainStatus",
+			Handler:    _ChatBotHub_GetDrainStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -2002,3 +2900,183 @@ var _ChatBotHub_serviceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "chatbothub.proto",
 }
+
+// ChatBotHubAdminClient is the client API for ChatBotHubAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ChatBotHubAdminClient interface {
+	InspectClient(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*ClientInspection, error)
+	ReconnectClient(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*OperationReply, error)
+	SetClientLogLevel(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*OperationReply, error)
+	GetBotLogs(ctx context.Context, in *BotLogsRequest, opts ...grpc.CallOption) (*BotLogsReply, error)
+}
+
+type chatBotHubAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewChatBotHubAdminClient(cc *grpc.ClientConn) ChatBotHubAdminClient {
+	return &chatBotHubAdminClient{cc}
+}
+
+func (c *chatBotHubAdminClient) InspectClient(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*ClientInspection, error) {
+	out := new(ClientInspection)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHubAdmin/InspectClient", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatBotHubAdminClient) ReconnectClient(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*OperationReply, error) {
+	out := new(OperationReply)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHubAdmin/ReconnectClient", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatBotHubAdminClient) SetClientLogLevel(ctx context.Context, in *ClientAdminRequest, opts ...grpc.CallOption) (*OperationReply, error) {
+	out := new(OperationReply)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHubAdmin/SetClientLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatBotHubAdminClient) GetBotLogs(ctx context.Context, in *BotLogsRequest, opts ...grpc.CallOption) (*BotLogsReply, error) {
+	out := new(BotLogsReply)
+	err := c.cc.Invoke(ctx, "/chatbothub.ChatBotHubAdmin/GetBotLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChatBotHubAdminServer is the server API for ChatBotHubAdmin service.
+type ChatBotHubAdminServer interface {
+	InspectClient(context.Context, *ClientAdminRequest) (*ClientInspection, error)
+	ReconnectClient(context.Context, *ClientAdminRequest) (*OperationReply, error)
+	SetClientLogLevel(context.Context, *ClientAdminRequest) (*OperationReply, error)
+	GetBotLogs(context.Context, *BotLogsRequest) (*BotLogsReply, error)
+}
+
+// UnimplementedChatBotHubAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedChatBotHubAdminServer struct {
+}
+
+func (*UnimplementedChatBotHubAdminServer) InspectClient(ctx context.Context, req *ClientAdminRequest) (*ClientInspection, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InspectClient not implemented")
+}
+func (*UnimplementedChatBotHubAdminServer) ReconnectClient(ctx context.Context, req *ClientAdminRequest) (*OperationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReconnectClient not implemented")
+}
+func (*UnimplementedChatBotHubAdminServer) SetClientLogLevel(ctx context.Context, req *ClientAdminRequest) (*OperationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetClientLogLevel not implemented")
+}
+func (*UnimplementedChatBotHubAdminServer) GetBotLogs(ctx context.Context, req *BotLogsRequest) (*BotLogsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBotLogs not implemented")
+}
+
+func RegisterChatBotHubAdminServer(s *grpc.Server, srv ChatBotHubAdminServer) {
+	s.RegisterService(&_ChatBotHubAdmin_serviceDesc, srv)
+}
+
+func _ChatBotHubAdmin_InspectClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubAdminServer).InspectClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHubAdmin/InspectClient",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubAdminServer).InspectClient(ctx, req.(*ClientAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatBotHubAdmin_ReconnectClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubAdminServer).ReconnectClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHubAdmin/ReconnectClient",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubAdminServer).ReconnectClient(ctx, req.(*ClientAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatBotHubAdmin_SetClientLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientAdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubAdminServer).SetClientLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHubAdmin/SetClientLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubAdminServer).SetClientLogLevel(ctx, req.(*ClientAdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatBotHubAdmin_GetBotLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BotLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotHubAdminServer).GetBotLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbothub.ChatBotHubAdmin/GetBotLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotHubAdminServer).GetBotLogs(ctx, req.(*BotLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ChatBotHubAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "chatbothub.ChatBotHubAdmin",
+	HandlerType: (*ChatBotHubAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InspectClient",
+			Handler:    _ChatBotHubAdmin_InspectClient_Handler,
+		},
+		{
+			MethodName: "ReconnectClient",
+			Handler:    _ChatBotHubAdmin_ReconnectClient_Handler,
+		},
+		{
+			MethodName: "SetClientLogLevel",
+			Handler:    _ChatBotHubAdmin_SetClientLogLevel_Handler,
+		},
+		{
+			MethodName: "GetBotLogs",
+			Handler:    _ChatBotHubAdmin_GetBotLogs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "chatbothub.proto",
+}
@@ -595,6 +595,92 @@ func (m *UpdateBotChatRoomResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_UpdateBotChatRoomResponse proto.InternalMessageInfo
 
+type RebuildBotFiltersRequest struct {
+	BotId                string   `protobuf:"bytes,1,opt,name=botId,proto3" json:"botId,omitempty"`
+	FilterId             string   `protobuf:"bytes,2,opt,name=filterId,proto3" json:"filterId,omitempty"`
+	MomentFilterId       string   `protobuf:"bytes,3,opt,name=momentFilterId,proto3" json:"momentFilterId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RebuildBotFiltersRequest) Reset()         { *m = RebuildBotFiltersRequest{} }
+func (m *RebuildBotFiltersRequest) String() string { return proto.CompactTextString(m) }
+func (*RebuildBotFiltersRequest) ProtoMessage()    {}
+func (*RebuildBotFiltersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_461bb3ac99194e85, []int{13}
+}
+
+func (m *RebuildBotFiltersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RebuildBotFiltersRequest.Unmarshal(m, b)
+}
+func (m *RebuildBotFiltersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RebuildBotFiltersRequest.Marshal(b, m, deterministic)
+}
+func (m *RebuildBotFiltersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebuildBotFiltersRequest.Merge(m, src)
+}
+func (m *RebuildBotFiltersRequest) XXX_Size() int {
+	return xxx_messageInfo_RebuildBotFiltersRequest.Size(m)
+}
+func (m *RebuildBotFiltersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebuildBotFiltersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RebuildBotFiltersRequest proto.InternalMessageInfo
+
+func (m *RebuildBotFiltersRequest) GetBotId() string {
+	if m != nil {
+		return m.BotId
+	}
+	return ""
+}
+
+func (m *RebuildBotFiltersRequest) GetFilterId() string {
+	if m != nil {
+		return m.FilterId
+	}
+	return ""
+}
+
+func (m *RebuildBotFiltersRequest) GetMomentFilterId() string {
+	if m != nil {
+		return m.MomentFilterId
+	}
+	return ""
+}
+
+type RebuildBotFiltersResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RebuildBotFiltersResponse) Reset()         { *m = RebuildBotFiltersResponse{} }
+func (m *RebuildBotFiltersResponse) String() string { return proto.CompactTextString(m) }
+func (*RebuildBotFiltersResponse) ProtoMessage()    {}
+func (*RebuildBotFiltersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_461bb3ac99194e85, []int{14}
+}
+
+func (m *RebuildBotFiltersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RebuildBotFiltersResponse.Unmarshal(m, b)
+}
+func (m *RebuildBotFiltersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RebuildBotFiltersResponse.Marshal(b, m, deterministic)
+}
+func (m *RebuildBotFiltersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebuildBotFiltersResponse.Merge(m, src)
+}
+func (m *RebuildBotFiltersResponse) XXX_Size() int {
+	return xxx_messageInfo_RebuildBotFiltersResponse.Size(m)
+}
+func (m *RebuildBotFiltersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebuildBotFiltersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RebuildBotFiltersResponse proto.InternalMessageInfo
+
 type ChatRoom struct {
 	// @inject_tag: json:"-" bson:"_id"
 	ObjectId []byte `protobuf:"bytes,1,opt,name=objectId,proto3" json:"-" bson:"_id"`
@@ -619,7 +705,7 @@ func (m *ChatRoom) Reset()         { *m = ChatRoom{} }
 func (m *ChatRoom) String() string { return proto.CompactTextString(m) }
 func (*ChatRoom) ProtoMessage()    {}
 func (*ChatRoom) Descriptor() ([]byte, []int) {
-	return fileDescriptor_461bb3ac99194e85, []int{13}
+	return fileDescriptor_461bb3ac99194e85, []int{15}
 }
 
 func (m *ChatRoom) XXX_Unmarshal(b []byte) error {
@@ -703,53 +789,58 @@ func init() {
 	proto.RegisterType((*GetBotChatRoomResponse)(nil), "chatbotweb.GetBotChatRoomResponse")
 	proto.RegisterType((*UpdateBotChatRoomRequest)(nil), "chatbotweb.UpdateBotChatRoomRequest")
 	proto.RegisterType((*UpdateBotChatRoomResponse)(nil), "chatbotweb.UpdateBotChatRoomResponse")
+	proto.RegisterType((*RebuildBotFiltersRequest)(nil), "chatbotweb.RebuildBotFiltersRequest")
+	proto.RegisterType((*RebuildBotFiltersResponse)(nil), "chatbotweb.RebuildBotFiltersResponse")
 	proto.RegisterType((*ChatRoom)(nil), "chatbotweb.ChatRoom")
 }
 
 func init() { proto.RegisterFile("web.proto", fileDescriptor_461bb3ac99194e85) }
 
 var fileDescriptor_461bb3ac99194e85 = []byte{
-	// 632 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xef, 0x6e, 0xd3, 0x3e,
-	0x14, 0xfd, 0x65, 0x6b, 0xbb, 0xf6, 0xee, 0x4f, 0x7f, 0x98, 0x6e, 0x64, 0x01, 0x6d, 0x9d, 0x61,
-	0x52, 0x55, 0xa1, 0x02, 0x83, 0x17, 0x58, 0x61, 0x1a, 0x45, 0x68, 0xaa, 0xc2, 0x36, 0x40, 0xe2,
-	0x4b, 0xd2, 0x78, 0x6d, 0x58, 0x1b, 0x67, 0x89, 0x4b, 0xe8, 0x57, 0x9e, 0x8b, 0x87, 0xe2, 0x11,
-	0x90, 0x9d, 0xd8, 0x49, 0xfa, 0x17, 0xc1, 0xb7, 0x9c, 0xeb, 0xe3, 0xe3, 0x73, 0xaf, 0xaf, 0x6f,
-	0xa0, 0x12, 0x11, 0xbb, 0xe5, 0x07, 0x94, 0x51, 0x04, 0xbd, 0x81, 0xc5, 0x6c, 0xca, 0x22, 0x62,
-	0xe3, 0x37, 0x80, 0xce, 0x09, 0x7b, 0x3d, 0xb0, 0xd8, 0x55, 0x48, 0x02, 0x93, 0xdc, 0x8d, 0x49,
-	0xc8, 0x90, 0x01, 0xe5, 0x71, 0x48, 0x82, 0x0b, 0x6b, 0x44, 0x74, 0xad, 0xae, 0x35, 0x2a, 0xa6,
-	0xc2, 0x08, 0x41, 0x81, 0x4d, 0x7c, 0xa2, 0xaf, 0x89, 0xb8, 0xf8, 0xc6, 0x0e, 0xec, 0x65, 0x54,
-	0x3e, 0x4c, 0xbc, 0xde, 0x5f, 0x2a, 0x71, 0xbe, 0x4d, 0xd9, 0x7b, 0xda, 0x77, 0x3d, 0x7d, 0x3d,
-	0xe6, 0x4b, 0x8c, 0x9f, 0xc1, 0xfd, 0x9c, 0xd7, 0xd0, 0xa7, 0x5e, 0x48, 0x90, 0x0e, 0x1b, 0xbe,
-	0x35, 0x19, 0x52, 0xcb, 0x11, 0x27, 0x6c, 0x99, 0x12, 0xe2, 0x63, 0xd8, 0x3e, 0x27, 0xac, 0x4d,
-	0x99, 0x74, 0x53, 0x83, 0xa2, 0x4d, 0x59, 0xc7, 0x49, 0xac, 0xc4, 0x00, 0x37, 0x61, 0x47, 0xd2,
-	0x56, 0x4a, 0x3e, 0x85, 0xda, 0xb5, 0x35, 0x74, 0x1d, 0x8b, 0x91, 0x4b, 0x7a, 0x4b, 0xbc, 0x8c,
-	0x32, 0xe3, 0x58, 0x2a, 0x0b, 0x80, 0x5f, 0xc0, 0xee, 0x14, 0x7b, 0xe5, 0x01, 0x3f, 0x34, 0x51,
-	0xcb, 0x36, 0x15, 0x89, 0x9a, 0x94, 0x8e, 0x42, 0x79, 0xc6, 0x1e, 0x94, 0x84, 0xe1, 0x50, 0xd7,
-	0xea, 0xeb, 0x8d, 0x8a, 0x99, 0x20, 0x74, 0x00, 0x70, 0x13, 0xd0, 0x11, 0xe7, 0x76, 0x9c, 0xa4,
-	0x9a, 0x99, 0x08, 0xf7, 0x36, 0x74, 0x47, 0x2e, 0x13, 0x05, 0x2d, 0x9a, 0x31, 0xe0, 0x95, 0xe6,
-	0x7d, 0x70, 0xc9, 0x6f, 0xa0, 0x10, 0x57, 0x5a, 0x62, 0x7c, 0x06, 0x0f, 0x66, 0x3c, 0x24, 0xce,
-	0x9b, 0x50, 0x74, 0x19, 0x19, 0xc5, 0x1e, 0x36, 0x4f, 0x6a, 0xad, 0xb4, 0x99, 0x5a, 0x92, 0x6d,
-	0xc6, 0x14, 0x7c, 0x07, 0xbb, 0x79, 0x99, 0xa5, 0xf7, 0xc0, 0xf3, 0xf3, 0x09, 0x09, 0x54, 0x0e,
-	0x09, 0x42, 0x4d, 0xf8, 0xbf, 0x17, 0x10, 0x8b, 0x91, 0xce, 0xcd, 0x05, 0x65, 0x67, 0xdf, 0xdd,
-	0x30, 0x4e, 0xa5, 0x6c, 0xce, 0xc4, 0xf1, 0xbb, 0xe9, 0xea, 0x29, 0xe3, 0xcf, 0xe3, 0x7c, 0x79,
-	0x4c, 0x1c, 0xbb, 0xc8, 0xbb, 0x62, 0xe1, 0xb7, 0xa0, 0x5f, 0xf9, 0xfc, 0xee, 0xfe, 0x35, 0x03,
-	0xfc, 0x10, 0xf6, 0xe7, 0x28, 0xc5, 0xc6, 0xf0, 0x4f, 0x0d, 0xca, 0x32, 0xc8, 0x6f, 0x85, 0xda,
-	0x5f, 0x49, 0x4f, 0x4a, 0x6f, 0x99, 0x0a, 0xa3, 0x1d, 0x58, 0x73, 0xa5, 0xf2, 0x9a, 0xeb, 0xa4,
-	0x1e, 0xd6, 0xe7, 0x7b, 0x28, 0xe4, 0xaa, 0xf8, 0x08, 0x2a, 0x71, 0xb5, 0x9c, 0x53, 0xa6, 0x17,
-	0xeb, 0x5a, 0xa3, 0x60, 0xa6, 0x01, 0xbe, 0x3a, 0x16, 0x0e, 0xf9, 0x6a, 0x29, 0x5e, 0x55, 0x81,
-	0x5c, 0xaf, 0x6c, 0xe4, 0x7b, 0xe5, 0xe4, 0x57, 0x01, 0x80, 0xdb, 0x6f, 0x53, 0xf6, 0x91, 0xd8,
-	0xa8, 0x0b, 0x9b, 0xfd, 0xf4, 0x91, 0xa2, 0x83, 0x6c, 0x8d, 0x67, 0x27, 0x8d, 0x71, 0xb8, 0x70,
-	0x3d, 0xa9, 0xce, 0x7f, 0xe8, 0x13, 0x54, 0xfb, 0xf9, 0xe1, 0x82, 0xf0, 0x82, 0x5d, 0x99, 0xc9,
-	0xf3, 0x27, 0xca, 0xa7, 0x50, 0xea, 0x8b, 0x66, 0x41, 0xfb, 0x53, 0xe4, 0x74, 0x66, 0x18, 0xc6,
-	0xbc, 0x25, 0x25, 0x71, 0x0d, 0xdb, 0xdf, 0xb2, 0x2f, 0x1c, 0xd5, 0xb3, 0xf4, 0x79, 0xa3, 0xc2,
-	0x38, 0x5a, 0xc2, 0x50, 0xba, 0x5f, 0xa0, 0x3a, 0xf5, 0x02, 0x67, 0x92, 0x9e, 0x33, 0x22, 0x8c,
-	0xc7, 0x4b, 0x39, 0x4a, 0xfd, 0xb3, 0x9c, 0x78, 0xaa, 0xef, 0x8e, 0x16, 0x6f, 0x94, 0xda, 0x78,
-	0x19, 0x45, 0x49, 0xdb, 0x70, 0x6f, 0x3c, 0xdd, 0xea, 0xe8, 0x49, 0x76, 0xeb, 0xa2, 0x37, 0x65,
-	0x1c, 0xaf, 0x60, 0xc9, 0x33, 0xda, 0xaf, 0xe0, 0xd0, 0x23, 0xac, 0x35, 0xb0, 0xa2, 0xdb, 0xc8,
-	0x65, 0x83, 0xc8, 0xf5, 0x1c, 0xb9, 0x75, 0x30, 0xb6, 0x5b, 0x11, 0xb1, 0xdb, 0xd5, 0xb4, 0x25,
-	0xbb, 0xfc, 0xa7, 0xd7, 0xd5, 0xec, 0x92, 0xf8, 0xfb, 0xbd, 0xfc, 0x1d, 0x00, 0x00, 0xff, 0xff,
-	0x50, 0x1e, 0x44, 0x8c, 0x0a, 0x07, 0x00, 0x00,
+	// 688 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xef, 0x52, 0xd3, 0x4e,
+	0x14, 0xfd, 0x05, 0xda, 0xd2, 0x5e, 0xa0, 0xfc, 0x5c, 0x01, 0x43, 0x74, 0xa0, 0xac, 0xe2, 0x30,
+	0x8c, 0x53, 0x15, 0x7d, 0x01, 0xaa, 0x88, 0x75, 0x1c, 0x86, 0x89, 0x80, 0x3a, 0xe3, 0x97, 0xa4,
+	0xb9, 0xb4, 0x91, 0x26, 0x1b, 0x92, 0xad, 0x95, 0xaf, 0x3e, 0x97, 0x8f, 0xe1, 0x03, 0x39, 0x9b,
+	0x64, 0xb7, 0x49, 0xfa, 0xcf, 0xd1, 0x6f, 0x39, 0x77, 0xcf, 0x9e, 0x7b, 0xee, 0xdd, 0xdd, 0x1b,
+	0xa8, 0x0d, 0xd1, 0x6e, 0x06, 0x21, 0xe3, 0x8c, 0x40, 0xa7, 0x67, 0x71, 0x9b, 0xf1, 0x21, 0xda,
+	0xf4, 0x35, 0x90, 0x13, 0xe4, 0xaf, 0x7a, 0x16, 0xbf, 0x88, 0x30, 0x34, 0xf1, 0x66, 0x80, 0x11,
+	0x27, 0x06, 0x54, 0x07, 0x11, 0x86, 0xa7, 0x96, 0x87, 0xba, 0xd6, 0xd0, 0xf6, 0x6b, 0xa6, 0xc2,
+	0x84, 0x40, 0x89, 0xdf, 0x06, 0xa8, 0x2f, 0xc4, 0xf1, 0xf8, 0x9b, 0x3a, 0xb0, 0x99, 0x51, 0xf9,
+	0x70, 0xeb, 0x77, 0xfe, 0x52, 0x49, 0xf0, 0x6d, 0xc6, 0xdf, 0xb3, 0xae, 0xeb, 0xeb, 0x8b, 0x09,
+	0x5f, 0x62, 0xfa, 0x14, 0xee, 0xe6, 0xbc, 0x46, 0x01, 0xf3, 0x23, 0x24, 0x3a, 0x2c, 0x05, 0xd6,
+	0x6d, 0x9f, 0x59, 0x4e, 0x9c, 0x61, 0xc5, 0x94, 0x90, 0xee, 0xc1, 0xea, 0x09, 0xf2, 0x16, 0xe3,
+	0xd2, 0xcd, 0x3a, 0x94, 0x6d, 0xc6, 0xdb, 0x4e, 0x6a, 0x25, 0x01, 0xf4, 0x00, 0xea, 0x92, 0x36,
+	0x57, 0xf2, 0x09, 0xac, 0x5f, 0x5a, 0x7d, 0xd7, 0xb1, 0x38, 0x9e, 0xb3, 0x6b, 0xf4, 0x33, 0xca,
+	0x5c, 0x60, 0xa9, 0x1c, 0x03, 0xfa, 0x1c, 0x36, 0x0a, 0xec, 0xb9, 0x09, 0x7e, 0x68, 0x71, 0x2f,
+	0x5b, 0x2c, 0x2e, 0xd4, 0x64, 0xcc, 0x8b, 0x64, 0x8e, 0x4d, 0xa8, 0xc4, 0x86, 0x23, 0x5d, 0x6b,
+	0x2c, 0xee, 0xd7, 0xcc, 0x14, 0x91, 0x6d, 0x80, 0xab, 0x90, 0x79, 0x82, 0xdb, 0x76, 0xd2, 0x6e,
+	0x66, 0x22, 0xc2, 0x5b, 0xdf, 0xf5, 0x5c, 0x1e, 0x37, 0xb4, 0x6c, 0x26, 0x40, 0x74, 0x5a, 0xdc,
+	0x83, 0x73, 0x71, 0x02, 0xa5, 0xa4, 0xd3, 0x12, 0xd3, 0x63, 0xb8, 0x37, 0xe6, 0x21, 0x75, 0x7e,
+	0x00, 0x65, 0x97, 0xa3, 0x97, 0x78, 0x58, 0x3e, 0x5c, 0x6f, 0x8e, 0x2e, 0x53, 0x53, 0xb2, 0xcd,
+	0x84, 0x42, 0x6f, 0x60, 0x23, 0x2f, 0x33, 0xf3, 0x1c, 0x44, 0x7d, 0x01, 0x62, 0xa8, 0x6a, 0x48,
+	0x11, 0x39, 0x80, 0xff, 0x3b, 0x21, 0x5a, 0x1c, 0xdb, 0x57, 0xa7, 0x8c, 0x1f, 0x7f, 0x77, 0xa3,
+	0xa4, 0x94, 0xaa, 0x39, 0x16, 0xa7, 0xef, 0x8a, 0xdd, 0x53, 0xc6, 0x9f, 0x25, 0xf5, 0x8a, 0x58,
+	0x9c, 0x76, 0x9a, 0x77, 0xc5, 0xa2, 0x6f, 0x41, 0xbf, 0x08, 0xc4, 0xd9, 0xfd, 0x6b, 0x05, 0xf4,
+	0x3e, 0x6c, 0x4d, 0x50, 0x4a, 0x8c, 0x51, 0x0e, 0xba, 0x89, 0xf6, 0xc0, 0xed, 0x3b, 0x2d, 0xc6,
+	0xdf, 0xb8, 0x7d, 0x8e, 0x61, 0x34, 0x3b, 0x8d, 0x01, 0xd5, 0xab, 0x98, 0xa7, 0x12, 0x29, 0x4c,
+	0x1e, 0x43, 0xdd, 0x63, 0x1e, 0xfa, 0xa9, 0x52, 0xdb, 0x49, 0x9f, 0x51, 0x21, 0x2a, 0x2c, 0x4d,
+	0xc8, 0x9a, 0x5a, 0xfa, 0xa9, 0x41, 0x55, 0xfa, 0x14, 0xd9, 0x98, 0xfd, 0x15, 0x3b, 0xd2, 0xc6,
+	0x8a, 0xa9, 0x30, 0xa9, 0xc3, 0x82, 0x2b, 0x3d, 0x2c, 0xb8, 0xce, 0xc8, 0xef, 0xe2, 0xe4, 0xb6,
+	0x94, 0x72, 0x07, 0xfb, 0x00, 0x6a, 0xc9, 0x01, 0x3a, 0x47, 0x5c, 0x2f, 0x37, 0xb4, 0xfd, 0x92,
+	0x39, 0x0a, 0x88, 0xd5, 0x41, 0xe0, 0xa4, 0xab, 0x95, 0x64, 0x55, 0x05, 0x72, 0xd7, 0x77, 0x29,
+	0x7f, 0x7d, 0x0f, 0x7f, 0x95, 0x01, 0x84, 0xfd, 0x16, 0xe3, 0x1f, 0xd1, 0x26, 0x67, 0xb0, 0xdc,
+	0x1d, 0xcd, 0x0d, 0xb2, 0x9d, 0x3d, 0xf6, 0xf1, 0xe1, 0x67, 0xec, 0x4c, 0x5d, 0x4f, 0xbb, 0xf3,
+	0x1f, 0xf9, 0x04, 0x6b, 0xdd, 0xfc, 0xbc, 0x23, 0x74, 0xca, 0xae, 0xcc, 0x30, 0xfc, 0x13, 0xe5,
+	0x23, 0xa8, 0x74, 0xe3, 0xfb, 0x4b, 0xb6, 0x0a, 0xe4, 0xd1, 0x18, 0x33, 0x8c, 0x49, 0x4b, 0x4a,
+	0xe2, 0x12, 0x56, 0xbf, 0x65, 0x87, 0x0e, 0x69, 0x64, 0xe9, 0x93, 0xa6, 0x97, 0xb1, 0x3b, 0x83,
+	0xa1, 0x74, 0xbf, 0xc0, 0x5a, 0x61, 0x28, 0x8c, 0x15, 0x3d, 0x61, 0x6a, 0x19, 0x0f, 0x67, 0x72,
+	0x94, 0xfa, 0x67, 0xa8, 0xe7, 0x17, 0xc9, 0xee, 0xf4, 0x8d, 0x52, 0x9b, 0xce, 0xa2, 0x28, 0x69,
+	0x1b, 0xee, 0x0c, 0x8a, 0xaf, 0x8f, 0x3c, 0xca, 0x6e, 0x9d, 0xf6, 0xcc, 0x8d, 0xbd, 0x39, 0xac,
+	0x6c, 0x8e, 0xb0, 0xf8, 0x9c, 0xf2, 0x39, 0xa6, 0xbd, 0x71, 0x63, 0x6f, 0x0e, 0x4b, 0xe6, 0x68,
+	0xbd, 0x84, 0x1d, 0x1f, 0x79, 0xb3, 0x67, 0x0d, 0xaf, 0x87, 0x2e, 0xef, 0x0d, 0x5d, 0xdf, 0x91,
+	0x5b, 0x7b, 0x03, 0xbb, 0x39, 0x44, 0xbb, 0xb5, 0x36, 0xba, 0xf6, 0x67, 0xe2, 0x5f, 0x7f, 0xa6,
+	0xd9, 0x95, 0xf8, 0xa7, 0xff, 0xe2, 0xf7, 0x00, 0x50, 0x08, 0xff, 0x69, 0x01, 0x08, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -771,6 +862,7 @@ type ChatBotWebClient interface {
 	GetBotChatRooms(ctx context.Context, in *GetBotChatRoomsRequest, opts ...grpc.CallOption) (*GetBotChatRoomsResponse, error)
 	GetBotChatRoom(ctx context.Context, in *GetBotChatRoomRequest, opts ...grpc.CallOption) (*GetBotChatRoomResponse, error)
 	UpdateBotChatRoom(ctx context.Context, in *UpdateBotChatRoomRequest, opts ...grpc.CallOption) (*UpdateBotChatRoomResponse, error)
+	RebuildBotFilters(ctx context.Context, in *RebuildBotFiltersRequest, opts ...grpc.CallOption) (*RebuildBotFiltersResponse, error)
 }
 
 type chatBotWebClient struct {
@@ -844,6 +936,15 @@ func (c *chatBotWebClient) UpdateBotChatRoom(ctx context.Context, in *UpdateBotC
 	return out, nil
 }
 
+func (c *chatBotWebClient) RebuildBotFilters(ctx context.Context, in *RebuildBotFiltersRequest, opts ...grpc.CallOption) (*RebuildBotFiltersResponse, error) {
+	out := new(RebuildBotFiltersResponse)
+	err := c.cc.Invoke(ctx, "/chatbotweb.ChatBotWeb/rebuildBotFilters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatBotWebServer is the server API for ChatBotWeb service.
 type ChatBotWebServer interface {
 	GetChatUser(context.Context, *GetChatUserRequest) (*GetChatUserResponse, error)
@@ -853,6 +954,7 @@ type ChatBotWebServer interface {
 	GetBotChatRooms(context.Context, *GetBotChatRoomsRequest) (*GetBotChatRoomsResponse, error)
 	GetBotChatRoom(context.Context, *GetBotChatRoomRequest) (*GetBotChatRoomResponse, error)
 	UpdateBotChatRoom(context.Context, *UpdateBotChatRoomRequest) (*UpdateBotChatRoomResponse, error)
+	RebuildBotFilters(context.Context, *RebuildBotFiltersRequest) (*RebuildBotFiltersResponse, error)
 }
 
 // UnimplementedChatBotWebServer can be embedded to have forward compatible implementations.
@@ -880,6 +982,9 @@ func (*UnimplementedChatBotWebServer) GetBotChatRoom(ctx context.Context, req *G
 func (*UnimplementedChatBotWebServer) UpdateBotChatRoom(ctx context.Context, req *UpdateBotChatRoomRequest) (*UpdateBotChatRoomResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateBotChatRoom not implemented")
 }
+func (*UnimplementedChatBotWebServer) RebuildBotFilters(ctx context.Context, req *RebuildBotFiltersRequest) (*RebuildBotFiltersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RebuildBotFilters not implemented")
+}
 
 func RegisterChatBotWebServer(s *grpc.Server, srv ChatBotWebServer) {
 	s.RegisterService(&_ChatBotWeb_serviceDesc, srv)
@@ -1011,6 +1116,24 @@ func _ChatBotWeb_UpdateBotChatRoom_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatBotWeb_RebuildBotFilters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebuildBotFiltersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatBotWebServer).RebuildBotFilters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chatbotweb.ChatBotWeb/RebuildBotFilters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatBotWebServer).RebuildBotFilters(ctx, req.(*RebuildBotFiltersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ChatBotWeb_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "chatbotweb.ChatBotWeb",
 	HandlerType: (*ChatBotWebServer)(nil),
@@ -1043,6 +1166,10 @@ var _ChatBotWeb_serviceDesc = grpc.ServiceDesc{
 			MethodName: "updateBotChatRoom",
 			Handler:    _ChatBotWeb_UpdateBotChatRoom_Handler,
 		},
+		{
+			MethodName: "rebuildBotFilters",
+			Handler:    _ChatBotWeb_RebuildBotFilters_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "web.proto",
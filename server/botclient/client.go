@@ -0,0 +1,493 @@
+package botclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
+)
+
+type Config struct {
+	// hub address, host:port
+	Addr       string
+	ClientId   string
+	ClientType string
+
+	// events the client handles besides the ones this package handles, sent
+	// with REGISTER
+	Events []string
+
+	// seconds between two PINGs sent to the hub, 10 by default
+	PingInterval int
+	// load reported with every PING, none when nil
+	Load func() PingBody
+	// reconnect backoff in seconds, doubled on each failure up to MaxBackoff
+	Backoff    int
+	MaxBackoff int
+}
+
+// ActionHandler executes one type of bot action. The returned data goes to
+// the result of the ACTIONREPLY; return an *ActionError to report a failure
+// with a status code.
+type ActionHandler interface {
+	HandleAction(c *Client, action *BotAction) (map[string]interface{}, error)
+}
+
+type ActionHandlerFunc func(c *Client, action *BotAction) (map[string]interface{}, error)
+
+func (f ActionHandlerFunc) HandleAction(c *Client, action *BotAction) (map[string]interface{}, error) {
+	return f(c, action)
+}
+
+type ActionError struct {
+	Status  int
+	Message string
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("action failed [%d] %s", e.Status, e.Message)
+}
+
+// Handlers are called for the commands of the hub. Unset handlers ignore
+// the command.
+type Handlers struct {
+	OnRegistered func(c *Client, body *RegisteredBody)
+	OnLogin      func(c *Client, body *LoginBody)
+	OnLogout     func(c *Client)
+	OnShutdown   func(c *Client)
+	OnBotMigrate func(c *Client, body *BotMigrateBody)
+	// LOGLEVEL is only declared at REGISTER when this is set
+	OnLogLevel func(c *Client, level string)
+	// any other event, by type
+	OnEvent func(c *Client, eventType string, body string)
+}
+
+// Client keeps a bot client connected to the hub: it registers, answers
+// heartbeats, dispatches BOTACTIONs to action handlers and reconnects with
+// backoff whenever the tunnel breaks, or when the hub asks it to.
+type Client struct {
+	Config   Config
+	Handlers Handlers
+
+	logger *logging.Logger
+
+	muxActions sync.Mutex
+	actions    map[string]ActionHandler
+
+	muxTunnel sync.Mutex
+	tunnel    pb.ChatBotHub_EventTunnelClient
+	addr      string
+
+	// actions being handled, waited for before a RECONNECT
+	inflight sync.WaitGroup
+
+	BotId string
+	Login string
+}
+
+func NewClient(config Config) *Client {
+	if config.PingInterval <= 0 {
+		config.PingInterval = 10
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 1
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 60
+	}
+
+	return &Client{
+		Config:  config,
+		logger:  logging.New("botclient"),
+		actions: make(map[string]ActionHandler),
+		addr:    config.Addr,
+	}
+}
+
+func (c *Client) log() *logging.Logger {
+	return c.logger.With(
+		"clientId", c.Config.ClientId,
+		"clientType", c.Config.ClientType,
+		"botId", c.BotId,
+		"login", c.Login)
+}
+
+func (c *Client) Debug(msg string, v ...interface{}) {
+	c.log().Debug(msg, v...)
+}
+
+func (c *Client) Info(msg string, v ...interface{}) {
+	c.log().Info(msg, v...)
+}
+
+func (c *Client) Error(err error, msg string, v ...interface{}) {
+	c.log().Error(err, msg, v...)
+}
+
+// HandleAction registers the handler of an action type. The action types
+// with a handler are declared to the hub at REGISTER, the hub rejects the
+// others up front.
+func (c *Client) HandleAction(actionType string, handler ActionHandler) {
+	c.muxActions.Lock()
+	defer c.muxActions.Unlock()
+
+	c.actions[actionType] = handler
+}
+
+func (c *Client) actionHandler(actionType string) ActionHandler {
+	c.muxActions.Lock()
+	defer c.muxActions.Unlock()
+
+	return c.actions[actionType]
+}
+
+func (c *Client) registerBody() RegisterBody {
+	c.muxActions.Lock()
+	actions := []string{}
+	for actionType := range c.actions {
+		actions = append(actions, actionType)
+	}
+	c.muxActions.Unlock()
+	sort.Strings(actions)
+
+	events := []string{PING, PONG, REGISTERED, LOGIN, LOGOUT, SHUTDOWN, BOTMIGRATE, RECONNECT, BOTACTION}
+	if c.Handlers.OnLogLevel != nil {
+		events = append(events, LOGLEVEL)
+	}
+	events = append(events, c.Config.Events...)
+
+	return RegisterBody{
+		ProtocolVersion: ProtocolVersion,
+		Actions:         actions,
+		Events:          events,
+	}
+}
+
+// Run connects and serves the tunnel until ctx is done, reconnecting with
+// backoff in between.
+func (c *Client) Run(ctx context.Context) error {
+	backoff := c.Config.Backoff
+
+	for {
+		start := time.Now()
+		err := c.serve(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err != nil {
+			c.Error(err, "c[%s] tunnel to %s broken", c.Config.ClientId, c.currentAddr())
+		}
+
+		// a tunnel that lived long enough resets the backoff
+		if time.Since(start) > time.Duration(c.Config.MaxBackoff)*time.Second {
+			backoff = c.Config.Backoff
+		}
+
+		c.Info("c[%s] reconnect in %ds", c.Config.ClientId, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(backoff) * time.Second):
+		}
+
+		backoff *= 2
+		if backoff > c.Config.MaxBackoff {
+			backoff = c.Config.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) currentAddr() string {
+	c.muxTunnel.Lock()
+	defer c.muxTunnel.Unlock()
+
+	return c.addr
+}
+
+func (c *Client) serve(ctx context.Context) error {
+	addr := c.currentAddr()
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), tracing.DialOption())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tunnelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tunnel, err := pb.NewChatBotHubClient(conn).EventTunnel(tunnelCtx)
+	if err != nil {
+		return err
+	}
+
+	c.muxTunnel.Lock()
+	c.tunnel = tunnel
+	c.muxTunnel.Unlock()
+
+	defer func() {
+		c.muxTunnel.Lock()
+		c.tunnel = nil
+		c.muxTunnel.Unlock()
+	}()
+
+	if err := c.Send(REGISTER, c.registerBody()); err != nil {
+		return err
+	}
+	c.Info("c[%s] registered to %s", c.Config.ClientId, addr)
+
+	go c.pingloop(tunnelCtx)
+
+	for {
+		in, err := tunnel.Recv()
+		if err != nil {
+			return err
+		}
+
+		if reconnect := c.dispatch(in); reconnect {
+			return nil
+		}
+	}
+}
+
+func (c *Client) pingloop(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(c.Config.PingInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var body interface{} = "ping from client"
+			if c.Config.Load != nil {
+				body = c.Config.Load()
+			}
+
+			if err := c.Send(PING, body); err != nil {
+				c.Error(err, "c[%s] send ping failed", c.Config.ClientId)
+			}
+		}
+	}
+}
+
+// dispatch handles one event of the hub. It returns true when the tunnel
+// should be reconnected.
+func (c *Client) dispatch(in *pb.EventReply) bool {
+	switch in.EventType {
+	case PING:
+		if err := c.Send(PONG, ""); err != nil {
+			c.Error(err, "c[%s] send pong failed", c.Config.ClientId)
+		}
+
+	case PONG:
+
+	case REGISTERED:
+		body := &RegisteredBody{}
+		if c.decode(in, body) && c.Handlers.OnRegistered != nil {
+			c.Handlers.OnRegistered(c, body)
+		}
+
+	case LOGIN:
+		body := &LoginBody{}
+		if c.decode(in, body) && c.Handlers.OnLogin != nil {
+			c.Handlers.OnLogin(c, body)
+		}
+
+	case LOGOUT:
+		if c.Handlers.OnLogout != nil {
+			c.Handlers.OnLogout(c)
+		}
+
+	case SHUTDOWN:
+		if c.Handlers.OnShutdown != nil {
+			c.Handlers.OnShutdown(c)
+		}
+
+	case BOTMIGRATE:
+		body := &BotMigrateBody{}
+		if c.decode(in, body) {
+			c.BotId = body.BotId
+			if c.Handlers.OnBotMigrate != nil {
+				c.Handlers.OnBotMigrate(c, body)
+			}
+		}
+
+	case LOGLEVEL:
+		body := &LogLevelBody{}
+		if c.decode(in, body) && c.Handlers.OnLogLevel != nil {
+			c.Handlers.OnLogLevel(c, body.Level)
+		}
+
+	case RECONNECT:
+		body := &ReconnectBody{}
+		if c.decode(in, body) {
+			c.Info("c[%s] hub asks to reconnect to %q", c.Config.ClientId, body.Target)
+			// finish the actions of the draining hub first, it waits for them
+			c.inflight.Wait()
+
+			if body.Target != "" {
+				c.muxTunnel.Lock()
+				c.addr = body.Target
+				c.muxTunnel.Unlock()
+			}
+			return true
+		}
+
+	case BOTACTION:
+		action := &BotAction{}
+		if c.decode(in, action) {
+			c.inflight.Add(1)
+			go func() {
+				defer c.inflight.Done()
+				c.runAction(action)
+			}()
+		}
+
+	default:
+		if c.Handlers.OnEvent != nil {
+			c.Handlers.OnEvent(c, in.EventType, in.Body)
+		} else {
+			c.Info("c[%s] unhandled event %s", c.Config.ClientId, in.EventType)
+		}
+	}
+
+	return false
+}
+
+func (c *Client) decode(in *pb.EventReply, v interface{}) bool {
+	if err := json.Unmarshal([]byte(in.Body), v); err != nil {
+		c.Error(err, "c[%s] malformed %s body %s", c.Config.ClientId, in.EventType, in.Body)
+		return false
+	}
+
+	return true
+}
+
+func (c *Client) runAction(action *BotAction) {
+	spanCtx, span := tracing.Start(
+		tracing.Extract(context.Background(), action.Trace), "botclient.action "+action.ActionType,
+		tracing.ActionRequestId.String(action.ActionRequestId),
+		tracing.ClientId.String(c.Config.ClientId))
+	defer span.End()
+
+	// the reply continues the trace from this span
+	if trace := tracing.Inject(spanCtx); trace != nil {
+		action.Trace = trace
+	}
+
+	handler := c.actionHandler(action.ActionType)
+	if handler == nil {
+		c.SendActionReply(action, nil, &ActionError{
+			Status:  -1,
+			Message: fmt.Sprintf("action %s not supported", action.ActionType),
+		})
+		return
+	}
+
+	data, err := handler.HandleAction(c, action)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if err := c.SendActionReply(action, data, err); err != nil {
+		c.Error(err, "c[%s] reply action %s failed", c.Config.ClientId, action.ActionRequestId)
+	}
+}
+
+// Send writes an event to the hub. A body that is not a string is sent as
+// json.
+func (c *Client) Send(eventType string, body interface{}) error {
+	var bodystr string
+	switch b := body.(type) {
+	case string:
+		bodystr = b
+	default:
+		bodym, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodystr = string(bodym)
+	}
+
+	c.muxTunnel.Lock()
+	defer c.muxTunnel.Unlock()
+
+	if c.tunnel == nil {
+		return fmt.Errorf("c[%s] not connected", c.Config.ClientId)
+	}
+
+	return c.tunnel.Send(&pb.EventRequest{
+		EventType:  eventType,
+		Body:       bodystr,
+		ClientId:   c.Config.ClientId,
+		ClientType: c.Config.ClientType,
+	})
+}
+
+func (c *Client) SendLoginScan(url string) error {
+	return c.Send(LOGINSCAN, LoginScanBody{Url: url})
+}
+
+func (c *Client) SendLoginDone(body LoginDoneBody) error {
+	c.BotId = body.BotId
+	c.Login = body.UserName
+
+	return c.Send(LOGINDONE, body)
+}
+
+func (c *Client) SendLoginFailed(errmsg string) error {
+	return c.Send(LOGINFAILED, errmsg)
+}
+
+func (c *Client) SendLogoutDone(errmsg string) error {
+	return c.Send(LOGOUTDONE, errmsg)
+}
+
+func (c *Client) SendUpdateToken(token string) error {
+	return c.Send(UPDATETOKEN, UpdateTokenBody{UserName: c.Login, Token: token})
+}
+
+// SendMessage sends a received message, eventType is MESSAGE, IMAGEMESSAGE
+// or EMOJIMESSAGE.
+func (c *Client) SendMessage(eventType string, msg Message) error {
+	if msg.MsgId == "" {
+		return fmt.Errorf("msgId required")
+	}
+
+	return c.Send(eventType, msg)
+}
+
+// SendActionReply reports the result of an action. A nil err with status 0
+// in data, or no status at all, marks the action done.
+func (c *Client) SendActionReply(action *BotAction, data map[string]interface{}, err error) error {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	result := &ActionResult{Success: true, Data: data}
+	if err != nil {
+		status := -1
+		if actionErr, ok := err.(*ActionError); ok {
+			status = actionErr.Status
+		}
+
+		data["status"] = status
+		data["message"] = err.Error()
+	} else if _, ok := data["status"]; !ok {
+		data["status"] = 0
+	}
+
+	return c.Send(ACTIONREPLY, ActionReplyBody{Body: action, Result: result})
+}
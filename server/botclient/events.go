@@ -0,0 +1,144 @@
+package botclient
+
+// event types of the EventTunnel protocol, as chathub names them
+const (
+	PING                 string = "PING"
+	PONG                 string = "PONG"
+	REGISTER             string = "REGISTER"
+	REGISTERED           string = "REGISTERED"
+	LOGIN                string = "LOGIN"
+	LOGOUT               string = "LOGOUT"
+	SHUTDOWN             string = "SHUTDOWN"
+	LOGINSCAN            string = "LOGINSCAN"
+	LOGINDONE            string = "LOGINDONE"
+	LOGINFAILED          string = "LOGINFAILED"
+	LOGOUTDONE           string = "LOGOUTDONE"
+	BOTMIGRATE           string = "BOTMIGRATE"
+	RECONNECT            string = "RECONNECT"
+	LOGLEVEL             string = "LOGLEVEL"
+	UPDATETOKEN          string = "UPDATETOKEN"
+	MESSAGE              string = "MESSAGE"
+	IMAGEMESSAGE         string = "IMAGEMESSAGE"
+	EMOJIMESSAGE         string = "EMOJIMESSAGE"
+	STATUSMESSAGE        string = "STATUSMESSAGE"
+	FRIENDREQUEST        string = "FRIENDREQUEST"
+	CONTACTINFO          string = "CONTACTINFO"
+	GROUPINFO            string = "GROUPINFO"
+	CONTACTSYNCDONE      string = "CONTACTSYNCDONE"
+	BOTACTION            string = "BOTACTION"
+	ACTIONREPLY          string = "ACTIONREPLY"
+	WEBSHORTCALL         string = "WEBSHORTCALL"
+	WEBSHORTCALLRESPONSE string = "WEBSHORTCALLRESPONSE"
+	ROOMJOIN             string = "ROOMJOIN"
+	USERACCEPTED         string = "USERACCEPTED"
+)
+
+const (
+	// the protocol version this package speaks, see chathub capabilities
+	ProtocolVersion int = 1
+)
+
+// RegisterBody is sent with REGISTER to declare what the client supports.
+type RegisterBody struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Actions         []string `json:"actions,omitempty"`
+	Events          []string `json:"events,omitempty"`
+}
+
+// RegisteredBody is the answer of the hub to REGISTER, with the version
+// both sides agreed on.
+type RegisteredBody struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// PingBody is sent with the PINGs of the client, the hub picks the least
+// loaded client for a login from it.
+type PingBody struct {
+	// bots hosted on the host of the client
+	Bots        int     `json:"bots"`
+	Cpu         float64 `json:"cpu"`
+	MessageRate float64 `json:"messageRate"`
+	Region      string  `json:"region,omitempty"`
+}
+
+// LoginBody comes with LOGIN. LoginInfo is the json of the login info the
+// client reported at its last LOGINDONE, when the hub asks for a relogin.
+type LoginBody struct {
+	BotId     string `json:"botId"`
+	Login     string `json:"login"`
+	Password  string `json:"password"`
+	LoginInfo string `json:"loginInfo"`
+}
+
+type LoginScanBody struct {
+	Url string `json:"url"`
+}
+
+type LoginDoneBody struct {
+	BotId           string        `json:"botId"`
+	UserName        string        `json:"userName"`
+	Alias           string        `json:"alias,omitempty"`
+	WxData          string        `json:"wxData,omitempty"`
+	Token           string        `json:"token,omitempty"`
+	LongServerList  []interface{} `json:"LongServerList,omitempty"`
+	ShortServerList []interface{} `json:"ShortServerList,omitempty"`
+}
+
+type UpdateTokenBody struct {
+	UserName string `json:"userName"`
+	Token    string `json:"token"`
+}
+
+type BotMigrateBody struct {
+	BotId string `json:"botId"`
+}
+
+// ReconnectBody comes with RECONNECT when the hub drains. An empty target
+// means reconnecting to the same address.
+type ReconnectBody struct {
+	Target string `json:"target"`
+}
+
+// LogLevelBody comes with LOGLEVEL, one of debug, info, warn and error.
+type LogLevelBody struct {
+	Level string `json:"level"`
+}
+
+// BotAction is the body of BOTACTION. Body is the json of the action
+// parameters, its fields depend on ActionType.
+type BotAction struct {
+	ActionRequestId string `json:"actionRequestId"`
+	ActionType      string `json:"actionType"`
+	Body            string `json:"body"`
+	// trace context, echoed back in the ACTIONREPLY
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// ActionResult is what the web reads from an ACTIONREPLY: the action is
+// done when Success is set and Data carries status 0.
+type ActionResult struct {
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+type ActionReplyBody struct {
+	Body   *BotAction    `json:"body"`
+	Result *ActionResult `json:"result"`
+}
+
+// Message is the body of MESSAGE, IMAGEMESSAGE and EMOJIMESSAGE. MsgId is
+// required, the hub drops duplicated ids. ImageId is required for image
+// messages, EmojiId for emoji messages.
+type Message struct {
+	MsgId       string `json:"msgId"`
+	FromUser    string `json:"fromUser"`
+	ToUser      string `json:"toUser"`
+	GroupId     string `json:"groupId,omitempty"`
+	Content     string `json:"content"`
+	MType       int    `json:"mType"`
+	Timestamp   int64  `json:"timestamp"`
+	ImageId     string `json:"imageId,omitempty"`
+	EmojiId     string `json:"emojiId,omitempty"`
+	ThumbnailId string `json:"thumbnailId,omitempty"`
+	MsgSource   string `json:"msgSource,omitempty"`
+}
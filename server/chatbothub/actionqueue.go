@@ -0,0 +1,330 @@
+package chatbothub
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/models"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	ActionInteractive string = "interactive"
+	ActionBulk        string = "bulk"
+	ActionBackground  string = "background"
+)
+
+var (
+	actionClasses []string = []string{ActionInteractive, ActionBulk, ActionBackground}
+
+	// action types not listed here are interactive
+	actionClassOf map[string]string = map[string]string{
+		GetRoomMembers:  ActionBulk,
+		GetContact:      ActionBulk,
+		CheckContact:    ActionBulk,
+		SearchContact:   ActionBulk,
+		SyncContact:     ActionBulk,
+		GetLabelList:    ActionBulk,
+		SnsTimeline:     ActionBackground,
+		SnsUserPage:     ActionBackground,
+		SnsGetObject:    ActionBackground,
+		GetRequestToken: ActionBackground,
+		RequestUrl:      ActionBackground,
+	}
+
+	actionQueueConfig ActionQueueConfig = ActionQueueConfig{}.withDefaults()
+)
+
+type ActionClassConfig struct {
+	// share of sends this class gets when every class has work waiting
+	Weight int `yaml:"weight"`
+	// minimal milliseconds between two sends of this class
+	Spacing int64 `yaml:"spacing"`
+	// actions beyond this depth are rejected, 0 means unlimited
+	MaxDepth int `yaml:"maxDepth"`
+}
+
+type ActionQueueConfig struct {
+	Interactive ActionClassConfig `yaml:"interactive"`
+	Bulk        ActionClassConfig `yaml:"bulk"`
+	Background  ActionClassConfig `yaml:"background"`
+}
+
+func (config ActionQueueConfig) withDefaults() ActionQueueConfig {
+	if config.Interactive.Weight <= 0 {
+		config.Interactive.Weight = 8
+	}
+	if config.Bulk.Weight <= 0 {
+		config.Bulk.Weight = 3
+	}
+	if config.Bulk.Spacing <= 0 {
+		config.Bulk.Spacing = 200
+	}
+	if config.Background.Weight <= 0 {
+		config.Background.Weight = 1
+	}
+	if config.Background.Spacing <= 0 {
+		config.Background.Spacing = 1000
+	}
+
+	return config
+}
+
+func (config ActionQueueConfig) class(name string) ActionClassConfig {
+	switch name {
+	case ActionBulk:
+		return config.Bulk
+	case ActionBackground:
+		return config.Background
+	default:
+		return config.Interactive
+	}
+}
+
+type queuedAction struct {
+	event      *pb.EventReply
+	enqueuedAt time.Time
+}
+
+type actionClassQueue struct {
+	config   ActionClassConfig
+	items    []queuedAction
+	current  int
+	lastSent time.Time
+	sent     int64
+	avgWait  float64
+}
+
+type ActionClassInfo struct {
+	Depth     int   `json:"depth"`
+	OldestAge int64 `json:"oldestAge"`
+	AvgWait   int64 `json:"avgWait"`
+	Sent      int64 `json:"sent"`
+}
+
+// ActionQueue holds the outbound actions of one bot, and sends them on
+// the bot tunnel by smooth weighted round robin over the priority classes.
+// The sending goroutine only lives while the queue is not empty, and stops
+// once the queue is closed.
+type ActionQueue struct {
+	mux     sync.Mutex
+	classes map[string]*actionClassQueue
+	running bool
+	closed  bool
+
+	// called with the actions failing to be sent, or left in the queue
+	// when it is closed, for their action requests to fail
+	onFailed func(event *pb.EventReply, err error)
+}
+
+func NewActionQueue() *ActionQueue {
+	q := &ActionQueue{classes: map[string]*actionClassQueue{}}
+	for _, name := range actionClasses {
+		q.classes[name] = &actionClassQueue{config: actionQueueConfig.class(name)}
+	}
+
+	return q
+}
+
+func ActionClass(actionType string) string {
+	if class, ok := actionClassOf[actionType]; ok {
+		return class
+	}
+
+	return ActionInteractive
+}
+
+func (q *ActionQueue) Push(bot *ChatBot, actionType string, event *pb.EventReply) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.closed {
+		return utils.NewClientError(utils.STATUS_INCONSISTENT,
+			fmt.Errorf("b[%s] dropped, action queue closed", bot.Login))
+	}
+
+	class := q.classes[ActionClass(actionType)]
+	if class.config.MaxDepth > 0 && len(class.items) >= class.config.MaxDepth {
+		metrics.QuotaRejections.WithLabelValues(actionType, "queue").Inc()
+		return utils.NewClientError(utils.RESOURCE_QUOTA_LIMIT,
+			fmt.Errorf("b[%s] %s action queue is full", bot.Login, ActionClass(actionType)))
+	}
+
+	class.items = append(class.items, queuedAction{event: event, enqueuedAt: time.Now()})
+
+	if !q.running {
+		q.running = true
+		go q.serve(bot)
+	}
+
+	return nil
+}
+
+// next pops the action to send now. When nothing can be sent yet because of
+// spacing, it returns how long to wait instead; both are empty when the
+// queue is drained.
+func (q *ActionQueue) next(now time.Time) (*queuedAction, *actionClassQueue, time.Duration) {
+	var picked *actionClassQueue
+	var wait time.Duration
+	total := 0
+
+	for _, name := range actionClasses {
+		class := q.classes[name]
+		if len(class.items) == 0 {
+			continue
+		}
+
+		ready := class.lastSent.Add(time.Duration(class.config.Spacing) * time.Millisecond)
+		if now.Before(ready) {
+			if d := ready.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		class.current += class.config.Weight
+		total += class.config.Weight
+		if picked == nil || class.current > picked.current {
+			picked = class
+		}
+	}
+
+	if picked == nil {
+		return nil, nil, wait
+	}
+
+	picked.current -= total
+	item := picked.items[0]
+	picked.items = picked.items[1:]
+	return &item, picked, 0
+}
+
+func (q *ActionQueue) serve(bot *ChatBot) {
+	for {
+		q.mux.Lock()
+		if q.closed {
+			q.running = false
+			q.mux.Unlock()
+			return
+		}
+
+		now := time.Now()
+		item, class, wait := q.next(now)
+		if item == nil && wait == 0 {
+			q.running = false
+			q.mux.Unlock()
+			return
+		}
+
+		if item != nil {
+			waited := float64(now.Sub(item.enqueuedAt) / time.Millisecond)
+			class.lastSent = now
+			class.sent += 1
+			class.avgWait = class.avgWait*0.9 + waited*0.1
+		}
+		q.mux.Unlock()
+
+		if item == nil {
+			time.Sleep(wait)
+			continue
+		}
+
+		o := &ErrorHandler{}
+		o.sendEvent(bot.tunnel, item.event)
+		if o.Err != nil {
+			bot.Error(o.Err, "c[%s] send queued action failed", bot.ClientId)
+			q.failed(item.event, o.Err)
+		}
+	}
+}
+
+func (q *ActionQueue) failed(event *pb.EventReply, err error) {
+	if q.onFailed != nil {
+		q.onFailed(event, err)
+	}
+}
+
+// Close stops the queue of a bot dropped, the actions still waiting fail.
+func (q *ActionQueue) Close() {
+	q.mux.Lock()
+	q.closed = true
+	pending := []queuedAction{}
+	for _, name := range actionClasses {
+		class := q.classes[name]
+		pending = append(pending, class.items...)
+		class.items = nil
+	}
+	q.mux.Unlock()
+
+	for _, item := range pending {
+		q.failed(item.event, fmt.Errorf("bot dropped before the action was sent"))
+	}
+}
+
+func (q *ActionQueue) Info() map[string]ActionClassInfo {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	now := time.Now()
+	info := map[string]ActionClassInfo{}
+	for name, class := range q.classes {
+		ci := ActionClassInfo{
+			Depth:   len(class.items),
+			AvgWait: int64(class.avgWait),
+			Sent:    class.sent,
+		}
+		if len(class.items) > 0 {
+			ci.OldestAge = int64(now.Sub(class.items[0].enqueuedAt) / time.Millisecond)
+		}
+		info[name] = ci
+	}
+
+	return info
+}
+
+// newChatBot is a bot whose actions failing in its queue fail their action
+// requests, rather than leave web waiting for a reply until timeout.
+func (hub *ChatHub) newChatBot() *ChatBot {
+	bot := NewChatBot()
+	bot.actionQueue.onFailed = func(event *pb.EventReply, err error) {
+		hub.failQueuedAction(bot, event, err)
+	}
+
+	return bot
+}
+
+// failQueuedAction replies the action failed to web, as the client would.
+func (hub *ChatHub) failQueuedAction(bot *ChatBot, event *pb.EventReply, err error) {
+	o := &ErrorHandler{}
+	actionRequestId := o.FromMapString("actionRequestId", o.FromJson(event.Body), "event.body", true, "")
+	if o.Err != nil || actionRequestId == "" {
+		return
+	}
+
+	hub.replyAction(actionRequestId)
+	bot.stats.actionReplied(actionRequestId)
+
+	if hub.rabbitmq == nil {
+		return
+	}
+
+	o.Err = hub.rabbitmq.Send(utils.CH_BotNotify, o.ToJson(models.MqEvent{
+		BotId:     bot.BotId,
+		EventType: ACTIONREPLY,
+		Body: o.ToJson(domains.ActionRequest{
+			ActionRequestId: actionRequestId,
+			Result: o.ToJson(map[string]interface{}{
+				"success": false,
+				"message": err.Error(),
+			}),
+			ReplyAt: utils.JSONTime{Time: time.Now()},
+		}),
+	}))
+	if o.Err != nil {
+		hub.Error(o.Err, "c[%s] fail action %s failed", bot.ClientId, actionRequestId)
+	}
+}
@@ -0,0 +1,165 @@
+package chatbothub
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+)
+
+func newTestActionQueue(interactive, bulk, background ActionClassConfig) *ActionQueue {
+	return &ActionQueue{classes: map[string]*actionClassQueue{
+		ActionInteractive: &actionClassQueue{config: interactive},
+		ActionBulk:        &actionClassQueue{config: bulk},
+		ActionBackground:  &actionClassQueue{config: background},
+	}}
+}
+
+func fillActionClass(q *ActionQueue, class string, n int) {
+	for i := 0; i < n; i++ {
+		q.classes[class].items = append(q.classes[class].items, queuedAction{
+			event:      &pb.EventReply{Body: class},
+			enqueuedAt: time.Now(),
+		})
+	}
+}
+
+// drainActionQueue pops every action, no spacing in the way, and returns
+// their classes in the order sent.
+func drainActionQueue(t *testing.T, q *ActionQueue) []string {
+	order := []string{}
+	now := time.Now()
+	for {
+		item, _, wait := q.next(now)
+		if item == nil {
+			if wait != 0 {
+				t.Fatalf("wait %s without spacing", wait)
+			}
+			return order
+		}
+		order = append(order, item.event.Body)
+	}
+}
+
+func TestActionQueueWeightedShares(t *testing.T) {
+	q := newTestActionQueue(
+		ActionClassConfig{Weight: 8},
+		ActionClassConfig{Weight: 3},
+		ActionClassConfig{Weight: 1})
+
+	fillActionClass(q, ActionInteractive, 80)
+	fillActionClass(q, ActionBulk, 80)
+	fillActionClass(q, ActionBackground, 80)
+
+	order := drainActionQueue(t, q)
+	if len(order) != 240 {
+		t.Fatalf("sent %d actions, expected 240", len(order))
+	}
+
+	// while every class has work, each round of 12 sends is 8, 3, 1
+	for round := 0; round < 5; round++ {
+		counts := map[string]int{}
+		for _, class := range order[round*12 : (round+1)*12] {
+			counts[class] += 1
+		}
+
+		if counts[ActionInteractive] != 8 || counts[ActionBulk] != 3 || counts[ActionBackground] != 1 {
+			t.Errorf("round %d sent %v, expected 8, 3, 1", round, counts)
+		}
+	}
+}
+
+func TestActionQueueSmoothOrder(t *testing.T) {
+	q := newTestActionQueue(
+		ActionClassConfig{Weight: 2},
+		ActionClassConfig{Weight: 1},
+		ActionClassConfig{Weight: 1})
+
+	fillActionClass(q, ActionInteractive, 2)
+	fillActionClass(q, ActionBulk, 1)
+	fillActionClass(q, ActionBackground, 1)
+
+	// smooth weighted round robin interleaves rather than bursts
+	expected := []string{ActionInteractive, ActionBulk, ActionBackground, ActionInteractive}
+	order := drainActionQueue(t, q)
+	if len(order) != len(expected) {
+		t.Fatalf("sent %v, expected %v", order, expected)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("sent %v, expected %v", order, expected)
+		}
+	}
+}
+
+func TestActionQueueIdleClassesGetNoShare(t *testing.T) {
+	q := newTestActionQueue(
+		ActionClassConfig{Weight: 8},
+		ActionClassConfig{Weight: 3},
+		ActionClassConfig{Weight: 1})
+
+	fillActionClass(q, ActionBackground, 3)
+
+	order := drainActionQueue(t, q)
+	if len(order) != 3 {
+		t.Fatalf("sent %d actions, expected 3", len(order))
+	}
+	for _, class := range order {
+		if class != ActionBackground {
+			t.Errorf("sent %s from an empty class", class)
+		}
+	}
+}
+
+func TestActionQueueSpacing(t *testing.T) {
+	q := newTestActionQueue(
+		ActionClassConfig{Weight: 8},
+		ActionClassConfig{Weight: 3, Spacing: 200},
+		ActionClassConfig{Weight: 1})
+
+	fillActionClass(q, ActionBulk, 2)
+
+	now := time.Now()
+	item, class, _ := q.next(now)
+	if item == nil {
+		t.Fatalf("first bulk action not sent")
+	}
+	class.lastSent = now
+
+	item, _, wait := q.next(now.Add(50 * time.Millisecond))
+	if item != nil {
+		t.Fatalf("bulk action sent within its spacing")
+	}
+	if wait != 150*time.Millisecond {
+		t.Errorf("wait %s, expected 150ms", wait)
+	}
+
+	if item, _, _ = q.next(now.Add(200 * time.Millisecond)); item == nil {
+		t.Errorf("bulk action not sent after its spacing")
+	}
+}
+
+func TestActionQueueClose(t *testing.T) {
+	q := newTestActionQueue(
+		ActionClassConfig{Weight: 8},
+		ActionClassConfig{Weight: 3},
+		ActionClassConfig{Weight: 1})
+
+	fillActionClass(q, ActionInteractive, 2)
+	fillActionClass(q, ActionBulk, 1)
+
+	failed := 0
+	q.onFailed = func(event *pb.EventReply, err error) {
+		failed += 1
+	}
+
+	q.Close()
+	if failed != 3 {
+		t.Errorf("%d actions failed on close, expected 3", failed)
+	}
+
+	bot := &ChatBot{Login: "login"}
+	if err := q.Push(bot, SendTextMessage, &pb.EventReply{}); err == nil {
+		t.Errorf("push to a closed queue should fail")
+	}
+}
@@ -0,0 +1,124 @@
+package chatbothub
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+type LogLevelBody struct {
+	Level string `json:"level"`
+}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// adminReply answers client errors in the reply, as the web api expects,
+// and the others as grpc errors.
+func adminReply(err error) (*pb.OperationReply, error) {
+	if err == nil {
+		return &pb.OperationReply{Code: 0, Message: "success"}, nil
+	}
+
+	switch clientError := err.(type) {
+	case *utils.ClientError:
+		return &pb.OperationReply{
+			Code:    int32(clientError.Code),
+			Message: clientError.Error(),
+		}, nil
+	default:
+		return nil, err
+	}
+}
+
+func (hub *ChatHub) adminBot(clientId string) (*ChatBot, error) {
+	if clientId == "" {
+		return nil, utils.NewClientError(utils.PARAM_REQUIRED, fmt.Errorf("clientId required"))
+	}
+
+	bot := hub.GetBot(clientId)
+	if bot == nil {
+		return nil, utils.NewClientError(utils.RESOURCE_NOT_FOUND,
+			fmt.Errorf("c[%s] not found on hub %s", clientId, hub.instance.InstanceId))
+	}
+
+	return bot, nil
+}
+
+func (hub *ChatHub) InspectClient(ctx context.Context, req *pb.ClientAdminRequest) (*pb.ClientInspection, error) {
+	bot, err := hub.adminBot(req.ClientId)
+	if err != nil {
+		reply, err := adminReply(err)
+		return &pb.ClientInspection{ClientError: reply}, err
+	}
+
+	inspection := &pb.ClientInspection{
+		BotsInfo:       NewBotsInfo(bot),
+		HubInstance:    hub.instance.InstanceId,
+		FilterId:       bot.filterId,
+		MomentFilterId: bot.momentFilterId,
+	}
+	bot.stats.fill(inspection)
+
+	return inspection, nil
+}
+
+// ReconnectClient asks the client to reconnect, to the target when given.
+func (hub *ChatHub) ReconnectClient(ctx context.Context, req *pb.ClientAdminRequest) (*pb.OperationReply, error) {
+	bot, err := hub.adminBot(req.ClientId)
+	if err != nil {
+		return adminReply(err)
+	}
+
+	if !bot.supportsEvent(RECONNECT) {
+		return adminReply(utils.NewClientError(utils.METHOD_UNSUPPORTED,
+			fmt.Errorf("c[%s] protocol v%d does not support %s",
+				bot.ClientId, bot.capabilities.ProtocolVersion, RECONNECT)))
+	}
+
+	hub.Info("[ADMIN] c[%s] reconnect to %q", bot.ClientId, req.Target)
+
+	o := &ErrorHandler{}
+	o.sendEvent(bot.tunnel, &pb.EventReply{
+		EventType:  RECONNECT,
+		ClientType: bot.ClientType,
+		ClientId:   bot.ClientId,
+		Body:       o.ToJson(ReconnectBody{Target: req.Target}),
+	})
+
+	return adminReply(o.Err)
+}
+
+func (hub *ChatHub) SetClientLogLevel(ctx context.Context, req *pb.ClientAdminRequest) (*pb.OperationReply, error) {
+	bot, err := hub.adminBot(req.ClientId)
+	if err != nil {
+		return adminReply(err)
+	}
+
+	level := strings.ToLower(req.LogLevel)
+	if !containsString(logLevels, level) {
+		return adminReply(utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("log level %q not in %v", req.LogLevel, logLevels)))
+	}
+
+	// version 0 clients get it anyway, and ignore it if they do not know it
+	if !bot.supportsEvent(LOGLEVEL) {
+		return adminReply(utils.NewClientError(utils.METHOD_UNSUPPORTED,
+			fmt.Errorf("c[%s] does not support %s", bot.ClientId, LOGLEVEL)))
+	}
+
+	hub.Info("[ADMIN] c[%s] log level %s", bot.ClientId, level)
+
+	o := &ErrorHandler{}
+	o.sendEvent(bot.tunnel, &pb.EventReply{
+		EventType:  LOGLEVEL,
+		ClientType: bot.ClientType,
+		ClientId:   bot.ClientId,
+		Body:       o.ToJson(LogLevelBody{Level: level}),
+	})
+
+	return adminReply(o.Err)
+}
@@ -0,0 +1,103 @@
+package chatbothub
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const defaultBotLogLines int = 200
+
+// botLogs keeps the latest log lines of each bot, those logged with a
+// botId field, for operators to read without searching the whole output.
+type botLogs struct {
+	mux   sync.Mutex
+	size  int
+	lines map[string][]*pb.LogLine
+}
+
+func newBotLogs(size int) *botLogs {
+	if size <= 0 {
+		size = defaultBotLogLines
+	}
+
+	return &botLogs{
+		size:  size,
+		lines: make(map[string][]*pb.LogLine),
+	}
+}
+
+func (b *botLogs) append(entry logging.Entry) {
+	botId, _ := entry.Fields["botId"].(string)
+	if botId == "" {
+		return
+	}
+
+	fields, _ := json.Marshal(entry.Fields)
+	line := &pb.LogLine{
+		At:      entry.Time.UnixNano() / 1e6,
+		Level:   entry.Level.String(),
+		Package: entry.Package,
+		Message: entry.Message,
+		Error:   entry.Error,
+		Fields:  string(fields),
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	lines := append(b.lines[botId], line)
+	if len(lines) > b.size {
+		lines = lines[len(lines)-b.size:]
+	}
+	b.lines[botId] = lines
+}
+
+// get returns the latest limit lines of the bot at level or above, all of
+// them when limit is 0.
+func (b *botLogs) get(botId string, level logging.Level, limit int) []*pb.LogLine {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	result := []*pb.LogLine{}
+	for _, line := range b.lines[botId] {
+		if l, err := logging.ParseLevel(line.Level); err == nil && l < level {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+
+	return result
+}
+
+// GetBotLogs answers the lines this instance kept of the bot, web asks the
+// instance owning the bot.
+func (hub *ChatHub) GetBotLogs(ctx context.Context, req *pb.BotLogsRequest) (*pb.BotLogsReply, error) {
+	if req.BotId == "" {
+		reply, err := adminReply(utils.NewClientError(utils.PARAM_REQUIRED, fmt.Errorf("botId required")))
+		return &pb.BotLogsReply{ClientError: reply}, err
+	}
+
+	level := logging.DebugLevel
+	if req.Level != "" {
+		var err error
+		if level, err = logging.ParseLevel(req.Level); err != nil {
+			reply, err := adminReply(utils.NewClientError(utils.PARAM_INVALID, err))
+			return &pb.BotLogsReply{ClientError: reply}, err
+		}
+	}
+
+	return &pb.BotLogsReply{
+		Lines: hub.botLogs.get(req.BotId, level, int(req.Limit)),
+	}, nil
+}
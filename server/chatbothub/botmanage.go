@@ -5,20 +5,14 @@ import (
 	"golang.org/x/net/context"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/models"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
 func (hub *ChatHub) GetAvailableBot(bottype string) *ChatBot {
-	hub.muxBots.Lock()
-	defer hub.muxBots.Unlock()
-
-	for _, v := range hub.bots {
-		if v.ClientType == bottype && v.Status == BeginRegistered {
-			return v
-		}
-	}
-
-	return nil
+	return hub.SelectAvailableBot(SelectRequest{ClientType: bottype})
 }
 
 func (hub *ChatHub) GetBot(clientid string) *ChatBot {
@@ -67,11 +61,38 @@ func (hub *ChatHub) SetBot(clientid string, thebot *ChatBot) {
 
 func (hub *ChatHub) DropBot(clientid string) {
 	hub.muxBots.Lock()
-	defer hub.muxBots.Unlock()
-
+	thebot := hub.bots[clientid]
 	delete(hub.bots, clientid)
+	hub.muxBots.Unlock()
 
 	//hub.Info("[DROP BOT] %s %#v", clientid, hub.bots)
+	if thebot != nil {
+		thebot.actionQueue.Close()
+	}
+	hub.releaseBot(thebot)
+}
+
+// notifyBotStatus tells the alerting in tasks that the bot logged in,
+// failed to log in or logged out, when NotifyBotStatus is on.
+func (hub *ChatHub) notifyBotStatus(bot *ChatBot, eventType string, message string) {
+	if !hub.Config.NotifyBotStatus || bot == nil || bot.BotId == "" {
+		return
+	}
+
+	o := &ErrorHandler{}
+	err := hub.rabbitmq.Send(utils.CH_BotStatus, o.ToJson(models.MqEvent{
+		BotId:     bot.BotId,
+		EventType: eventType,
+		Body: o.ToJson(models.BotStatusBody{
+			Login:      bot.Login,
+			ClientId:   bot.ClientId,
+			ClientType: bot.ClientType,
+			Message:    message,
+		}),
+	}))
+	if err != nil {
+		hub.Error(err, "notify bot status %s of b[%s] failed", eventType, bot.BotId)
+	}
 }
 
 func (o *ErrorHandler) FindFromLines(lines []string, target string) bool {
@@ -89,13 +110,11 @@ func (o *ErrorHandler) FindFromLines(lines []string, target string) bool {
 }
 
 func (hub *ChatHub) GetBots(ctx context.Context, req *pb.BotsRequest) (*pb.BotsReply, error) {
-	hub.muxBots.Lock()
-	defer hub.muxBots.Unlock()
-
 	o := &ErrorHandler{}
 
 	botm := make(map[string]*pb.BotsInfo)
 
+	hub.muxBots.Lock()
 	for _, v := range hub.bots {
 		if len(req.Logins) > 0 {
 			if o.FindFromLines(req.Logins, v.Login) {
@@ -113,16 +132,19 @@ func (hub *ChatHub) GetBots(ctx context.Context, req *pb.BotsRequest) (*pb.BotsR
 			botm[v.ClientId] = NewBotsInfo(v)
 		}
 	}
+	hub.muxBots.Unlock()
 
 	bots := make([]*pb.BotsInfo, 0)
 	for _, v := range botm {
 		bots = append(bots, v)
 	}
 
+	bots = append(bots, hub.peerBots(ctx, req)...)
+
 	return &pb.BotsReply{BotsInfo: bots}, nil
 }
 
-func (ctx *ErrorHandler) sendEvent(tunnel pb.ChatBotHub_EventTunnelServer, event *pb.EventReply) {
+func (ctx *ErrorHandler) sendEvent(tunnel EventTunnelStream, event *pb.EventReply) {
 	if ctx.Err != nil {
 		return
 	}
@@ -149,6 +171,11 @@ func (hub *ChatHub) BotLogout(ctx context.Context, req *pb.BotLogoutRequest) (*p
 
 	bot := hub.GetBotById(req.BotId)
 	if bot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.BotLogout(peer.Context, req)
+		}
+
 		hub.Info("cannot find bot %s\n%#v", req.BotId, hub.bots)
 		return &pb.OperationReply{
 			Code:    int32(utils.RESOURCE_NOT_FOUND),
@@ -174,12 +201,22 @@ func (hub *ChatHub) BotShutdown(ctx context.Context, req *pb.BotLogoutRequest) (
 	if len(req.BotId) > 0 {
 		bot = hub.GetBotById(req.BotId)
 		if bot == nil {
+			if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+				defer peer.Cancel()
+				return peer.HubClient.BotShutdown(peer.Context, req)
+			}
+
 			hub.Info("cannot find bot %s for shutdown, ignore", req.BotId, hub.bots)
 			return &pb.OperationReply{Code: 0, Message: "success"}, nil
 		}
 	} else if len(req.ClientId) > 0 {
 		bot = hub.GetBot(req.ClientId)
 		if bot == nil {
+			if peer := hub.ownerPeer(ctx, domains.BotOwnerByClient, req.ClientId); peer != nil {
+				defer peer.Cancel()
+				return peer.HubClient.BotShutdown(peer.Context, req)
+			}
+
 			hub.Info("cannot find bot %s for shutdown, ignore", req.ClientId, hub.bots)
 			return &pb.OperationReply{Code: 0, Message: "success"}, nil
 		}
@@ -197,7 +234,13 @@ func (hub *ChatHub) BotLogin(ctx context.Context, req *pb.BotLoginRequest) (*pb.
 	hub.Info("recieve login bot cmd from web %s: %s %s", req.ClientId, req.ClientType, req.Login)
 	var bot *ChatBot
 	if req.ClientId == "" {
-		bot = hub.GetAvailableBot(req.ClientType)
+		if !hub.isDraining() {
+			bot = hub.SelectAvailableBot(SelectRequest{
+				ClientType: req.ClientType,
+				Login:      req.Login,
+				Region:     req.Region,
+			})
+		}
 		if bot != nil {
 			req.ClientId = bot.ClientId
 		}
@@ -205,6 +248,27 @@ func (hub *ChatHub) BotLogin(ctx context.Context, req *pb.BotLoginRequest) (*pb.
 		bot = hub.GetBot(req.ClientId)
 	}
 
+	if bot == nil {
+		if req.ClientId != "" {
+			if peer := hub.ownerPeer(ctx, domains.BotOwnerByClient, req.ClientId); peer != nil {
+				defer peer.Cancel()
+				return peer.HubClient.BotLogin(peer.Context, req)
+			}
+		} else if reply := hub.peerBotLogin(ctx, req); reply != nil {
+			return reply, nil
+		}
+	}
+
+	if bot != nil && hub.isDraining() {
+		return &pb.BotLoginReply{
+			Msg: fmt.Sprintf("LOGIN BOT FAILED"),
+			ClientError: &pb.OperationReply{
+				Code:    int32(utils.RESOURCE_INSUFFICIENT),
+				Message: hub.drainingError().Error(),
+			},
+		}, nil
+	}
+
 	o := &ErrorHandler{}
 	if bot != nil {
 		if req.BotId == "" {
@@ -281,11 +345,31 @@ func (hub *ChatHub) BotAction(ctx context.Context, req *pb.BotActionRequest) (*p
 
 	bot := hub.GetBotByLogin(req.Login)
 	if bot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByLogin, req.Login); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.BotAction(tracing.WithSpanOf(peer.Context, ctx), req)
+		}
+
 		o.Err = fmt.Errorf("b[%s] not found", req.Login)
 	}
 
+	if o.Err == nil && hub.isDraining() {
+		o.Err = utils.NewClientError(utils.RESOURCE_INSUFFICIENT, hub.drainingError())
+	}
+
 	if o.Err == nil {
+		o.Err = bot.checkActionSupported(req.ActionType)
+	}
+
+	if o.Err == nil {
+		traceAction(ctx, req.ActionRequestId)
 		o.Err = bot.BotAction(req.ActionRequestId, req.ActionType, req.ActionBody)
+		forgetActionTrace(req.ActionRequestId)
+	}
+
+	if o.Err == nil {
+		hub.trackAction(req.ActionRequestId)
+		hub.meterAction(bot, req.ActionType)
 	}
 
 	if o.Err != nil {
@@ -327,6 +411,11 @@ func (hub *ChatHub) WebShortCallResponse(ctx context.Context, req *pb.EventReply
 	bot := hub.GetBotById(req.BotId)
 
 	if bot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.WebShortCallResponse(peer.Context, req)
+		}
+
 		o.Err = fmt.Errorf("b[%s] not found", req.ClientId)
 	}
 
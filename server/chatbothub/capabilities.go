@@ -0,0 +1,112 @@
+package chatbothub
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	// protocol version of this hub. Clients that send no capabilities at
+	// REGISTER speak version 0, and are assumed to support the baseline,
+	// what clients did before negotiation existed.
+	HubProtocolVersion int = 1
+)
+
+var (
+	// actions of version 0 clients
+	baselineActions = []string{
+		AddContact, DeleteContact, AcceptUser,
+		SendTextMessage, SendAppMessage, SendImageMessage, SendImageResourceMessage,
+		CreateRoom, AddRoomMember, InviteRoomMember, GetRoomMembers, DeleteRoomMember,
+		SetRoomAnnouncement, SetRoomName, GetRoomQRCode,
+		GetContactQRCode, GetContact, CheckContact, SearchContact, SyncContact,
+		SnsTimeline, SnsUserPage, SnsGetObject, SnsComment, SnsLike, SnsUpload,
+		SnsobjectOP, SnsSendMoment,
+		GetLabelList, AddLabel, DeleteLabel, SetLabel,
+		GetRequestToken, RequestUrl,
+	}
+
+	// events the hub sends to version 0 clients, the ones added along with
+	// negotiation, RECONNECT and LOGLEVEL, are not among them
+	baselineEvents = []string{
+		PING, PONG, LOGIN, LOGOUT, SHUTDOWN, BOTMIGRATE, BOTACTION,
+	}
+)
+
+// ClientCapabilities is the body of REGISTER, declared by the client.
+type ClientCapabilities struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Actions         []string `json:"actions,omitempty"`
+	Events          []string `json:"events,omitempty"`
+}
+
+type RegisteredBody struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+func parseCapabilities(body string) (ClientCapabilities, error) {
+	caps := ClientCapabilities{}
+	if body == "" || body == "{}" {
+		return caps, nil
+	}
+
+	if err := json.Unmarshal([]byte(body), &caps); err != nil {
+		return ClientCapabilities{}, err
+	}
+
+	// agree on the lower of the two versions
+	if caps.ProtocolVersion > HubProtocolVersion {
+		caps.ProtocolVersion = HubProtocolVersion
+	}
+
+	if caps.ProtocolVersion < 0 {
+		caps.ProtocolVersion = 0
+	}
+
+	return caps, nil
+}
+
+func containsString(list []string, target string) bool {
+	for _, s := range list {
+		if s == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// speaksWechat tells the client sends and takes the wechat bodies, the same
+// for WECHATBOT and WECHATMACPRO. It picks how a body is read or built, what
+// the client can do is up to supportsAction and supportsEvent.
+func (bot *ChatBot) speaksWechat() bool {
+	return bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO
+}
+
+func (bot *ChatBot) supportsAction(actionType string) bool {
+	if bot.capabilities.ProtocolVersion == 0 {
+		return containsString(baselineActions, actionType)
+	}
+
+	return containsString(bot.capabilities.Actions, actionType)
+}
+
+func (bot *ChatBot) supportsEvent(eventType string) bool {
+	if bot.capabilities.ProtocolVersion == 0 {
+		return containsString(baselineEvents, eventType)
+	}
+
+	return containsString(bot.capabilities.Events, eventType)
+}
+
+func (bot *ChatBot) checkActionSupported(actionType string) error {
+	if bot.supportsAction(actionType) {
+		return nil
+	}
+
+	return utils.NewClientError(utils.METHOD_UNSUPPORTED,
+		fmt.Errorf("c[%s] b[%s] protocol v%d does not support a[%s]",
+			bot.ClientType, bot.Login, bot.capabilities.ProtocolVersion, actionType))
+}
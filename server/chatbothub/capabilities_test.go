@@ -0,0 +1,55 @@
+package chatbothub
+
+import (
+	"testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := parseCapabilities("")
+	if err != nil || caps.ProtocolVersion != 0 {
+		t.Errorf("empty body should be protocol v0, got %+v err %v", caps, err)
+	}
+
+	caps, err = parseCapabilities(`{"protocolVersion": 9, "actions": ["SendTextMessage"]}`)
+	if err != nil || caps.ProtocolVersion != HubProtocolVersion {
+		t.Errorf("newer client should agree on the hub version, got %+v err %v", caps, err)
+	}
+
+	if _, err = parseCapabilities(`{"protocolVersion":`); err == nil {
+		t.Errorf("malformed capabilities should fail")
+	}
+}
+
+func TestV0ClientSupportsBaseline(t *testing.T) {
+	bot := &ChatBot{}
+
+	if !bot.supportsAction(SendTextMessage) || !bot.supportsEvent(BOTACTION) {
+		t.Errorf("v0 client should support the baseline actions and events")
+	}
+
+	for _, eventType := range []string{RECONNECT, LOGLEVEL} {
+		if bot.supportsEvent(eventType) {
+			t.Errorf("v0 client should not be sent %s", eventType)
+		}
+	}
+
+	if bot.checkActionSupported("NoSuchAction") == nil {
+		t.Errorf("v0 client should not support actions out of the baseline")
+	}
+}
+
+func TestV1ClientSupportsDeclared(t *testing.T) {
+	bot := &ChatBot{capabilities: ClientCapabilities{
+		ProtocolVersion: 1,
+		Actions:         []string{SendTextMessage},
+		Events:          []string{RECONNECT},
+	}}
+
+	if !bot.supportsAction(SendTextMessage) || bot.supportsAction(SendImageMessage) {
+		t.Errorf("v1 client should support only the actions declared")
+	}
+
+	if !bot.supportsEvent(RECONNECT) || bot.supportsEvent(LOGLEVEL) {
+		t.Errorf("v1 client should support only the events declared")
+	}
+}
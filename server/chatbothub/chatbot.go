@@ -3,8 +3,6 @@ package chatbothub
 import (
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 	"time"
 
@@ -13,6 +11,7 @@ import (
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -28,6 +27,7 @@ const (
 	WorkingLoggedIn     ChatBotStatus = 200
 	ShuttingdownDone    ChatBotStatus = 404
 	FailingDisconnected ChatBotStatus = 500
+	WaitingForClient    ChatBotStatus = 501
 )
 
 func (status ChatBotStatus) String() string {
@@ -40,6 +40,7 @@ func (status ChatBotStatus) String() string {
 		LoggingStaging:      "登录接入中",
 		WorkingLoggedIn:     "已登录",
 		FailingDisconnected: "连接断开",
+		WaitingForClient:    "等待客户端重连",
 	}
 
 	return names[status]
@@ -54,23 +55,29 @@ type LoginInfo struct {
 }
 
 type ChatBot struct {
-	ClientId     string        `json:"clientId"`
-	ClientType   string        `json:"clientType"`
-	Name         string        `json:"name"`
-	StartAt      int64         `json:"startAt"`
-	LastPing     int64         `json:"lastPing"`
-	Login        string        `json:"login"`
-	NotifyUrl    string        `json:"notifyurl"`
-	LoginInfo    LoginInfo     `json:"loginInfo"`
-	Status       ChatBotStatus `json:"status"`
-	BotId        string        `json:"botId"`
-	ScanUrl      string        `json:"scanUrl"`
-	tunnel       pb.ChatBotHub_EventTunnelServer
-	errmsg       string
-	filter       Filter
-	momentFilter Filter
-	logger       *log.Logger
-	pinglooping  bool
+	ClientId       string        `json:"clientId"`
+	ClientType     string        `json:"clientType"`
+	Name           string        `json:"name"`
+	StartAt        int64         `json:"startAt"`
+	LastPing       int64         `json:"lastPing"`
+	Login          string        `json:"login"`
+	NotifyUrl      string        `json:"notifyurl"`
+	LoginInfo      LoginInfo     `json:"loginInfo"`
+	Status         ChatBotStatus `json:"status"`
+	BotId          string        `json:"botId"`
+	ScanUrl        string        `json:"scanUrl"`
+	tunnel         EventTunnelStream
+	errmsg         string
+	filter         Filter
+	momentFilter   Filter
+	filterId       string
+	momentFilterId string
+	capabilities   ClientCapabilities
+	load           loadReport
+	loginFailedAt  time.Time
+	stats          *clientStats
+	pinglooping    bool
+	actionQueue    *ActionQueue
 }
 
 const (
@@ -110,21 +117,41 @@ const (
 	RequestUrl               string = "RequestUrl"
 )
 
+var botLogger = logging.New("bot")
+
+// log carries the ids of the bot, so that the hub keeps the line in the
+// log stream of the bot.
+func (bot *ChatBot) log() *logging.Logger {
+	return botLogger.With(
+		"botId", bot.BotId,
+		"clientId", bot.ClientId,
+		"clientType", bot.ClientType,
+		"login", bot.Login)
+}
+
+func (bot *ChatBot) Debug(msg string, v ...interface{}) {
+	bot.log().Debug(msg, v...)
+}
+
 func (bot *ChatBot) Info(msg string, v ...interface{}) {
-	bot.logger.Printf(msg, v...)
+	bot.log().Info(msg, v...)
+}
+
+func (bot *ChatBot) Warn(msg string, v ...interface{}) {
+	bot.log().Warn(msg, v...)
 }
 
 func (bot *ChatBot) Error(err error, msg string, v ...interface{}) {
 	raven.CaptureError(err, nil)
 
-	bot.logger.Printf(msg, v...)
-	bot.logger.Printf("Error %v", err)
+	bot.log().Error(err, msg, v...)
 }
 
 func NewChatBot() *ChatBot {
 	return &ChatBot{
-		Status: BeginNew,
-		logger: log.New(os.Stdout, "[BOT] ", log.Ldate|log.Ltime),
+		Status:      BeginNew,
+		actionQueue: NewActionQueue(),
+		stats:       newClientStats(),
 	}
 }
 
@@ -161,7 +188,7 @@ func (bot *ChatBot) closePingloop() {
 }
 
 func (bot *ChatBot) register(clientId string, clientType string,
-	tunnel pb.ChatBotHub_EventTunnelServer) (*ChatBot, error) {
+	tunnel EventTunnelStream) (*ChatBot, error) {
 
 	// if bot.Status != BeginNew && bot.Status != BeginRegistered && bot.Status != FailingDisconnected {
 	// 	return bot, fmt.Errorf("bot status %s cannot register", bot.Status)
@@ -174,7 +201,7 @@ func (bot *ChatBot) register(clientId string, clientType string,
 	ts := time.Now().UnixNano() / 1e6
 	bot.StartAt = ts
 	bot.LastPing = ts
-	bot.tunnel = tunnel
+	bot.tunnel = &inspectedTunnel{EventTunnelStream: tunnel, stats: bot.stats}
 	bot.Status = BeginRegistered
 
 	return bot, nil
@@ -516,13 +543,33 @@ func (o *ErrorHandler) SendAction(bot *ChatBot, arId string, actionType string,
 		"actionType":      actionType,
 		"body":            body,
 	}
+	if trace := actionTrace(arId); trace != nil {
+		actionm["trace"] = trace
+	}
 
-	o.sendEvent(bot.tunnel, &pb.EventReply{
+	event := &pb.EventReply{
 		EventType:  BOTACTION,
 		ClientType: bot.ClientType,
 		ClientId:   bot.ClientId,
 		Body:       o.ToJson(actionm),
-	})
+	}
+	if o.Err != nil {
+		return
+	}
+
+	if bot.Status == WaitingForClient {
+		o.Err = utils.NewClientError(utils.STATUS_INCONSISTENT,
+			fmt.Errorf("b[%s] restored, waiting for its client to register", bot.Login))
+		return
+	}
+
+	if bot.tunnel == nil {
+		o.Err = fmt.Errorf("tunnel is null")
+		return
+	}
+
+	// sent later by the queue, in the order of its priority class
+	o.Err = bot.actionQueue.Push(bot, actionType, event)
 }
 
 type ActionParam struct {
@@ -564,7 +611,7 @@ func NewActionParamFloat(name string, hasdefault bool, defaultvalue float64) Act
 }
 
 func (o *ErrorHandler) CommonActionDispatch(bot *ChatBot, arId string, body string, actionType string, params []ActionParam) {
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bot.Info("action %s", actionType)
 		bodym := o.FromJson(body)
 		if o.Err != nil {
@@ -640,7 +687,7 @@ func (bot *ChatBot) AddLabel(actionType string, arId string, body string) error
 
 func (bot *ChatBot) DeleteLabel(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bodym := o.FromJson(body)
 		labelId := int(o.FromMapFloat("labelId", bodym, "actionbody", false, 0.0))
 		if o.Err != nil {
@@ -661,7 +708,7 @@ func (bot *ChatBot) DeleteLabel(actionType string, arId string, body string) err
 func (bot *ChatBot) SetLabel(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
 
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bodym := o.FromJson(body)
 		userId := o.FromMapString("userId", bodym, "actionbody", false, "")
 		alias := o.FromMapString("alias", bodym, "actionbody", true, "")
@@ -860,7 +907,7 @@ func (bot *ChatBot) SearchContact(actionType string, arId string, body string) e
 
 func (bot *ChatBot) AcceptUser(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		var msg domains.WechatFriendRequest
 		o.Err = json.Unmarshal([]byte(body), &msg)
 		if o.Err != nil {
@@ -893,7 +940,7 @@ func (bot *ChatBot) AcceptUser(actionType string, arId string, body string) erro
 func (bot *ChatBot) CreateRoom(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
 
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bot.Info("Create Room")
 		bodym := o.FromJson(body)
 		memberList := o.ListValue(o.FromMap("memberList", bodym, "actionbody", []interface{}{}), false, nil)
@@ -945,7 +992,7 @@ func (bot *ChatBot) SetRoomAnnouncement(actionType string, arId string, body str
 
 func (bot *ChatBot) GetContactQRCode(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bodym := o.FromJson(body)
 		userId := o.FromMapString("userId", bodym, "actionbody", false, "")
 		alias := o.FromMapString("alias", bodym, "actionbody", true, "")
@@ -1014,7 +1061,7 @@ func (bot *ChatBot) GetRoomMembers(actionType string, arId string, body string)
 func (bot *ChatBot) AddContact(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
 
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bodym := o.FromJson(body)
 		stranger := o.FromMapString("stranger", bodym, "actionbody", false, "")
 		ticket := o.FromMapString("ticket", bodym, "actionbody", false, "")
@@ -1228,7 +1275,7 @@ lensid="%s"></emoji>`
 func (bot *ChatBot) SendTextMessage(actionType string, arId string, body string) error {
 	o := &ErrorHandler{}
 
-	if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+	if bot.speaksWechat() {
 		bodym := o.FromJson(body)
 		if o.Err != nil {
 			bot.Info("parse body failed " + body)
@@ -1384,7 +1431,7 @@ func (bot *ChatBot) SendTextMessage(actionType string, arId string, body string)
 					}))
 				}
 			} else if bot.ClientType == WECHATMACPRO {
-				fmt.Printf("b[%s] sending app message %s\n", bot.ClientType, body)
+				bot.Debug("sending app message %s", body)
 				o.SendAction(bot, arId, SendAppMessage, body)
 			}
 
@@ -4,10 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
-	"os"
 	"regexp"
 	"sync"
 	"time"
@@ -17,13 +15,21 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/globalsign/mgo"
 	"github.com/gomodule/redigo/redis"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/models"
+	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -40,6 +46,47 @@ type ChatHubConfig struct {
 	Mongo    utils.MongoConfig
 	Oss      utils.OssConfig
 	Rabbitmq utils.RabbitMQConfig
+
+	ActionQueue ActionQueueConfig
+
+	// identity of this instance in the hub cluster, and the address
+	// other hubs and web use to reach it
+	InstanceId string
+	Advertise  string
+	Lease      int
+
+	// seconds bots restored from the registry snapshot wait for their clients
+	RestoreTimeout int
+
+	// seconds a drain waits for action replies, and where clients reconnect
+	DrainTimeout int
+	DrainTarget  string
+
+	// port accepting bot clients over websocket, off when empty. Clients
+	// present the secret on upgrade, and browsers only connect from the
+	// origins listed
+	WebsocketPort    string
+	WebsocketSecret  string
+	WebsocketOrigins []string
+
+	// port serving /metrics and /loglevels, off when empty
+	MetricsPort string
+
+	// publish login and logout events to the durable bot status queue, only
+	// consumed by the alerting of tasks, turn on along with it
+	NotifyBotStatus bool
+
+	// seconds a disconnected client has to come back before its bot fails
+	// over to another client, failover is off when negative
+	FailoverGrace int
+
+	ClientSelection ClientSelectionConfig
+
+	// log lines kept per bot for GET /bots/{botId}/logs, 200 when 0
+	BotLogLines int
+
+	// journal of the raw tunnel events, for replay
+	Journal JournalConfig
 }
 
 var (
@@ -49,7 +96,10 @@ var (
 func (hub *ChatHub) init() {
 	hub.restfulclient = httpx.NewHttpClient()
 
-	hub.logger = log.New(os.Stdout, "[HUB] ", log.Ldate|log.Ltime)
+	hub.logger = logging.New("hub")
+	hub.botLogs = newBotLogs(hub.Config.BotLogLines)
+	logging.AddSink(hub.botLogs.append)
+
 	var err error
 	hub.fluentLogger, err = fluent.New(fluent.Config{
 		FluentPort:   hub.Config.Fluent.Port,
@@ -61,8 +111,12 @@ func (hub *ChatHub) init() {
 		hub.Error(err, "create fluentLogger failed %v", err)
 	}
 	hub.bots = make(map[string]*ChatBot)
+	hub.initCluster()
+	actionQueueConfig = hub.Config.ActionQueue.withDefaults()
 	hub.streamingNodes = make(map[string]*StreamingNode)
 	hub.filters = make(map[string]Filter)
+	hub.inflight = make(map[string]time.Time)
+	hub.affinity = make(map[string]string)
 
 	o := &ErrorHandler{}
 
@@ -77,6 +131,22 @@ func (hub *ChatHub) init() {
 		return
 	}
 
+	if o.EnsureUsageCounterIndexes(hub.mongoDb); o.Err != nil {
+		hub.Error(o.Err, "usage mongo ensure indexes fail, usage is not metered")
+		o.Err = nil
+	} else {
+		hub.usage = domains.NewUsageMeter()
+		hub.mediaSizes = make(chan struct{}, mediaSizeLookups)
+	}
+
+	if hub.Config.Journal.Sink != "" {
+		if hub.journal, err = hub.newEventJournal(); err != nil {
+			hub.Error(err, "create event journal failed, events are not journaled")
+		} else {
+			hub.Info("journal events to %s", hub.Config.Journal.Sink)
+		}
+	}
+
 	hub.redispool = utils.NewRedisPool(
 		fmt.Sprintf("%s:%s", hub.Config.Redis.Host, hub.Config.Redis.Port),
 		hub.Config.Redis.Db, hub.Config.Redis.Password)
@@ -102,6 +172,13 @@ func (hub *ChatHub) init() {
 		hub.Error(err, "declare queue getcontact failed")
 		return
 	}
+	if hub.Config.NotifyBotStatus {
+		err = hub.rabbitmq.DeclareQueue(utils.CH_BotStatus, true, false, false, false)
+		if err != nil {
+			hub.Error(err, "declare queue botstatus failed")
+			return
+		}
+	}
 
 	// set global variable chathub
 	chathub = hub
@@ -126,11 +203,13 @@ type ChatHub struct {
 	Config          ChatHubConfig
 	Webhost         string
 	Webport         string
+	WebGrpcport     string
 	WebBaseUrl      string
 	restfulclient   *http.Client
 	WebSecretPhrase string
-	logger          *log.Logger
+	logger          *logging.Logger
 	fluentLogger    *fluent.Fluent
+	botLogs         *botLogs
 
 	muxBots sync.Mutex
 	bots    map[string]*ChatBot
@@ -151,6 +230,32 @@ type ChatHub struct {
 	ossBucket *oss.Bucket
 
 	rabbitmq *utils.RabbitMQWrapper
+
+	// Bots of the instance is counted under muxBots
+	instance *domains.HubInstance
+	muxPeers sync.Mutex
+	peers    map[string]*rpc.GRPCWrapper
+
+	muxInflight sync.Mutex
+	inflight    map[string]time.Time
+
+	muxFailover sync.Mutex
+
+	muxSelection sync.Mutex
+	affinity     map[string]string
+	roundRobin   int
+
+	drain      drainState
+	checker    *health.Checker
+	grpcServer *grpc.Server
+
+	journal *eventJournal
+	usage   *domains.UsageMeter
+	// the image size lookups in progress, see meterMessage
+	mediaSizes chan struct{}
+	// set on the hubs replaying a journal, see NewSandboxHub
+	sandbox  *sandbox
+	wsServer *http.Server
 }
 
 func NewBotsInfo(bot *ChatBot) *pb.BotsInfo {
@@ -169,6 +274,10 @@ func NewBotsInfo(bot *ChatBot) *pb.BotsInfo {
 		MomentFilterInfo: o.ToJson(bot.momentFilter),
 		BotId:            bot.BotId,
 		ScanUrl:          bot.ScanUrl,
+		ActionQueueInfo:  o.ToJson(bot.actionQueue.Info()),
+		ProtocolVersion:  int32(bot.capabilities.ProtocolVersion),
+		SupportedActions: bot.capabilities.Actions,
+		SupportedEvents:  bot.capabilities.Events,
 	}
 }
 
@@ -182,6 +291,7 @@ const (
 	PING                 string = "PING"
 	PONG                 string = "PONG"
 	REGISTER             string = "REGISTER"
+	REGISTERED           string = "REGISTERED"
 	LOGIN                string = "LOGIN"
 	LOGOUT               string = "LOGOUT"
 	SHUTDOWN             string = "SHUTDOWN"
@@ -190,6 +300,8 @@ const (
 	LOGINFAILED          string = "LOGINFAILED"
 	LOGOUTDONE           string = "LOGOUTDONE"
 	BOTMIGRATE           string = "BOTMIGRATE"
+	RECONNECT            string = "RECONNECT"
+	LOGLEVEL             string = "LOGLEVEL"
 	UPDATETOKEN          string = "UPDATETOKEN"
 	MESSAGE              string = "MESSAGE"
 	IMAGEMESSAGE         string = "IMAGEMESSAGE"
@@ -205,15 +317,23 @@ const (
 	WEBSHORTCALLRESPONSE string = "WEBSHORTCALLRESPONSE"
 	ROOMJOIN             string = "ROOMJOIN"
 	USERACCEPTED         string = "USERACCEPTED"
+	ACTIONBREAKER        string = "ACTIONBREAKER"
 )
 
+func (ctx *ChatHub) Debug(msg string, v ...interface{}) {
+	ctx.logger.Debug(msg, v...)
+}
+
 func (ctx *ChatHub) Info(msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
+	ctx.logger.Info(msg, v...)
+}
+
+func (ctx *ChatHub) Warn(msg string, v ...interface{}) {
+	ctx.logger.Warn(msg, v...)
 }
 
 func (ctx *ChatHub) Error(err error, msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
-	ctx.logger.Printf("Error %v", err)
+	ctx.logger.Error(err, msg, v...)
 	raven.CaptureError(err, nil)
 }
 
@@ -316,12 +436,15 @@ func (hub *ChatHub) verifyMessage(bot *ChatBot, inEvent *pb.EventRequest) (map[s
 		return nil, o.Err
 	}
 
-	isdup, err := hub.checkIsDupMessage(bodyJSON)
-	if err != nil {
-		return nil, err
-	}
-	if isdup {
-		return nil, nil
+	// a replay sees the journaled messages again, they are all dups
+	if hub.sandbox == nil {
+		isdup, err := hub.checkIsDupMessage(bodyJSON)
+		if err != nil {
+			return nil, err
+		}
+		if isdup {
+			return nil, nil
+		}
 	}
 
 	imageId := ""
@@ -351,7 +474,7 @@ func (hub *ChatHub) verifyMessage(bot *ChatBot, inEvent *pb.EventRequest) (map[s
 		thumbnailId = o.FromMapString("thumbnailId", bodyJSON, "actionBody", true, "")
 	}
 
-	if imageId != "" {
+	if imageId != "" && hub.ossBucket != nil {
 		if signedURL, signedThumbnail, err := utils.GenSignedURLPair(hub.ossBucket, messageType, imageId, thumbnailId); err != nil {
 			hub.Error(err, "error occurred while generate signed image url")
 		} else {
@@ -367,28 +490,34 @@ func (hub *ChatHub) verifyMessage(bot *ChatBot, inEvent *pb.EventRequest) (map[s
 func (hub *ChatHub) onReceiveMessage(bot *ChatBot, inEvent *pb.EventRequest) error {
 	bodyJSON, err := hub.verifyMessage(bot, inEvent)
 	if err != nil {
-		hub.Info("[FILTER DEBUG] verify failed %v", err)
+		hub.Debug("[FILTER] verify failed %v", err)
 		return err
 	}
 	if bodyJSON == nil {
-		hub.Info("[FILTER DEBUG] verify failed json nil")
+		hub.Debug("[FILTER] verify failed json nil")
 		return nil
 	}
 
 	o := ErrorHandler{}
 	newBodyStr := o.ToJson(bodyJSON)
 
+	if hub.sandbox != nil {
+		return hub.sandbox.fill(bot, inEvent.EventType, newBodyStr)
+	}
+
+	hub.meterMessage(bot, inEvent.EventType, bodyJSON)
+
 	// process concurrently
 	hub.saveMessageToDB(bot, bodyJSON)
 	hub.updateChatRoom(bot, bodyJSON)
 	hub.sendEventToSubStreamingNodes(bot, inEvent.EventType, newBodyStr)
 
 	if bot.filter != nil {
-		if err := bot.filter.Fill(newBodyStr); err != nil {
+		if err := fillChain("MSG", bot.filter, newBodyStr); err != nil {
 			return err
 		}
 	} else {
-		hub.Info("[FILTER DEBUG] c[%s] filter is nil", bot.ClientId)
+		hub.Debug("[FILTER] c[%s] filter is nil", bot.ClientId)
 	}
 
 	if len(newBodyStr) > 32*1024 {
@@ -506,6 +635,14 @@ func (hub *ChatHub) onSendMessage(bot *ChatBot, actionType string, actionBody ma
 }
 
 func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
+	return hub.serveEventTunnel(tunnel)
+}
+
+// serveEventTunnel runs the event loop of one client connection, whatever
+// transport it came over.
+func (hub *ChatHub) serveEventTunnel(tunnel EventTunnelStream) error {
+	tunnel = hub.journaled(tunnel)
+
 	for {
 		in, err := tunnel.Recv()
 		if err == io.EOF {
@@ -515,6 +652,8 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 			return err
 		}
 
+		metrics.TunnelEvents.WithLabelValues(EventIn, in.EventType, in.ClientType).Inc()
+
 		if in.EventType == PING || in.EventType == PONG {
 			thebot := hub.GetBot(in.ClientId)
 			if thebot != nil {
@@ -524,6 +663,14 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 					hub.Error(err, "send PING to c[%s] FAILED %s [%s]", in.ClientType, err.Error(), in.ClientId)
 				}
 				thebot.LastPing = ts
+				if in.EventType == PONG {
+					thebot.stats.pongReceived()
+				}
+				if in.EventType == PING {
+					if load, ok := parseClientLoad(in.Body); ok {
+						thebot.load.set(load)
+					}
+				}
 
 				if bot := hub.GetBot(in.ClientId); bot != nil {
 					hub.SetBot(in.ClientId, thebot)
@@ -537,13 +684,33 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 			var bot *ChatBot
 			if bot = hub.GetBot(in.ClientId); bot == nil {
 				hub.Info("c[%s] not found, create new bot", in.ClientId)
-				bot = NewChatBot()
+				bot = hub.newChatBot()
+			}
+
+			caps, err := parseCapabilities(in.Body)
+			if err != nil {
+				hub.Error(err, "c[%s] capabilities malformed, take as protocol v0 %s", in.ClientId, in.Body)
 			}
 
 			if newbot, err := bot.register(in.ClientId, in.ClientType, tunnel); err != nil {
 				hub.Error(err, "register failed")
 			} else {
+				newbot.capabilities = caps
+				if caps.ProtocolVersion > 0 {
+					o := ErrorHandler{}
+					o.sendEvent(tunnel, &pb.EventReply{
+						EventType:  REGISTERED,
+						ClientType: in.ClientType,
+						ClientId:   in.ClientId,
+						Body:       o.ToJson(RegisteredBody{ProtocolVersion: caps.ProtocolVersion}),
+					})
+					if o.Err != nil {
+						hub.Error(o.Err, "c[%s] send registered failed", in.ClientId)
+					}
+				}
+
 				hub.SetBot(in.ClientId, newbot)
+				hub.claimBot(newbot)
 				hub.Info("c[%s] registered [%s]", in.ClientType, in.ClientId)
 
 				if newbot.canReLogin() {
@@ -574,6 +741,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 					if err = bot.pingloop(); err != nil {
 						hub.Error(err, "c[%s]%s disconnected", bot.ClientType, bot.ClientId)
 						hub.DropBot(bot.ClientId)
+						hub.failoverDropped(bot)
 					}
 				}(newbot)
 			}
@@ -584,12 +752,14 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				continue
 			}
 
+			bot.stats.recordEvent(EventIn, in.EventType, in.Body)
+
 			o := ErrorHandler{}
 			var thebot *ChatBot
 
 			switch eventType := in.EventType; eventType {
 			case LOGINDONE:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					body := o.FromJson(in.Body)
 					var botId string
 					var userName string
@@ -656,6 +826,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 							bot.logout()
 							continue
 						}
+						hub.rememberAffinity(userName, bot.ClientId)
 
 						var resp *httpx.RestfulResponse
 						resp, o.Err = httpx.RestfulCallRetry(hub.restfulclient,
@@ -776,8 +947,12 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 					}
 				}
 
+				if o.Err == nil {
+					hub.notifyBotStatus(thebot, LOGINDONE, "")
+				}
+
 			case LOGINSCAN:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					body := o.FromJson(in.Body)
 					var scanUrl string
 					if body != nil {
@@ -832,10 +1007,13 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 			case LOGINFAILED:
 				hub.Info("LOGINFAILED %v", in)
 				thebot, o.Err = bot.loginFail(in.Body)
+				bot.loginFailedAt = time.Now()
+				hub.notifyBotStatus(bot, LOGINFAILED, in.Body)
 
 			case LOGOUTDONE:
 				hub.Info("LOGOUTDONE c[%s]", in)
 				thebot, o.Err = bot.logoutDone(in.Body)
+				hub.notifyBotStatus(thebot, LOGOUTDONE, in.Body)
 				// think abount closing the connection
 				// grpc connection can't close from server side, but it's ok
 				// client side will process.exit(0) receiving logout
@@ -856,7 +1034,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 					hub.Info("ACTIONREPLY %s", in.Body)
 				}
 
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					body := o.FromJson(in.Body)
 					var actionBody map[string]interface{}
 					var result map[string]interface{}
@@ -874,8 +1052,16 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 
 						actionRequestId = o.FromMapString("actionRequestId", actionBody, "actionBody", false, "")
 					}
+					hub.replyAction(actionRequestId)
+					bot.stats.actionReplied(actionRequestId)
 
 					actionType := actionBody["actionType"]
+
+					spanCtx, span := tracing.Start(
+						tracing.ExtractFrom(context.Background(), actionBody["trace"]), "hub.ACTIONREPLY",
+						tracing.ActionRequestId.String(actionRequestId),
+						tracing.ClientId.String(bot.ClientId))
+
 					if actionType == SendTextMessage || actionType == SendAppMessage || actionType == SendImageMessage || actionType == SendImageResourceMessage {
 						hub.onSendMessage(bot, actionType.(string), actionBody, result)
 					}
@@ -908,20 +1094,23 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 									Result:          resultstring,
 									ReplyAt:         utils.JSONTime{Time: time.Now()},
 								}),
+								Trace: tracing.Inject(spanCtx),
 							}))
 						}
 					}
+
+					tracing.End(span, o.Err)
 				} else {
 					hub.Info("c[%s] not support event %s", bot.ClientId, in.EventType)
 				}
 
 			case MESSAGE, IMAGEMESSAGE, EMOJIMESSAGE:
-				hub.Info("[FILTER DEBUG] onReceiveMessage")
+				hub.Debug("[FILTER] onReceiveMessage")
 
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO || bot.ClientType == QQBOT {
+				if bot.speaksWechat() || bot.ClientType == QQBOT {
 					o.Err = hub.onReceiveMessage(bot, in)
 					if o.Err != nil {
-						hub.Info("[FILTER DEBUG] onReceiveMessage failed %v ", o.Err)
+						hub.Debug("[FILTER] onReceiveMessage failed %v ", o.Err)
 					}
 				} else {
 					o.Err = fmt.Errorf("unhandled client type %s", bot.ClientType)
@@ -939,7 +1128,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				}
 
 			case USERACCEPTED:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					hub.Info("USERACCEPTED\n%s\n", in.Body)
 
 					o.Err = hub.rabbitmq.Send(utils.CH_BotNotify, o.ToJson(models.MqEvent{
@@ -950,7 +1139,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				}
 
 			case STATUSMESSAGE:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					hub.Info("status message\n%s\n", in.Body)
 
 					bodyString := in.Body
@@ -973,7 +1162,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				}
 
 			case CONTACTINFO:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					hub.Info("contact info \n%s\n", in.Body)
 
 					if o.Err == nil {
@@ -986,7 +1175,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				}
 
 			case GROUPINFO:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					hub.Info("group info \n%s\n", in.Body)
 
 					if o.Err == nil {
@@ -999,7 +1188,7 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 				}
 
 			case WEBSHORTCALL:
-				if bot.ClientType == WECHATBOT || bot.ClientType == WECHATMACPRO {
+				if bot.speaksWechat() {
 					hub.Info("WEBSHORTCALL \n%s\n", in.Body)
 
 					if o.Err == nil {
@@ -1020,6 +1209,10 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 					if bot := hub.GetBot(in.ClientId); bot != nil {
 						hub.SetBot(in.ClientId, thebot)
 					}
+
+					if in.EventType == LOGINDONE {
+						hub.claimBot(thebot)
+					}
 				}
 			} else {
 				hub.Error(o.Err, "[%s] Error %s", in.EventType, o.Err.Error())
@@ -1031,6 +1224,20 @@ func (hub *ChatHub) EventTunnel(tunnel pb.ChatBotHub_EventTunnelServer) error {
 func (hub *ChatHub) Serve() {
 	hub.init()
 
+	restored := hub.restoreRegistry()
+	go hub.clusterloop()
+	go hub.registryloop(restored)
+	go hub.rebindFilters(restored)
+	go hub.failoverloop()
+	go hub.serveMetrics()
+
+	if hub.usage != nil {
+		go hub.meterloop()
+	}
+
+	hub.checker = hub.newChecker()
+	go hub.checker.Watch(context.Background())
+
 	hub.Info("chat hub starts....")
 	hub.Info("lisening to %s:%s", hub.Config.Host, hub.Config.Port)
 	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%s", hub.Config.Host, hub.Config.Port))
@@ -1038,12 +1245,27 @@ func (hub *ChatHub) Serve() {
 		hub.Error(err, "failed to listen")
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(tracing.ServerOption())
+	hub.grpcServer = s
 	pb.RegisterChatBotHubServer(s, hub)
+	pb.RegisterChatBotHubAdminServer(s, hub)
 	reflection.Register(s)
+
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	hub.checker.Serving(healthServer)
+
+	// the servers a drain stops are set before anything can drain
+	hub.wsServer = hub.websocketTunnelServer()
+	if hub.wsServer != nil {
+		go hub.serveWebsocketTunnel(hub.wsServer)
+	}
+	go hub.drainOnSignal()
+
 	if err := s.Serve(lis); err != nil {
 		hub.Error(err, "failed to serve")
 	}
 
+	hub.waitDrained()
 	hub.Info("chat hub ends.")
 }
@@ -0,0 +1,270 @@
+package chatbothub
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
+)
+
+const (
+	// set on rpcs forwarded between hub instances, so they are never forwarded twice
+	forwardedMetadataKey string = "x-chathub-forwarded"
+)
+
+func (hub *ChatHub) initCluster() {
+	hostname, _ := os.Hostname()
+
+	// stable across restarts, so the instance finds its registry snapshot again
+	instanceId := hub.Config.InstanceId
+	if instanceId == "" {
+		instanceId = fmt.Sprintf("%s-%s", hostname, hub.Config.Port)
+	}
+
+	addr := hub.Config.Advertise
+	if addr == "" {
+		addr = fmt.Sprintf("%s:%s", hostname, hub.Config.Port)
+	}
+
+	hub.instance = &domains.HubInstance{
+		InstanceId: instanceId,
+		Addr:       addr,
+		StartAt:    time.Now().Unix(),
+	}
+	hub.peers = make(map[string]*rpc.GRPCWrapper)
+}
+
+func (hub *ChatHub) clusterLease() int {
+	if hub.Config.Lease > 0 {
+		return hub.Config.Lease
+	}
+
+	return domains.HubDefaultLease
+}
+
+// clusterloop keeps the instance and the bots connected to it registered,
+// and drops local bots that were claimed by another instance.
+func (hub *ChatHub) clusterloop() {
+	lease := hub.clusterLease()
+	ticker := time.NewTicker(time.Duration(lease) * time.Second / 3)
+
+	for ; ; <-ticker.C {
+		if hub.redispool == nil {
+			continue
+		}
+
+		bots := []*ChatBot{}
+		hub.muxBots.Lock()
+		for _, bot := range hub.bots {
+			bots = append(bots, bot)
+		}
+		hub.instance.Bots = len(bots)
+		instance := *hub.instance
+		hub.muxBots.Unlock()
+
+		o := &ErrorHandler{}
+		conn := hub.redispool.Get()
+
+		o.RegisterHubInstance(conn, &instance, lease)
+
+		for _, bot := range bots {
+			if o.Err != nil {
+				break
+			}
+
+			if !o.RefreshBotOwnership(conn, hub.instance.InstanceId, lease,
+				bot.ClientId, bot.Login, bot.BotId) && o.Err == nil {
+				hub.Info("c[%s] b[%s] owned by another hub now, drop it", bot.ClientId, bot.Login)
+				bot.closePingloop()
+				hub.DropBot(bot.ClientId)
+			}
+		}
+
+		conn.Close()
+
+		if o.Err != nil {
+			hub.Error(o.Err, "refresh hub instance %s failed", hub.instance.InstanceId)
+		}
+	}
+}
+
+// claimBot takes over the bot from any other instance, called when its
+// client registers or logs in here.
+func (hub *ChatHub) claimBot(bot *ChatBot) {
+	if hub.redispool == nil || bot == nil {
+		return
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	defer conn.Close()
+
+	o.ClaimBotOwnership(conn, hub.instance.InstanceId, hub.clusterLease(),
+		bot.ClientId, bot.Login, bot.BotId)
+	if o.Err != nil {
+		hub.Error(o.Err, "claim c[%s] failed", bot.ClientId)
+	}
+}
+
+func (hub *ChatHub) releaseBot(bot *ChatBot) {
+	if hub.redispool == nil || bot == nil {
+		return
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	defer conn.Close()
+
+	o.ReleaseBotOwnership(conn, hub.instance.InstanceId, bot.ClientId, bot.Login, bot.BotId)
+	if o.Err != nil {
+		hub.Error(o.Err, "release c[%s] failed", bot.ClientId)
+	}
+}
+
+func forwardedContext(ctx context.Context, instanceId string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, forwardedMetadataKey, instanceId)
+}
+
+func isForwarded(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(forwardedMetadataKey)) > 0
+}
+
+func (hub *ChatHub) peerWrapper(instance *domains.HubInstance) (*rpc.GRPCWrapper, error) {
+	hub.muxPeers.Lock()
+	defer hub.muxPeers.Unlock()
+
+	wrapper, ok := hub.peers[instance.Addr]
+	if !ok {
+		wrapper = rpc.CreateGRPCWrapper(instance.Addr)
+		hub.peers[instance.Addr] = wrapper
+	}
+
+	peer, err := wrapper.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	peer.Context = forwardedContext(peer.Context, hub.instance.InstanceId)
+	return peer, nil
+}
+
+// ownerPeer returns a client of the instance owning the bot, when that is
+// not this instance. Callers should Cancel the wrapper when done.
+func (hub *ChatHub) ownerPeer(ctx context.Context, kind string, id string) *rpc.GRPCWrapper {
+	if hub.redispool == nil || isForwarded(ctx) {
+		return nil
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	defer conn.Close()
+
+	owner := o.GetBotOwner(conn, kind, id)
+	if o.Err != nil {
+		hub.Error(o.Err, "get owner of %s %s failed", kind, id)
+		return nil
+	}
+
+	if owner == nil || owner.InstanceId == hub.instance.InstanceId {
+		return nil
+	}
+
+	peer, err := hub.peerWrapper(owner)
+	if err != nil {
+		hub.Error(err, "connect hub %s %s failed", owner.InstanceId, owner.Addr)
+		return nil
+	}
+
+	return peer
+}
+
+// peerBots collects bots from every other live instance.
+func (hub *ChatHub) peerBots(ctx context.Context, req *pb.BotsRequest) []*pb.BotsInfo {
+	bots := []*pb.BotsInfo{}
+	if hub.redispool == nil || isForwarded(ctx) {
+		return bots
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	instances := o.GetHubInstances(conn)
+	conn.Close()
+
+	if o.Err != nil {
+		hub.Error(o.Err, "get hub instances failed")
+		return bots
+	}
+
+	for _, instance := range instances {
+		if instance.InstanceId == hub.instance.InstanceId {
+			continue
+		}
+
+		peer, err := hub.peerWrapper(instance)
+		if err != nil {
+			hub.Error(err, "connect hub %s %s failed", instance.InstanceId, instance.Addr)
+			continue
+		}
+
+		reply, err := peer.HubClient.GetBots(peer.Context, req)
+		peer.Cancel()
+		if err != nil {
+			hub.Error(err, "get bots from hub %s failed", instance.InstanceId)
+			continue
+		}
+
+		bots = append(bots, reply.BotsInfo...)
+	}
+
+	return bots
+}
+
+// peerBotLogin asks the other instances, one by one, to log in with one of
+// their available clients, when this instance has none left.
+func (hub *ChatHub) peerBotLogin(ctx context.Context, req *pb.BotLoginRequest) *pb.BotLoginReply {
+	if hub.redispool == nil || isForwarded(ctx) {
+		return nil
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	instances := o.GetHubInstances(conn)
+	conn.Close()
+
+	if o.Err != nil {
+		hub.Error(o.Err, "get hub instances failed")
+		return nil
+	}
+
+	for _, instance := range instances {
+		if instance.InstanceId == hub.instance.InstanceId {
+			continue
+		}
+
+		peer, err := hub.peerWrapper(instance)
+		if err != nil {
+			hub.Error(err, "connect hub %s %s failed", instance.InstanceId, instance.Addr)
+			continue
+		}
+
+		reply, err := peer.HubClient.BotLogin(peer.Context, req)
+		peer.Cancel()
+		if err != nil {
+			hub.Error(err, "login on hub %s failed", instance.InstanceId)
+			continue
+		}
+
+		if reply.ClientError == nil || reply.ClientError.Code == 0 {
+			return reply
+		}
+	}
+
+	return nil
+}
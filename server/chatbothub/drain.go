@@ -0,0 +1,347 @@
+package chatbothub
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+)
+
+const (
+	DrainServing      string = "SERVING"
+	DrainReconnecting string = "RECONNECTING"
+	DrainWaiting      string = "WAITING"
+	DrainFlushing     string = "FLUSHING"
+	DrainStopped      string = "STOPPED"
+
+	defaultDrainTimeout int = 30
+
+	// actions never replied are forgotten after this long
+	inflightActionTTL time.Duration = 10 * time.Minute
+)
+
+type drainState struct {
+	mux      sync.Mutex
+	draining bool
+	phase    string
+	target   string
+	startAt  int64
+	deadline int64
+	// closed when the drain is done, flushes included
+	done chan struct{}
+}
+
+type ReconnectBody struct {
+	Target string `json:"target"`
+}
+
+func (hub *ChatHub) isDraining() bool {
+	hub.drain.mux.Lock()
+	defer hub.drain.mux.Unlock()
+
+	return hub.drain.draining
+}
+
+func (hub *ChatHub) setDrainPhase(phase string) {
+	hub.drain.mux.Lock()
+	defer hub.drain.mux.Unlock()
+
+	hub.drain.phase = phase
+	hub.Info("[DRAIN] %s", phase)
+}
+
+// trackAction remembers an action sent to a client until its ACTIONREPLY
+// comes back, so that draining can wait for it.
+func (hub *ChatHub) trackAction(actionRequestId string) {
+	if actionRequestId == "" {
+		return
+	}
+
+	hub.muxInflight.Lock()
+	defer hub.muxInflight.Unlock()
+
+	now := time.Now()
+	if len(hub.inflight) >= 1024 {
+		for arId, sentAt := range hub.inflight {
+			if sentAt.Add(inflightActionTTL).Before(now) {
+				delete(hub.inflight, arId)
+			}
+		}
+	}
+
+	hub.inflight[actionRequestId] = now
+}
+
+func (hub *ChatHub) replyAction(actionRequestId string) {
+	hub.muxInflight.Lock()
+	defer hub.muxInflight.Unlock()
+
+	delete(hub.inflight, actionRequestId)
+}
+
+func (hub *ChatHub) inflightActions() int {
+	hub.muxInflight.Lock()
+	defer hub.muxInflight.Unlock()
+
+	count := 0
+	now := time.Now()
+	for _, sentAt := range hub.inflight {
+		if sentAt.Add(inflightActionTTL).After(now) {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+func (hub *ChatHub) drainTimeout() time.Duration {
+	timeout := hub.Config.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	return time.Duration(timeout) * time.Second
+}
+
+// drainTarget picks where the clients should reconnect: the requested
+// address, the configured one, or another live instance of the cluster.
+// Empty means the hub service address the clients already know.
+func (hub *ChatHub) drainTarget(target string) string {
+	if target != "" {
+		return target
+	}
+
+	if hub.Config.DrainTarget != "" {
+		return hub.Config.DrainTarget
+	}
+
+	if hub.redispool == nil {
+		return ""
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	defer conn.Close()
+
+	for _, instance := range o.GetHubInstances(conn) {
+		if instance.InstanceId != hub.instance.InstanceId {
+			return instance.Addr
+		}
+	}
+
+	if o.Err != nil {
+		hub.Error(o.Err, "[DRAIN] get hub instances failed")
+	}
+
+	return ""
+}
+
+// beginDrain stops accepting actions from now on. It returns false when
+// the hub is draining already.
+func (hub *ChatHub) beginDrain(timeout time.Duration) bool {
+	hub.drain.mux.Lock()
+	defer hub.drain.mux.Unlock()
+
+	if hub.drain.draining {
+		return false
+	}
+
+	if timeout <= 0 {
+		timeout = hub.drainTimeout()
+	}
+
+	now := time.Now()
+	hub.drain.draining = true
+	hub.drain.phase = DrainReconnecting
+	hub.drain.startAt = now.Unix()
+	hub.drain.deadline = now.Add(timeout).Unix()
+	hub.drain.done = make(chan struct{})
+	return true
+}
+
+// waitDrained blocks until a drain begun is done, the grpc server returns
+// from Serve as soon as it is stopped, before the last flushes.
+func (hub *ChatHub) waitDrained() {
+	hub.drain.mux.Lock()
+	done := hub.drain.done
+	hub.drain.mux.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// Drain stops accepting actions, asks every client to reconnect to target,
+// waits for in-flight action replies until timeout, flushes rabbitmq and
+// stops the grpc server.
+func (hub *ChatHub) Drain(target string, timeout time.Duration) {
+	if hub.beginDrain(timeout) {
+		hub.runDrain(target)
+	}
+}
+
+// runDrain does the rest of the drain once begun. Clients are expected to
+// finish their current actions before they leave, so their tunnels are kept
+// open meanwhile.
+func (hub *ChatHub) runDrain(target string) {
+	hub.drain.mux.Lock()
+	deadline := time.Unix(hub.drain.deadline, 0)
+	hub.drain.mux.Unlock()
+
+	target = hub.drainTarget(target)
+	hub.drain.mux.Lock()
+	hub.drain.target = target
+	hub.drain.mux.Unlock()
+
+	hub.Info("[DRAIN] %s to %s", DrainReconnecting, target)
+	bots := []*ChatBot{}
+	hub.muxBots.Lock()
+	for _, bot := range hub.bots {
+		bots = append(bots, bot)
+	}
+	hub.muxBots.Unlock()
+
+	for _, bot := range bots {
+		if bot.tunnel == nil || !bot.supportsEvent(RECONNECT) {
+			continue
+		}
+
+		o := &ErrorHandler{}
+		o.sendEvent(bot.tunnel, &pb.EventReply{
+			EventType:  RECONNECT,
+			ClientType: bot.ClientType,
+			ClientId:   bot.ClientId,
+			Body:       o.ToJson(ReconnectBody{Target: target}),
+		})
+		if o.Err != nil {
+			hub.Error(o.Err, "[DRAIN] c[%s] send reconnect failed", bot.ClientId)
+		}
+	}
+
+	hub.setDrainPhase(DrainWaiting)
+	for hub.inflightActions() > 0 && time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if inflight := hub.inflightActions(); inflight > 0 {
+		hub.Info("[DRAIN] %d actions not replied before deadline", inflight)
+	}
+
+	hub.setDrainPhase(DrainFlushing)
+	if hub.rabbitmq != nil {
+		flushTimeout := deadline.Sub(time.Now())
+		if flushTimeout < 5*time.Second {
+			flushTimeout = 5 * time.Second
+		}
+
+		if err := hub.rabbitmq.Flush(flushTimeout); err != nil {
+			hub.Error(err, "[DRAIN] flush rabbitmq failed")
+		}
+	}
+
+	hub.saveRegistry()
+	if hub.redispool != nil {
+		o := &ErrorHandler{}
+		conn := hub.redispool.Get()
+		o.UnregisterHubInstance(conn, hub.instance.InstanceId)
+		conn.Close()
+
+		if o.Err != nil {
+			hub.Error(o.Err, "[DRAIN] unregister hub instance failed")
+		}
+	}
+
+	hub.setDrainPhase(DrainStopped)
+	if hub.wsServer != nil {
+		if err := hub.wsServer.Close(); err != nil {
+			hub.Error(err, "[DRAIN] close websocket tunnel failed")
+		}
+	}
+
+	if hub.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			hub.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			// tunnels of clients that did not leave
+			hub.grpcServer.Stop()
+		}
+	}
+
+	// the events of the last tunnels, and what they used
+	if hub.journal != nil {
+		hub.journal.Flush(5 * time.Second)
+	}
+	if hub.usage != nil {
+		hub.flushUsage()
+	}
+
+	hub.drain.mux.Lock()
+	close(hub.drain.done)
+	hub.drain.mux.Unlock()
+}
+
+func (hub *ChatHub) drainOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+
+	sig := <-sigs
+	hub.Info("[DRAIN] received %s", sig)
+	hub.Drain("", 0)
+}
+
+func (hub *ChatHub) drainStatus() *pb.DrainStatusReply {
+	hub.drain.mux.Lock()
+	reply := &pb.DrainStatusReply{
+		Draining: hub.drain.draining,
+		Phase:    hub.drain.phase,
+		Target:   hub.drain.target,
+		StartAt:  hub.drain.startAt,
+		Deadline: hub.drain.deadline,
+	}
+	hub.drain.mux.Unlock()
+
+	if !reply.Draining {
+		reply.Phase = DrainServing
+	}
+
+	hub.muxBots.Lock()
+	reply.Bots = int32(len(hub.bots))
+	hub.muxBots.Unlock()
+
+	reply.InflightActions = int32(hub.inflightActions())
+	if hub.rabbitmq != nil {
+		reply.PendingMqSends = int32(hub.rabbitmq.Pending())
+	}
+
+	return reply
+}
+
+func (hub *ChatHub) DrainHub(ctx context.Context, req *pb.DrainHubRequest) (*pb.DrainStatusReply, error) {
+	hub.Info("[DRAIN] requested, target %s timeout %d", req.Target, req.Timeout)
+
+	if hub.beginDrain(time.Duration(req.Timeout) * time.Second) {
+		go hub.runDrain(req.Target)
+	}
+
+	return hub.drainStatus(), nil
+}
+
+func (hub *ChatHub) GetDrainStatus(ctx context.Context, req *pb.DrainStatusRequest) (*pb.DrainStatusReply, error) {
+	return hub.drainStatus(), nil
+}
+
+func (hub *ChatHub) drainingError() error {
+	return fmt.Errorf("hub %s is draining", hub.instance.InstanceId)
+}
@@ -0,0 +1,185 @@
+package chatbothub
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/models"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	// seconds a disconnected client has to come back before its bot is
+	// moved to another client
+	defaultFailoverGrace int = 60
+)
+
+type BotMigrateBody struct {
+	BotId        string `json:"botId"`
+	Login        string `json:"login"`
+	FromClientId string `json:"fromClientId"`
+	ToClientId   string `json:"toClientId"`
+}
+
+// canFailover tells whether the bot holds what another client needs to log
+// it in again, the same as a relogin on REGISTER.
+func (bot *ChatBot) canFailover() bool {
+	if len(bot.BotId) == 0 || len(bot.Login) == 0 {
+		return false
+	}
+
+	if bot.ClientType == WECHATMACPRO {
+		return true
+	}
+
+	return len(bot.LoginInfo.WxData) > 0 && len(bot.LoginInfo.Token) > 0
+}
+
+// failoverGrace is negative when failover is off.
+func (hub *ChatHub) failoverGrace() time.Duration {
+	grace := hub.Config.FailoverGrace
+	if grace == 0 {
+		grace = defaultFailoverGrace
+	}
+
+	return time.Duration(grace) * time.Second
+}
+
+// failoverloop moves the bots of clients disconnected for longer than the
+// grace period to available clients of the same type.
+func (hub *ChatHub) failoverloop() {
+	grace := hub.failoverGrace()
+	if grace < 0 {
+		hub.Info("[FAILOVER] off")
+		return
+	}
+
+	for range time.Tick(5 * time.Second) {
+		if hub.isDraining() {
+			continue
+		}
+
+		now := time.Now().UnixNano() / 1e6
+		candidates := []*ChatBot{}
+		hub.muxBots.Lock()
+		for _, bot := range hub.bots {
+			if bot.Status == FailingDisconnected && bot.canFailover() &&
+				now-bot.LastPing > int64(grace/time.Millisecond) {
+				candidates = append(candidates, bot)
+			}
+		}
+		hub.muxBots.Unlock()
+
+		for _, bot := range candidates {
+			if err := hub.failover(bot); err != nil {
+				hub.Error(err, "[FAILOVER] c[%s] b[%s] failed, retry later", bot.ClientId, bot.Login)
+			}
+		}
+	}
+}
+
+// failoverDropped is called when the pingloop of a client gives up.
+func (hub *ChatHub) failoverDropped(bot *ChatBot) {
+	if hub.failoverGrace() < 0 || hub.isDraining() || !bot.canFailover() {
+		return
+	}
+
+	if err := hub.failover(bot); err != nil {
+		hub.Error(err, "[FAILOVER] c[%s] b[%s] lost", bot.ClientId, bot.Login)
+	}
+}
+
+// failover logs the bot of a disconnected client in on an available client
+// with the saved login info, then tells web the bot has migrated.
+func (hub *ChatHub) failover(bot *ChatBot) error {
+	hub.muxFailover.Lock()
+	defer hub.muxFailover.Unlock()
+
+	// the client may have come back, or the bot been taken over, meanwhile
+	if bot.Status != FailingDisconnected {
+		return nil
+	}
+	if thebot := hub.GetBot(bot.ClientId); thebot != nil && thebot != bot {
+		return nil
+	}
+	if thebot := hub.GetBotById(bot.BotId); thebot != nil && thebot != bot {
+		return nil
+	}
+
+	load, _ := bot.load.get()
+
+	// the target is prepared before muxBots is released, so that a login
+	// selecting meanwhile cannot take it too
+	hub.muxBots.Lock()
+	target := hub.selectAvailableBot(SelectRequest{
+		ClientType: bot.ClientType,
+		Login:      bot.Login,
+		Region:     load.Region,
+	})
+	if target == nil {
+		hub.muxBots.Unlock()
+		return utils.NewClientError(utils.RESOURCE_INSUFFICIENT,
+			fmt.Errorf("no available client of type %s", bot.ClientType))
+	}
+
+	o := &ErrorHandler{}
+
+	// the target is restored when it cannot take the bot over, and the
+	// source is kept for the next try
+	loginInfo, filterId, momentFilterId := target.LoginInfo, target.filterId, target.momentFilterId
+	botId, login, scanUrl, status := target.BotId, target.Login, target.ScanUrl, target.Status
+
+	target.LoginInfo = bot.LoginInfo
+	target.filterId = bot.filterId
+	target.momentFilterId = bot.momentFilterId
+	_, o.Err = target.prepareLogin(bot.BotId, bot.Login)
+	hub.muxBots.Unlock()
+
+	hub.Info("[FAILOVER] b[%s] %s c[%s] -> c[%s]", bot.BotId, bot.Login, bot.ClientId, target.ClientId)
+
+	o.sendEvent(target.tunnel, &pb.EventReply{
+		EventType:  LOGIN,
+		ClientType: target.ClientType,
+		ClientId:   target.ClientId,
+		Body: o.ToJson(
+			LoginBody{
+				BotId:     target.BotId,
+				Login:     target.Login,
+				LoginInfo: o.ToJson(target.LoginInfo),
+			}),
+	})
+	if o.Err != nil {
+		hub.muxBots.Lock()
+		target.LoginInfo, target.filterId, target.momentFilterId = loginInfo, filterId, momentFilterId
+		target.BotId, target.Login, target.ScanUrl, target.Status = botId, login, scanUrl, status
+		hub.muxBots.Unlock()
+		return o.Err
+	}
+
+	if hub.GetBot(bot.ClientId) == bot {
+		bot.closePingloop()
+		hub.DropBot(bot.ClientId)
+	}
+
+	hub.claimBot(target)
+	go hub.rebindBotFilters(target)
+
+	if hub.rabbitmq != nil {
+		o.Err = hub.rabbitmq.Send(utils.CH_BotNotify, o.ToJson(models.MqEvent{
+			BotId:     target.BotId,
+			EventType: BOTMIGRATE,
+			Body: o.ToJson(BotMigrateBody{
+				BotId:        target.BotId,
+				Login:        target.Login,
+				FromClientId: bot.ClientId,
+				ToClientId:   target.ClientId,
+			}),
+		}))
+		if o.Err != nil {
+			hub.Error(o.Err, "[FAILOVER] b[%s] notify web failed", target.BotId)
+		}
+	}
+
+	return nil
+}
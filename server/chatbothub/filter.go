@@ -3,25 +3,36 @@ package chatbothub
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/fluent/fluent-logger-golang/fluent"
 
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 )
 
+var filterLogger = logging.New("filter")
+
 type Filter interface {
 	Fill(string) error
 	Next(Filter) error
 }
 
+// fillChain fills a bot's filter chain from its root, timing the chain as a
+// whole rather than each filter chained.
+func fillChain(source string, filter Filter, msg string) error {
+	defer metrics.Since(metrics.FilterFillSeconds.WithLabelValues(source), time.Now())
+	return filter.Fill(msg)
+}
+
 type BranchTag struct {
 	Key   string
 	Value string
@@ -131,12 +142,15 @@ func (f *WechatMomentFilter) Fill(msg string) error {
 
 type PlainFilter struct {
 	BaseFilter
-	logger     *log.Logger
+	logger     *logging.Logger
 	NextFilter Filter `json:"next"`
 }
 
-func NewPlainFilter(filterId string, filterName string, logger *log.Logger) *PlainFilter {
-	return &PlainFilter{BaseFilter: NewBaseFilter(filterId, filterName, "过滤:空"), logger: logger}
+func NewPlainFilter(filterId string, filterName string, logger *logging.Logger) *PlainFilter {
+	return &PlainFilter{
+		BaseFilter: NewBaseFilter(filterId, filterName, "过滤:空"),
+		logger:     logger.With("filterId", filterId),
+	}
 }
 
 func (f *PlainFilter) String() string {
@@ -182,10 +196,10 @@ func (f *PlainFilter) Fill(msg string) error {
 			}
 
 			if len(groupId) > 0 {
-				f.logger.Printf("%s[%s](%d) [%s] %s->%s (%d) %s",
+				f.logger.Info("%s[%s](%d) [%s] %s->%s (%d) %s",
 					f.Name, f.Type, mtype, groupId, fromUser, toUser, status, brief)
 			} else {
-				f.logger.Printf("%s[%s](%d) %s->%s (%d) %s",
+				f.logger.Info("%s[%s](%d) %s->%s (%d) %s",
 					f.Name, f.Type, mtype, fromUser, toUser, status, brief)
 			}
 
@@ -193,19 +207,19 @@ func (f *PlainFilter) Fill(msg string) error {
 			var msg WechatMsg
 			o.Err = json.Unmarshal([]byte(o.ToJson(content["msg"])), &msg)
 			if len(msg.AppMsg.Title) > 0 {
-				f.logger.Printf("%s[%s](%d) %s->%s (%d) appmsg: <%s>%s",
+				f.logger.Info("%s[%s](%d) %s->%s (%d) appmsg: <%s>%s",
 					f.Name, f.Type, mtype, fromUser, toUser, status, msg.AppMsg.SourceDisplayName, msg.AppMsg.Title)
 			} else if len(msg.Emoji.Attributions.FromUserName) > 0 {
-				f.logger.Printf("%s[%s](%d) %s->%s (%d) emoji: <%s>%s",
+				f.logger.Info("%s[%s](%d) %s->%s (%d) emoji: <%s>%s",
 					f.Name, f.Type, mtype, fromUser, toUser, status, msg.Emoji.Attributions.Type, msg.Emoji.Attributions.ProductId)
 			}
 
 		default:
-			f.logger.Printf("%s[%s](%d) %s->%s (%d) %T %v",
+			f.logger.Info("%s[%s](%d) %s->%s (%d) %T %v",
 				f.Name, f.Type, mtype, fromUser, toUser, status, content, content)
 		}
 	} else {
-		f.logger.Printf("%s[%s] %s ...", f.Name, f.Type, brief)
+		f.logger.Info("%s[%s] %s ...", f.Name, f.Type, brief)
 	}
 
 	if f.NextFilter != nil && o.Err == nil {
@@ -320,13 +334,13 @@ func (f *RegexRouter) Fill(msg string) error {
 		return nil
 	}
 
-	fmt.Printf("[FILTER DEBUG] matching %s\n", msg)
+	filterLogger.Debug("[FILTER] matching %s", msg)
 
 	for k, v := range f.NextFilter {
 		if cr, found := f.compiledRegexp[k]; found {
 			if cr.MatchString(msg) {
 				if v != nil {
-					fmt.Printf("[FILTER DEBUG][%s][%s] filled\n", f.Name, k)
+					filterLogger.Debug("[FILTER][%s][%s] filled", f.Name, k)
 					return v.Fill(msg)
 				}
 			}
@@ -334,7 +348,7 @@ func (f *RegexRouter) Fill(msg string) error {
 	}
 
 	if f.DefaultNextFilter != nil {
-		fmt.Printf("[FILTER DEBUG][%s][default] filled\n", f.Name)
+		filterLogger.Debug("[FILTER][%s][default] filled", f.Name)
 		return f.DefaultNextFilter.Fill(msg)
 	}
 
@@ -434,7 +448,7 @@ func (f *KVRouter) Fill(msg string) error {
 	}
 
 	if f.NextFilter == nil && f.DefaultNextFilter != nil {
-		fmt.Printf("[FILTER DEBUG][%s][default] filled\n", f.Name)
+		filterLogger.Debug("[FILTER][%s][default] filled", f.Name)
 		return f.DefaultNextFilter.Fill(msg)
 	}
 
@@ -459,7 +473,7 @@ func (f *KVRouter) Fill(msg string) error {
 						if err := nextfilter.Fill(msg); err != nil {
 							errlist = append(errlist, err)
 						} else {
-							fmt.Printf("[FILTER DEBUG][%s][%s][%s] filled\n", f.Name, k, regstr)
+							filterLogger.Debug("[FILTER][%s][%s][%s] filled", f.Name, k, regstr)
 						}
 					}
 				}
@@ -469,10 +483,10 @@ func (f *KVRouter) Fill(msg string) error {
 
 	if !fillOnce {
 		if f.DefaultNextFilter != nil {
-			fmt.Printf("[FILTER DEBUG][%s][default] filled\n", f.Name)
+			filterLogger.Debug("[FILTER][%s][default] filled", f.Name)
 			return f.DefaultNextFilter.Fill(msg)
 		} else {
-			fmt.Printf("[FILTER DEBUG][%s][default] is null\n", f.Name)
+			filterLogger.Debug("[FILTER][%s][default] is null", f.Name)
 			return nil
 		}
 	}
@@ -519,7 +533,8 @@ func (f *WebTrigger) Fill(msg string) error {
 		var u *url.URL
 		u, err = url.Parse(f.Action.Url)
 		if err != nil {
-			fmt.Printf("[WebTrigger] failed parse url %s\n%s\n", f.Action.Url, err)
+			metrics.WebTriggers.WithLabelValues("invalid_url").Inc()
+			filterLogger.Error(err, "[WebTrigger] failed parse url %s", f.Action.Url)
 			return
 		}
 		domain := strings.Split(u.Host, ":")[0]
@@ -536,16 +551,23 @@ func (f *WebTrigger) Fill(msg string) error {
 		rr.Params["msg"] = msg
 		rr.CookieJar = jar
 
-		if resp, err := httpx.RestfulCallRetry(f.restfulclient, rr, 5, 1); err != nil {
-			fmt.Printf("[WebTrigger] failed %s\n%v\n", err, resp)
+		start := time.Now()
+		resp, err := httpx.RestfulCallRetry(f.restfulclient, rr, 5, 1)
+		metrics.Since(metrics.WebTriggerSeconds, start)
+
+		if err != nil {
+			metrics.WebTriggers.WithLabelValues("failed").Inc()
+			filterLogger.Error(err, "[WebTrigger] failed %v", resp)
 		} else {
+			metrics.WebTriggers.WithLabelValues("success").Inc()
+
 			//save cookies
 			o.SaveWebTriggerCookies(chathub.redispool, header, domain, resp.Cookies)
 			if o.Err != nil {
-				fmt.Printf("[WebTrigger] save cookie failed %s\n", o.Err)
+				filterLogger.Error(o.Err, "[WebTrigger] save cookie failed")
 			}
 
-			fmt.Printf("[WebTrigger DEBUG] trigger %s returned\n%s\n", f.Action.Url, o.ToJson(resp))
+			filterLogger.Debug("[WebTrigger] trigger %s returned %s", f.Action.Url, o.ToJson(resp))
 		}
 	}()
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"golang.org/x/net/context"
 )
@@ -35,7 +36,7 @@ func (hub *ChatHub) CreateFilterByType(
 	case WECHATMOMENTFILTER:
 		filter = NewWechatMomentFilter(filterId, filterName)
 	case PLAINFILTER:
-		filter = NewPlainFilter(filterId, filterName, hub.logger)
+		filter = NewPlainFilter(filterId, filterName, filterLogger)
 	case FLUENTFILTER:
 		if tag, ok := hub.Config.Fluent.Tags["msg"]; ok {
 			filter = NewFluentFilter(filterId, filterName, hub.fluentLogger, tag)
@@ -97,6 +98,11 @@ func (hub *ChatHub) FilterCreate(
 		}
 	}
 
+	filter = hub.metered(req.FilterId, req.FilterType, filter)
+	if hub.sandbox != nil {
+		filter = hub.sandbox.wrap(req, filter)
+	}
+
 	hub.SetFilter(req.FilterId, filter)
 	return &pb.OperationReply{Code: 0, Message: "success"}, nil
 }
@@ -106,6 +112,11 @@ func (hub *ChatHub) FilterFill(
 
 	bot := hub.GetBotById(req.BotId)
 	if bot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.FilterFill(peer.Context, req)
+		}
+
 		return nil, fmt.Errorf("b[%s] not found", req.BotId)
 	}
 
@@ -113,11 +124,11 @@ func (hub *ChatHub) FilterFill(
 
 	if req.Source == "MSG" {
 		if bot.filter != nil {
-			err = bot.filter.Fill(req.Body)
+			err = fillChain(req.Source, bot.filter, req.Body)
 		}
 	} else if req.Source == "MOMENT" {
 		if bot.momentFilter != nil {
-			err = bot.momentFilter.Fill(req.Body)
+			err = fillChain(req.Source, bot.momentFilter, req.Body)
 		}
 	} else {
 		return nil, fmt.Errorf("not support filter source %s", req.Source)
@@ -193,6 +204,11 @@ func (hub *ChatHub) BotFilter(
 
 	thebot := hub.GetBotById(req.BotId)
 	if thebot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.BotFilter(peer.Context, req)
+		}
+
 		return &pb.OperationReply{
 			Code:    int32(utils.RESOURCE_NOT_FOUND),
 			Message: fmt.Sprintf("bot %s not found", req.BotId),
@@ -208,6 +224,7 @@ func (hub *ChatHub) BotFilter(
 	}
 
 	thebot.filter = thefilter
+	thebot.filterId = req.FilterId
 
 	hub.SetBot(thebot.ClientId, thebot)
 	return &pb.OperationReply{Code: 0, Message: "success"}, nil
@@ -218,6 +235,11 @@ func (hub *ChatHub) BotMomentFilter(
 
 	thebot := hub.GetBotById(req.BotId)
 	if thebot == nil {
+		if peer := hub.ownerPeer(ctx, domains.BotOwnerByBot, req.BotId); peer != nil {
+			defer peer.Cancel()
+			return peer.HubClient.BotMomentFilter(peer.Context, req)
+		}
+
 		return &pb.OperationReply{
 			Code:    int32(utils.RESOURCE_NOT_FOUND),
 			Message: fmt.Sprintf("bot %s not found", req.BotId),
@@ -233,6 +255,7 @@ func (hub *ChatHub) BotMomentFilter(
 	}
 
 	thebot.momentFilter = thefilter
+	thebot.momentFilterId = req.FilterId
 
 	hub.SetBot(thebot.ClientId, thebot)
 	return &pb.OperationReply{Code: 0, Message: "success"}, nil
@@ -0,0 +1,28 @@
+package chatbothub
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
+)
+
+// newChecker checks what the hub needs to take clients and actions. A
+// draining hub is not ready either, so that no new client is sent to it.
+func (hub *ChatHub) newChecker() *health.Checker {
+	return health.NewChecker(
+		health.Mongo("mongo", hub.mongoDb),
+		health.Redis("redis", hub.redispool),
+		health.Check{Name: "rabbitmq", Check: func(context.Context) error {
+			return hub.rabbitmq.Ping()
+		}},
+		health.TCP("fluent", fmt.Sprintf("%s:%d", hub.Config.Fluent.Host, hub.Config.Fluent.Port)),
+		health.Check{Name: "drain", Check: func(context.Context) error {
+			if hub.isDraining() {
+				return fmt.Errorf("hub %s is draining", hub.instance.InstanceId)
+			}
+			return nil
+		}},
+	)
+}
@@ -0,0 +1,175 @@
+package chatbothub
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+const (
+	EventIn  string = "IN"
+	EventOut string = "OUT"
+
+	maxPingSamples   int = 60
+	maxSendErrors    int = 20
+	maxRecentEvents  int = 50
+	maxEventBodySize int = 240
+)
+
+// clientStats keeps what operators inspect of a client: ping latencies,
+// tunnel send errors, actions waiting for their reply and recent events.
+type clientStats struct {
+	mux sync.Mutex
+
+	pingSentAt int64
+	pings      []*pb.PingSample
+	sendErrors []*pb.TunnelSendError
+	events     []*pb.ClientEvent
+	inflight   map[string]*pb.InflightAction
+}
+
+func newClientStats() *clientStats {
+	return &clientStats{
+		inflight: make(map[string]*pb.InflightAction),
+	}
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / 1e6
+}
+
+func (s *clientStats) pingSent() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.pingSentAt = nowMillis()
+}
+
+func (s *clientStats) pongReceived() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.pingSentAt == 0 {
+		return
+	}
+
+	now := nowMillis()
+	s.pings = append(s.pings, &pb.PingSample{At: now, Latency: now - s.pingSentAt})
+	if len(s.pings) > maxPingSamples {
+		s.pings = s.pings[len(s.pings)-maxPingSamples:]
+	}
+	s.pingSentAt = 0
+}
+
+func (s *clientStats) sendFailed(eventType string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.sendErrors = append(s.sendErrors, &pb.TunnelSendError{
+		At:        nowMillis(),
+		EventType: eventType,
+		Error:     err.Error(),
+	})
+	if len(s.sendErrors) > maxSendErrors {
+		s.sendErrors = s.sendErrors[len(s.sendErrors)-maxSendErrors:]
+	}
+}
+
+func (s *clientStats) recordEvent(direction string, eventType string, body string) {
+	if eventType == PING || eventType == PONG {
+		return
+	}
+
+	if len(body) > maxEventBodySize {
+		body = body[:maxEventBodySize]
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.events = append(s.events, &pb.ClientEvent{
+		At:        nowMillis(),
+		Direction: direction,
+		EventType: eventType,
+		Body:      body,
+	})
+	if len(s.events) > maxRecentEvents {
+		s.events = s.events[len(s.events)-maxRecentEvents:]
+	}
+}
+
+func (s *clientStats) actionSent(body string) {
+	action := struct {
+		ActionRequestId string `json:"actionRequestId"`
+		ActionType      string `json:"actionType"`
+	}{}
+	if err := json.Unmarshal([]byte(body), &action); err != nil || action.ActionRequestId == "" {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := nowMillis()
+	ttl := int64(inflightActionTTL / time.Millisecond)
+	for arId, a := range s.inflight {
+		if a.SentAt+ttl < now {
+			delete(s.inflight, arId)
+		}
+	}
+
+	s.inflight[action.ActionRequestId] = &pb.InflightAction{
+		ActionRequestId: action.ActionRequestId,
+		ActionType:      action.ActionType,
+		SentAt:          now,
+	}
+}
+
+func (s *clientStats) actionReplied(actionRequestId string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.inflight, actionRequestId)
+}
+
+func (s *clientStats) fill(inspection *pb.ClientInspection) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	inspection.Pings = append([]*pb.PingSample{}, s.pings...)
+	inspection.SendErrors = append([]*pb.TunnelSendError{}, s.sendErrors...)
+	inspection.RecentEvents = append([]*pb.ClientEvent{}, s.events...)
+
+	inspection.InflightActions = []*pb.InflightAction{}
+	for _, a := range s.inflight {
+		inspection.InflightActions = append(inspection.InflightActions, a)
+	}
+}
+
+// inspectedTunnel records what the hub sends to a client in its stats.
+type inspectedTunnel struct {
+	EventTunnelStream
+	stats *clientStats
+}
+
+func (t *inspectedTunnel) Send(event *pb.EventReply) error {
+	err := t.EventTunnelStream.Send(event)
+	if err != nil {
+		t.stats.sendFailed(event.EventType, err)
+		return err
+	}
+
+	switch event.EventType {
+	case PING:
+		t.stats.pingSent()
+	case BOTACTION:
+		t.stats.actionSent(event.Body)
+	}
+
+	metrics.TunnelEvents.WithLabelValues(EventOut, event.EventType, event.ClientType).Inc()
+	t.stats.recordEvent(EventOut, event.EventType, event.Body)
+	return nil
+}
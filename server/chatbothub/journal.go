@@ -0,0 +1,474 @@
+package chatbothub
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+)
+
+const (
+	JOURNAL_FILE  string = "file"
+	JOURNAL_MONGO string = "mongo"
+
+	journalFileName   string = "journal.jsonl"
+	journalFilePrefix string = "journal-"
+)
+
+// JournalConfig turns on the journal of the raw tunnel events, off when
+// Sink is empty.
+type JournalConfig struct {
+	// file or mongo
+	Sink string
+
+	// file sink: the directory, the MB a file grows to before it rotates,
+	// and the rotated files kept, 64 and 10 when 0
+	Dir      string
+	MaxSize  int
+	MaxFiles int
+
+	// mongo sink: the days events are kept, 7 when 0
+	Retention int
+
+	// the bots journaled, all when empty
+	BotIds []string
+
+	// events waiting to be written, dropped beyond, 4096 when 0
+	Buffer int
+}
+
+func (c JournalConfig) withDefaults() JournalConfig {
+	if c.MaxSize <= 0 {
+		c.MaxSize = 64
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = 10
+	}
+	if c.Retention <= 0 {
+		c.Retention = 7
+	}
+	if c.Buffer <= 0 {
+		c.Buffer = 4096
+	}
+	return c
+}
+
+var journalLogger = logging.New("journal")
+
+// the credentials carried by the login events, never journaled
+var journalRedactedEvents = map[string]bool{
+	LOGIN:       true,
+	LOGINDONE:   true,
+	UPDATETOKEN: true,
+}
+
+var journalRedactedFields = []string{"password", "loginInfo", "wxData", "token"}
+
+const journalRedacted string = "<redacted>"
+
+// redactJournalBody blanks the credentials out of the body of a login
+// event. A body that cannot be parsed is not journaled at all.
+func redactJournalBody(eventType string, body string) string {
+	if !journalRedactedEvents[eventType] || body == "" {
+		return body
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return journalRedacted
+	}
+
+	for _, field := range journalRedactedFields {
+		if _, ok := fields[field]; ok {
+			fields[field] = journalRedacted
+		}
+	}
+
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return journalRedacted
+	}
+
+	return string(redacted)
+}
+
+type journalSink interface {
+	write(entries []domains.JournalEntry) error
+}
+
+// eventJournal writes the journaled events to its sink in batches, off the
+// tunnel goroutines, so a slow sink drops events rather than slowing the
+// clients down.
+type eventJournal struct {
+	config  JournalConfig
+	botIds  map[string]bool
+	entries chan domains.JournalEntry
+	flushes chan chan struct{}
+	sink    journalSink
+}
+
+func (hub *ChatHub) newEventJournal() (*eventJournal, error) {
+	config := hub.Config.Journal.withDefaults()
+
+	j := &eventJournal{
+		config:  config,
+		botIds:  map[string]bool{},
+		entries: make(chan domains.JournalEntry, config.Buffer),
+		flushes: make(chan chan struct{}),
+	}
+
+	for _, botId := range config.BotIds {
+		j.botIds[botId] = true
+	}
+
+	switch config.Sink {
+	case JOURNAL_FILE:
+		sink, err := newFileJournalSink(config.Dir, config.MaxSize, config.MaxFiles)
+		if err != nil {
+			return nil, err
+		}
+		j.sink = sink
+
+	case JOURNAL_MONGO:
+		if hub.mongoDb == nil {
+			return nil, fmt.Errorf("journal to mongo, but mongo not connected")
+		}
+
+		o := &ErrorHandler{}
+		if o.EnsureEventJournalIndexes(hub.mongoDb, time.Duration(config.Retention)*24*time.Hour); o.Err != nil {
+			return nil, o.Err
+		}
+		j.sink = &mongoJournalSink{db: hub.mongoDb}
+
+	default:
+		return nil, fmt.Errorf("journal sink %s not supported, should be file or mongo", config.Sink)
+	}
+
+	go j.writeloop()
+	return j, nil
+}
+
+// record journals the event, unless it is a ping or its bot is not
+// journaled.
+func (j *eventJournal) record(direction string, bot *ChatBot, clientId string, clientType string, eventType string, body string) {
+	if eventType == PING || eventType == PONG {
+		return
+	}
+
+	entry := domains.JournalEntry{
+		Id:         bson.NewObjectId(),
+		At:         time.Now(),
+		Direction:  direction,
+		ClientId:   clientId,
+		ClientType: clientType,
+		EventType:  eventType,
+		Body:       redactJournalBody(eventType, body),
+	}
+
+	if bot != nil {
+		entry.BotId = bot.BotId
+		entry.Login = bot.Login
+	}
+
+	if len(j.botIds) > 0 && !j.botIds[entry.BotId] {
+		return
+	}
+
+	select {
+	case j.entries <- entry:
+	default:
+		journalLogger.With("clientId", clientId).Warn("journal full, drop %s %s", direction, eventType)
+	}
+}
+
+func (j *eventJournal) writeloop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	batch := []domains.JournalEntry{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := j.sink.write(batch); err != nil {
+			journalLogger.Error(err, "write %d journal entries failed", len(batch))
+		}
+		batch = []domains.JournalEntry{}
+	}
+
+	for {
+		select {
+		case entry := <-j.entries:
+			batch = append(batch, entry)
+			if len(batch) >= 100 {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case done := <-j.flushes:
+			for pending := true; pending; {
+				select {
+				case entry := <-j.entries:
+					batch = append(batch, entry)
+				default:
+					pending = false
+				}
+			}
+			flush()
+			close(done)
+		}
+	}
+}
+
+// Flush writes the events waiting to be written, for the last ones not to
+// be lost when the hub stops. It gives up after timeout.
+func (j *eventJournal) Flush(timeout time.Duration) {
+	done := make(chan struct{})
+
+	select {
+	case j.flushes <- done:
+	case <-time.After(timeout):
+		journalLogger.Warn("flush journal timeout")
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		journalLogger.Warn("flush journal timeout")
+	}
+}
+
+type mongoJournalSink struct {
+	db *mgo.Database
+}
+
+func (s *mongoJournalSink) write(entries []domains.JournalEntry) error {
+	o := &ErrorHandler{}
+	o.SaveJournalEntries(s.db, entries)
+	return o.Err
+}
+
+// fileJournalSink appends the entries as json lines to journal.jsonl in
+// dir, which is renamed journal-<unixnano>.jsonl once it exceeds maxSize,
+// keeping the latest maxFiles of those.
+type fileJournalSink struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+func newFileJournalSink(dir string, maxSizeMB int, maxFiles int) (*fileJournalSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("journal to file, but dir not set")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &fileJournalSink{
+		dir:      dir,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		maxFiles: maxFiles,
+	}
+
+	return s, s.open()
+}
+
+func (s *fileJournalSink) open() error {
+	file, err := os.OpenFile(filepath.Join(s.dir, journalFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	// a journal left by an earlier version may be readable by others
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileJournalSink) write(entries []domains.JournalEntry) error {
+	w := bufio.NewWriter(s.file)
+	for i := range entries {
+		line, err := json.Marshal(&entries[i])
+		if err != nil {
+			return err
+		}
+
+		n, err := w.Write(append(line, '\n'))
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if s.size >= s.maxSize {
+		return s.rotate()
+	}
+
+	return nil
+}
+
+func (s *fileJournalSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := filepath.Join(s.dir, fmt.Sprintf("%s%d.jsonl", journalFilePrefix, time.Now().UnixNano()))
+	if err := os.Rename(filepath.Join(s.dir, journalFileName), rotated); err != nil {
+		return err
+	}
+
+	if files, err := rotatedJournalFiles(s.dir); err == nil && len(files) > s.maxFiles {
+		for _, file := range files[:len(files)-s.maxFiles] {
+			if err := os.Remove(file); err != nil {
+				journalLogger.Error(err, "remove journal %s failed", file)
+			}
+		}
+	}
+
+	return s.open()
+}
+
+// rotatedJournalFiles returns the rotated journals in dir, oldest first.
+func rotatedJournalFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, journalFilePrefix) && strings.HasSuffix(name, ".jsonl") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	// the names differ only in the unixnano of the rotation
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadJournalDir reads at most limit entries of the slice from the journal
+// files in dir, oldest first.
+func ReadJournalDir(dir string, criteria domains.JournalCriteria, limit int) ([]domains.JournalEntry, error) {
+	files, err := rotatedJournalFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	current := filepath.Join(dir, journalFileName)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+
+	entries := []domains.JournalEntry{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() && len(entries) < limit {
+			entry := domains.JournalEntry{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if criteria.Match(&entry) {
+				entries = append(entries, entry)
+			}
+		}
+
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entries) >= limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// journaledTunnel journals the events of a client connection, both ways.
+// Events sent without a client id are of the client last heard from.
+type journaledTunnel struct {
+	EventTunnelStream
+	hub     *ChatHub
+	journal *eventJournal
+
+	clientId   string
+	clientType string
+}
+
+func (t *journaledTunnel) Recv() (*pb.EventRequest, error) {
+	in, err := t.EventTunnelStream.Recv()
+	if err == nil && in != nil {
+		t.clientId = in.ClientId
+		t.clientType = in.ClientType
+		t.journal.record(domains.JOURNAL_IN, t.hub.GetBot(in.ClientId),
+			in.ClientId, in.ClientType, in.EventType, in.Body)
+	}
+
+	return in, err
+}
+
+func (t *journaledTunnel) Send(event *pb.EventReply) error {
+	err := t.EventTunnelStream.Send(event)
+	if err == nil {
+		clientId, clientType := event.ClientId, event.ClientType
+		if clientId == "" {
+			clientId, clientType = t.clientId, t.clientType
+		}
+		t.journal.record(domains.JOURNAL_OUT, t.hub.GetBot(clientId),
+			clientId, clientType, event.EventType, event.Body)
+	}
+
+	return err
+}
+
+// journaled wraps the tunnel when the journal is on.
+func (hub *ChatHub) journaled(tunnel EventTunnelStream) EventTunnelStream {
+	if hub.journal == nil {
+		return tunnel
+	}
+
+	return &journaledTunnel{EventTunnelStream: tunnel, hub: hub, journal: hub.journal}
+}
@@ -0,0 +1,61 @@
+package chatbothub
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+var (
+	connectedBotsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "hub", "bots_connected"),
+		"Clients connected to this hub.",
+		[]string{"client_type"}, nil)
+
+	loggedInBotsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "hub", "bots_logged_in"),
+		"Bots logged in on the clients of this hub.",
+		[]string{"client_type"}, nil)
+)
+
+// botsCollector counts the bots of the hub at every scrape.
+type botsCollector struct {
+	hub *ChatHub
+}
+
+func (c *botsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- connectedBotsDesc
+	ch <- loggedInBotsDesc
+}
+
+func (c *botsCollector) Collect(ch chan<- prometheus.Metric) {
+	connected := map[string]int{}
+	loggedIn := map[string]int{}
+
+	c.hub.muxBots.Lock()
+	for _, bot := range c.hub.bots {
+		switch bot.Status {
+		case FailingDisconnected, WaitingForClient, ShuttingdownDone:
+			continue
+		case WorkingLoggedIn:
+			loggedIn[bot.ClientType] += 1
+		}
+		connected[bot.ClientType] += 1
+	}
+	c.hub.muxBots.Unlock()
+
+	for clientType, n := range connected {
+		ch <- prometheus.MustNewConstMetric(
+			connectedBotsDesc, prometheus.GaugeValue, float64(n), clientType)
+	}
+
+	for clientType, n := range loggedIn {
+		ch <- prometheus.MustNewConstMetric(
+			loggedInBotsDesc, prometheus.GaugeValue, float64(n), clientType)
+	}
+}
+
+func (hub *ChatHub) serveMetrics() {
+	prometheus.MustRegister(&botsCollector{hub: hub})
+	metrics.Serve(hub.Config.Host, hub.Config.MetricsPort)
+}
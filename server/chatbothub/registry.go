@@ -0,0 +1,196 @@
+package chatbothub
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	pbweb "github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
+)
+
+const (
+	// seconds a restored bot waits for its client before it is dropped
+	defaultRestoreTimeout int = 600
+
+	// restored bots rebinding their filters at once
+	rebindConcurrency int = 8
+)
+
+func snapshotBot(bot *ChatBot) domains.HubBotSnapshot {
+	o := &ErrorHandler{}
+
+	return domains.HubBotSnapshot{
+		ClientId:       bot.ClientId,
+		ClientType:     bot.ClientType,
+		Name:           bot.Name,
+		Login:          bot.Login,
+		NotifyUrl:      bot.NotifyUrl,
+		LoginInfo:      o.ToJson(bot.LoginInfo),
+		Status:         int32(bot.Status),
+		BotId:          bot.BotId,
+		FilterId:       bot.filterId,
+		MomentFilterId: bot.momentFilterId,
+		SavedAt:        time.Now().Unix(),
+	}
+}
+
+func (hub *ChatHub) saveRegistry() {
+	if hub.redispool == nil {
+		return
+	}
+
+	snapshots := []domains.HubBotSnapshot{}
+	hub.muxBots.Lock()
+	for _, bot := range hub.bots {
+		if bot.ClientId != "" {
+			snapshots = append(snapshots, snapshotBot(bot))
+		}
+	}
+	hub.muxBots.Unlock()
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	defer conn.Close()
+
+	o.SaveHubRegistry(conn, hub.instance.InstanceId, snapshots)
+	if o.Err != nil {
+		hub.Error(o.Err, "save hub registry failed")
+	}
+}
+
+// restoreRegistry brings back the bots of the last run of this instance,
+// waiting for their clients to register again. It returns the restored bots.
+func (hub *ChatHub) restoreRegistry() []*ChatBot {
+	restored := []*ChatBot{}
+	if hub.redispool == nil {
+		return restored
+	}
+
+	o := &ErrorHandler{}
+	conn := hub.redispool.Get()
+	snapshots := o.LoadHubRegistry(conn, hub.instance.InstanceId)
+	conn.Close()
+
+	if o.Err != nil {
+		hub.Error(o.Err, "load hub registry failed")
+		return restored
+	}
+
+	for _, snapshot := range snapshots {
+		if hub.GetBot(snapshot.ClientId) != nil {
+			continue
+		}
+
+		bot := hub.newChatBot()
+		bot.ClientId = snapshot.ClientId
+		bot.ClientType = snapshot.ClientType
+		bot.Name = snapshot.Name
+		bot.Login = snapshot.Login
+		bot.NotifyUrl = snapshot.NotifyUrl
+		bot.BotId = snapshot.BotId
+		bot.filterId = snapshot.FilterId
+		bot.momentFilterId = snapshot.MomentFilterId
+		bot.StartAt = time.Now().UnixNano() / 1e6
+		bot.Status = WaitingForClient
+
+		if err := json.Unmarshal([]byte(snapshot.LoginInfo), &bot.LoginInfo); err != nil {
+			hub.Error(err, "c[%s] restore login info failed", snapshot.ClientId)
+		}
+
+		hub.SetBot(bot.ClientId, bot)
+		restored = append(restored, bot)
+
+		hub.Info("c[%s] b[%s] restored from %s, waiting for client",
+			bot.ClientId, bot.Login, ChatBotStatus(snapshot.Status))
+	}
+
+	return restored
+}
+
+func (hub *ChatHub) restoreTimeout() time.Duration {
+	timeout := hub.Config.RestoreTimeout
+	if timeout <= 0 {
+		timeout = defaultRestoreTimeout
+	}
+
+	return time.Duration(timeout) * time.Second
+}
+
+// registryloop snapshots the registry periodically, and drops the restored
+// bots whose clients did not come back in time.
+func (hub *ChatHub) registryloop(restored []*ChatBot) {
+	deadline := time.Now().Add(hub.restoreTimeout())
+
+	for range time.Tick(5 * time.Second) {
+		if restored != nil && time.Now().After(deadline) {
+			for _, bot := range restored {
+				if thebot := hub.GetBot(bot.ClientId); thebot == bot && bot.Status == WaitingForClient {
+					hub.Info("c[%s] b[%s] client did not register again, drop it", bot.ClientId, bot.Login)
+					hub.DropBot(bot.ClientId)
+				}
+			}
+			restored = nil
+		}
+
+		hub.saveRegistry()
+	}
+}
+
+// rebindFilters asks web to rebuild the filters of the restored bots from
+// the database, since filter instances only live in hub memory. Bots are
+// rebound a few at a time, so their waits for the server overlap.
+func (hub *ChatHub) rebindFilters(restored []*ChatBot) {
+	sem := make(chan struct{}, rebindConcurrency)
+	wg := sync.WaitGroup{}
+
+	for _, bot := range restored {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(bot *ChatBot) {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			hub.rebindBotFilters(bot)
+		}(bot)
+	}
+
+	wg.Wait()
+}
+
+// rebindBotFilters asks web to rebuild the filters of one bot.
+func (hub *ChatHub) rebindBotFilters(bot *ChatBot) {
+	if bot.BotId == "" {
+		return
+	}
+
+	webWrapper := rpc.CreateGRPCWrapper(fmt.Sprintf("%s:%s", hub.Webhost, hub.WebGrpcport))
+
+	for retry := 0; retry < 5; retry++ {
+		// web calls back into this instance, give the server time to listen
+		time.Sleep(time.Duration(retry+1) * 2 * time.Second)
+
+		wrapper, err := webWrapper.Clone()
+		if err != nil {
+			hub.Error(err, "connect web grpc failed")
+			continue
+		}
+
+		_, err = wrapper.WebClient.RebuildBotFilters(wrapper.Context, &pbweb.RebuildBotFiltersRequest{
+			BotId:          bot.BotId,
+			FilterId:       bot.filterId,
+			MomentFilterId: bot.momentFilterId,
+		})
+		wrapper.Cancel()
+
+		if err == nil {
+			hub.Info("b[%s] filters rebound", bot.BotId)
+			break
+		}
+
+		hub.Error(err, "b[%s] rebind filters failed, retry %d", bot.BotId, retry)
+	}
+}
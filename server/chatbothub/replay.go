@@ -0,0 +1,336 @@
+package chatbothub
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+)
+
+// sandbox records what a replayed message goes through, in place of the
+// side effects: the message is not saved, not sent to streaming nor web,
+// and the filters triggering webs or posting to fluent only say they would.
+type sandbox struct {
+	mux     sync.Mutex
+	message string
+	filters []string
+	effects []string
+}
+
+func (s *sandbox) reset() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.message = ""
+	s.filters = []string{}
+	s.effects = []string{}
+}
+
+func (s *sandbox) filled(f *sandboxFilter) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.filters = append(s.filters, fmt.Sprintf("%s[%s]#%s", f.name, f.filterType, f.filterId))
+}
+
+func (s *sandbox) effect(format string, v ...interface{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.effects = append(s.effects, fmt.Sprintf(format, v...))
+}
+
+// fill stands for what onReceiveMessage does with a verified message.
+func (s *sandbox) fill(bot *ChatBot, eventType string, message string) error {
+	s.mux.Lock()
+	s.message = message
+	s.mux.Unlock()
+
+	if bot.filter != nil {
+		if err := bot.filter.Fill(message); err != nil {
+			return err
+		}
+	}
+
+	s.effect("botNotify %s", eventType)
+	return nil
+}
+
+// sandboxFilter traces the fills of the filter it wraps, routers included.
+// A filter with side effects is not filled, it records the effect and
+// fills the next filter itself.
+type sandboxFilter struct {
+	sandbox    *sandbox
+	filterId   string
+	name       string
+	filterType string
+
+	inner  Filter
+	effect string
+	next   Filter
+}
+
+func (f *sandboxFilter) Fill(msg string) error {
+	f.sandbox.filled(f)
+
+	if f.effect == "" {
+		return f.inner.Fill(msg)
+	}
+
+	f.sandbox.effect("%s by %s", f.effect, f.filterId)
+	if f.next != nil {
+		return f.next.Fill(msg)
+	}
+
+	return nil
+}
+
+func (f *sandboxFilter) Next(filter Filter) error {
+	f.next = filter
+	return f.inner.Next(filter)
+}
+
+func (f *sandboxFilter) Branch(tag BranchTag, filter Filter) error {
+	if router, ok := f.inner.(Router); ok {
+		return router.Branch(tag, filter)
+	}
+
+	return fmt.Errorf("filter type %T cannot branch", f.inner)
+}
+
+func (s *sandbox) wrap(req *pb.FilterCreateRequest, filter Filter) Filter {
+	f := &sandboxFilter{
+		sandbox:    s,
+		filterId:   req.FilterId,
+		name:       req.FilterName,
+		filterType: req.FilterType,
+		inner:      filter,
+	}
+
+	switch ff := filter.(type) {
+	case *WebTrigger:
+		f.effect = fmt.Sprintf("trigger %s %s", ff.Action.Method, ff.Action.Url)
+	case *FluentFilter:
+		f.effect = fmt.Sprintf("post fluent %s", ff.tag)
+	}
+
+	return f
+}
+
+// NewSandboxHub creates a hub to replay journaled events in, connected to
+// nothing. Its bots are added with SandboxBot, and their filters built with
+// BuildBotFilters.
+func NewSandboxHub(config ChatHubConfig) *ChatHub {
+	if config.Fluent.Tags == nil {
+		config.Fluent.Tags = map[string]string{}
+	}
+	if _, ok := config.Fluent.Tags["msg"]; !ok {
+		config.Fluent.Tags["msg"] = "replay"
+	}
+
+	return &ChatHub{
+		Config:   config,
+		logger:   logging.New("replay"),
+		bots:     make(map[string]*ChatBot),
+		filters:  make(map[string]Filter),
+		inflight: make(map[string]time.Time),
+		affinity: make(map[string]string),
+		sandbox:  &sandbox{},
+	}
+}
+
+// SandboxBot adds a logged in bot to the sandbox hub.
+func (hub *ChatHub) SandboxBot(botId string, login string, clientId string, clientType string) *ChatBot {
+	bot := NewChatBot()
+	bot.BotId = botId
+	bot.Login = login
+	bot.ClientId = clientId
+	bot.ClientType = clientType
+	bot.Status = WorkingLoggedIn
+
+	hub.SetBot(clientId, bot)
+	return bot
+}
+
+func checkOperationReply(reply *pb.OperationReply, err error) error {
+	if err != nil {
+		return err
+	}
+	if reply != nil && reply.Code != 0 {
+		return fmt.Errorf("%d %s", reply.Code, reply.Message)
+	}
+	return nil
+}
+
+// BuildBotFilters builds the filter chain from filterId as web does when a
+// bot logs in, and sets it as the message filter of the bot.
+func (hub *ChatHub) BuildBotFilters(q dbx.Queryable, bot *ChatBot, filterId string) error {
+	if err := hub.buildFilterChain(q, filterId); err != nil {
+		return err
+	}
+
+	return checkOperationReply(hub.BotFilter(context.Background(), &pb.BotFilterRequest{
+		BotId:    bot.BotId,
+		FilterId: filterId,
+	}))
+}
+
+func (hub *ChatHub) buildFilterChain(q dbx.Queryable, filterId string) error {
+	ctx := context.Background()
+	o := &ErrorHandler{}
+
+	lastFilterId := ""
+	currentFilterId := filterId
+
+	for currentFilterId != "" {
+		filter := o.GetFilterById(q, currentFilterId)
+		if o.Err != nil {
+			return o.Err
+		}
+		if filter == nil {
+			return fmt.Errorf("cannot find filter %s", currentFilterId)
+		}
+
+		if err := checkOperationReply(hub.FilterCreate(ctx, &pb.FilterCreateRequest{
+			FilterId:   filter.FilterId,
+			FilterType: filter.FilterType,
+			FilterName: filter.FilterName,
+			Body:       filter.Body.String,
+		})); err != nil {
+			return err
+		}
+
+		if err := hub.buildRouterBranches(q, filter); err != nil {
+			return err
+		}
+
+		if lastFilterId != "" {
+			if err := checkOperationReply(hub.FilterNext(ctx, &pb.FilterNextRequest{
+				FilterId:     lastFilterId,
+				NextFilterId: filter.FilterId,
+			})); err != nil {
+				return err
+			}
+		}
+
+		lastFilterId = currentFilterId
+		currentFilterId = ""
+		if filter.Next.Valid {
+			currentFilterId = filter.Next.String
+		}
+	}
+
+	return nil
+}
+
+// buildRouterBranches builds the children of a router, listed in its body,
+// KVRouter as {key: {regexp: filterId}}, RegexRouter as {regexp: filterId}.
+func (hub *ChatHub) buildRouterBranches(q dbx.Queryable, filter *domains.Filter) error {
+	if !filter.Body.Valid || filter.Body.String == "" {
+		return nil
+	}
+
+	o := &ErrorHandler{}
+	bodym := o.FromJson(filter.Body.String)
+	if o.Err != nil {
+		return o.Err
+	}
+
+	branch := func(key string, value string, childFilterId string) error {
+		if err := hub.buildFilterChain(q, childFilterId); err != nil {
+			return err
+		}
+
+		return checkOperationReply(hub.RouterBranch(context.Background(), &pb.RouterBranchRequest{
+			Tag:      &pb.BranchTag{Key: key, Value: value},
+			RouterId: filter.FilterId,
+			FilterId: childFilterId,
+		}))
+	}
+
+	switch filter.FilterType {
+	case KVROUTER:
+		for key, v := range bodym {
+			vm, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("KVRouter %s: unexpected filter.body.key type %T", filter.FilterId, v)
+			}
+			for value, fid := range vm {
+				if childFilterId, ok := fid.(string); ok {
+					if err := branch(key, value, childFilterId); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+	case REGEXROUTER:
+		for regstr, fid := range bodym {
+			if childFilterId, ok := fid.(string); ok {
+				if err := branch(regstr, "", childFilterId); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReplayResult is what a journaled event went through in the sandbox.
+type ReplayResult struct {
+	At        time.Time `json:"at"`
+	EventType string    `json:"eventType"`
+	Body      string    `json:"body"`
+	// the message after verifyMessage, empty when it was dropped
+	Message string   `json:"message,omitempty"`
+	Filters []string `json:"filters"`
+	Effects []string `json:"effects"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Replay feeds a journaled message event of the bot back through
+// onReceiveMessage, in the sandbox hub.
+func (hub *ChatHub) Replay(bot *ChatBot, entry *domains.JournalEntry) *ReplayResult {
+	hub.sandbox.reset()
+
+	err := hub.onReceiveMessage(bot, &pb.EventRequest{
+		EventType:  entry.EventType,
+		Body:       entry.Body,
+		ClientId:   bot.ClientId,
+		ClientType: bot.ClientType,
+	})
+
+	hub.sandbox.mux.Lock()
+	defer hub.sandbox.mux.Unlock()
+
+	result := &ReplayResult{
+		At:        entry.At,
+		EventType: entry.EventType,
+		Body:      entry.Body,
+		Message:   hub.sandbox.message,
+		Filters:   hub.sandbox.filters,
+		Effects:   hub.sandbox.effects,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// IsReplayable tells whether Replay takes the event, the message events
+// the hub hands to onReceiveMessage.
+func IsReplayable(eventType string) bool {
+	switch eventType {
+	case MESSAGE, IMAGEMESSAGE, EMOJIMESSAGE:
+		return true
+	}
+	return false
+}
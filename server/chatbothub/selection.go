@@ -0,0 +1,267 @@
+package chatbothub
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SelectFirst       string = "first"
+	SelectLeastLoaded string = "leastloaded"
+	SelectRoundRobin  string = "roundrobin"
+	SelectAffinity    string = "affinity"
+
+	// seconds a client is avoided after a LOGINFAILED
+	defaultAvoidLoginFailed int = 300
+)
+
+type ClientSelectionConfig struct {
+	// one of first, leastloaded, roundrobin and affinity, leastloaded by
+	// default
+	Policy string `yaml:"policy"`
+	// seconds to avoid a client after a LOGINFAILED, never when negative
+	AvoidLoginFailed int `yaml:"avoidLoginFailed"`
+}
+
+// ClientLoad is reported by clients in their PING bodies.
+type ClientLoad struct {
+	// bots hosted on the host of the client
+	Bots        int     `json:"bots"`
+	Cpu         float64 `json:"cpu"`
+	MessageRate float64 `json:"messageRate"`
+	Region      string  `json:"region,omitempty"`
+}
+
+// loadReport is the last load a client reported, set by its PING while
+// selections read it.
+type loadReport struct {
+	mux      sync.Mutex
+	load     ClientLoad
+	reported bool
+}
+
+func (r *loadReport) set(load ClientLoad) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.load = load
+	r.reported = true
+}
+
+func (r *loadReport) get() (ClientLoad, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.load, r.reported
+}
+
+// SelectRequest is what a login asks of the client it gets.
+type SelectRequest struct {
+	ClientType string
+	Login      string
+	Region     string
+}
+
+// ClientSelector picks a client among the available ones, candidates is
+// never empty.
+type ClientSelector interface {
+	Select(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot
+}
+
+type ClientSelectorFunc func(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot
+
+func (f ClientSelectorFunc) Select(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot {
+	return f(hub, candidates, req)
+}
+
+var (
+	muxSelectors sync.Mutex
+	selectors    = map[string]ClientSelector{
+		SelectFirst:       ClientSelectorFunc(selectFirst),
+		SelectLeastLoaded: ClientSelectorFunc(selectLeastLoaded),
+		SelectRoundRobin:  ClientSelectorFunc(selectRoundRobin),
+		SelectAffinity:    ClientSelectorFunc(selectAffinity),
+	}
+)
+
+// RegisterClientSelector adds a selection policy, to be named in the hub
+// config.
+func RegisterClientSelector(policy string, selector ClientSelector) {
+	muxSelectors.Lock()
+	defer muxSelectors.Unlock()
+
+	selectors[policy] = selector
+}
+
+func (hub *ChatHub) clientSelector() ClientSelector {
+	muxSelectors.Lock()
+	defer muxSelectors.Unlock()
+
+	policy := strings.ToLower(hub.Config.ClientSelection.Policy)
+	if selector, found := selectors[policy]; found {
+		return selector
+	}
+
+	return selectors[SelectLeastLoaded]
+}
+
+// parseClientLoad reads the load from a PING body. Clients that predate
+// load reporting send plain text, which leaves the load unknown.
+func parseClientLoad(body string) (ClientLoad, bool) {
+	load := ClientLoad{}
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return load, false
+	}
+
+	if err := json.Unmarshal([]byte(body), &load); err != nil {
+		return load, false
+	}
+
+	return load, true
+}
+
+func (hub *ChatHub) avoidLoginFailed() time.Duration {
+	avoid := hub.Config.ClientSelection.AvoidLoginFailed
+	if avoid == 0 {
+		avoid = defaultAvoidLoginFailed
+	}
+
+	return time.Duration(avoid) * time.Second
+}
+
+// SelectAvailableBot picks an idle client for a login with the configured
+// policy. Clients that failed a login recently are only picked when there
+// is no other.
+func (hub *ChatHub) SelectAvailableBot(req SelectRequest) *ChatBot {
+	hub.muxBots.Lock()
+	defer hub.muxBots.Unlock()
+
+	return hub.selectAvailableBot(req)
+}
+
+// selectAvailableBot is SelectAvailableBot with muxBots held, for callers
+// that mark the client taken before letting go of the lock.
+func (hub *ChatHub) selectAvailableBot(req SelectRequest) *ChatBot {
+	avoid := hub.avoidLoginFailed()
+	now := time.Now()
+
+	candidates := []*ChatBot{}
+	failed := []*ChatBot{}
+
+	for _, v := range hub.bots {
+		if v.ClientType != req.ClientType || v.Status != BeginRegistered {
+			continue
+		}
+
+		if avoid > 0 && !v.loginFailedAt.IsZero() && now.Sub(v.loginFailedAt) < avoid {
+			failed = append(failed, v)
+		} else {
+			candidates = append(candidates, v)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = failed
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// map order is random, keep policies deterministic
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ClientId < candidates[j].ClientId
+	})
+
+	return hub.clientSelector().Select(hub, candidates, req)
+}
+
+func (hub *ChatHub) rememberAffinity(login string, clientId string) {
+	if login == "" {
+		return
+	}
+
+	hub.muxSelection.Lock()
+	defer hub.muxSelection.Unlock()
+
+	hub.affinity[login] = clientId
+}
+
+func selectFirst(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot {
+	return candidates[0]
+}
+
+// selectLeastLoaded prefers the client whose host runs the fewest bots,
+// then the lower cpu, then the lower message rate. Clients that never
+// reported their load come last.
+func selectLeastLoaded(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if lessLoaded(c, best) {
+			best = c
+		}
+	}
+
+	return best
+}
+
+func lessLoaded(a *ChatBot, b *ChatBot) bool {
+	aload, areported := a.load.get()
+	bload, breported := b.load.get()
+
+	if areported != breported {
+		return areported
+	}
+
+	if aload.Bots != bload.Bots {
+		return aload.Bots < bload.Bots
+	}
+
+	if aload.Cpu != bload.Cpu {
+		return aload.Cpu < bload.Cpu
+	}
+
+	return aload.MessageRate < bload.MessageRate
+}
+
+func selectRoundRobin(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot {
+	hub.muxSelection.Lock()
+	defer hub.muxSelection.Unlock()
+
+	hub.roundRobin += 1
+	return candidates[hub.roundRobin%len(candidates)]
+}
+
+// selectAffinity prefers the client the account was last logged in on,
+// then clients of the requested region, least loaded among them.
+func selectAffinity(hub *ChatHub, candidates []*ChatBot, req SelectRequest) *ChatBot {
+	hub.muxSelection.Lock()
+	clientId := hub.affinity[req.Login]
+	hub.muxSelection.Unlock()
+
+	if clientId != "" {
+		for _, c := range candidates {
+			if c.ClientId == clientId {
+				return c
+			}
+		}
+	}
+
+	if req.Region != "" {
+		regional := []*ChatBot{}
+		for _, c := range candidates {
+			if load, _ := c.load.get(); load.Region == req.Region {
+				regional = append(regional, c)
+			}
+		}
+
+		if len(regional) > 0 {
+			return selectLeastLoaded(hub, regional, req)
+		}
+	}
+
+	return selectLeastLoaded(hub, candidates, req)
+}
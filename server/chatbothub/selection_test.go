@@ -0,0 +1,132 @@
+package chatbothub
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSelectionHub(policy string, clients ...*ChatBot) *ChatHub {
+	hub := &ChatHub{
+		Config:   ChatHubConfig{ClientSelection: ClientSelectionConfig{Policy: policy}},
+		bots:     map[string]*ChatBot{},
+		affinity: map[string]string{},
+	}
+
+	for _, c := range clients {
+		c.ClientType = WECHATBOT
+		if c.Status == 0 {
+			c.Status = BeginRegistered
+		}
+		hub.bots[c.ClientId] = c
+	}
+
+	return hub
+}
+
+func newTestClient(clientId string, load *ClientLoad) *ChatBot {
+	c := &ChatBot{ClientId: clientId}
+	if load != nil {
+		c.load.set(*load)
+	}
+	return c
+}
+
+func selectTestClient(t *testing.T, hub *ChatHub, req SelectRequest) string {
+	req.ClientType = WECHATBOT
+	c := hub.SelectAvailableBot(req)
+	if c == nil {
+		t.Fatalf("no client selected")
+	}
+	return c.ClientId
+}
+
+func TestSelectFirst(t *testing.T) {
+	hub := newTestSelectionHub(SelectFirst,
+		newTestClient("c3", nil),
+		newTestClient("c1", &ClientLoad{Bots: 9}),
+		newTestClient("c2", &ClientLoad{Bots: 1}))
+
+	if id := selectTestClient(t, hub, SelectRequest{}); id != "c1" {
+		t.Errorf("selected %s, expected the first client by id c1", id)
+	}
+}
+
+func TestSelectLeastLoaded(t *testing.T) {
+	hub := newTestSelectionHub(SelectLeastLoaded,
+		newTestClient("c1", nil),
+		newTestClient("c2", &ClientLoad{Bots: 3, Cpu: 0.1}),
+		newTestClient("c3", &ClientLoad{Bots: 2, Cpu: 0.9}),
+		newTestClient("c4", &ClientLoad{Bots: 2, Cpu: 0.5, MessageRate: 10}),
+		newTestClient("c5", &ClientLoad{Bots: 2, Cpu: 0.5, MessageRate: 5}))
+
+	if id := selectTestClient(t, hub, SelectRequest{}); id != "c5" {
+		t.Errorf("selected %s, expected c5 by bots, cpu then message rate", id)
+	}
+
+	// clients that never reported come last
+	hub = newTestSelectionHub(SelectLeastLoaded,
+		newTestClient("c1", nil),
+		newTestClient("c2", &ClientLoad{Bots: 100}))
+	if id := selectTestClient(t, hub, SelectRequest{}); id != "c2" {
+		t.Errorf("selected %s, expected c2 which reported its load", id)
+	}
+}
+
+func TestSelectRoundRobin(t *testing.T) {
+	hub := newTestSelectionHub(SelectRoundRobin,
+		newTestClient("c1", nil),
+		newTestClient("c2", nil),
+		newTestClient("c3", nil))
+
+	seen := map[string]int{}
+	for i := 0; i < 6; i++ {
+		seen[selectTestClient(t, hub, SelectRequest{})] += 1
+	}
+
+	for _, id := range []string{"c1", "c2", "c3"} {
+		if seen[id] != 2 {
+			t.Errorf("round robin selected %v, expected each client twice", seen)
+			break
+		}
+	}
+}
+
+func TestSelectAffinity(t *testing.T) {
+	hub := newTestSelectionHub(SelectAffinity,
+		newTestClient("c1", &ClientLoad{Bots: 1, Region: "north"}),
+		newTestClient("c2", &ClientLoad{Bots: 5, Region: "south"}),
+		newTestClient("c3", &ClientLoad{Bots: 3, Region: "south"}))
+
+	hub.rememberAffinity("login", "c2")
+	if id := selectTestClient(t, hub, SelectRequest{Login: "login", Region: "north"}); id != "c2" {
+		t.Errorf("selected %s, expected c2 the login was last on", id)
+	}
+
+	if id := selectTestClient(t, hub, SelectRequest{Login: "other", Region: "south"}); id != "c3" {
+		t.Errorf("selected %s, expected c3 least loaded in the region", id)
+	}
+
+	if id := selectTestClient(t, hub, SelectRequest{Login: "other", Region: "west"}); id != "c1" {
+		t.Errorf("selected %s, expected c1 least loaded with no client in the region", id)
+	}
+}
+
+func TestSelectAvoidsLoginFailed(t *testing.T) {
+	failed := newTestClient("c1", &ClientLoad{Bots: 0})
+	failed.loginFailedAt = time.Now()
+
+	hub := newTestSelectionHub(SelectLeastLoaded,
+		failed,
+		newTestClient("c2", &ClientLoad{Bots: 9}),
+		&ChatBot{ClientId: "c3", Status: LoggingPrepared})
+
+	if id := selectTestClient(t, hub, SelectRequest{}); id != "c2" {
+		t.Errorf("selected %s, expected c2 over the client that failed a login", id)
+	}
+
+	// with no other client the failed one is still picked
+	delete(hub.bots, "c2")
+	if id := selectTestClient(t, hub, SelectRequest{}); id != "c1" {
+		t.Errorf("selected %s, expected c1 as the only idle client", id)
+	}
+}
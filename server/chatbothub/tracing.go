@@ -0,0 +1,32 @@
+package chatbothub
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
+)
+
+// actionTraces hands the trace of a BotAction call to the BOTACTION event
+// built for it, as the action functions take no context. Clients echo the
+// action back in their ACTIONREPLY, trace included.
+var actionTraces sync.Map
+
+func traceAction(ctx context.Context, arId string) {
+	if trace := tracing.Inject(ctx); trace != nil {
+		actionTraces.Store(arId, trace)
+	}
+}
+
+func forgetActionTrace(arId string) {
+	actionTraces.Delete(arId)
+}
+
+func actionTrace(arId string) map[string]string {
+	if trace, ok := actionTraces.Load(arId); ok {
+		return trace.(map[string]string)
+	}
+
+	return nil
+}
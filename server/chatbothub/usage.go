@@ -0,0 +1,152 @@
+package chatbothub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	// seconds between two flushes of the usage metered by the hub
+	usageFlushInterval = 10 * time.Second
+
+	// image sizes looked up in oss at once, the images received beyond are
+	// not metered
+	mediaSizeLookups = 16
+)
+
+// meteredFilter counts the fills of the filter it wraps, and the webs
+// triggered when it is a WebTrigger, for the bot of the message.
+type meteredFilter struct {
+	Filter
+	hub        *ChatHub
+	filterId   string
+	filterType string
+	webTrigger bool
+}
+
+func (f *meteredFilter) Fill(msg string) error {
+	scope, subjectId := domains.USAGE_SCOPE_FILTER, f.filterId
+	if bot := f.hub.botOfMessage(msg); bot != nil {
+		scope, subjectId = domains.USAGE_SCOPE_BOT, bot.BotId
+	}
+
+	f.hub.usage.Add(scope, subjectId, domains.USAGE_FILTERS, f.filterType, 1)
+	if f.webTrigger {
+		f.hub.usage.Add(scope, subjectId, domains.USAGE_WEBTRIGGERS, "", 1)
+	}
+
+	return f.Filter.Fill(msg)
+}
+
+// botOfMessage returns the bot on the hub that sent or received the
+// message, nil when there is none.
+func (hub *ChatHub) botOfMessage(msg string) *ChatBot {
+	o := &ErrorHandler{}
+	header := o.ChatMessageHeaderFromMessage(msg)
+	if o.Err != nil {
+		return nil
+	}
+
+	for _, login := range []string{header.ToUser, header.FromUser} {
+		if login == "" {
+			continue
+		}
+		if bot := hub.GetBotByLogin(login); bot != nil && bot.BotId != "" {
+			return bot
+		}
+	}
+
+	return nil
+}
+
+func (f *meteredFilter) Branch(tag BranchTag, filter Filter) error {
+	if router, ok := f.Filter.(Router); ok {
+		return router.Branch(tag, filter)
+	}
+
+	return fmt.Errorf("filter type %T cannot branch", f.Filter)
+}
+
+func (hub *ChatHub) metered(filterId string, filterType string, filter Filter) Filter {
+	if hub.usage == nil {
+		return filter
+	}
+
+	_, webTrigger := filter.(*WebTrigger)
+	return &meteredFilter{
+		Filter:     filter,
+		hub:        hub,
+		filterId:   filterId,
+		filterType: filterType,
+		webTrigger: webTrigger,
+	}
+}
+
+// meterMessage counts the message received by the bot, and the bytes of
+// its image in oss, which are looked up off the message path.
+func (hub *ChatHub) meterMessage(bot *ChatBot, eventType string, bodyJSON map[string]interface{}) {
+	if hub.usage == nil {
+		return
+	}
+
+	hub.usage.Add(domains.USAGE_SCOPE_BOT, bot.BotId, domains.USAGE_MESSAGES, eventType, 1)
+
+	if hub.ossBucket == nil {
+		return
+	}
+
+	var messageType utils.MessageType
+	switch eventType {
+	case IMAGEMESSAGE:
+		messageType = utils.MessageTypeImage
+	case EMOJIMESSAGE:
+		messageType = utils.MessageTypeEmoji
+	default:
+		return
+	}
+
+	imageId, _ := bodyJSON["imageId"].(string)
+	if imageId == "" {
+		return
+	}
+
+	select {
+	case hub.mediaSizes <- struct{}{}:
+	default:
+		hub.Debug("b[%s] %d image sizes in lookup, %s %s not metered",
+			bot.BotId, mediaSizeLookups, eventType, imageId)
+		return
+	}
+
+	go func() {
+		defer func() { <-hub.mediaSizes }()
+
+		size, err := utils.OssImageSize(hub.ossBucket, messageType, imageId)
+		if err != nil {
+			hub.Error(err, "b[%s] get size of %s %s failed", bot.BotId, eventType, imageId)
+			return
+		}
+
+		hub.usage.Add(domains.USAGE_SCOPE_BOT, bot.BotId, domains.USAGE_MEDIABYTES, eventType, size)
+	}()
+}
+
+func (hub *ChatHub) meterAction(bot *ChatBot, actionType string) {
+	hub.usage.Add(domains.USAGE_SCOPE_BOT, bot.BotId, domains.USAGE_ACTIONS, actionType, 1)
+}
+
+func (hub *ChatHub) flushUsage() {
+	o := &ErrorHandler{}
+	if o.FlushUsageMeter(hub.mongoDb, hub.usage); o.Err != nil {
+		hub.Error(o.Err, "flush usage failed")
+	}
+}
+
+func (hub *ChatHub) meterloop() {
+	for range time.Tick(usageFlushInterval) {
+		hub.flushUsage()
+	}
+}
@@ -0,0 +1,165 @@
+package chatbothub
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+)
+
+const (
+	WebsocketTunnelPath string = "/eventtunnel"
+
+	wsWriteWait = 10 * time.Second
+)
+
+// EventTunnelStream is a client connection as the event loop sees it. The
+// grpc EventTunnel stream is one, a websocket carrying json encoded
+// EventRequest and EventReply is another.
+type EventTunnelStream interface {
+	Send(*pb.EventReply) error
+	Recv() (*pb.EventRequest, error)
+}
+
+// wsTunnel adapts a websocket connection to EventTunnelStream, one json
+// event per text message.
+type wsTunnel struct {
+	conn *websocket.Conn
+
+	// websocket allows one writer at a time, and the pingloop, actions and
+	// the event loop all send
+	muxWrite sync.Mutex
+}
+
+func (t *wsTunnel) Send(event *pb.EventReply) error {
+	t.muxWrite.Lock()
+	defer t.muxWrite.Unlock()
+
+	if err := t.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+
+	return t.conn.WriteJSON(event)
+}
+
+func (t *wsTunnel) Recv() (*pb.EventRequest, error) {
+	for {
+		msgType, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+
+		if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
+			continue
+		}
+
+		in := &pb.EventRequest{}
+		if err := json.Unmarshal(data, in); err != nil {
+			return nil, fmt.Errorf("malformed event %s: %s", string(data), err)
+		}
+
+		return in, nil
+	}
+}
+
+// websocketSecret reads the secret a client presents, as a bearer token
+// or, for clients that cannot set headers, the token query parameter.
+func websocketSecret(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return r.URL.Query().Get("token")
+}
+
+func (hub *ChatHub) authorizeWebsocketTunnel(r *http.Request) bool {
+	secret := websocketSecret(r)
+	return secret != "" &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(hub.Config.WebsocketSecret)) == 1
+}
+
+// checkWebsocketOrigin lets bot clients, which send no Origin, through, and
+// browsers only from the configured origins.
+func (hub *ChatHub) checkWebsocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range hub.Config.WebsocketOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (hub *ChatHub) acceptWebsocketTunnel(w http.ResponseWriter, r *http.Request) {
+	if hub.isDraining() {
+		http.Error(w, hub.drainingError().Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if !hub.authorizeWebsocketTunnel(r) {
+		hub.Info("[WSTUNNEL] unauthorized from %s", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     hub.checkWebsocketOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		hub.Error(err, "[WSTUNNEL] upgrade from %s failed", r.RemoteAddr)
+		return
+	}
+	defer conn.Close()
+
+	hub.Info("[WSTUNNEL] connected from %s", r.RemoteAddr)
+	if err := hub.serveEventTunnel(&wsTunnel{conn: conn}); err != nil {
+		hub.Error(err, "[WSTUNNEL] %s disconnected", r.RemoteAddr)
+	}
+}
+
+// websocketTunnelServer is the server accepting bot clients over
+// websocket, nil unless a websocket port and secret are configured.
+func (hub *ChatHub) websocketTunnelServer() *http.Server {
+	if hub.Config.WebsocketPort == "" {
+		return nil
+	}
+
+	if hub.Config.WebsocketSecret == "" {
+		hub.Error(fmt.Errorf("websocketSecret is empty"), "[WSTUNNEL] not serving without a secret")
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(WebsocketTunnelPath, hub.acceptWebsocketTunnel)
+
+	addr := fmt.Sprintf("%s:%s", hub.Config.Host, hub.Config.WebsocketPort)
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// serveWebsocketTunnel serves until the drain closes the server.
+func (hub *ChatHub) serveWebsocketTunnel(server *http.Server) {
+	hub.Info("[WSTUNNEL] listening to %s%s", server.Addr, WebsocketTunnelPath)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		hub.Error(err, "[WSTUNNEL] failed to serve")
+	}
+}
@@ -0,0 +1,49 @@
+package chatbothub
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizeWebsocketTunnel(t *testing.T) {
+	hub := &ChatHub{Config: ChatHubConfig{WebsocketSecret: "secret"}}
+
+	r := httptest.NewRequest("GET", WebsocketTunnelPath, nil)
+	if hub.authorizeWebsocketTunnel(r) {
+		t.Errorf("tunnel without a secret should not be authorized")
+	}
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	if hub.authorizeWebsocketTunnel(r) {
+		t.Errorf("tunnel with a wrong secret should not be authorized")
+	}
+
+	r.Header.Set("Authorization", "Bearer secret")
+	if !hub.authorizeWebsocketTunnel(r) {
+		t.Errorf("tunnel with the secret as bearer token should be authorized")
+	}
+
+	r = httptest.NewRequest("GET", WebsocketTunnelPath+"?token=secret", nil)
+	if !hub.authorizeWebsocketTunnel(r) {
+		t.Errorf("tunnel with the secret as token should be authorized")
+	}
+}
+
+func TestCheckWebsocketOrigin(t *testing.T) {
+	hub := &ChatHub{Config: ChatHubConfig{WebsocketOrigins: []string{"https://hub.example.com"}}}
+
+	r := httptest.NewRequest("GET", WebsocketTunnelPath, nil)
+	if !hub.checkWebsocketOrigin(r) {
+		t.Errorf("client without an origin should be let through")
+	}
+
+	r.Header.Set("Origin", "https://HUB.example.com")
+	if !hub.checkWebsocketOrigin(r) {
+		t.Errorf("configured origin should be let through")
+	}
+
+	r.Header.Set("Origin", "https://evil.example.com")
+	if hub.checkWebsocketOrigin(r) {
+		t.Errorf("origin not configured should be rejected")
+	}
+}
@@ -40,8 +40,19 @@ func (o *ErrorHandler) Connect(db *Database, driverName string, dataSourceName s
 		return
 	}
 
+	sqldb, err := observedDB(driverName, dataSourceName)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
 	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
-	db.Conn, o.Err = sqlx.ConnectContext(ctx, driverName, dataSourceName)
+	if o.Err = sqldb.PingContext(ctx); o.Err != nil {
+		sqldb.Close()
+		return
+	}
+
+	db.Conn = sqlx.NewDb(sqldb, driverName)
 }
 
 func (o *ErrorHandler) Begin(db *Database) *sqlx.Tx {
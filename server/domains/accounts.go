@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -17,6 +18,8 @@ type ErrorHandler struct {
 	dbx.ErrorHandler
 }
 
+var logger = logging.New("domains")
+
 type Account struct {
 	AccountId   string         `db:"accountid"`
 	AccountName string         `db:"accountname"`
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -26,6 +27,8 @@ type ActionRequest struct {
 	Result          string         `json:"result"`
 	CreateAt        utils.JSONTime `json:"createAt"`
 	ReplyAt         utils.JSONTime `json:"replyAt"`
+	Attempt         int            `json:"attempt"`
+	NextRetryAt     int64          `json:"nextRetryAt,omitempty"`
 }
 
 type ActionResult struct {
@@ -113,6 +116,7 @@ func (o *ErrorHandler) NewActionRequest(login string, actiontype string, actionb
 			ActionBody:      actionbody,
 			Status:          status,
 			CreateAt:        utils.JSONTime{Time: time.Now()},
+			Attempt:         1,
 		}
 	}
 }
@@ -451,6 +455,8 @@ func (o *ErrorHandler) UpdateApiLog(db *mgo.Database, ar *ActionRequest) {
 		return
 	}
 
+	defer metrics.ObserveMongo(ApilogCollection, "upsert", time.Now())
+
 	col := db.C(ApilogCollection)
 
 	_, o.Err = col.Upsert(
@@ -462,6 +468,7 @@ func (o *ErrorHandler) UpdateApiLog(db *mgo.Database, ar *ActionRequest) {
 			"actionType":           ar.ActionType,
 			"actionRequestContent": o.FromJson(ar.ActionBody),
 			"actionReplyContent":   o.FromJson(ar.Result),
+			"actionReplyText":      ar.Result,
 			"status":               ar.Status,
 			"createAt":             ar.CreateAt.Time,
 			"updateAt":             time.Now(),
@@ -0,0 +1,186 @@
+package domains
+
+import (
+	"math"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+const (
+	// sorted set of action request ids, scored by the unix time they are due
+	ActionRetryKey string = "ARRETRY"
+)
+
+type RetryPolicy struct {
+	MaxAttempts   int     `yaml:"maxAttempts"`
+	Backoff       int64   `yaml:"backoff"`
+	BackoffFactor float64 `yaml:"backoffFactor"`
+	MaxBackoff    int64   `yaml:"maxBackoff"`
+	// retry when the bot never replied in time
+	RetryOnTimeout bool `yaml:"retryOnTimeout"`
+	// failure codes worth retrying, empty means any failure
+	RetryableCodes []int `yaml:"retryableCodes"`
+	// actions that cannot be repeated safely are never retried unless this is set
+	AllowNonIdempotent bool `yaml:"allowNonIdempotent"`
+}
+
+type ActionAttempt struct {
+	Attempt     int       `json:"attempt" bson:"attempt"`
+	Status      string    `json:"status" bson:"status"`
+	Code        int       `json:"code" bson:"code"`
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`
+	ClientId    string    `json:"clientId" bson:"clientId"`
+	At          time.Time `json:"at" bson:"at"`
+	NextRetryAt time.Time `json:"nextRetryAt,omitempty" bson:"nextRetryAt,omitempty"`
+}
+
+func (policy RetryPolicy) retryable(timedout bool, code int) bool {
+	if timedout {
+		return policy.RetryOnTimeout
+	}
+
+	if len(policy.RetryableCodes) == 0 {
+		return true
+	}
+
+	for _, c := range policy.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BackoffAfter returns the delay before the attempt following the given one.
+func (policy RetryPolicy) BackoffAfter(attempt int) time.Duration {
+	factor := policy.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	delay := float64(policy.Backoff) * math.Pow(factor, float64(attempt-1))
+	if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+
+	return time.Duration(delay) * time.Second
+}
+
+func (ar *ActionRequest) CurrentAttempt() int {
+	// records saved before attempts were counted
+	if ar.Attempt <= 0 {
+		return 1
+	}
+
+	return ar.Attempt
+}
+
+// ScheduleActionRetry records the failed attempt in apilog, and queues the
+// action request for another attempt if the policy allows it.
+// It returns whether a retry was scheduled.
+func (o *ErrorHandler) ScheduleActionRetry(conn redis.Conn, apilogdb *mgo.Database, ar *ActionRequest, policy RetryPolicy, timedout bool, code int, errmsg string) bool {
+	return o.scheduleActionRetry(conn, apilogdb, ar, policy, policy.retryable(timedout, code), code, errmsg)
+}
+
+// ScheduleRejectedActionRetry is ScheduleActionRetry for an attempt that
+// never reached the bot. There is no reply status to match retryableCodes
+// against, the caller tells whether the rejection is worth another attempt.
+func (o *ErrorHandler) ScheduleRejectedActionRetry(conn redis.Conn, apilogdb *mgo.Database, ar *ActionRequest, policy RetryPolicy, transient bool, errmsg string) bool {
+	return o.scheduleActionRetry(conn, apilogdb, ar, policy, transient, 0, errmsg)
+}
+
+func (o *ErrorHandler) scheduleActionRetry(conn redis.Conn, apilogdb *mgo.Database, ar *ActionRequest, policy RetryPolicy, retryable bool, code int, errmsg string) bool {
+	if o.Err != nil {
+		return false
+	}
+
+	now := time.Now()
+	attempt := ActionAttempt{
+		Attempt:  ar.CurrentAttempt(),
+		Status:   ar.Status,
+		Code:     code,
+		Error:    errmsg,
+		ClientId: ar.ClientId,
+		At:       now,
+	}
+
+	scheduled := ar.CurrentAttempt() < policy.MaxAttempts && retryable
+	ar.NextRetryAt = 0
+
+	if scheduled {
+		due := now.Add(policy.BackoffAfter(ar.CurrentAttempt()))
+		attempt.NextRetryAt = due
+		ar.NextRetryAt = due.Unix()
+
+		// keep the action request around until the retry picks it up
+		key := ar.redisKey()
+		ttl, _ := redis.Int64(o.RedisDo(conn, timeout, "TTL", key), nil)
+		if minttl := int64(due.Sub(now).Seconds()) + 60*60; ttl < minttl {
+			o.RedisDo(conn, timeout, "EXPIRE", key, minttl)
+		}
+
+		o.RedisDo(conn, timeout, "ZADD", ActionRetryKey, due.Unix(), ar.ActionRequestId)
+	}
+
+	o.AppendApiLogAttempt(apilogdb, ar, attempt)
+	if o.Err != nil {
+		return false
+	}
+
+	return scheduled
+}
+
+// ClaimDueActionRetries pops the action request ids whose retry is due.
+// ZREM decides the winner when several instances claim the same id.
+func (o *ErrorHandler) ClaimDueActionRetries(conn redis.Conn, limit int) []string {
+	if o.Err != nil {
+		return nil
+	}
+
+	ids, err := redis.Strings(o.RedisDo(conn, timeout, "ZRANGEBYSCORE",
+		ActionRetryKey, "-inf", time.Now().Unix(), "LIMIT", 0, limit), nil)
+	if o.Err != nil {
+		return nil
+	}
+	if err != nil {
+		o.Err = err
+		return nil
+	}
+
+	claimed := []string{}
+	for _, id := range ids {
+		removed, err := redis.Int(o.RedisDo(conn, timeout, "ZREM", ActionRetryKey, id), nil)
+		if o.Err != nil {
+			return claimed
+		}
+		if err == nil && removed == 1 {
+			claimed = append(claimed, id)
+		}
+	}
+
+	return claimed
+}
+
+func (o *ErrorHandler) AppendApiLogAttempt(db *mgo.Database, ar *ActionRequest, attempt ActionAttempt) {
+	if o.Err != nil {
+		return
+	}
+
+	defer metrics.ObserveMongo(ApilogCollection, "upsert", time.Now())
+
+	col := db.C(ApilogCollection)
+
+	_, o.Err = col.Upsert(
+		bson.M{"sys": "chathub", "actionRequestId": ar.ActionRequestId},
+		bson.M{
+			"$set":  bson.M{"attempt": ar.CurrentAttempt(), "updateAt": time.Now()},
+			"$push": bson.M{"attempts": attempt},
+		},
+	)
+}
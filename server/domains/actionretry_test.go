@@ -0,0 +1,54 @@
+package domains
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffAfter(t *testing.T) {
+	policy := RetryPolicy{Backoff: 30, BackoffFactor: 2, MaxBackoff: 100}
+
+	expected := []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second, 100 * time.Second}
+	for i, delay := range expected {
+		if got := policy.BackoffAfter(i + 1); got != delay {
+			t.Errorf("backoff after attempt %d is %s, expected %s", i+1, got, delay)
+		}
+	}
+}
+
+func TestBackoffAfterNoFactor(t *testing.T) {
+	// a factor below 1 would shrink the delay, it is taken as a fixed backoff
+	for _, factor := range []float64{0, 0.5, 1} {
+		policy := RetryPolicy{Backoff: 30, BackoffFactor: factor}
+		if got := policy.BackoffAfter(3); got != 30*time.Second {
+			t.Errorf("backoff with factor %v is %s, expected 30s", factor, got)
+		}
+	}
+
+	// no max backoff leaves the delay growing
+	policy := RetryPolicy{Backoff: 30, BackoffFactor: 2}
+	if got := policy.BackoffAfter(6); got != 960*time.Second {
+		t.Errorf("backoff after attempt 6 is %s, expected 960s", got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	policy := RetryPolicy{RetryOnTimeout: true}
+	if !policy.retryable(true, 0) {
+		t.Errorf("timeout should be retried with retryOnTimeout")
+	}
+	if !policy.retryable(false, 500) {
+		t.Errorf("empty retryable codes should retry any failure")
+	}
+
+	policy = RetryPolicy{RetryableCodes: []int{-1, 500}}
+	if policy.retryable(true, 500) {
+		t.Errorf("timeout should not be retried without retryOnTimeout, whatever the code")
+	}
+	if !policy.retryable(false, 500) || !policy.retryable(false, -1) {
+		t.Errorf("listed codes should be retried")
+	}
+	if policy.retryable(false, 404) || policy.retryable(false, 0) {
+		t.Errorf("codes not listed should not be retried")
+	}
+}
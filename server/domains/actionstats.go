@@ -0,0 +1,364 @@
+package domains
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	ActionRollupCollection string = "action_rollups"
+
+	// rollups are kept for 30 days
+	actionRollupTTL time.Duration = 30 * 24 * time.Hour
+)
+
+// ActionRollup is the latency and success rate of the actions of a type
+// created within window before ComputedAt, of a bot, or of all bots of a
+// client type when Login is empty.
+//
+// Latency is from the request to the reply, of the actions replied, done
+// or failed, its percentiles are estimated within 10%. Success rate is of the actions finished, done, failed or
+// timed out, the pending ones are only counted.
+type ActionRollup struct {
+	Id          bson.ObjectId `json:"-" bson:"_id"`
+	Window      string        `json:"window" bson:"window"`
+	ComputedAt  time.Time     `json:"computedAt" bson:"computedAt"`
+	Login       string        `json:"login" bson:"login"`
+	ClientType  string        `json:"clientType" bson:"clientType"`
+	ActionType  string        `json:"actionType" bson:"actionType"`
+	Done        int           `json:"done" bson:"done"`
+	Failed      int           `json:"failed" bson:"failed"`
+	Timeout     int           `json:"timeout" bson:"timeout"`
+	Pending     int           `json:"pending" bson:"pending"`
+	SuccessRate float64       `json:"successRate" bson:"successRate"`
+	P50Ms       float64       `json:"p50Ms" bson:"p50Ms"`
+	P95Ms       float64       `json:"p95Ms" bson:"p95Ms"`
+	P99Ms       float64       `json:"p99Ms" bson:"p99Ms"`
+}
+
+type ActionRollupCriteria struct {
+	Window     string
+	Logins     []string
+	ClientType string
+	ActionType string
+	From       time.Time
+	To         time.Time
+}
+
+type actionRollupKey struct {
+	login      string
+	clientType string
+	actionType string
+}
+
+// the latencies are counted in buckets growing by latencyBucketRatio, for
+// the percentiles to be estimated within 10% from bounded counts
+const latencyBucketRatio float64 = 1.1
+
+// latencyBucket is the bucket of a latency, as the rollup pipeline
+// computes it.
+func latencyBucket(latencyMs float64) int {
+	if latencyMs < 0 {
+		latencyMs = 0
+	}
+	return int(math.Floor(math.Log(latencyMs+1) / math.Log(latencyBucketRatio)))
+}
+
+// latencyBucketMs is the upper bound of the latencies of a bucket.
+func latencyBucketMs(bucket int) float64 {
+	return math.Pow(latencyBucketRatio, float64(bucket+1)) - 1
+}
+
+type actionRollupAcc struct {
+	rollup ActionRollup
+	// count of the replied actions per latency bucket
+	latencies map[int]int
+}
+
+func (acc *actionRollupAcc) add(status string, bucket int, n int) {
+	switch status {
+	case apiLogStatus["DONE"]:
+		acc.rollup.Done += n
+		acc.latencies[bucket] += n
+	case apiLogStatus["FAILED"]:
+		acc.rollup.Failed += n
+		acc.latencies[bucket] += n
+	case apiLogStatus["TIMEOUT"]:
+		acc.rollup.Timeout += n
+	default:
+		acc.rollup.Pending += n
+	}
+}
+
+func (acc *actionRollupAcc) finish() ActionRollup {
+	r := acc.rollup
+	if finished := r.Done + r.Failed + r.Timeout; finished > 0 {
+		r.SuccessRate = float64(r.Done) / float64(finished)
+	}
+
+	r.P50Ms = bucketPercentile(acc.latencies, 50)
+	r.P95Ms = bucketPercentile(acc.latencies, 95)
+	r.P99Ms = bucketPercentile(acc.latencies, 99)
+	return r
+}
+
+// percentile of sorted values by nearest rank, 0 when there are none.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// bucketPercentile is percentile of the latencies counted per bucket, the
+// upper bound of the bucket ranked, 0 when there are none.
+func bucketPercentile(buckets map[int]int, p float64) float64 {
+	total := 0
+	keys := make([]int, 0, len(buckets))
+	for bucket, n := range buckets {
+		total += n
+		keys = append(keys, bucket)
+	}
+	if total == 0 {
+		return 0
+	}
+	sort.Ints(keys)
+
+	rank := int(math.Ceil(p / 100 * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	seen := 0
+	for _, bucket := range keys {
+		seen += buckets[bucket]
+		if seen >= rank {
+			return latencyBucketMs(bucket)
+		}
+	}
+	return latencyBucketMs(keys[len(keys)-1])
+}
+
+func (o *ErrorHandler) EnsureActionRollupIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	col := db.C(ActionRollupCollection)
+	keys := [][]string{
+		[]string{"window", "-computedAt"},
+		[]string{"window", "login", "-computedAt"},
+	}
+
+	for _, key := range keys {
+		o.Err = col.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+
+	o.Err = col.EnsureIndex(mgo.Index{
+		Key:         []string{"computedAt"},
+		Background:  true,
+		ExpireAfter: actionRollupTTL,
+	})
+}
+
+// ComputeActionRollups rolls up the apilogs of the actions created within
+// window before now, per bot, action type and client type, and per action
+// type and client type over all bots. Mongo counts the apilogs, the
+// latency percentiles are estimated from the counts per latency bucket.
+func (o *ErrorHandler) ComputeActionRollups(db *mgo.Database, window time.Duration, now time.Time) []ActionRollup {
+	if o.Err != nil {
+		return nil
+	}
+
+	windowName := window.String()
+	accs := map[actionRollupKey]*actionRollupAcc{}
+	acc := func(key actionRollupKey) *actionRollupAcc {
+		if a, ok := accs[key]; ok {
+			return a
+		}
+
+		a := &actionRollupAcc{
+			rollup: ActionRollup{
+				Window:     windowName,
+				ComputedAt: now,
+				Login:      key.login,
+				ClientType: key.clientType,
+				ActionType: key.actionType,
+			},
+			latencies: map[int]int{},
+		}
+		accs[key] = a
+		return a
+	}
+
+	// the apilogs are counted in mongo per status and latency bucket, the
+	// latencies of the actions not replied in bucket -1
+	replied := bson.M{"$or": []bson.M{
+		bson.M{"$eq": []interface{}{"$status", apiLogStatus["DONE"]}},
+		bson.M{"$eq": []interface{}{"$status", apiLogStatus["FAILED"]}},
+	}}
+	latencyMs := bson.M{"$max": []interface{}{bson.M{"$subtract": []interface{}{"$updateAt", "$createAt"}}, 0}}
+	bucket := bson.M{"$floor": bson.M{"$divide": []interface{}{
+		bson.M{"$ln": bson.M{"$add": []interface{}{latencyMs, 1}}},
+		math.Log(latencyBucketRatio),
+	}}}
+
+	pipeline := []bson.M{
+		bson.M{"$match": bson.M{
+			"sys":      "chathub",
+			"createAt": bson.M{"$gte": now.Add(-window), "$lt": now},
+		}},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"login":      "$botWxId",
+				"clientType": "$clientType",
+				"actionType": "$actionType",
+				"status":     "$status",
+				"bucket":     bson.M{"$cond": []interface{}{replied, bucket, -1}},
+			},
+			"n": bson.M{"$sum": 1},
+		}},
+	}
+
+	type bucketCount struct {
+		Id struct {
+			Login      string  `bson:"login"`
+			ClientType string  `bson:"clientType"`
+			ActionType string  `bson:"actionType"`
+			Status     string  `bson:"status"`
+			Bucket     float64 `bson:"bucket"`
+		} `bson:"_id"`
+		N int `bson:"n"`
+	}
+
+	counts := []bucketCount{}
+	func() {
+		defer metrics.ObserveMongo(ApilogCollection, "aggregate", time.Now())
+		o.Err = db.C(ApilogCollection).Pipe(pipeline).AllowDiskUse().All(&counts)
+	}()
+	if o.Err != nil {
+		return nil
+	}
+
+	for _, c := range counts {
+		b := int(c.Id.Bucket)
+		acc(actionRollupKey{c.Id.Login, c.Id.ClientType, c.Id.ActionType}).add(c.Id.Status, b, c.N)
+		acc(actionRollupKey{"", c.Id.ClientType, c.Id.ActionType}).add(c.Id.Status, b, c.N)
+	}
+
+	rollups := make([]ActionRollup, 0, len(accs))
+	for _, a := range accs {
+		r := a.finish()
+		r.Id = bson.NewObjectId()
+		rollups = append(rollups, r)
+	}
+
+	return rollups
+}
+
+func (o *ErrorHandler) SaveActionRollups(db *mgo.Database, rollups []ActionRollup) {
+	if o.Err != nil || len(rollups) == 0 {
+		return
+	}
+
+	docs := make([]interface{}, len(rollups))
+	for i := range rollups {
+		docs[i] = rollups[i]
+	}
+
+	defer metrics.ObserveMongo(ActionRollupCollection, "insert", time.Now())
+	o.Err = db.C(ActionRollupCollection).Insert(docs...)
+}
+
+// LatestActionRollupAt returns when the rollups of the window were last
+// computed, zero when they never were.
+func (o *ErrorHandler) LatestActionRollupAt(db *mgo.Database, window string) time.Time {
+	if o.Err != nil {
+		return time.Time{}
+	}
+
+	defer metrics.ObserveMongo(ActionRollupCollection, "find", time.Now())
+
+	latest := ActionRollup{}
+	err := db.C(ActionRollupCollection).Find(bson.M{"window": window}).
+		Sort("-computedAt").Select(bson.M{"computedAt": 1}).One(&latest)
+	if err == mgo.ErrNotFound {
+		return time.Time{}
+	} else if err != nil {
+		o.Err = err
+		return time.Time{}
+	}
+
+	return latest.ComputedAt
+}
+
+// QueryActionRollups returns the rollups of the window computed between
+// From and To, of the logins, or of all bots when Logins is nil.
+func (o *ErrorHandler) QueryActionRollups(db *mgo.Database, criteria ActionRollupCriteria) []ActionRollup {
+	if o.Err != nil {
+		return nil
+	}
+
+	if criteria.Window == "" {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("window should not be empty"))
+		return nil
+	}
+
+	query := bson.M{"window": criteria.Window}
+
+	if criteria.Logins == nil {
+		query["login"] = ""
+	} else {
+		query["login"] = bson.M{"$in": criteria.Logins}
+	}
+
+	if criteria.ClientType != "" {
+		query["clientType"] = strings.ToUpper(criteria.ClientType)
+	}
+
+	if criteria.ActionType != "" {
+		query["actionType"] = criteria.ActionType
+	}
+
+	computedAt := bson.M{}
+	if !criteria.From.IsZero() {
+		computedAt["$gte"] = criteria.From
+	}
+	if !criteria.To.IsZero() {
+		computedAt["$lte"] = criteria.To
+	}
+	if len(computedAt) > 0 {
+		query["computedAt"] = computedAt
+	}
+
+	rollups := []ActionRollup{}
+	defer metrics.ObserveMongo(ActionRollupCollection, "find", time.Now())
+
+	o.Err = db.C(ActionRollupCollection).Find(query).
+		Sort("-computedAt", "clientType", "actionType", "login").All(&rollups)
+	if o.Err != nil {
+		return nil
+	}
+
+	return rollups
+}
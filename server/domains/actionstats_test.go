@@ -0,0 +1,79 @@
+package domains
+
+import (
+	"math"
+	"testing"
+)
+
+func latencyBuckets(latencies ...float64) map[int]int {
+	buckets := map[int]int{}
+	for _, latencyMs := range latencies {
+		buckets[latencyBucket(latencyMs)] += 1
+	}
+	return buckets
+}
+
+func TestLatencyBucket(t *testing.T) {
+	for _, latencyMs := range []float64{0, 1, 9, 120, 999, 45000, 600000} {
+		bucket := latencyBucket(latencyMs)
+		upper := latencyBucketMs(bucket)
+		if latencyMs > upper {
+			t.Errorf("%vms above the upper bound %vms of its bucket %d", latencyMs, upper, bucket)
+		}
+		if upper > latencyMs*latencyBucketRatio+1 {
+			t.Errorf("%vms estimated as %vms, more than 10%% off", latencyMs, upper)
+		}
+	}
+
+	if latencyBucket(-5) != latencyBucket(0) {
+		t.Errorf("negative latency should count as 0")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	if p := bucketPercentile(map[int]int{}, 50); p != 0 {
+		t.Errorf("percentile of nothing should be 0, got %v", p)
+	}
+
+	latencies := []float64{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, float64(i*10))
+	}
+	buckets := latencyBuckets(latencies...)
+
+	for _, c := range []struct {
+		p        float64
+		expected float64
+	}{{50, 500}, {95, 950}, {99, 990}, {100, 1000}, {1, 10}} {
+		got := bucketPercentile(buckets, c.p)
+		if got < c.expected || got > c.expected*latencyBucketRatio+1 {
+			t.Errorf("p%v is %v, expected about %v", c.p, got, c.expected)
+		}
+	}
+}
+
+func TestActionRollupAcc(t *testing.T) {
+	acc := &actionRollupAcc{latencies: map[int]int{}}
+	acc.add(apiLogStatus["DONE"], latencyBucket(100), 6)
+	acc.add(apiLogStatus["FAILED"], latencyBucket(2000), 2)
+	acc.add(apiLogStatus["TIMEOUT"], -1, 2)
+	acc.add(apiLogStatus["NEW"], -1, 5)
+
+	r := acc.finish()
+	if r.Done != 6 || r.Failed != 2 || r.Timeout != 2 || r.Pending != 5 {
+		t.Fatalf("counts %+v, expected 6 done, 2 failed, 2 timeout, 5 pending", r)
+	}
+
+	// the pending ones are only counted
+	if math.Abs(r.SuccessRate-0.6) > 1e-9 {
+		t.Errorf("success rate %v, expected 0.6", r.SuccessRate)
+	}
+
+	// the timed out ones have no latency
+	if r.P50Ms < 100 || r.P50Ms > 100*latencyBucketRatio+1 {
+		t.Errorf("p50 %vms, expected about 100ms", r.P50Ms)
+	}
+	if r.P99Ms < 2000 || r.P99Ms > 2000*latencyBucketRatio+1 {
+		t.Errorf("p99 %vms, expected about 2000ms", r.P99Ms)
+	}
+}
@@ -0,0 +1,200 @@
+package domains
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	// the bot has not pinged for threshold seconds
+	ALERT_BOTOFFLINE string = "BOTOFFLINE"
+	// the bot failed to log in
+	ALERT_LOGINFAILED string = "LOGINFAILED"
+	// the bot logged out
+	ALERT_LOGOUT string = "LOGOUT"
+	// the rate of failing actions of the bot, within window seconds, reaches threshold
+	ALERT_ACTIONFAILING string = "ACTIONFAILING"
+)
+
+var AlertKinds = []string{ALERT_BOTOFFLINE, ALERT_LOGINFAILED, ALERT_LOGOUT, ALERT_ACTIONFAILING}
+
+// AlertRule tells when to alert the account of its bot, or of all its bots
+// when BotId is null, and where to deliver the alert besides the global
+// webhooks of tasks.
+type AlertRule struct {
+	AlertRuleId    string         `db:"alertruleid"`
+	AccountId      string         `db:"accountid"`
+	BotId          sql.NullString `db:"botid"`
+	Kind           string         `db:"kind"`
+	Threshold      float64        `db:"threshold"`
+	Window         int            `db:"window"`
+	Webhook        sql.NullString `db:"webhook"`
+	NotifyCallback bool           `db:"notifycallback"`
+	CreateAt       mysql.NullTime `db:"createat"`
+	UpdateAt       mysql.NullTime `db:"updateat"`
+	DeleteAt       mysql.NullTime `db:"deleteat"`
+}
+
+func (o *ErrorHandler) CheckAlertKind(kind string) {
+	if o.Err != nil {
+		return
+	}
+
+	for _, k := range AlertKinds {
+		if k == kind {
+			return
+		}
+	}
+
+	o.Err = utils.NewClientError(utils.PARAM_INVALID,
+		fmt.Errorf("alert kind %s not supported, should be one of %v", kind, AlertKinds))
+}
+
+func (o *ErrorHandler) NewAlertRule(accountId string, botId string, kind string) *AlertRule {
+	if o.Err != nil {
+		return nil
+	}
+
+	var rid uuid.UUID
+	if rid, o.Err = uuid.NewRandom(); o.Err != nil {
+		return nil
+	} else {
+		return &AlertRule{
+			AlertRuleId:    rid.String(),
+			AccountId:      accountId,
+			BotId:          sql.NullString{String: botId, Valid: botId != ""},
+			Kind:           kind,
+			NotifyCallback: true,
+		}
+	}
+}
+
+func (o *ErrorHandler) SaveAlertRule(q dbx.Queryable, rule *AlertRule) {
+	if o.Err != nil {
+		return
+	}
+
+	query := "INSERT INTO alertrules " +
+		"(alertruleid, accountid, botid, kind, threshold, `window`, webhook, notifycallback) " +
+		" VALUES " +
+		"(:alertruleid, :accountid, :botid, :kind, :threshold, :window, :webhook, :notifycallback)"
+	ctx, _ := o.DefaultContext()
+	_, o.Err = q.NamedExecContext(ctx, query, rule)
+}
+
+func (o *ErrorHandler) UpdateAlertRule(q dbx.Queryable, rule *AlertRule) {
+	if o.Err != nil {
+		return
+	}
+
+	query := "UPDATE alertrules " +
+		"SET threshold = :threshold " +
+		", `window` = :window " +
+		", webhook = :webhook " +
+		", notifycallback = :notifycallback " +
+		"WHERE alertruleid = :alertruleid"
+
+	ctx, _ := o.DefaultContext()
+	_, o.Err = q.NamedExecContext(ctx, query, rule)
+}
+
+func (o *ErrorHandler) DeleteAlertRule(q dbx.Queryable, ruleId string) {
+	if o.Err != nil {
+		return
+	}
+
+	query := `UPDATE alertrules SET deleteat=CURRENT_TIMESTAMP WHERE alertruleid = ?`
+	ctx, _ := o.DefaultContext()
+	_, o.Err = q.ExecContext(ctx, query, ruleId)
+}
+
+func (o *ErrorHandler) GetAlertRuleById(q dbx.Queryable, ruleId string) *AlertRule {
+	if o.Err != nil {
+		return nil
+	}
+
+	rules := []AlertRule{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &rules,
+		`
+SELECT *
+FROM alertrules
+WHERE alertruleid=?
+  AND deleteat is NULL`, ruleId)
+
+	if rule := o.Head(rules, fmt.Sprintf("AlertRule %s more than one instance", ruleId)); rule != nil {
+		return rule.(*AlertRule)
+	} else {
+		return nil
+	}
+}
+
+func (o *ErrorHandler) GetAlertRulesByAccountName(q dbx.Queryable, accountName string) []AlertRule {
+	if o.Err != nil {
+		return nil
+	}
+
+	rules := []AlertRule{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &rules,
+		`
+SELECT r.*
+FROM alertrules as r
+LEFT JOIN accounts as a on r.accountid = a.accountid
+WHERE a.accountname=?
+  AND a.deleteat is NULL
+  AND r.deleteat is NULL
+ORDER BY r.createat desc`, accountName)
+
+	return rules
+}
+
+// GetAlertRules returns the rules of all accounts, for tasks to evaluate.
+func (o *ErrorHandler) GetAlertRules(q dbx.Queryable) []AlertRule {
+	if o.Err != nil {
+		return nil
+	}
+
+	rules := []AlertRule{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &rules,
+		`
+SELECT *
+FROM alertrules
+WHERE deleteat is NULL`)
+
+	return rules
+}
+
+func (o *ErrorHandler) CheckAlertRuleOwner(q dbx.Queryable, ruleId string, accountName string) {
+	if o.Err != nil {
+		return
+	}
+
+	rules := []AlertRule{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &rules,
+		`
+SELECT r.*
+FROM alertrules as r
+LEFT JOIN accounts as a on r.accountid = a.accountid
+WHERE a.accountname=?
+  AND r.alertruleid=?
+  AND a.deleteat is NULL
+  AND r.deleteat is NULL`, accountName, ruleId)
+
+	head := o.Head(rules, fmt.Sprintf("AlertRule %s more than one instance", ruleId))
+	if o.Err != nil {
+		return
+	}
+
+	if head == nil {
+		o.Err = utils.NewClientError(utils.RESOURCE_ACCESS_DENIED, fmt.Errorf("cannot access alert rule %s or not found", ruleId))
+	}
+}
@@ -0,0 +1,285 @@
+package domains
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	AlertCollection string = "alerts"
+
+	ALERT_FIRING   string = "FIRING"
+	ALERT_RESOLVED string = "RESOLVED"
+)
+
+// Alert is one firing of a rule for a bot, resolved later when the bot
+// recovers. There is at most one firing alert of a key at a time, so
+// the rule does not alert again while it keeps matching.
+type Alert struct {
+	Id         bson.ObjectId   `json:"alertId" bson:"_id"`
+	AlertKey   string          `json:"-" bson:"alertKey"`
+	RuleId     string          `json:"ruleId" bson:"ruleId"`
+	AccountId  string          `json:"-" bson:"accountId"`
+	BotId      string          `json:"botId" bson:"botId"`
+	Login      string          `json:"login" bson:"login"`
+	Kind       string          `json:"kind" bson:"kind"`
+	Status     string          `json:"status" bson:"status"`
+	Message    string          `json:"message" bson:"message"`
+	FiredAt    time.Time       `json:"firedAt" bson:"firedAt"`
+	ResolvedAt *time.Time      `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	Deliveries []AlertDelivery `json:"deliveries" bson:"deliveries"`
+}
+
+// AlertDelivery records one notification of the alert, of its firing or
+// of its resolving, to a webhook or to the bot callback.
+type AlertDelivery struct {
+	Target string    `json:"target" bson:"target"`
+	Url    string    `json:"url" bson:"url"`
+	Status string    `json:"status" bson:"status"`
+	At     time.Time `json:"at" bson:"at"`
+	Ok     bool      `json:"ok" bson:"ok"`
+	Error  string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+type AlertCriteria struct {
+	AccountId string
+	BotId     string
+	Kind      string
+	Status    string
+	From      time.Time
+	To        time.Time
+}
+
+func AlertKey(ruleId string, botId string) string {
+	return fmt.Sprintf("%s:%s", ruleId, botId)
+}
+
+func (o *ErrorHandler) EnsureAlertIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	col := db.C(AlertCollection)
+	keys := [][]string{
+		[]string{"alertKey", "status"},
+		[]string{"accountId", "-firedAt", "-_id"},
+		[]string{"accountId", "botId", "-firedAt", "-_id"},
+	}
+
+	for _, key := range keys {
+		o.Err = col.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+}
+
+func (o *ErrorHandler) NewAlert(ruleId string, accountId string, botId string, login string, kind string, message string) *Alert {
+	if o.Err != nil {
+		return nil
+	}
+
+	return &Alert{
+		Id:         bson.NewObjectId(),
+		AlertKey:   AlertKey(ruleId, botId),
+		RuleId:     ruleId,
+		AccountId:  accountId,
+		BotId:      botId,
+		Login:      login,
+		Kind:       kind,
+		Status:     ALERT_FIRING,
+		Message:    message,
+		FiredAt:    time.Now(),
+		Deliveries: []AlertDelivery{},
+	}
+}
+
+func (o *ErrorHandler) SaveAlert(db *mgo.Database, alert *Alert) {
+	if o.Err != nil {
+		return
+	}
+
+	defer metrics.ObserveMongo(AlertCollection, "insert", time.Now())
+	o.Err = db.C(AlertCollection).Insert(alert)
+}
+
+// GetFiringAlert returns the firing alert of the key, nil if there is none.
+func (o *ErrorHandler) GetFiringAlert(db *mgo.Database, alertKey string) *Alert {
+	if o.Err != nil {
+		return nil
+	}
+
+	defer metrics.ObserveMongo(AlertCollection, "find", time.Now())
+
+	alert := &Alert{}
+	err := db.C(AlertCollection).Find(bson.M{
+		"alertKey": alertKey,
+		"status":   ALERT_FIRING,
+	}).One(alert)
+	if err == mgo.ErrNotFound {
+		return nil
+	} else if err != nil {
+		o.Err = err
+		return nil
+	}
+
+	return alert
+}
+
+func (o *ErrorHandler) ResolveAlert(db *mgo.Database, alert *Alert) {
+	if o.Err != nil {
+		return
+	}
+
+	now := time.Now()
+	alert.Status = ALERT_RESOLVED
+	alert.ResolvedAt = &now
+
+	defer metrics.ObserveMongo(AlertCollection, "update", time.Now())
+	o.Err = db.C(AlertCollection).UpdateId(alert.Id, bson.M{"$set": bson.M{
+		"status":     alert.Status,
+		"resolvedAt": alert.ResolvedAt,
+	}})
+}
+
+func (o *ErrorHandler) AppendAlertDeliveries(db *mgo.Database, alert *Alert, deliveries []AlertDelivery) {
+	if o.Err != nil || len(deliveries) == 0 {
+		return
+	}
+
+	alert.Deliveries = append(alert.Deliveries, deliveries...)
+
+	defer metrics.ObserveMongo(AlertCollection, "update", time.Now())
+	o.Err = db.C(AlertCollection).UpdateId(alert.Id, bson.M{"$push": bson.M{
+		"deliveries": bson.M{"$each": deliveries},
+	}})
+}
+
+func (o *ErrorHandler) AlertCriteriaToBson(criteria AlertCriteria) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	query := bson.M{"accountId": criteria.AccountId}
+
+	if criteria.BotId != "" {
+		query["botId"] = criteria.BotId
+	}
+
+	if criteria.Kind != "" {
+		if o.CheckAlertKind(strings.ToUpper(criteria.Kind)); o.Err != nil {
+			return nil
+		}
+		query["kind"] = strings.ToUpper(criteria.Kind)
+	}
+
+	if criteria.Status != "" {
+		status := strings.ToUpper(criteria.Status)
+		if status != ALERT_FIRING && status != ALERT_RESOLVED {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("status %s not supported, should be one of FIRING, RESOLVED", criteria.Status))
+			return nil
+		}
+		query["status"] = status
+	}
+
+	firedAt := bson.M{}
+	if !criteria.From.IsZero() {
+		firedAt["$gte"] = criteria.From
+	}
+	if !criteria.To.IsZero() {
+		firedAt["$lt"] = criteria.To
+	}
+	if len(firedAt) > 0 {
+		query["firedAt"] = firedAt
+	}
+
+	return query
+}
+
+// the cursor carries firedAt and _id of the last alert returned, alerts are
+// listed in descending order of both, as apilogs are.
+func alertCursor(alert *Alert) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%d:%s", alert.FiredAt.UnixNano(), alert.Id.Hex())))
+}
+
+func (o *ErrorHandler) parseAlertCursor(cursor string) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	invalid := utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("cursor %s invalid", cursor))
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	t := strings.Split(string(raw), ":")
+	if len(t) != 2 || !bson.IsObjectIdHex(t[1]) {
+		o.Err = invalid
+		return nil
+	}
+
+	nano, err := strconv.ParseInt(t[0], 10, 64)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	firedAt := time.Unix(0, nano)
+	id := bson.ObjectIdHex(t[1])
+
+	return bson.M{"$or": []bson.M{
+		bson.M{"firedAt": bson.M{"$lt": firedAt}},
+		bson.M{"firedAt": firedAt, "_id": bson.M{"$lt": id}},
+	}}
+}
+
+// QueryAlerts returns at most limit alerts after cursor, and the cursor of
+// the next page, which is empty when there are no more alerts.
+func (o *ErrorHandler) QueryAlerts(db *mgo.Database, criteria AlertCriteria, cursor string, limit int) ([]Alert, string) {
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	query := o.AlertCriteriaToBson(criteria)
+	if cursor != "" {
+		if after := o.parseAlertCursor(cursor); after != nil {
+			query = bson.M{"$and": []bson.M{query, after}}
+		}
+	}
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	alerts := []Alert{}
+	defer metrics.ObserveMongo(AlertCollection, "find", time.Now())
+
+	o.Err = db.C(AlertCollection).Find(query).Sort("-firedAt", "-_id").Limit(limit + 1).All(&alerts)
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	next := ""
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+		next = alertCursor(&alerts[limit-1])
+	}
+
+	return alerts, next
+}
@@ -0,0 +1,259 @@
+package domains
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+type ApiLog struct {
+	Id                   bson.ObjectId   `json:"-" bson:"_id"`
+	ActionRequestId      string          `json:"actionRequestId" bson:"actionRequestId"`
+	Login                string          `json:"login" bson:"botWxId"`
+	ClientId             string          `json:"clientId" bson:"clientId"`
+	ClientType           string          `json:"clientType" bson:"clientType"`
+	ActionType           string          `json:"actionType" bson:"actionType"`
+	ActionRequestContent interface{}     `json:"actionRequestContent" bson:"actionRequestContent"`
+	ActionReplyContent   interface{}     `json:"actionReplyContent" bson:"actionReplyContent"`
+	ActionReplyText      string          `json:"actionReplyText" bson:"actionReplyText"`
+	Status               string          `json:"status" bson:"status"`
+	Attempt              int             `json:"attempt" bson:"attempt"`
+	Attempts             []ActionAttempt `json:"attempts" bson:"attempts"`
+	CreateAt             time.Time       `json:"createAt" bson:"createAt"`
+	UpdateAt             time.Time       `json:"updateAt" bson:"updateAt"`
+}
+
+type ApiLogCriteria struct {
+	Logins     []string
+	ActionType string
+	Status     string
+	From       time.Time
+	To         time.Time
+	Result     string
+}
+
+// status names accepted by the query api, mapped to what is stored in apilog
+var apiLogStatus = map[string]string{
+	"NEW":     "NEW",
+	"DONE":    "Done",
+	"FAILED":  "Failed",
+	"TIMEOUT": "TIMEOUT",
+}
+
+func (o *ErrorHandler) EnsureApiLogIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	col := db.C(ApilogCollection)
+	keys := [][]string{
+		[]string{"sys", "actionRequestId"},
+		[]string{"sys", "botWxId", "-createAt", "-_id"},
+		[]string{"sys", "-createAt", "-_id"},
+	}
+
+	for _, key := range keys {
+		o.Err = col.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+}
+
+func (o *ErrorHandler) ApiLogCriteriaToBson(criteria ApiLogCriteria) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	query := bson.M{
+		"sys":     "chathub",
+		"botWxId": bson.M{"$in": criteria.Logins},
+	}
+
+	if criteria.ActionType != "" {
+		query["actionType"] = criteria.ActionType
+	}
+
+	if criteria.Status != "" {
+		if status, ok := apiLogStatus[strings.ToUpper(criteria.Status)]; ok {
+			query["status"] = status
+		} else {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("status %s not supported, should be one of NEW, DONE, FAILED, TIMEOUT", criteria.Status))
+			return nil
+		}
+	}
+
+	createAt := bson.M{}
+	if !criteria.From.IsZero() {
+		createAt["$gte"] = criteria.From
+	}
+	if !criteria.To.IsZero() {
+		createAt["$lt"] = criteria.To
+	}
+	if len(createAt) > 0 {
+		query["createAt"] = createAt
+	}
+
+	if criteria.Result != "" {
+		result := bson.RegEx{
+			Pattern: regexp.QuoteMeta(criteria.Result),
+			Options: "i",
+		}
+		// records saved before actionReplyText have only the parsed reply,
+		// matched on the fields a failed reply tells why in
+		query["$or"] = []bson.M{
+			bson.M{"actionReplyText": result},
+			bson.M{"actionReplyText": bson.M{"$exists": false}, "actionReplyContent.message": result},
+			bson.M{"actionReplyText": bson.M{"$exists": false}, "actionReplyContent.error": result},
+		}
+	}
+
+	return query
+}
+
+// apiLogReplyText stands in for actionReplyText on records saved without it.
+func apiLogReplyText(content interface{}) string {
+	switch c := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	default:
+		if b, err := json.Marshal(c); err == nil {
+			return string(b)
+		}
+		return ""
+	}
+}
+
+// the cursor carries createAt and _id of the last record returned,
+// records are listed in descending order of both.
+func apiLogCursor(al *ApiLog) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%d:%s", al.CreateAt.UnixNano(), al.Id.Hex())))
+}
+
+func (o *ErrorHandler) parseApiLogCursor(cursor string) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	invalid := utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("cursor %s invalid", cursor))
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	t := strings.Split(string(raw), ":")
+	if len(t) != 2 || !bson.IsObjectIdHex(t[1]) {
+		o.Err = invalid
+		return nil
+	}
+
+	nano, err := strconv.ParseInt(t[0], 10, 64)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	createAt := time.Unix(0, nano)
+	id := bson.ObjectIdHex(t[1])
+
+	return bson.M{"$or": []bson.M{
+		bson.M{"createAt": bson.M{"$lt": createAt}},
+		bson.M{"createAt": createAt, "_id": bson.M{"$lt": id}},
+	}}
+}
+
+// QueryApiLogs returns at most limit records after cursor, and the cursor
+// of the next page, which is empty when there are no more records.
+func (o *ErrorHandler) QueryApiLogs(db *mgo.Database, criteria ApiLogCriteria, cursor string, limit int) ([]ApiLog, string) {
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	query := o.ApiLogCriteriaToBson(criteria)
+	if cursor != "" {
+		if after := o.parseApiLogCursor(cursor); after != nil {
+			query = bson.M{"$and": []bson.M{query, after}}
+		}
+	}
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	apilogs := []ApiLog{}
+	defer metrics.ObserveMongo(ApilogCollection, "find", time.Now())
+
+	o.Err = db.C(ApilogCollection).Find(query).Sort("-createAt", "-_id").Limit(limit + 1).All(&apilogs)
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	for i := range apilogs {
+		if apilogs[i].ActionReplyText == "" {
+			apilogs[i].ActionReplyText = apiLogReplyText(apilogs[i].ActionReplyContent)
+		}
+	}
+
+	next := ""
+	if len(apilogs) > limit {
+		apilogs = apilogs[:limit]
+		next = apiLogCursor(&apilogs[limit-1])
+	}
+
+	return apilogs, next
+}
+
+func (o *ErrorHandler) CountApiLogsByStatus(db *mgo.Database, criteria ApiLogCriteria) map[string]int {
+	if o.Err != nil {
+		return nil
+	}
+
+	query := o.ApiLogCriteriaToBson(criteria)
+	if o.Err != nil {
+		return nil
+	}
+
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+
+	defer metrics.ObserveMongo(ApilogCollection, "aggregate", time.Now())
+
+	o.Err = db.C(ApilogCollection).Pipe([]bson.M{
+		bson.M{"$match": query},
+		bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+	}).All(&groups)
+	if o.Err != nil {
+		return nil
+	}
+
+	counts := map[string]int{}
+	for name := range apiLogStatus {
+		counts[name] = 0
+	}
+
+	for _, g := range groups {
+		counts[strings.ToUpper(g.Status)] += g.Count
+	}
+
+	return counts
+}
@@ -0,0 +1,238 @@
+package domains
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	AuditLogCollection string = "audit_logs"
+
+	AUDIT_BOT_CREATE         string = "bot.create"
+	AUDIT_BOT_UPDATE         string = "bot.update"
+	AUDIT_BOT_DELETE         string = "bot.delete"
+	AUDIT_BOT_LOGIN          string = "bot.login"
+	AUDIT_BOT_LOGOUT         string = "bot.logout"
+	AUDIT_BOT_CLEARLOGININFO string = "bot.clearlogininfo"
+	AUDIT_CLIENT_RECOVER     string = "client.recover"
+	AUDIT_FILTER_CREATE      string = "filter.create"
+	AUDIT_FILTER_UPDATE      string = "filter.update"
+	AUDIT_FILTER_DELETE      string = "filter.delete"
+	AUDIT_ACCOUNT_LOGIN      string = "account.login"
+	AUDIT_TOKEN_SDK          string = "token.sdk"
+	AUDIT_TOKEN_CHILD        string = "token.child"
+	AUDIT_TOKEN_REFRESH      string = "token.refresh"
+
+	AUDIT_TARGET_ACCOUNT string = "account"
+	AUDIT_TARGET_BOT     string = "bot"
+	AUDIT_TARGET_CLIENT  string = "client"
+	AUDIT_TARGET_FILTER  string = "filter"
+)
+
+// AuditActor is who operated the account: the account itself, or a child
+// token issued by it, told apart by the child metadata.
+type AuditActor struct {
+	AccountName string `json:"accountName" bson:"accountName"`
+	ClientType  string `json:"clientType" bson:"clientType"`
+	// set when the operation is done with a child token
+	ChildMetadata string `json:"childMetadata,omitempty" bson:"childMetadata,omitempty"`
+	ChildAuthUrl  string `json:"childAuthUrl,omitempty" bson:"childAuthUrl,omitempty"`
+	ClientIp      string `json:"clientIp" bson:"clientIp"`
+	RequestId     string `json:"requestId,omitempty" bson:"requestId,omitempty"`
+}
+
+// AuditLog records one operation on the account, with the snapshots of
+// its target before and after, either nil when the target is created or
+// deleted.
+type AuditLog struct {
+	Id         bson.ObjectId `json:"auditId" bson:"_id"`
+	At         time.Time     `json:"at" bson:"at"`
+	AuditActor `bson:",inline"`
+	Action     string      `json:"action" bson:"action"`
+	TargetType string      `json:"targetType" bson:"targetType"`
+	TargetId   string      `json:"targetId" bson:"targetId"`
+	Before     interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After      interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+type AuditLogCriteria struct {
+	AccountName   string
+	Action        string
+	TargetType    string
+	TargetId      string
+	ChildMetadata string
+	From          time.Time
+	To            time.Time
+}
+
+func (o *ErrorHandler) EnsureAuditLogIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	col := db.C(AuditLogCollection)
+	keys := [][]string{
+		[]string{"accountName", "-at", "-_id"},
+		[]string{"accountName", "targetType", "targetId", "-at", "-_id"},
+		[]string{"accountName", "action", "-at", "-_id"},
+	}
+
+	for _, key := range keys {
+		o.Err = col.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+}
+
+func (o *ErrorHandler) NewAuditLog(actor AuditActor, action string, targetType string, targetId string, before interface{}, after interface{}) *AuditLog {
+	if o.Err != nil {
+		return nil
+	}
+
+	return &AuditLog{
+		Id:         bson.NewObjectId(),
+		At:         time.Now(),
+		AuditActor: actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Before:     before,
+		After:      after,
+	}
+}
+
+func (o *ErrorHandler) SaveAuditLogs(db *mgo.Database, logs []*AuditLog) {
+	if o.Err != nil || len(logs) == 0 {
+		return
+	}
+
+	docs := make([]interface{}, len(logs))
+	for i := range logs {
+		docs[i] = logs[i]
+	}
+
+	defer metrics.ObserveMongo(AuditLogCollection, "insert", time.Now())
+	o.Err = db.C(AuditLogCollection).Insert(docs...)
+}
+
+func (o *ErrorHandler) AuditLogCriteriaToBson(criteria AuditLogCriteria) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	query := bson.M{"accountName": criteria.AccountName}
+
+	if criteria.Action != "" {
+		query["action"] = strings.ToLower(criteria.Action)
+	}
+	if criteria.TargetType != "" {
+		query["targetType"] = strings.ToLower(criteria.TargetType)
+	}
+	if criteria.TargetId != "" {
+		query["targetId"] = criteria.TargetId
+	}
+	if criteria.ChildMetadata != "" {
+		query["childMetadata"] = criteria.ChildMetadata
+	}
+
+	at := bson.M{}
+	if !criteria.From.IsZero() {
+		at["$gte"] = criteria.From
+	}
+	if !criteria.To.IsZero() {
+		at["$lt"] = criteria.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	return query
+}
+
+// the cursor carries at and _id of the last audit log returned, listed in
+// descending order of both, as alerts are.
+func auditLogCursor(log *AuditLog) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%d:%s", log.At.UnixNano(), log.Id.Hex())))
+}
+
+func (o *ErrorHandler) parseAuditLogCursor(cursor string) bson.M {
+	if o.Err != nil {
+		return nil
+	}
+
+	invalid := utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("cursor %s invalid", cursor))
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	t := strings.Split(string(raw), ":")
+	if len(t) != 2 || !bson.IsObjectIdHex(t[1]) {
+		o.Err = invalid
+		return nil
+	}
+
+	nano, err := strconv.ParseInt(t[0], 10, 64)
+	if err != nil {
+		o.Err = invalid
+		return nil
+	}
+
+	at := time.Unix(0, nano)
+	id := bson.ObjectIdHex(t[1])
+
+	return bson.M{"$or": []bson.M{
+		bson.M{"at": bson.M{"$lt": at}},
+		bson.M{"at": at, "_id": bson.M{"$lt": id}},
+	}}
+}
+
+// QueryAuditLogs returns at most limit audit logs after cursor, latest
+// first, and the cursor of the next page, empty when there are no more.
+func (o *ErrorHandler) QueryAuditLogs(db *mgo.Database, criteria AuditLogCriteria, cursor string, limit int) ([]AuditLog, string) {
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	query := o.AuditLogCriteriaToBson(criteria)
+	if cursor != "" {
+		if after := o.parseAuditLogCursor(cursor); after != nil {
+			query = bson.M{"$and": []bson.M{query, after}}
+		}
+	}
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	logs := []AuditLog{}
+	defer metrics.ObserveMongo(AuditLogCollection, "find", time.Now())
+
+	o.Err = db.C(AuditLogCollection).Find(query).Sort("-at", "-_id").Limit(limit + 1).All(&logs)
+	if o.Err != nil {
+		return nil, ""
+	}
+
+	next := ""
+	if len(logs) > limit {
+		logs = logs[:limit]
+		next = auditLogCursor(&logs[limit-1])
+	}
+
+	return logs, next
+}
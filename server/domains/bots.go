@@ -202,6 +202,23 @@ WHERE a.accountname=?
 	return bots
 }
 
+func (o *ErrorHandler) GetBotsByAccountId(q dbx.Queryable, accountId string) []Bot {
+	if o.Err != nil {
+		return nil
+	}
+
+	bots := []Bot{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &bots,
+		`
+SELECT *
+FROM bots
+WHERE accountid=?
+  AND deleteat is NULL`, accountId)
+
+	return bots
+}
+
 func (o *ErrorHandler) GetBotById(q dbx.Queryable, botid string) *Bot {
 	if o.Err != nil {
 		return nil
@@ -299,6 +316,25 @@ WHERE login=?
 	}
 }
 
+// GetBotLogins returns the logins of the bots ever logged in, of all
+// accounts.
+func (o *ErrorHandler) GetBotLogins(q dbx.Queryable) []string {
+	if o.Err != nil {
+		return nil
+	}
+
+	logins := []string{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &logins,
+		`
+SELECT DISTINCT login
+FROM bots
+WHERE login <> ''
+  AND deleteat is NULL`)
+
+	return logins
+}
+
 func (o *ErrorHandler) CheckBotOwner(q dbx.Queryable, login string, accountName string) {
 	if o.Err != nil {
 		return
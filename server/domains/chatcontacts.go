@@ -159,7 +159,7 @@ func (o *ErrorHandler) SyncChatContact(q dbx.Queryable, botIds []string, lastId
 		mincount = o.getSameUpdateAtCount(q, lastChatContact)
 	}
 
-	fmt.Println("mincount", mincount)
+	logger.Debug("mincount %d", mincount)
 
 	if pagesize <= mincount {
 		pagesize = mincount + 1
@@ -6,6 +6,7 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"strings"
 	"time"
 )
@@ -56,6 +57,8 @@ func (o *ErrorHandler) EnsureChatRoomIndexes(db *mgo.Database) {
 func (o *ErrorHandler) GetChatRoomWithId(db *mgo.Database, roomId string) *pb.ChatRoom {
 	result := &pb.ChatRoom{}
 
+	defer metrics.ObserveMongo(ChatRoomCollection, "find", time.Now())
+
 	o.Err = db.C(ChatRoomCollection).Find(bson.M{
 		"_id": bson.ObjectIdHex(roomId),
 	}).One(result)
@@ -73,6 +76,8 @@ func (o *ErrorHandler) GetChatRoomWithId(db *mgo.Database, roomId string) *pb.Ch
 func (o *ErrorHandler) GetChatRoomWithPeerId(db *mgo.Database, botId string, peerId string) *pb.ChatRoom {
 	result := &pb.ChatRoom{}
 
+	defer metrics.ObserveMongo(ChatRoomCollection, "find", time.Now())
+
 	o.Err = db.C(ChatRoomCollection).Find(bson.M{
 		"botId":  botId,
 		"peerId": peerId,
@@ -117,6 +122,8 @@ func (o *ErrorHandler) GetChatRooms(db *mgo.Database, botIds []string, chatType
 		criteria["chatType"] = chatType
 	}
 
+	defer metrics.ObserveMongo(ChatRoomCollection, "find", time.Now())
+
 	query := db.C(ChatRoomCollection).Find(criteria).Sort("-updatedAt").Limit(int(limit))
 
 	var result []*pb.ChatRoom
@@ -148,6 +155,8 @@ func (o *ErrorHandler) UpdateOrCreateChatRoom(db *mgo.Database, botId string, pe
 		chatType = "group"
 	}
 
+	defer metrics.ObserveMongo(ChatRoomCollection, "upsert", time.Now())
+
 	_, o.Err = db.C(ChatRoomCollection).Upsert(bson.M{
 		"botId":  botId,
 		"peerId": peerId,
@@ -163,6 +172,8 @@ func (o *ErrorHandler) UpdateOrCreateChatRoom(db *mgo.Database, botId string, pe
 }
 
 func (o *ErrorHandler) UpdateChatRoomLastReadMsgId(db *mgo.Database, botId string, peerId string, msgId string) {
+	defer metrics.ObserveMongo(ChatRoomCollection, "update", time.Now())
+
 	o.Err = db.C(ChatRoomCollection).Update(bson.M{
 		"botId":  botId,
 		"peerId": peerId,
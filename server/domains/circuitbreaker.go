@@ -0,0 +1,286 @@
+package domains
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	BreakerClosed   string = "CLOSED"
+	BreakerOpen     string = "OPEN"
+	BreakerHalfOpen string = "HALFOPEN"
+)
+
+// ActionBreaker guards one action type of one bot.
+// CLOSED lets every action through; OPEN rejects them until
+// HealthCheckConfig.RecoverTime has passed; then HALFOPEN lets a single probe
+// action through, whose result closes or reopens the breaker.
+type ActionBreaker struct {
+	Login      string `json:"login" redis:"login"`
+	ActionType string `json:"actionType" redis:"actionType"`
+	State      string `json:"state" redis:"state"`
+	OpenedAt   int64  `json:"openedAt" redis:"openedAt"`
+	ProbeId    string `json:"probeId" redis:"probeId"`
+	ProbeAt    int64  `json:"probeAt" redis:"probeAt"`
+	ChangedAt  int64  `json:"changedAt" redis:"changedAt"`
+}
+
+type BreakerTransition struct {
+	Login      string `json:"login"`
+	ActionType string `json:"actionType"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Reason     string `json:"reason"`
+	At         int64  `json:"at"`
+}
+
+func breakerRedisKey(login string, actionType string) string {
+	return fmt.Sprintf("BREAKER:%s:%s", login, actionType)
+}
+
+func (o *ErrorHandler) GetActionBreaker(conn redis.Conn, login string, actionType string) *ActionBreaker {
+	if o.Err != nil {
+		return nil
+	}
+
+	breaker := &ActionBreaker{}
+	ret := o.RedisValue(o.RedisDo(conn, timeout, "HGETALL", breakerRedisKey(login, actionType)))
+	if o.Err != nil {
+		return nil
+	}
+
+	o.Err = redis.ScanStruct(ret, breaker)
+	if o.Err != nil {
+		return nil
+	}
+
+	breaker.Login = login
+	breaker.ActionType = actionType
+	if breaker.State == "" {
+		breaker.State = BreakerClosed
+	}
+
+	return breaker
+}
+
+// the tries to update a breaker changed by another web meanwhile
+const breakerUpdateAttempts int = 5
+
+// breakerIndexKey lists the action types of the breakers of a bot not
+// closed, so that the breakers of an account are found without a scan.
+func breakerIndexKey(login string) string {
+	return fmt.Sprintf("BREAKERS:%s", login)
+}
+
+// updateActionBreaker reads the breaker, lets update change it, and saves
+// it unless it changed meanwhile, in which case it tries again. update
+// returns false when there is nothing to save. The returned transition is
+// nil unless the breaker state changed.
+func (o *ErrorHandler) updateActionBreaker(conn redis.Conn, login string, actionType string,
+	update func(breaker *ActionBreaker, now int64) (bool, string, error)) *BreakerTransition {
+	if o.Err != nil {
+		return nil
+	}
+
+	key := breakerRedisKey(login, actionType)
+	for attempt := 0; attempt < breakerUpdateAttempts; attempt++ {
+		o.RedisDo(conn, timeout, "WATCH", key)
+		breaker := o.GetActionBreaker(conn, login, actionType)
+		if o.Err != nil {
+			return nil
+		}
+
+		from := breaker.State
+		now := time.Now().Unix()
+		save, reason, err := update(breaker, now)
+		if err != nil || !save {
+			o.RedisDo(conn, timeout, "UNWATCH")
+			if o.Err == nil {
+				o.Err = err
+			}
+			return nil
+		}
+
+		if breaker.State != from {
+			breaker.ChangedAt = now
+		}
+
+		o.RedisSend(conn, "MULTI")
+		if breaker.State == BreakerClosed {
+			o.RedisSend(conn, "DEL", key)
+			o.RedisSend(conn, "SREM", breakerIndexKey(login), actionType)
+		} else {
+			o.RedisSend(conn, "HMSET", redis.Args{}.Add(key).AddFlat(breaker)...)
+			o.RedisSend(conn, "SADD", breakerIndexKey(login), actionType)
+		}
+		ret := o.RedisDo(conn, timeout, "EXEC")
+		if o.Err != nil {
+			return nil
+		}
+
+		// the breaker changed since it was read
+		if ret == nil {
+			continue
+		}
+
+		if breaker.State == from {
+			return nil
+		}
+
+		return &BreakerTransition{
+			Login:      login,
+			ActionType: actionType,
+			From:       from,
+			To:         breaker.State,
+			Reason:     reason,
+			At:         now,
+		}
+	}
+
+	o.Err = fmt.Errorf("breaker of %s %s keeps changing, call later", login, actionType)
+	return nil
+}
+
+// breakerAllow lets ar through the breaker, or returns why not. It returns
+// true when the breaker changed and should be saved.
+func breakerAllow(breaker *ActionBreaker, ar *ActionRequest, check HealthCheckConfig, now int64) (bool, string, error) {
+	switch breaker.State {
+	case BreakerOpen:
+		if now-breaker.OpenedAt < check.RecoverTime {
+			return false, "", fmt.Errorf("action %s of %s is failing, call later", ar.ActionType, ar.Login)
+		}
+
+		breaker.State = BreakerHalfOpen
+		breaker.ProbeId = ar.ActionRequestId
+		breaker.ProbeAt = now
+		return true, "recover time passed, probing", nil
+
+	case BreakerHalfOpen:
+		// a probe that never reported back does not hold the breaker forever
+		if breaker.ProbeId != "" && now-breaker.ProbeAt < check.RecoverTime {
+			return false, "", fmt.Errorf("action %s of %s is probing, call later", ar.ActionType, ar.Login)
+		}
+
+		breaker.ProbeId = ar.ActionRequestId
+		breaker.ProbeAt = now
+		return true, "", nil
+	}
+
+	return false, "", nil
+}
+
+// breakerReport feeds the outcome of ar into the breaker, failing tells
+// whether the failures of the action exceed the thresholds. It returns
+// true when the breaker changed and should be saved.
+func breakerReport(breaker *ActionBreaker, ar *ActionRequest, success bool, failing bool, now int64) (bool, string) {
+	switch breaker.State {
+	case BreakerHalfOpen:
+		if breaker.ProbeId != ar.ActionRequestId {
+			return false, ""
+		}
+
+		if success {
+			breaker.State = BreakerClosed
+			return true, "probe succeeded"
+		}
+
+		breaker.State = BreakerOpen
+		breaker.OpenedAt = now
+		breaker.ProbeId = ""
+		return true, "probe failed"
+
+	case BreakerClosed:
+		if success || !failing {
+			return false, ""
+		}
+
+		breaker.State = BreakerOpen
+		breaker.OpenedAt = now
+		return true, "failing threshold exceeded"
+	}
+
+	return false, ""
+}
+
+// BreakerAllow decides whether ar may be sent to the bot. When an open
+// breaker has waited long enough, ar becomes the half-open probe, so it is
+// to be called right before ar is sent, once nothing else may reject it.
+// The returned transition is nil unless the breaker state changed.
+func (o *ErrorHandler) BreakerAllow(conn redis.Conn, ar *ActionRequest, check HealthCheckConfig) *BreakerTransition {
+	return o.updateActionBreaker(conn, ar.Login, ar.ActionType,
+		func(breaker *ActionBreaker, now int64) (bool, string, error) {
+			return breakerAllow(breaker, ar, check, now)
+		})
+}
+
+// BreakerReport feeds the outcome of ar into its breaker.
+// Failures are expected to be saved by SaveFailingActionRequest before
+// reporting, so that its FAILING:* entry tells whether the thresholds
+// in HealthCheckConfig are exceeded.
+func (o *ErrorHandler) BreakerReport(conn redis.Conn, ar *ActionRequest, success bool) *BreakerTransition {
+	if o.Err != nil {
+		return nil
+	}
+
+	fb := o.NewFailingBot(ar)
+	failing := false
+	if !success {
+		score := o.RedisDo(conn, timeout, "ZSCORE", fb.actionRedisKey(), ar.ActionType)
+		failing = score != nil
+	}
+
+	transition := o.updateActionBreaker(conn, ar.Login, ar.ActionType,
+		func(breaker *ActionBreaker, now int64) (bool, string, error) {
+			save, reason := breakerReport(breaker, ar, success, failing, now)
+			return save, reason, nil
+		})
+
+	if transition != nil && transition.To == BreakerClosed {
+		o.RecoverAction(conn, fb.actionRedisKey(), ar.ActionType)
+	}
+
+	return transition
+}
+
+// ResetActionBreaker closes the breaker by hand, e.g. from /botactions/recoveraction.
+func (o *ErrorHandler) ResetActionBreaker(conn redis.Conn, login string, actionType string) *BreakerTransition {
+	return o.updateActionBreaker(conn, login, actionType,
+		func(breaker *ActionBreaker, now int64) (bool, string, error) {
+			if breaker.State == BreakerClosed {
+				return false, "", nil
+			}
+
+			breaker.State = BreakerClosed
+			return true, "reset", nil
+		})
+}
+
+// GetActionBreakers returns the breakers of the bots of logins not closed.
+func (o *ErrorHandler) GetActionBreakers(conn redis.Conn, logins []string) []*ActionBreaker {
+	if o.Err != nil {
+		return []*ActionBreaker{}
+	}
+
+	breakers := []*ActionBreaker{}
+	for _, login := range logins {
+		ret := o.RedisValue(o.RedisDo(conn, timeout, "SMEMBERS", breakerIndexKey(login)))
+		for _, actionType := range ret {
+			breaker := o.GetActionBreaker(conn, login, o.RedisString(actionType))
+			if o.Err != nil {
+				return []*ActionBreaker{}
+			}
+
+			if breaker.State != BreakerClosed {
+				breakers = append(breakers, breaker)
+			}
+		}
+
+		if o.Err != nil {
+			return []*ActionBreaker{}
+		}
+	}
+
+	return breakers
+}
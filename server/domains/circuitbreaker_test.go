@@ -0,0 +1,103 @@
+package domains
+
+import (
+	"testing"
+)
+
+func TestBreakerAllowClosed(t *testing.T) {
+	breaker := &ActionBreaker{State: BreakerClosed}
+	ar := &ActionRequest{ActionRequestId: "ar1", Login: "login", ActionType: "SendTextMessage"}
+
+	save, _, err := breakerAllow(breaker, ar, HealthCheckConfig{RecoverTime: 60}, 1000)
+	if err != nil || save {
+		t.Errorf("closed breaker should let ar through unchanged, got save %v err %v", save, err)
+	}
+}
+
+func TestBreakerAllowOpen(t *testing.T) {
+	check := HealthCheckConfig{RecoverTime: 60}
+	ar := &ActionRequest{ActionRequestId: "ar1", Login: "login", ActionType: "SendTextMessage"}
+
+	breaker := &ActionBreaker{State: BreakerOpen, OpenedAt: 1000}
+	if _, _, err := breakerAllow(breaker, ar, check, 1030); err == nil {
+		t.Errorf("open breaker should reject ar before recover time")
+	}
+	if breaker.State != BreakerOpen {
+		t.Errorf("rejected ar should leave the breaker %s, got %s", BreakerOpen, breaker.State)
+	}
+
+	save, _, err := breakerAllow(breaker, ar, check, 1060)
+	if err != nil || !save {
+		t.Fatalf("open breaker should probe after recover time, got save %v err %v", save, err)
+	}
+	if breaker.State != BreakerHalfOpen || breaker.ProbeId != "ar1" || breaker.ProbeAt != 1060 {
+		t.Errorf("breaker should be probing with ar1, got %+v", breaker)
+	}
+}
+
+func TestBreakerAllowHalfOpen(t *testing.T) {
+	check := HealthCheckConfig{RecoverTime: 60}
+	breaker := &ActionBreaker{State: BreakerHalfOpen, ProbeId: "ar1", ProbeAt: 1000}
+
+	ar2 := &ActionRequest{ActionRequestId: "ar2", Login: "login", ActionType: "SendTextMessage"}
+	if _, _, err := breakerAllow(breaker, ar2, check, 1010); err == nil {
+		t.Errorf("half open breaker should reject ar while probing")
+	}
+
+	// the probe never reported back
+	save, _, err := breakerAllow(breaker, ar2, check, 1060)
+	if err != nil || !save {
+		t.Fatalf("stale probe should be replaced, got save %v err %v", save, err)
+	}
+	if breaker.State != BreakerHalfOpen || breaker.ProbeId != "ar2" {
+		t.Errorf("breaker should be probing with ar2, got %+v", breaker)
+	}
+}
+
+func TestBreakerReportProbe(t *testing.T) {
+	ar1 := &ActionRequest{ActionRequestId: "ar1"}
+	ar2 := &ActionRequest{ActionRequestId: "ar2"}
+
+	breaker := &ActionBreaker{State: BreakerHalfOpen, ProbeId: "ar1"}
+	if save, _ := breakerReport(breaker, ar2, true, false, 1000); save {
+		t.Errorf("only the probe should close the breaker")
+	}
+
+	if save, _ := breakerReport(breaker, ar1, true, false, 1000); !save || breaker.State != BreakerClosed {
+		t.Errorf("succeeded probe should close the breaker, got %+v", breaker)
+	}
+
+	breaker = &ActionBreaker{State: BreakerHalfOpen, ProbeId: "ar1"}
+	if save, _ := breakerReport(breaker, ar1, false, false, 1000); !save {
+		t.Fatalf("failed probe should reopen the breaker")
+	}
+	if breaker.State != BreakerOpen || breaker.OpenedAt != 1000 || breaker.ProbeId != "" {
+		t.Errorf("failed probe should reopen the breaker from now, got %+v", breaker)
+	}
+}
+
+func TestBreakerReportClosed(t *testing.T) {
+	ar := &ActionRequest{ActionRequestId: "ar1"}
+
+	breaker := &ActionBreaker{State: BreakerClosed}
+	if save, _ := breakerReport(breaker, ar, false, false, 1000); save {
+		t.Errorf("failure below the thresholds should keep the breaker closed")
+	}
+	if save, _ := breakerReport(breaker, ar, true, true, 1000); save {
+		t.Errorf("success should keep the breaker closed")
+	}
+
+	if save, _ := breakerReport(breaker, ar, false, true, 1000); !save {
+		t.Fatalf("failure over the thresholds should open the breaker")
+	}
+	if breaker.State != BreakerOpen || breaker.OpenedAt != 1000 {
+		t.Errorf("breaker should be open from now, got %+v", breaker)
+	}
+}
+
+func TestBreakerReportOpen(t *testing.T) {
+	breaker := &ActionBreaker{State: BreakerOpen, OpenedAt: 1000}
+	if save, _ := breakerReport(breaker, &ActionRequest{ActionRequestId: "ar1"}, true, false, 1010); save {
+		t.Errorf("open breaker only changes by its probe, got %+v", breaker)
+	}
+}
@@ -0,0 +1,165 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+const (
+	EventJournalCollection string = "event_journal"
+
+	JOURNAL_IN  string = "IN"
+	JOURNAL_OUT string = "OUT"
+)
+
+// JournalEntry is a raw tunnel event as the hub received it from, or sent
+// it to, a client.
+type JournalEntry struct {
+	Id         bson.ObjectId `json:"-" bson:"_id"`
+	At         time.Time     `json:"at" bson:"at"`
+	Direction  string        `json:"direction" bson:"direction"`
+	BotId      string        `json:"botId" bson:"botId"`
+	Login      string        `json:"login" bson:"login"`
+	ClientId   string        `json:"clientId" bson:"clientId"`
+	ClientType string        `json:"clientType" bson:"clientType"`
+	EventType  string        `json:"eventType" bson:"eventType"`
+	Body       string        `json:"body" bson:"body"`
+}
+
+type JournalCriteria struct {
+	BotId      string
+	ClientId   string
+	Direction  string
+	EventTypes []string
+	From       time.Time
+	To         time.Time
+}
+
+// Match tells whether the entry is in the slice the criteria selects, for
+// the journals read from files.
+func (criteria JournalCriteria) Match(entry *JournalEntry) bool {
+	if criteria.BotId != "" && entry.BotId != criteria.BotId {
+		return false
+	}
+	if criteria.ClientId != "" && entry.ClientId != criteria.ClientId {
+		return false
+	}
+	if criteria.Direction != "" && entry.Direction != criteria.Direction {
+		return false
+	}
+	if len(criteria.EventTypes) > 0 {
+		found := false
+		for _, eventType := range criteria.EventTypes {
+			if entry.EventType == eventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !criteria.From.IsZero() && entry.At.Before(criteria.From) {
+		return false
+	}
+	if !criteria.To.IsZero() && !entry.At.Before(criteria.To) {
+		return false
+	}
+
+	return true
+}
+
+func (criteria JournalCriteria) toBson() bson.M {
+	query := bson.M{}
+
+	if criteria.BotId != "" {
+		query["botId"] = criteria.BotId
+	}
+	if criteria.ClientId != "" {
+		query["clientId"] = criteria.ClientId
+	}
+	if criteria.Direction != "" {
+		query["direction"] = criteria.Direction
+	}
+	if len(criteria.EventTypes) > 0 {
+		query["eventType"] = bson.M{"$in": criteria.EventTypes}
+	}
+
+	at := bson.M{}
+	if !criteria.From.IsZero() {
+		at["$gte"] = criteria.From
+	}
+	if !criteria.To.IsZero() {
+		at["$lt"] = criteria.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	return query
+}
+
+// EnsureEventJournalIndexes indexes the journal, which mongo expires
+// retention after the events.
+func (o *ErrorHandler) EnsureEventJournalIndexes(db *mgo.Database, retention time.Duration) {
+	if o.Err != nil {
+		return
+	}
+
+	col := db.C(EventJournalCollection)
+	keys := [][]string{
+		[]string{"botId", "at"},
+		[]string{"clientId", "at"},
+	}
+
+	for _, key := range keys {
+		o.Err = col.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+
+	o.Err = col.EnsureIndex(mgo.Index{
+		Key:         []string{"at"},
+		Background:  true,
+		ExpireAfter: retention,
+	})
+}
+
+func (o *ErrorHandler) SaveJournalEntries(db *mgo.Database, entries []JournalEntry) {
+	if o.Err != nil || len(entries) == 0 {
+		return
+	}
+
+	docs := make([]interface{}, len(entries))
+	for i := range entries {
+		docs[i] = entries[i]
+	}
+
+	defer metrics.ObserveMongo(EventJournalCollection, "insert", time.Now())
+	o.Err = db.C(EventJournalCollection).Insert(docs...)
+}
+
+// QueryJournal returns at most limit entries of the slice, oldest first.
+func (o *ErrorHandler) QueryJournal(db *mgo.Database, criteria JournalCriteria, limit int) []JournalEntry {
+	if o.Err != nil {
+		return nil
+	}
+
+	entries := []JournalEntry{}
+	defer metrics.ObserveMongo(EventJournalCollection, "find", time.Now())
+
+	o.Err = db.C(EventJournalCollection).Find(criteria.toBson()).Sort("at", "_id").Limit(limit).All(&entries)
+	if o.Err != nil {
+		return nil
+	}
+
+	return entries
+}
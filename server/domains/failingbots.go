@@ -247,6 +247,18 @@ func (o *ErrorHandler) ActionRequestCountByTime(conn redis.Conn, keyPattern stri
 	return o.RedisMatchCountCond(conn, keyPattern, cmp)
 }
 
+// LoginActionCounts returns how many actions of the login failed, and how
+// many were requested, within the last checkTimeout seconds.
+func (o *ErrorHandler) LoginActionCounts(conn redis.Conn, login string, checkTimeout int64) (int, int) {
+	if o.Err != nil {
+		return 0, 0
+	}
+
+	failed := o.FailingActionCount(conn, fmt.Sprintf("ARFAIL:*:*:%s:*", login), checkTimeout)
+	total := o.ActionRequestCountByTime(conn, fmt.Sprintf("ARHOUR:%s:*", login), checkTimeout)
+	return failed, total
+}
+
 func (o *ErrorHandler) SaveFailingActionRequest(conn redis.Conn, ar *ActionRequest, actionCheck, botCheck HealthCheckConfig) {
 	if o.Err != nil {
 		return
@@ -258,11 +270,12 @@ func (o *ErrorHandler) SaveFailingActionRequest(conn redis.Conn, ar *ActionReque
 	}
 
 	fb := o.NewFailingBot(ar)
+	arLogger := logger.With("arId", ar.ActionRequestId, "login", ar.Login)
 
 	count := o.FailingActionCount(conn, ar.redisFailKeyPattern(), actionCheck.CheckTime)
 	ncount := o.ActionRequestCountByTime(conn, ar.redisHourKeyPattern(), actionCheck.CheckTime)
 
-	fmt.Printf("[action healthy debug] faCount %d, nCount %d\n", count, ncount)
+	arLogger.Debug("[action healthy] faCount %d, nCount %d", count, ncount)
 
 	if ncount > 0 {
 		rate := float64(count) / float64(ncount)
@@ -272,11 +285,11 @@ func (o *ErrorHandler) SaveFailingActionRequest(conn redis.Conn, ar *ActionReque
 		}
 	}
 
-	fmt.Printf("[action healthy debug] key %s\n", ar.redisHourLoginKeyPattern())
+	arLogger.Debug("[action healthy] key %s", ar.redisHourLoginKeyPattern())
 
 	bcount := o.FailingActionCount(conn, ar.redisFailKeyBotPattern(), botCheck.CheckTime)
 	bncount := o.ActionRequestCountByTime(conn, ar.redisHourLoginKeyPattern(), botCheck.CheckTime)
-	fmt.Printf("[action healthy debug] fbCount %d, nCount %d\n", bcount, bncount)
+	arLogger.Debug("[action healthy] fbCount %d, nCount %d", bcount, bncount)
 
 	if bncount > 0 {
 		rate := float64(bcount) / float64(bncount)
@@ -311,21 +324,7 @@ func (o *ErrorHandler) ActionIsHealthy(conn redis.Conn, ar *ActionRequest) bool
 		}
 	}
 
-	fas := o.RedisValue(o.RedisDo(conn, timeout, "ZRANGE", akey, "0", "-1"))
-	if o.Err != nil {
-		return false
-	}
-
-	for _, k := range fas {
-		switch key := k.(type) {
-		case []uint8:
-			if ar.ActionType == string(key) {
-				o.Err = fmt.Errorf("action %s of %s is failing, call later", ar.ActionType, akey)
-				return false
-			}
-		}
-	}
-
+	// failing actions are guarded by their breaker, see BreakerAllow
 	return true
 }
 
@@ -104,6 +104,27 @@ WHERE filterid=?
 	}
 }
 
+// GetFilterByIdNull returns the filter even if deleted.
+func (o *ErrorHandler) GetFilterByIdNull(q dbx.Queryable, filterid string) *Filter {
+	if o.Err != nil {
+		return nil
+	}
+
+	filters := []Filter{}
+	ctx, _ := o.DefaultContext()
+	o.Err = q.SelectContext(ctx, &filters,
+		`
+SELECT *
+FROM filters
+WHERE filterid=?`, filterid)
+
+	if filter := o.Head(filters, fmt.Sprintf("Filter %s more than one instance", filterid)); filter != nil {
+		return filter.(*Filter)
+	} else {
+		return nil
+	}
+}
+
 func (o *ErrorHandler) GetFilterByAccountId(q dbx.Queryable, accountid string) []Filter {
 	if o.Err != nil {
 		return nil
@@ -139,7 +160,7 @@ WHERE a.accountname=?
   AND a.deleteat is NULL
   AND f.deleteat is NULL`, accountName, filterId)
 
-	fmt.Printf("check filter owner %v\n", filters)
+	logger.Debug("check filter owner %v", filters)
 
 	head := o.Head(filters, fmt.Sprintf("Filter %s more than one instance", filterId))
 	if o.Err != nil {
@@ -0,0 +1,220 @@
+package domains
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	BotOwnerByClient string = "client"
+	BotOwnerByLogin  string = "login"
+	BotOwnerByBot    string = "bot"
+
+	HubDefaultLease int = 30
+)
+
+// HubInstance is one running chathub process, registered with a lease so
+// that crashed instances drop out of the cluster by themselves.
+type HubInstance struct {
+	InstanceId string `json:"instanceId"`
+	Addr       string `json:"addr"`
+	StartAt    int64  `json:"startAt"`
+	Bots       int    `json:"bots"`
+	UpdateAt   int64  `json:"updateAt"`
+}
+
+// release only if the lease is still ours, a newer owner may have taken it
+var releaseOwnerScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func hubInstanceKey(instanceId string) string {
+	return fmt.Sprintf("HUBINSTANCE:%s", instanceId)
+}
+
+func botOwnerKey(kind string, id string) string {
+	return fmt.Sprintf("BOTOWNER:%s:%s", kind, id)
+}
+
+func (o *ErrorHandler) RegisterHubInstance(conn redis.Conn, instance *HubInstance, lease int) {
+	if o.Err != nil {
+		return
+	}
+
+	if lease <= 0 {
+		lease = HubDefaultLease
+	}
+
+	instance.UpdateAt = time.Now().Unix()
+	o.RedisDo(conn, timeout, "SET", hubInstanceKey(instance.InstanceId), o.ToJson(instance), "EX", lease)
+}
+
+func (o *ErrorHandler) UnregisterHubInstance(conn redis.Conn, instanceId string) {
+	if o.Err != nil {
+		return
+	}
+
+	o.RedisDo(conn, timeout, "DEL", hubInstanceKey(instanceId))
+}
+
+func (o *ErrorHandler) GetHubInstance(conn redis.Conn, instanceId string) *HubInstance {
+	if o.Err != nil {
+		return nil
+	}
+
+	instancestr := o.RedisString(o.RedisDo(conn, timeout, "GET", hubInstanceKey(instanceId)))
+	if o.Err != nil || instancestr == "" {
+		return nil
+	}
+
+	instance := &HubInstance{}
+	o.Err = json.Unmarshal([]byte(instancestr), instance)
+	if o.Err != nil {
+		return nil
+	}
+
+	return instance
+}
+
+func (o *ErrorHandler) GetHubInstances(conn redis.Conn) []*HubInstance {
+	if o.Err != nil {
+		return []*HubInstance{}
+	}
+
+	instances := []*HubInstance{}
+	for _, key := range o.RedisMatch(conn, "HUBINSTANCE:*") {
+		instance := o.GetHubInstance(conn, strings.TrimPrefix(key, "HUBINSTANCE:"))
+		if o.Err != nil {
+			return []*HubInstance{}
+		}
+
+		// expired between SCAN and GET
+		if instance != nil {
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances
+}
+
+// ClaimBotOwnership records instanceId as the owner of the bot under each
+// of the non empty ids, overwriting the former owner; the bot client is
+// connected to this instance now.
+func (o *ErrorHandler) ClaimBotOwnership(conn redis.Conn, instanceId string, lease int, clientId string, login string, botId string) {
+	if o.Err != nil {
+		return
+	}
+
+	if lease <= 0 {
+		lease = HubDefaultLease
+	}
+
+	ids := map[string]string{
+		BotOwnerByClient: clientId,
+		BotOwnerByLogin:  login,
+		BotOwnerByBot:    botId,
+	}
+
+	o.RedisSend(conn, "MULTI")
+	for kind, id := range ids {
+		if id != "" {
+			o.RedisSend(conn, "SET", botOwnerKey(kind, id), instanceId, "EX", lease)
+		}
+	}
+	o.RedisDo(conn, timeout, "EXEC")
+}
+
+func (o *ErrorHandler) ReleaseBotOwnership(conn redis.Conn, instanceId string, clientId string, login string, botId string) {
+	if o.Err != nil {
+		return
+	}
+
+	ids := map[string]string{
+		BotOwnerByClient: clientId,
+		BotOwnerByLogin:  login,
+		BotOwnerByBot:    botId,
+	}
+
+	for kind, id := range ids {
+		if id == "" {
+			continue
+		}
+
+		_, o.Err = releaseOwnerScript.Do(conn, botOwnerKey(kind, id), instanceId)
+		if o.Err != nil {
+			return
+		}
+	}
+}
+
+// GetBotOwner returns the hub instance that owns the bot, or nil when the
+// bot is not connected to any live instance.
+func (o *ErrorHandler) GetBotOwner(conn redis.Conn, kind string, id string) *HubInstance {
+	if o.Err != nil {
+		return nil
+	}
+
+	if id == "" {
+		return nil
+	}
+
+	instanceId := o.RedisString(o.RedisDo(conn, timeout, "GET", botOwnerKey(kind, id)))
+	if o.Err != nil || instanceId == "" {
+		return nil
+	}
+
+	return o.GetHubInstance(conn, instanceId)
+}
+
+// keep the lease if it is ours or free, report whether it still is ours
+var refreshOwnerScript = redis.NewScript(1, `
+local cur = redis.call("GET", KEYS[1])
+if cur == ARGV[1] or not cur then
+  redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+  return 1
+end
+return 0`)
+
+// RefreshBotOwnership extends the leases held by instanceId. It returns
+// false when another instance has claimed the bot in the meantime, which
+// means the bot client reconnected or logged in somewhere else.
+func (o *ErrorHandler) RefreshBotOwnership(conn redis.Conn, instanceId string, lease int, clientId string, login string, botId string) bool {
+	if o.Err != nil {
+		return false
+	}
+
+	if lease <= 0 {
+		lease = HubDefaultLease
+	}
+
+	ids := map[string]string{
+		BotOwnerByClient: clientId,
+		BotOwnerByLogin:  login,
+		BotOwnerByBot:    botId,
+	}
+
+	owned := true
+	for kind, id := range ids {
+		if id == "" {
+			continue
+		}
+
+		var ret int
+		ret, o.Err = redis.Int(refreshOwnerScript.Do(conn, botOwnerKey(kind, id), instanceId, lease))
+		if o.Err != nil {
+			return false
+		}
+
+		if ret == 0 {
+			owned = false
+		}
+	}
+
+	return owned
+}
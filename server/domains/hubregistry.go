@@ -0,0 +1,79 @@
+package domains
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// a snapshot older than this is of no use to a restarting hub
+	HubRegistryTTL int = 24 * 3600
+)
+
+// HubBotSnapshot is what a hub remembers of one of its bots across restarts.
+type HubBotSnapshot struct {
+	ClientId       string `json:"clientId"`
+	ClientType     string `json:"clientType"`
+	Name           string `json:"name"`
+	Login          string `json:"login"`
+	NotifyUrl      string `json:"notifyUrl"`
+	LoginInfo      string `json:"loginInfo"`
+	Status         int32  `json:"status"`
+	BotId          string `json:"botId"`
+	FilterId       string `json:"filterId"`
+	MomentFilterId string `json:"momentFilterId"`
+	SavedAt        int64  `json:"savedAt"`
+}
+
+func hubRegistryKey(instanceId string) string {
+	return fmt.Sprintf("HUBREGISTRY:%s", instanceId)
+}
+
+// SaveHubRegistry replaces the registry snapshot of the instance.
+func (o *ErrorHandler) SaveHubRegistry(conn redis.Conn, instanceId string, bots []HubBotSnapshot) {
+	if o.Err != nil {
+		return
+	}
+
+	key := hubRegistryKey(instanceId)
+
+	o.RedisSend(conn, "MULTI")
+	o.RedisSend(conn, "DEL", key)
+	for _, bot := range bots {
+		o.RedisSend(conn, "HSET", key, bot.ClientId, o.ToJson(bot))
+	}
+	o.RedisSend(conn, "EXPIRE", key, HubRegistryTTL)
+	o.RedisDo(conn, timeout, "EXEC")
+}
+
+func (o *ErrorHandler) LoadHubRegistry(conn redis.Conn, instanceId string) []HubBotSnapshot {
+	if o.Err != nil {
+		return []HubBotSnapshot{}
+	}
+
+	ret := o.RedisDo(conn, timeout, "HGETALL", hubRegistryKey(instanceId))
+	if o.Err != nil {
+		return []HubBotSnapshot{}
+	}
+
+	var values map[string]string
+	values, o.Err = redis.StringMap(ret, nil)
+	if o.Err != nil {
+		return []HubBotSnapshot{}
+	}
+
+	bots := []HubBotSnapshot{}
+	for _, v := range values {
+		bot := HubBotSnapshot{}
+		if err := json.Unmarshal([]byte(v), &bot); err != nil {
+			// skip the broken entry, the bot will register again anyway
+			continue
+		}
+
+		bots = append(bots, bot)
+	}
+
+	return bots
+}
@@ -0,0 +1,187 @@
+package domains
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	IdempotencyPending string = "PENDING"
+	IdempotencyDone    string = "DONE"
+
+	IdempotencyDefaultTTL int = 24 * 60 * 60
+	IdempotencyPendingTTL int = 5 * 60
+)
+
+type IdempotencyRecord struct {
+	Key             string         `json:"key"`
+	AccountName     string         `json:"accountName"`
+	Login           string         `json:"login"`
+	Fingerprint     string         `json:"fingerprint"`
+	Status          string         `json:"status"`
+	ActionRequestId string         `json:"actionRequestId"`
+	Response        string         `json:"response"`
+	CreateAt        utils.JSONTime `json:"createAt"`
+
+	// the failure of an action dispatched, replayed as is
+	ErrorCode    int    `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+func (rec *IdempotencyRecord) redisKey() string {
+	return fmt.Sprintf("IDEMPOTENCY:%s:%s:%s", rec.AccountName, rec.Login, rec.Key)
+}
+
+// fingerprint identifies the request body, so that reusing one key for
+// a different action can be told apart from a plain retry.
+func idempotencyFingerprint(actionType string, actionBody string) string {
+	return utils.HexString(utils.CheckSum([]byte(actionType + "\n" + actionBody)))
+}
+
+func (o *ErrorHandler) NewIdempotencyRecord(accountName string, login string, key string, actionType string, actionBody string) *IdempotencyRecord {
+	if o.Err != nil {
+		return nil
+	}
+
+	if len(key) > 256 {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("idempotency key should not be longer than 256"))
+		return nil
+	}
+
+	return &IdempotencyRecord{
+		Key:         key,
+		AccountName: accountName,
+		Login:       login,
+		Fingerprint: idempotencyFingerprint(actionType, actionBody),
+		Status:      IdempotencyPending,
+		CreateAt:    utils.JSONTime{Time: time.Now()},
+	}
+}
+
+// ReserveIdempotencyRecord marks the key as in use with SET NX.
+// It returns nil when the reservation succeeded and the caller should go on
+// dispatching the action, otherwise the record stored by the earlier call.
+// ttl only needs to cover the dispatch, a web that dies before settling the
+// key leaves it PENDING no longer than that. SaveIdempotencyRecord sets the
+// full ttl with the result.
+func (o *ErrorHandler) ReserveIdempotencyRecord(conn redis.Conn, rec *IdempotencyRecord, ttl int) *IdempotencyRecord {
+	if o.Err != nil {
+		return nil
+	}
+
+	if ttl <= 0 {
+		ttl = IdempotencyPendingTTL
+	}
+
+	key := rec.redisKey()
+	recstr := o.ToJson(rec)
+
+	ret := o.RedisDo(conn, timeout, "SET", key, recstr, "EX", ttl, "NX")
+	if o.Err != nil {
+		return nil
+	}
+
+	if ret != nil {
+		// reserved by us
+		return nil
+	}
+
+	return o.GetIdempotencyRecord(conn, rec)
+}
+
+func (o *ErrorHandler) GetIdempotencyRecord(conn redis.Conn, rec *IdempotencyRecord) *IdempotencyRecord {
+	if o.Err != nil {
+		return nil
+	}
+
+	recstr := o.RedisString(o.RedisDo(conn, timeout, "GET", rec.redisKey()))
+	if o.Err != nil {
+		return nil
+	}
+
+	if recstr == "" {
+		o.Err = fmt.Errorf("idempotency record %s expired while reading", rec.Key)
+		return nil
+	}
+
+	var found IdempotencyRecord
+	o.Err = json.Unmarshal([]byte(recstr), &found)
+	if o.Err != nil {
+		return nil
+	}
+
+	return &found
+}
+
+// CheckIdempotencyRecord compares a stored record with the incoming one and
+// returns a conflict error when the key cannot be replayed.
+func (o *ErrorHandler) CheckIdempotencyRecord(found *IdempotencyRecord, rec *IdempotencyRecord) {
+	if o.Err != nil {
+		return
+	}
+
+	if found.Fingerprint != rec.Fingerprint {
+		o.Err = utils.NewClientError(utils.RESOURCE_CONFLICT,
+			fmt.Errorf("idempotency key %s already used with a different request body", rec.Key))
+		return
+	}
+
+	if found.Status != IdempotencyDone {
+		o.Err = utils.NewClientError(utils.RESOURCE_CONFLICT,
+			fmt.Errorf("request with idempotency key %s is still in progress", rec.Key))
+		return
+	}
+}
+
+// SetFailure keeps err to be replayed, or clears the failure when nil.
+func (rec *IdempotencyRecord) SetFailure(err error) {
+	rec.ErrorCode, rec.ErrorMessage = 0, ""
+	if err == nil {
+		return
+	}
+
+	rec.ErrorCode = int(utils.UNKNOWN)
+	if clientErr, ok := err.(*utils.ClientError); ok {
+		rec.ErrorCode = int(clientErr.ErrorCode())
+	}
+	rec.ErrorMessage = err.Error()
+}
+
+// Failure is the error the action failed with, nil when it succeeded.
+func (rec *IdempotencyRecord) Failure() error {
+	if rec.ErrorMessage == "" {
+		return nil
+	}
+
+	return utils.NewClientError(utils.ClientErrorCode(rec.ErrorCode), errors.New(rec.ErrorMessage))
+}
+
+func (o *ErrorHandler) SaveIdempotencyRecord(conn redis.Conn, rec *IdempotencyRecord, ttl int) {
+	if o.Err != nil {
+		return
+	}
+
+	if ttl <= 0 {
+		ttl = IdempotencyDefaultTTL
+	}
+
+	rec.Status = IdempotencyDone
+	o.RedisDo(conn, timeout, "SET", rec.redisKey(), o.ToJson(rec), "EX", ttl)
+}
+
+// ReleaseIdempotencyRecord drops a pending reservation, so that a request
+// which failed before reaching the bot can be retried with the same key.
+func (o *ErrorHandler) ReleaseIdempotencyRecord(conn redis.Conn, rec *IdempotencyRecord) {
+	if o.Err != nil {
+		return
+	}
+
+	o.RedisDo(conn, timeout, "DEL", rec.redisKey())
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"github.com/mitchellh/mapstructure"
@@ -168,6 +169,8 @@ func (o *ErrorHandler) GetWechatMessages(query *mgo.Query) []*WechatMessage {
 func (o *ErrorHandler) GetWechatMessageWithMsgId(db *mgo.Database, msgId string) *WechatMessage {
 	result := &WechatMessage{}
 
+	defer metrics.ObserveMongo(WechatMessageCollection, "find", time.Now())
+
 	o.Err = db.C(WechatMessageCollection).Find(bson.M{"msgId": msgId}).One(result)
 	if o.Err != nil {
 		return nil
@@ -178,6 +181,8 @@ func (o *ErrorHandler) GetWechatMessageWithMsgId(db *mgo.Database, msgId string)
 
 func (o *ErrorHandler) ContainsWechatMessageWithMsgId(db *mgo.Database, msgId string) (bool, error) {
 	var count int
+	defer metrics.ObserveMongo(WechatMessageCollection, "count", time.Now())
+
 	count, o.Err = db.C(WechatMessageCollection).Find(bson.M{"msgId": msgId}).Count()
 	if o.Err != nil {
 		return false, o.Err
@@ -228,6 +233,8 @@ func (o *ErrorHandler) EnsureMessageIndexes(db *mgo.Database) {
 }
 
 func (o *ErrorHandler) UpdateWechatMessages(db *mgo.Database, messages []map[string]interface{}) {
+	defer metrics.ObserveMongo(WechatMessageCollection, "upsert", time.Now())
+
 	col := db.C(WechatMessageCollection)
 
 	for _, message := range messages {
@@ -235,7 +242,7 @@ func (o *ErrorHandler) UpdateWechatMessages(db *mgo.Database, messages []map[str
 		o.Err = mapstructure.Decode(message, &wechatMessage)
 
 		if o.Err != nil {
-			fmt.Printf("[save message debug] unmarshal json failed %s\n", message)
+			logger.Error(o.Err, "[save message] unmarshal json failed %s", message)
 			return
 		}
 
@@ -245,7 +252,7 @@ func (o *ErrorHandler) UpdateWechatMessages(db *mgo.Database, messages []map[str
 			var cjson []byte
 			cjson, o.Err = bson.MarshalJSON(content)
 			if o.Err != nil {
-				fmt.Printf("[save message debug] marshal json failed %s\n", content)
+				logger.Error(o.Err, "[save message] marshal json failed %s", content)
 				return
 			}
 			wechatMessage.Content = string(cjson)
@@ -257,12 +264,12 @@ func (o *ErrorHandler) UpdateWechatMessages(db *mgo.Database, messages []map[str
 			var srcjson []byte
 			srcjson, o.Err = bson.MarshalJSON(src)
 			if o.Err != nil {
-				fmt.Printf("[save message debug] marshal json failed %s\n", src)
+				logger.Error(o.Err, "[save message] marshal json failed %s", src)
 				return
 			}
 			o.Err = json.Unmarshal(srcjson, &msgsource)
 			if o.Err != nil {
-				fmt.Printf("[save message debug] unmarshal json failed %s\n", srcjson)
+				logger.Error(o.Err, "[save message] unmarshal json failed %s", srcjson)
 				return
 			}
 			wechatMessage.MsgSource = &msgsource
@@ -350,6 +357,8 @@ func (o *ErrorHandler) getChatLatestUnreadMessage(db *mgo.Database, userId strin
 		return nil
 	}
 
+	defer metrics.ObserveMongo(WechatMessageCollection, "find", time.Now())
+
 	query := db.C(
 		WechatMessageCollection,
 	).Find(
@@ -378,6 +387,8 @@ func (o *ErrorHandler) getChatUnreadMessagesCount(db *mgo.Database, userId strin
 		return 0
 	}
 
+	defer metrics.ObserveMongo(WechatMessageCollection, "count", time.Now())
+
 	count, err := db.C(
 		WechatMessageCollection,
 	).Find(
@@ -432,72 +443,47 @@ func (o *ErrorHandler) GetMessagesHistories(db *mgo.Database, userId string, pee
 		}
 	}
 
-	var result []*WechatMessage
-
 	// 默认 page size 15 条
 	const pageSize = 15
 
+	// 除了从某条消息往后翻, 都是取最近的一页再倒过来
+	sort := "-updatedAt"
 	if direction == "new" {
 		if fromMessage != nil {
 			criteria["updatedAt"] = bson.M{"$gt": fromMessage.UpdatedAt}
-
-			query := db.C(
-				WechatMessageCollection,
-			).Find(
-				criteria,
-			).Sort(
-				"updatedAt",
-			).Limit(pageSize)
-
-			result = o.GetWechatMessages(query)
-			if o.Err != nil {
-				return nil
-			}
-		} else {
-			query := db.C(
-				WechatMessageCollection,
-			).Find(
-				criteria,
-			).Sort(
-				"-updatedAt",
-			).Limit(pageSize)
-
-			result = o.GetWechatMessages(query)
-
-			if o.Err != nil {
-				return nil
-			}
-
-			// reverse
-			for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-				result[i], result[j] = result[j], result[i]
-			}
+			sort = "updatedAt"
 		}
 	} else if direction == "old" {
 		if fromMessage != nil {
 			criteria["updatedAt"] = bson.M{"$lt": fromMessage.UpdatedAt}
 		}
+	} else {
+		o.Err = fmt.Errorf("illegal direction: %s\n", direction)
+		return nil
+	}
+
+	result := func() []*WechatMessage {
+		defer metrics.ObserveMongo(WechatMessageCollection, "find", time.Now())
 
 		query := db.C(
 			WechatMessageCollection,
 		).Find(
 			criteria,
 		).Sort(
-			"-updatedAt",
+			sort,
 		).Limit(pageSize)
 
-		result = o.GetWechatMessages(query)
-		if o.Err != nil {
-			return nil
-		}
+		return o.GetWechatMessages(query)
+	}()
+	if o.Err != nil {
+		return nil
+	}
 
+	if sort == "-updatedAt" {
 		// reverse
 		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
 			result[i], result[j] = result[j], result[i]
 		}
-	} else {
-		o.Err = fmt.Errorf("illegal direction: %s\n", direction)
-		return nil
 	}
 
 	return result
@@ -0,0 +1,835 @@
+package domains
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+const (
+	MessageStatCollection       string = "message_stats"
+	MessageResponseCollection   string = "message_responses"
+	MessageChatStateCollection  string = "message_chat_states"
+	MessageStatCursorCollection string = "message_stat_cursors"
+
+	// stats and response times are kept for 180 days
+	messageStatTTL time.Duration = 180 * 24 * time.Hour
+
+	messageStatCursorId string = "wechat"
+
+	// seconds the aggregations of the stats are cached in redis
+	MessageStatCacheTTL int = 60
+
+	MESSAGE_CHAT_GROUP   string = "group"
+	MESSAGE_CHAT_PRIVATE string = "private"
+)
+
+// MessageStat is the count of the messages of a bot in a chat within an
+// hour, of a sender and a message type. Peer is the group of a group chat,
+// the contact of a private chat. The bot is the sender of the outbound
+// messages.
+type MessageStat struct {
+	Id       string    `bson:"_id"`
+	Hour     time.Time `bson:"hour"`
+	Login    string    `bson:"login"`
+	Peer     string    `bson:"peer"`
+	Group    bool      `bson:"group"`
+	Sender   string    `bson:"sender"`
+	Outbound bool      `bson:"outbound"`
+	MsgType  int       `bson:"msgType"`
+	Count    int       `bson:"count"`
+	// the messages counted, for a message saved again not to be counted twice
+	MsgIds []string `bson:"msgIds"`
+}
+
+// MessageResponse is the time a bot took to respond in a chat, from the
+// first inbound message not responded to the next outbound message.
+type MessageResponse struct {
+	Id          bson.ObjectId `bson:"_id"`
+	Login       string        `bson:"login"`
+	Peer        string        `bson:"peer"`
+	Group       bool          `bson:"group"`
+	InboundAt   time.Time     `bson:"inboundAt"`
+	RespondedAt time.Time     `bson:"respondedAt"`
+	Seconds     float64       `bson:"seconds"`
+}
+
+// messageChatState is what is left of a chat by the last computation,
+// for the response times to carry on across the computations.
+type messageChatState struct {
+	Id    string `bson:"_id"`
+	Login string `bson:"login"`
+	Peer  string `bson:"peer"`
+	// the first inbound message not responded yet, zero when none
+	PendingSince time.Time `bson:"pendingSince"`
+	LastAt       time.Time `bson:"lastAt"`
+}
+
+// messageStatCursor is the last message computed, the messages are taken
+// in the order of updatedAt then _id.
+type messageStatCursor struct {
+	Id         string        `bson:"_id"`
+	UpdatedAt  time.Time     `bson:"updatedAt"`
+	LastId     bson.ObjectId `bson:"lastId,omitempty"`
+	ComputedAt time.Time     `bson:"computedAt"`
+}
+
+// statMessage is a message as the stats are computed of.
+type statMessage struct {
+	Id            bson.ObjectId `bson:"_id"`
+	WechatMessage `bson:",inline"`
+}
+
+type messageEvent struct {
+	at       time.Time
+	outbound bool
+}
+
+// messageTime is when the message was sent, its timestamp in seconds, or
+// in milliseconds for some clients, when it was saved lacking one.
+func messageTime(msg *WechatMessage) time.Time {
+	switch {
+	case msg.Timestamp == 0:
+		return msg.UpdatedAt.UTC()
+	case msg.Timestamp > 1e12:
+		return time.Unix(0, int64(msg.Timestamp)*int64(time.Millisecond)).UTC()
+	default:
+		return time.Unix(int64(msg.Timestamp), 0).UTC()
+	}
+}
+
+func isGroupPeer(peer string) bool {
+	return len(peer) > 9 && peer[len(peer)-9:] == "@chatroom"
+}
+
+func (o *ErrorHandler) EnsureMessageStatIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	indexes := map[string][][]string{
+		MessageStatCollection: [][]string{
+			[]string{"login", "hour"},
+			[]string{"login", "peer", "hour"},
+		},
+		MessageResponseCollection: [][]string{
+			[]string{"login", "respondedAt"},
+			[]string{"login", "peer", "respondedAt"},
+		},
+	}
+
+	for collection, keys := range indexes {
+		col := db.C(collection)
+		for _, key := range keys {
+			o.Err = col.EnsureIndex(mgo.Index{
+				Key:        key,
+				Background: true,
+			})
+			if o.Err != nil {
+				return
+			}
+		}
+	}
+
+	ttls := map[string]string{
+		MessageStatCollection:      "hour",
+		MessageResponseCollection:  "respondedAt",
+		MessageChatStateCollection: "lastAt",
+	}
+
+	for collection, key := range ttls {
+		o.Err = db.C(collection).EnsureIndex(mgo.Index{
+			Key:         []string{key},
+			Background:  true,
+			ExpireAfter: messageStatTTL,
+		})
+		if o.Err != nil {
+			return
+		}
+	}
+}
+
+func (o *ErrorHandler) getMessageStatCursor(db *mgo.Database) messageStatCursor {
+	if o.Err != nil {
+		return messageStatCursor{}
+	}
+
+	cursor := messageStatCursor{}
+	defer metrics.ObserveMongo(MessageStatCursorCollection, "find", time.Now())
+
+	err := db.C(MessageStatCursorCollection).FindId(messageStatCursorId).One(&cursor)
+	if err == mgo.ErrNotFound {
+		return messageStatCursor{}
+	}
+	o.Err = err
+
+	return cursor
+}
+
+// getCountedMessageStats returns the stats counted before of ids.
+func (o *ErrorHandler) getCountedMessageStats(db *mgo.Database, ids []string) map[string]*MessageStat {
+	if o.Err != nil {
+		return nil
+	}
+
+	stats := []MessageStat{}
+	defer metrics.ObserveMongo(MessageStatCollection, "find", time.Now())
+
+	o.Err = db.C(MessageStatCollection).Find(bson.M{"_id": bson.M{"$in": ids}}).
+		Select(bson.M{"count": 1, "msgIds": 1}).All(&stats)
+	if o.Err != nil {
+		return nil
+	}
+
+	ret := map[string]*MessageStat{}
+	for i := range stats {
+		ret[stats[i].Id] = &stats[i]
+	}
+
+	return ret
+}
+
+// pairMessageResponses carries the chat on through its events, pairing
+// the first inbound message not responded with the next outbound one.
+func pairMessageResponses(chat *messageChatState, events []messageEvent) []MessageResponse {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	responses := []MessageResponse{}
+	for _, event := range events {
+		if event.at.After(chat.LastAt) {
+			chat.LastAt = event.at
+		}
+
+		if !event.outbound {
+			if chat.PendingSince.IsZero() {
+				chat.PendingSince = event.at
+			}
+			continue
+		}
+
+		if chat.PendingSince.IsZero() || event.at.Before(chat.PendingSince) {
+			continue
+		}
+
+		responses = append(responses, MessageResponse{
+			Id:          bson.NewObjectId(),
+			Login:       chat.Login,
+			Peer:        chat.Peer,
+			Group:       isGroupPeer(chat.Peer),
+			InboundAt:   chat.PendingSince,
+			RespondedAt: event.at,
+			Seconds:     event.at.Sub(chat.PendingSince).Seconds(),
+		})
+		chat.PendingSince = time.Time{}
+	}
+
+	return responses
+}
+
+func (o *ErrorHandler) getMessageChatStates(db *mgo.Database, ids []string) map[string]*messageChatState {
+	if o.Err != nil {
+		return nil
+	}
+
+	states := []messageChatState{}
+	defer metrics.ObserveMongo(MessageChatStateCollection, "find", time.Now())
+
+	o.Err = db.C(MessageChatStateCollection).Find(bson.M{"_id": bson.M{"$in": ids}}).All(&states)
+	if o.Err != nil {
+		return nil
+	}
+
+	ret := map[string]*messageChatState{}
+	for i := range states {
+		ret[states[i].Id] = &states[i]
+	}
+
+	return ret
+}
+
+// ComputeMessageStats counts the messages of the bots saved since the last
+// computation and until, at most limit of them, into the hourly stats, and
+// the responses of the bots into response times. It returns the number of
+// messages taken, 0 once it has caught up.
+//
+// The messages are taken in the order they are saved. What is saved late
+// is counted in its own hour, but is not responded to in time order. A
+// message saved again is taken again, but counted once by its msgId, and
+// a computation failing halfway is taken again by the next one.
+func (o *ErrorHandler) ComputeMessageStats(db *mgo.Database, logins []string, until time.Time, limit int) int {
+	if o.Err != nil || len(logins) == 0 {
+		return 0
+	}
+
+	cursor := o.getMessageStatCursor(db)
+	if o.Err != nil {
+		return 0
+	}
+
+	byLogins := bson.M{"$or": []bson.M{
+		bson.M{"fromUser": bson.M{"$in": logins}},
+		bson.M{"toUser": bson.M{"$in": logins}},
+	}}
+
+	query := bson.M{"updatedAt": bson.M{"$gt": cursor.UpdatedAt, "$lte": until}}
+	if cursor.LastId != "" {
+		// after the last message, saved at the same time or later
+		query = bson.M{
+			"updatedAt": bson.M{"$gte": cursor.UpdatedAt, "$lte": until},
+			"$and": []bson.M{
+				bson.M{"$or": []bson.M{
+					bson.M{"updatedAt": bson.M{"$gt": cursor.UpdatedAt}},
+					bson.M{"_id": bson.M{"$gt": cursor.LastId}},
+				}},
+				byLogins,
+			},
+		}
+	} else {
+		query["$or"] = byLogins["$or"]
+	}
+
+	messages := []statMessage{}
+	func() {
+		defer metrics.ObserveMongo(WechatMessageCollection, "find", time.Now())
+		o.Err = db.C(WechatMessageCollection).Find(query).Select(bson.M{
+			"msgId":     1,
+			"msgType":   1,
+			"groupId":   1,
+			"fromUser":  1,
+			"toUser":    1,
+			"timestamp": 1,
+			"updatedAt": 1,
+		}).Sort("updatedAt", "_id").Limit(limit).All(&messages)
+	}()
+	if o.Err != nil || len(messages) == 0 {
+		return 0
+	}
+
+	isBot := map[string]bool{}
+	for _, login := range logins {
+		isBot[login] = true
+	}
+
+	// a message counts for its sender and its receiver, both bots or not
+	type countedMessage struct {
+		msg      *statMessage
+		key      string
+		at       time.Time
+		login    string
+		peer     string
+		outbound bool
+	}
+
+	counted := []countedMessage{}
+	keys := map[string]bool{}
+	count := func(msg *statMessage, login string, peer string, outbound bool) {
+		if peer == "" {
+			return
+		}
+
+		at := messageTime(&msg.WechatMessage)
+		key := fmt.Sprintf("%d|%s|%s|%s|%d", at.Truncate(time.Hour).Unix(), login, peer, msg.FromUser, msg.MsgType)
+		counted = append(counted, countedMessage{
+			msg: msg, key: key, at: at, login: login, peer: peer, outbound: outbound,
+		})
+		keys[key] = true
+	}
+
+	for i := range messages {
+		msg := &messages[i]
+
+		if isBot[msg.FromUser] {
+			peer := msg.GroupId
+			if peer == "" {
+				peer = msg.ToUser
+			}
+			count(msg, msg.FromUser, peer, true)
+		}
+
+		// the outbound messages of a group are sent to the group itself
+		if isBot[msg.ToUser] && msg.ToUser != msg.FromUser {
+			peer := msg.GroupId
+			if peer == "" {
+				peer = msg.FromUser
+			}
+			count(msg, msg.ToUser, peer, false)
+		}
+	}
+
+	statIds := make([]string, 0, len(keys))
+	for key := range keys {
+		statIds = append(statIds, key)
+	}
+
+	before := o.getCountedMessageStats(db, statIds)
+	if o.Err != nil {
+		return 0
+	}
+
+	seen := map[string]map[string]bool{}
+	for key, stat := range before {
+		seen[key] = map[string]bool{}
+		for _, msgId := range stat.MsgIds {
+			seen[key][msgId] = true
+		}
+	}
+
+	stats := map[string]*MessageStat{}
+	events := map[string][]messageEvent{}
+	chats := map[string]*messageChatState{}
+
+	for _, c := range counted {
+		if c.msg.MsgId != "" {
+			if seen[c.key] == nil {
+				seen[c.key] = map[string]bool{}
+			}
+			if seen[c.key][c.msg.MsgId] {
+				continue
+			}
+			seen[c.key][c.msg.MsgId] = true
+		}
+
+		stat, ok := stats[c.key]
+		if !ok {
+			stat = &MessageStat{
+				Id:       c.key,
+				Hour:     c.at.Truncate(time.Hour),
+				Login:    c.login,
+				Peer:     c.peer,
+				Group:    c.msg.GroupId != "",
+				Sender:   c.msg.FromUser,
+				Outbound: c.outbound,
+				MsgType:  c.msg.MsgType,
+				MsgIds:   []string{},
+			}
+			if prev, ok := before[c.key]; ok {
+				stat.Count = prev.Count
+			}
+			stats[c.key] = stat
+		}
+		stat.Count += 1
+		if c.msg.MsgId != "" {
+			stat.MsgIds = append(stat.MsgIds, c.msg.MsgId)
+		}
+
+		chatId := c.login + "|" + c.peer
+		if _, ok := chats[chatId]; !ok {
+			chats[chatId] = &messageChatState{Id: chatId, Login: c.login, Peer: c.peer}
+		}
+		events[chatId] = append(events[chatId], messageEvent{at: c.at, outbound: c.outbound})
+	}
+
+	chatIds := make([]string, 0, len(chats))
+	for chatId := range chats {
+		chatIds = append(chatIds, chatId)
+	}
+
+	states := o.getMessageChatStates(db, chatIds)
+	if o.Err != nil {
+		return 0
+	}
+
+	responses := []interface{}{}
+	for chatId, chat := range chats {
+		if state, ok := states[chatId]; ok {
+			chat.PendingSince, chat.LastAt = state.PendingSince, state.LastAt
+		}
+
+		for _, response := range pairMessageResponses(chat, events[chatId]) {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(stats) > 0 {
+		func() {
+			bulk := db.C(MessageStatCollection).Bulk()
+			bulk.Unordered()
+			for _, stat := range stats {
+				bulk.Upsert(bson.M{"_id": stat.Id}, bson.M{
+					"$set": bson.M{
+						"hour":     stat.Hour,
+						"login":    stat.Login,
+						"peer":     stat.Peer,
+						"group":    stat.Group,
+						"sender":   stat.Sender,
+						"outbound": stat.Outbound,
+						"msgType":  stat.MsgType,
+						"count":    stat.Count,
+					},
+					"$addToSet": bson.M{"msgIds": bson.M{"$each": stat.MsgIds}},
+				})
+			}
+
+			defer metrics.ObserveMongo(MessageStatCollection, "upsert", time.Now())
+			_, o.Err = bulk.Run()
+		}()
+		if o.Err != nil {
+			return 0
+		}
+	}
+
+	if len(responses) > 0 {
+		func() {
+			defer metrics.ObserveMongo(MessageResponseCollection, "insert", time.Now())
+			o.Err = db.C(MessageResponseCollection).Insert(responses...)
+		}()
+		if o.Err != nil {
+			return 0
+		}
+	}
+
+	if len(chats) > 0 {
+		func() {
+			bulk := db.C(MessageChatStateCollection).Bulk()
+			bulk.Unordered()
+			for _, chat := range chats {
+				bulk.Upsert(bson.M{"_id": chat.Id}, chat)
+			}
+
+			defer metrics.ObserveMongo(MessageChatStateCollection, "upsert", time.Now())
+			_, o.Err = bulk.Run()
+		}()
+		if o.Err != nil {
+			return 0
+		}
+	}
+
+	last := messages[len(messages)-1]
+	func() {
+		defer metrics.ObserveMongo(MessageStatCursorCollection, "upsert", time.Now())
+		_, o.Err = db.C(MessageStatCursorCollection).UpsertId(messageStatCursorId, messageStatCursor{
+			Id:         messageStatCursorId,
+			UpdatedAt:  last.UpdatedAt,
+			LastId:     last.Id,
+			ComputedAt: time.Now(),
+		})
+	}()
+	if o.Err != nil {
+		return 0
+	}
+
+	return len(messages)
+}
+
+type MessageStatCriteria struct {
+	Logins []string
+	Peer   string
+	// MESSAGE_CHAT_GROUP or MESSAGE_CHAT_PRIVATE, both when empty
+	ChatType string
+	From     time.Time
+	To       time.Time
+}
+
+func (criteria MessageStatCriteria) match(timeField string) bson.M {
+	query := bson.M{
+		"login":   bson.M{"$in": criteria.Logins},
+		timeField: bson.M{"$gte": criteria.From, "$lt": criteria.To},
+	}
+	if criteria.Peer != "" {
+		query["peer"] = criteria.Peer
+	}
+	switch criteria.ChatType {
+	case MESSAGE_CHAT_GROUP:
+		query["group"] = true
+	case MESSAGE_CHAT_PRIVATE:
+		query["group"] = false
+	}
+
+	return query
+}
+
+func (o *ErrorHandler) pipeMessageStats(db *mgo.Database, collection string, pipeline []bson.M, result interface{}) {
+	if o.Err != nil {
+		return
+	}
+
+	defer metrics.ObserveMongo(collection, "aggregate", time.Now())
+	o.Err = db.C(collection).Pipe(pipeline).AllowDiskUse().All(result)
+}
+
+var inboundCount = bson.M{"$sum": bson.M{"$cond": []interface{}{"$outbound", 0, "$count"}}}
+var outboundCount = bson.M{"$sum": bson.M{"$cond": []interface{}{"$outbound", "$count", 0}}}
+
+// MessageSeriesPoint is the count of the messages of a bot, or of a bot in
+// a chat, within a day or an hour, in UTC.
+type MessageSeriesPoint struct {
+	Time     string `json:"time"`
+	Login    string `json:"login"`
+	Peer     string `json:"peer,omitempty"`
+	Inbound  int    `json:"inbound"`
+	Outbound int    `json:"outbound"`
+}
+
+// MessageTimeSeries counts the messages per bot, and per chat when byPeer,
+// per day, or per hour when hourly.
+func (o *ErrorHandler) MessageTimeSeries(db *mgo.Database, criteria MessageStatCriteria, hourly bool, byPeer bool) []MessageSeriesPoint {
+	if o.Err != nil {
+		return nil
+	}
+
+	format := "%Y-%m-%d"
+	if hourly {
+		format = "%Y-%m-%dT%H:00:00Z"
+	}
+
+	id := bson.M{
+		"time":  bson.M{"$dateToString": bson.M{"format": format, "date": "$hour"}},
+		"login": "$login",
+	}
+	if byPeer {
+		id["peer"] = "$peer"
+	}
+
+	rows := []struct {
+		Id struct {
+			Time  string `bson:"time"`
+			Login string `bson:"login"`
+			Peer  string `bson:"peer"`
+		} `bson:"_id"`
+		Inbound  int `bson:"inbound"`
+		Outbound int `bson:"outbound"`
+	}{}
+
+	o.pipeMessageStats(db, MessageStatCollection, []bson.M{
+		bson.M{"$match": criteria.match("hour")},
+		bson.M{"$group": bson.M{
+			"_id":      id,
+			"inbound":  inboundCount,
+			"outbound": outboundCount,
+		}},
+		bson.M{"$sort": bson.D{
+			{Name: "_id.time", Value: 1},
+			{Name: "_id.login", Value: 1},
+			{Name: "_id.peer", Value: 1},
+		}},
+	}, &rows)
+	if o.Err != nil {
+		return nil
+	}
+
+	points := make([]MessageSeriesPoint, 0, len(rows))
+	for _, row := range rows {
+		points = append(points, MessageSeriesPoint{
+			Time:     row.Id.Time,
+			Login:    row.Id.Login,
+			Peer:     row.Id.Peer,
+			Inbound:  row.Inbound,
+			Outbound: row.Outbound,
+		})
+	}
+
+	return points
+}
+
+// MessageCount is the count of the messages of a sender, or in a chat,
+// over the bots in Logins.
+type MessageCount struct {
+	Key      string   `json:"key" bson:"_id"`
+	Logins   []string `json:"logins" bson:"logins"`
+	Group    bool     `json:"group" bson:"group"`
+	Inbound  int      `json:"inbound" bson:"inbound"`
+	Outbound int      `json:"outbound" bson:"outbound"`
+	Total    int      `json:"total" bson:"total"`
+}
+
+// TopMessageCounts returns the senders other than the bots sending the
+// most messages when by is sender, the chats with the most messages when
+// by is peer.
+func (o *ErrorHandler) TopMessageCounts(db *mgo.Database, criteria MessageStatCriteria, by string, limit int) []MessageCount {
+	if o.Err != nil {
+		return nil
+	}
+
+	match := criteria.match("hour")
+	switch by {
+	case "sender":
+		match["outbound"] = false
+	case "peer":
+	default:
+		o.Err = fmt.Errorf("top messages by %s not supported", by)
+		return nil
+	}
+
+	counts := []MessageCount{}
+	o.pipeMessageStats(db, MessageStatCollection, []bson.M{
+		bson.M{"$match": match},
+		bson.M{"$group": bson.M{
+			"_id":      "$" + by,
+			"logins":   bson.M{"$addToSet": "$login"},
+			"group":    bson.M{"$max": "$group"},
+			"inbound":  inboundCount,
+			"outbound": outboundCount,
+			"total":    bson.M{"$sum": "$count"},
+		}},
+		bson.M{"$sort": bson.D{{Name: "total", Value: -1}, {Name: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	}, &counts)
+	if o.Err != nil {
+		return nil
+	}
+
+	return counts
+}
+
+type MessageTypeCount struct {
+	MsgType  int `json:"msgType" bson:"_id"`
+	Inbound  int `json:"inbound" bson:"inbound"`
+	Outbound int `json:"outbound" bson:"outbound"`
+	Total    int `json:"total" bson:"total"`
+}
+
+// MessageTypeDistribution counts the messages per message type, the most
+// sent first.
+func (o *ErrorHandler) MessageTypeDistribution(db *mgo.Database, criteria MessageStatCriteria) []MessageTypeCount {
+	if o.Err != nil {
+		return nil
+	}
+
+	counts := []MessageTypeCount{}
+	o.pipeMessageStats(db, MessageStatCollection, []bson.M{
+		bson.M{"$match": criteria.match("hour")},
+		bson.M{"$group": bson.M{
+			"_id":      "$msgType",
+			"inbound":  inboundCount,
+			"outbound": outboundCount,
+			"total":    bson.M{"$sum": "$count"},
+		}},
+		bson.M{"$sort": bson.D{{Name: "total", Value: -1}, {Name: "_id", Value: 1}}},
+	}, &counts)
+	if o.Err != nil {
+		return nil
+	}
+
+	return counts
+}
+
+// MessageResponseTime is how fast a bot responds in a chat, over the
+// responses within the criteria.
+type MessageResponseTime struct {
+	Login       string  `json:"login"`
+	Peer        string  `json:"peer"`
+	Group       bool    `json:"group"`
+	Count       int     `json:"count"`
+	MeanSeconds float64 `json:"meanSeconds"`
+	P50Seconds  float64 `json:"p50Seconds"`
+	P90Seconds  float64 `json:"p90Seconds"`
+}
+
+// messageResponseTime sums up the response seconds of one chat, sorting them.
+func messageResponseTime(login string, peer string, group bool, seconds []float64) MessageResponseTime {
+	sort.Float64s(seconds)
+
+	sum := 0.0
+	for _, s := range seconds {
+		sum += s
+	}
+
+	rt := MessageResponseTime{
+		Login: login,
+		Peer:  peer,
+		Group: group,
+		Count: len(seconds),
+	}
+	if len(seconds) > 0 {
+		rt.MeanSeconds = math.Round(sum/float64(len(seconds))*1000) / 1000
+	}
+	rt.P50Seconds = percentile(seconds, 50)
+	rt.P90Seconds = percentile(seconds, 90)
+	return rt
+}
+
+// MessageResponseTimes returns the response times per chat, of the chats
+// responded the most first.
+func (o *ErrorHandler) MessageResponseTimes(db *mgo.Database, criteria MessageStatCriteria, limit int) []MessageResponseTime {
+	if o.Err != nil {
+		return nil
+	}
+
+	rows := []struct {
+		Id struct {
+			Login string `bson:"login"`
+			Peer  string `bson:"peer"`
+		} `bson:"_id"`
+		Group   bool      `bson:"group"`
+		Seconds []float64 `bson:"seconds"`
+	}{}
+
+	o.pipeMessageStats(db, MessageResponseCollection, []bson.M{
+		bson.M{"$match": criteria.match("respondedAt")},
+		bson.M{"$group": bson.M{
+			"_id":     bson.M{"login": "$login", "peer": "$peer"},
+			"group":   bson.M{"$first": "$group"},
+			"seconds": bson.M{"$push": "$seconds"},
+		}},
+	}, &rows)
+	if o.Err != nil {
+		return nil
+	}
+
+	times := make([]MessageResponseTime, 0, len(rows))
+	for _, row := range rows {
+		times = append(times, messageResponseTime(row.Id.Login, row.Id.Peer, row.Group, row.Seconds))
+	}
+
+	sort.Slice(times, func(i, j int) bool {
+		if times[i].Count != times[j].Count {
+			return times[i].Count > times[j].Count
+		}
+		if times[i].Login != times[j].Login {
+			return times[i].Login < times[j].Login
+		}
+		return times[i].Peer < times[j].Peer
+	})
+
+	if len(times) > limit {
+		times = times[:limit]
+	}
+
+	return times
+}
+
+// MessageStatCacheKey is the redis key of an aggregation of the stats, of
+// its name and arguments.
+func MessageStatCacheKey(name string, args ...interface{}) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%v", args)))
+	return fmt.Sprintf("MESSAGESTATS:%s:%s", name, hex.EncodeToString(sum[:]))
+}
+
+// GetCachedMessageStat fills v with the aggregation cached at key, and
+// returns false when it is not cached.
+func (o *ErrorHandler) GetCachedMessageStat(conn redis.Conn, key string, v interface{}) bool {
+	if o.Err != nil {
+		return false
+	}
+
+	cached := o.RedisString(o.RedisDo(conn, timeout, "GET", key))
+	if o.Err != nil || cached == "" {
+		return false
+	}
+
+	if o.Err = json.Unmarshal([]byte(cached), v); o.Err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (o *ErrorHandler) CacheMessageStat(conn redis.Conn, key string, v interface{}) {
+	if o.Err != nil {
+		return
+	}
+
+	o.RedisDo(conn, timeout, "SET", key, o.ToJson(v), "EX", MessageStatCacheTTL)
+}
@@ -0,0 +1,85 @@
+package domains
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPairMessageResponses(t *testing.T) {
+	base := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return base.Add(time.Duration(seconds) * time.Second)
+	}
+
+	chat := &messageChatState{Id: "bot|peer", Login: "bot", Peer: "peer"}
+	responses := pairMessageResponses(chat, []messageEvent{
+		// out of order, as saved late
+		{at: at(30), outbound: true},
+		{at: at(0), outbound: false},
+		{at: at(10), outbound: false},
+		{at: at(40), outbound: true},
+		{at: at(50), outbound: false},
+	})
+
+	// the first inbound message not responded pairs with the next outbound
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, expected 1", len(responses))
+	}
+	if !responses[0].InboundAt.Equal(at(0)) || !responses[0].RespondedAt.Equal(at(30)) || responses[0].Seconds != 30 {
+		t.Errorf("response %+v should be from 0s to 30s", responses[0])
+	}
+	if responses[0].Group {
+		t.Errorf("response of a private chat should not be of a group")
+	}
+
+	// the last inbound message is left pending for the next computation
+	if !chat.PendingSince.Equal(at(50)) || !chat.LastAt.Equal(at(50)) {
+		t.Errorf("chat should be pending since 50s, got %+v", chat)
+	}
+
+	responses = pairMessageResponses(chat, []messageEvent{{at: at(65), outbound: true}})
+	if len(responses) != 1 || responses[0].Seconds != 15 {
+		t.Fatalf("pending inbound should pair across computations, got %+v", responses)
+	}
+	if !chat.PendingSince.IsZero() {
+		t.Errorf("chat should not be pending once responded, got %+v", chat)
+	}
+}
+
+func TestPairMessageResponsesOutboundFirst(t *testing.T) {
+	base := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	chat := &messageChatState{Id: "bot|group@chatroom", Login: "bot", Peer: "group@chatroom"}
+	responses := pairMessageResponses(chat, []messageEvent{
+		{at: base, outbound: true},
+		{at: base.Add(time.Second), outbound: true},
+	})
+	if len(responses) != 0 {
+		t.Errorf("outbound messages with nothing to respond should not pair, got %+v", responses)
+	}
+
+	responses = pairMessageResponses(chat, []messageEvent{
+		{at: base.Add(2 * time.Second), outbound: false},
+		{at: base.Add(5 * time.Second), outbound: true},
+	})
+	if len(responses) != 1 || !responses[0].Group || responses[0].Seconds != 3 {
+		t.Errorf("group response should take 3s, got %+v", responses)
+	}
+}
+
+func TestMessageResponseTime(t *testing.T) {
+	seconds := []float64{30, 5, 10, 20, 15, 25, 35, 40, 45, 50}
+
+	rt := messageResponseTime("bot", "peer", false, seconds)
+	if rt.Count != 10 || rt.MeanSeconds != 27.5 {
+		t.Errorf("response time %+v should count 10 with mean 27.5", rt)
+	}
+	if rt.P50Seconds != 25 || rt.P90Seconds != 45 {
+		t.Errorf("response time %+v should have p50 25 and p90 45", rt)
+	}
+
+	rt = messageResponseTime("bot", "peer", false, []float64{})
+	if rt.Count != 0 || rt.MeanSeconds != 0 || rt.P50Seconds != 0 || rt.P90Seconds != 0 {
+		t.Errorf("chat with no responses should be all 0, got %+v", rt)
+	}
+}
@@ -58,7 +58,7 @@ func (o *ErrorHandler) SelectByCriteria(
 
 	criteria := o.FromJson(query)
 	if o.Err != nil {
-		fmt.Printf("[SEARCH CRITERIA] parse failed\n%s\n", query)
+		logger.Warn("[SEARCH CRITERIA] parse failed %s", query)
 		o.Err = utils.NewClientError(utils.PARAM_INVALID, o.Err)
 		return []interface{}{}, utils.Paging{}
 	}
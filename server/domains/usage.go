@@ -0,0 +1,284 @@
+package domains
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+)
+
+const (
+	UsageCounterCollection string = "usage_counters"
+	DailyUsageCollection   string = "usage_daily"
+
+	// the day of a counter, in UTC
+	UsageDayLayout string = "2006-01-02"
+
+	// what a counter is counted for, a bot, a filter or an account. The
+	// filters are shared by the bots of an account, their fills are counted
+	// for the bot of the message, or for the account of the filter when the
+	// bot is not known.
+	USAGE_SCOPE_BOT     string = "bot"
+	USAGE_SCOPE_FILTER  string = "filter"
+	USAGE_SCOPE_ACCOUNT string = "account"
+
+	USAGE_MESSAGES    string = "messages"
+	USAGE_ACTIONS     string = "actions"
+	USAGE_FILTERS     string = "filters"
+	USAGE_WEBTRIGGERS string = "webtriggers"
+	USAGE_MEDIABYTES  string = "mediaBytes"
+	// counted in seconds, rolled up in minutes
+	USAGE_WEBSOCKET_SECONDS string = "websocketSeconds"
+	USAGE_WEBSOCKET_MINUTES string = "websocketMinutes"
+)
+
+func UsageDay(t time.Time) string {
+	return t.UTC().Format(UsageDayLayout)
+}
+
+type UsageCounterKey struct {
+	Day       string
+	Scope     string
+	SubjectId string
+	Kind      string
+	Type      string
+}
+
+func (key UsageCounterKey) id() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", key.Day, key.Scope, key.SubjectId, key.Kind, key.Type)
+}
+
+// UsageCounter is the raw count of the day of a subject, as the hub and
+// streaming count it, to be rolled up per account and bot by tasks.
+type UsageCounter struct {
+	Id        string `bson:"_id"`
+	Day       string `bson:"day"`
+	Scope     string `bson:"scope"`
+	SubjectId string `bson:"subjectId"`
+	Kind      string `bson:"kind"`
+	Type      string `bson:"type"`
+	Value     int64  `bson:"value"`
+}
+
+// UsageMeter adds up the usage in memory, until it is flushed to the
+// usage counters with FlushUsageMeter. A nil meter counts nothing.
+type UsageMeter struct {
+	mux    sync.Mutex
+	counts map[UsageCounterKey]int64
+}
+
+func NewUsageMeter() *UsageMeter {
+	return &UsageMeter{counts: map[UsageCounterKey]int64{}}
+}
+
+func (m *UsageMeter) Add(scope string, subjectId string, kind string, usageType string, n int64) {
+	if m == nil || subjectId == "" || n <= 0 {
+		return
+	}
+
+	key := UsageCounterKey{
+		Day:       UsageDay(time.Now()),
+		Scope:     scope,
+		SubjectId: subjectId,
+		Kind:      kind,
+		Type:      usageType,
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.counts[key] += n
+}
+
+func (m *UsageMeter) take() map[UsageCounterKey]int64 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	counts := m.counts
+	m.counts = map[UsageCounterKey]int64{}
+	return counts
+}
+
+// putBack adds counts taken but not flushed back to the meter, for the
+// next flush.
+func (m *UsageMeter) putBack(counts map[UsageCounterKey]int64) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for key, n := range counts {
+		m.counts[key] += n
+	}
+}
+
+func (o *ErrorHandler) EnsureUsageCounterIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	o.Err = db.C(UsageCounterCollection).EnsureIndex(mgo.Index{
+		Key:        []string{"day"},
+		Background: true,
+	})
+}
+
+// FlushUsageMeter adds what the meter counted since the last flush to the
+// usage counters. What fails to be flushed is put back to the meter, and
+// flushed again the next time.
+func (o *ErrorHandler) FlushUsageMeter(db *mgo.Database, m *UsageMeter) {
+	if o.Err != nil || m == nil {
+		return
+	}
+
+	counts := m.take()
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]UsageCounterKey, 0, len(counts))
+	bulk := db.C(UsageCounterCollection).Bulk()
+	bulk.Unordered()
+	for key, n := range counts {
+		keys = append(keys, key)
+		bulk.Upsert(bson.M{"_id": key.id()}, bson.M{
+			"$set": bson.M{
+				"day":       key.Day,
+				"scope":     key.Scope,
+				"subjectId": key.SubjectId,
+				"kind":      key.Kind,
+				"type":      key.Type,
+			},
+			"$inc": bson.M{"value": n},
+		})
+	}
+
+	defer metrics.ObserveMongo(UsageCounterCollection, "upsert", time.Now())
+	if _, o.Err = bulk.Run(); o.Err == nil {
+		return
+	}
+
+	// the upserts that failed alone, or all of them when the bulk did not run
+	bulkErr, ok := o.Err.(*mgo.BulkError)
+	if !ok {
+		m.putBack(counts)
+		return
+	}
+
+	failed := map[UsageCounterKey]int64{}
+	for _, c := range bulkErr.Cases() {
+		if c.Index < 0 || c.Index >= len(keys) {
+			m.putBack(counts)
+			return
+		}
+		failed[keys[c.Index]] = counts[keys[c.Index]]
+	}
+	m.putBack(failed)
+}
+
+func (o *ErrorHandler) GetUsageCounters(db *mgo.Database, days []string) []UsageCounter {
+	if o.Err != nil {
+		return nil
+	}
+
+	counters := []UsageCounter{}
+	defer metrics.ObserveMongo(UsageCounterCollection, "find", time.Now())
+
+	o.Err = db.C(UsageCounterCollection).Find(bson.M{"day": bson.M{"$in": days}}).All(&counters)
+	if o.Err != nil {
+		return nil
+	}
+
+	return counters
+}
+
+// DailyUsage is the usage of a day of an account, per bot. BotId is empty
+// for the usage of the account not bound to a bot, the filters and the
+// websockets.
+type DailyUsage struct {
+	Id         string    `json:"-" bson:"_id"`
+	Day        string    `json:"day" bson:"day"`
+	AccountId  string    `json:"-" bson:"accountId"`
+	BotId      string    `json:"botId" bson:"botId"`
+	Kind       string    `json:"kind" bson:"kind"`
+	Type       string    `json:"type" bson:"type"`
+	Value      float64   `json:"value" bson:"value"`
+	ComputedAt time.Time `json:"-" bson:"computedAt"`
+}
+
+func NewDailyUsage(day string, accountId string, botId string, kind string, usageType string) *DailyUsage {
+	return &DailyUsage{
+		Id:        fmt.Sprintf("%s|%s|%s|%s|%s", day, accountId, botId, kind, usageType),
+		Day:       day,
+		AccountId: accountId,
+		BotId:     botId,
+		Kind:      kind,
+		Type:      usageType,
+	}
+}
+
+func (o *ErrorHandler) EnsureDailyUsageIndexes(db *mgo.Database) {
+	if o.Err != nil {
+		return
+	}
+
+	o.Err = db.C(DailyUsageCollection).EnsureIndex(mgo.Index{
+		Key:        []string{"accountId", "day"},
+		Background: true,
+	})
+}
+
+// SaveDailyUsages replaces the usages rolled up before, a day is rolled up
+// again until its counters stop growing.
+func (o *ErrorHandler) SaveDailyUsages(db *mgo.Database, usages []*DailyUsage) {
+	if o.Err != nil || len(usages) == 0 {
+		return
+	}
+
+	bulk := db.C(DailyUsageCollection).Bulk()
+	bulk.Unordered()
+	for _, usage := range usages {
+		bulk.Upsert(bson.M{"_id": usage.Id}, usage)
+	}
+
+	defer metrics.ObserveMongo(DailyUsageCollection, "upsert", time.Now())
+	_, o.Err = bulk.Run()
+}
+
+type DailyUsageCriteria struct {
+	AccountId string
+	BotId     string
+	Kind      string
+	// days as UsageDayLayout, both included
+	From string
+	To   string
+}
+
+func (o *ErrorHandler) QueryDailyUsages(db *mgo.Database, criteria DailyUsageCriteria) []DailyUsage {
+	if o.Err != nil {
+		return nil
+	}
+
+	query := bson.M{
+		"accountId": criteria.AccountId,
+		"day":       bson.M{"$gte": criteria.From, "$lte": criteria.To},
+	}
+	if criteria.BotId != "" {
+		query["botId"] = criteria.BotId
+	}
+	if criteria.Kind != "" {
+		query["kind"] = criteria.Kind
+	}
+
+	usages := []DailyUsage{}
+	defer metrics.ObserveMongo(DailyUsageCollection, "find", time.Now())
+
+	o.Err = db.C(DailyUsageCollection).Find(query).Sort("day", "botId", "kind", "type").All(&usages)
+	if o.Err != nil {
+		return nil
+	}
+
+	return usages
+}
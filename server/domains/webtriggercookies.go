@@ -53,11 +53,11 @@ func (o *ErrorHandler) LoadCookiesFromString(cookiestrings []string) []*http.Coo
 	resp, o.Err = http.ReadResponse(bufio.NewReader(strings.NewReader(rawResponse)), nil)
 
 	if o.Err != nil {
-		fmt.Printf("[WEBTRIGGER_COOKIE] err %v\n", o.Err)
+		logger.Error(o.Err, "[WEBTRIGGER_COOKIE] read response failed")
 		return []*http.Cookie{}
 	}
 
-	fmt.Printf("[WEBTRIGGER_COOKIE] load from string %v\n", resp.Cookies())
+	logger.Debug("[WEBTRIGGER_COOKIE] load from string %v", resp.Cookies())
 
 	return resp.Cookies()
 }
@@ -76,7 +76,7 @@ func (o *ErrorHandler) LoadWebTriggerCookies(pool *redis.Pool, header ChatMessag
 	}
 
 	if o.Err != nil {
-		fmt.Printf("[WEBTRIGGER_COOKIE] error %s\n", o.Err)
+		logger.Error(o.Err, "[WEBTRIGGER_COOKIE] load failed")
 	}
 
 	return o.LoadCookiesFromString(cstrings)
@@ -99,6 +99,6 @@ func (o *ErrorHandler) SaveWebTriggerCookies(
 	}
 	o.RedisDo(conn, timeout, "EXEC")
 	if o.Err != nil {
-		fmt.Printf("[WEBTRIGGER_COOKIE] error %s\n", o.Err)
+		logger.Error(o.Err, "[WEBTRIGGER_COOKIE] save failed")
 	}
 }
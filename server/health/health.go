@@ -0,0 +1,295 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/gomodule/redigo/redis"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+)
+
+const (
+	LivePath  string = "/healthz"
+	ReadyPath string = "/readyz"
+
+	// seconds between two rounds of checks, and each check's timeout
+	DefaultInterval int = 10
+	DefaultTimeout  int = 3
+)
+
+var logger = logging.New("health")
+
+// Check tells whether a dependency is up, returning nil when it is. An
+// informational check is reported without taking the service out.
+type Check struct {
+	Name          string
+	Check         func(ctx context.Context) error
+	Informational bool
+}
+
+type Result struct {
+	Name          string  `json:"name"`
+	Ok            bool    `json:"ok"`
+	Informational bool    `json:"informational,omitempty"`
+	LatencyMs     float64 `json:"latencyMs"`
+	Error         string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Ready     bool      `json:"ready"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Checker checks the dependencies of a service every interval, keeps the
+// last report for /readyz and sets the status of the grpc health servers
+// it serves.
+type Checker struct {
+	interval time.Duration
+	timeout  time.Duration
+	checks   []Check
+
+	mux     sync.Mutex
+	last    *Report
+	stopped bool
+	servers []*grpchealth.Server
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{
+		interval: time.Duration(DefaultInterval) * time.Second,
+		timeout:  time.Duration(DefaultTimeout) * time.Second,
+		checks:   checks,
+	}
+}
+
+// Serving registers a grpc health server, whose overall status, the one of
+// the empty service name, is SERVING while the checks pass.
+func (c *Checker) Serving(server *grpchealth.Server) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.servers = append(c.servers, server)
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if c.last != nil && c.last.Ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	server.SetServingStatus("", status)
+}
+
+// Stop marks the service not ready for good, when it is shutting down.
+func (c *Checker) Stop() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.stopped = true
+	for _, server := range c.servers {
+		server.Shutdown()
+	}
+}
+
+// Run checks all dependencies at once, each within the timeout.
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{
+		Ready:     true,
+		CheckedAt: time.Now(),
+		Checks:    make([]Result, len(c.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if !result.Ok && !result.Informational {
+			report.Ready = false
+		}
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.stopped {
+		report.Ready = false
+	}
+
+	if c.last != nil && c.last.Ready != report.Ready {
+		logger.Info("ready %v -> %v", c.last.Ready, report.Ready)
+	}
+	c.last = report
+
+	if !c.stopped {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if report.Ready {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		for _, server := range c.servers {
+			server.SetServingStatus("", status)
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	cctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panics %v", r)
+			}
+		}()
+		done <- check.Check(cctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-cctx.Done():
+		err = fmt.Errorf("timeout after %s", c.timeout)
+	}
+
+	result := Result{
+		Name:          check.Name,
+		Ok:            err == nil,
+		Informational: check.Informational,
+		LatencyMs:     float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		logger.With("check", check.Name).Warn("dependency down: %s", err)
+	}
+
+	return result
+}
+
+// Last returns the last report, running the checks when there is none yet.
+func (c *Checker) Last(ctx context.Context) *Report {
+	c.mux.Lock()
+	last := c.last
+	c.mux.Unlock()
+
+	if last == nil {
+		return c.Run(ctx)
+	}
+
+	return last
+}
+
+// Watch runs the checks every interval until ctx is done.
+func (c *Checker) Watch(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LiveHandler answers 204 while alive returns true, 503 otherwise. It does
+// not look at the dependencies, a service waiting for them is still alive.
+func LiveHandler(alive func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if alive() {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+}
+
+// ReadyHandler answers the last report, with 200 when ready and 503 when
+// not.
+func ReadyHandler(c *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Last(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// SQL checks a mysql connection pool, a *sql.DB or *sqlx.DB.
+func SQL(name string, db pinger) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		if db == nil {
+			return fmt.Errorf("not connected")
+		}
+		return db.PingContext(ctx)
+	}}
+}
+
+func Mongo(name string, db *mgo.Database) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		if db == nil || db.Session == nil {
+			return fmt.Errorf("not connected")
+		}
+
+		session := db.Session.Copy()
+		defer session.Close()
+
+		return session.Ping()
+	}}
+}
+
+func Redis(name string, pool *redis.Pool) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		if pool == nil {
+			return fmt.Errorf("not connected")
+		}
+
+		conn := pool.Get()
+		defer conn.Close()
+
+		_, err := conn.Do("PING")
+		return err
+	}}
+}
+
+// TCP checks that addr accepts connections, for services like fluentd
+// whose clients have no ping.
+func TCP(name string, addr string) Check {
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}}
+}
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"math"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -162,6 +163,84 @@ func NewHttpClient() *http.Client {
 	}
 }
 
+// the private and shared address ranges, besides loopback and link local
+var privateNets = func() []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// publicIP tells whether ip is routable on the internet, not a loopback,
+// private, link local or otherwise reserved address.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicUrl checks that rawurl is a http or https url whose host
+// resolves to public addresses only, for urls given by users that the
+// servers post to.
+func CheckPublicUrl(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("should be a http or https url, got %s", rawurl)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("no host in %s", rawurl)
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return fmt.Errorf("host %s resolves to non public address %s", u.Hostname(), ip)
+		}
+	}
+
+	return nil
+}
+
+// NewPublicHttpClient returns a client that only connects to public
+// addresses, checked on every dial, so urls given by users can neither
+// resolve nor redirect to the internal network.
+func NewPublicHttpClient() *http.Client {
+	client := NewHttpClient()
+	client.Transport.(*http.Transport).Dial = nil
+	client.Transport.(*http.Transport).DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 10 * 60 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("dial %s refused, not a public address", address)
+			}
+			return nil
+		},
+	}).DialContext
+
+	return client
+}
+
 func RestfulCall(req *RestfulRequest) (*RestfulResponse, error) {
 	client := NewHttpClient()
 	return RestfulCallCore(client, req)
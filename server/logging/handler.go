@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const LevelPath string = "/loglevels"
+
+// LevelHandler lists the package levels on GET, and sets the level of the
+// form param "package" to "level" on POST. An empty package sets the
+// default level.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPost:
+			r.ParseForm()
+			level, err := ParseLevel(r.Form.Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			SetLevel(r.Form.Get("package"), level)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Levels())
+	})
+}
@@ -0,0 +1,260 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (level Level) String() string {
+	if level < DebugLevel || level > ErrorLevel {
+		return fmt.Sprintf("level(%d)", int32(level))
+	}
+
+	return levelNames[level]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.ToLower(name) == n {
+			return Level(i), nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("log level %q not in %v", name, levelNames)
+}
+
+// Entry is one log line. Fields carry the structured context, botId,
+// clientId, arId and the like.
+type Entry struct {
+	Time    time.Time              `json:"time"`
+	Level   Level                  `json:"-"`
+	Package string                 `json:"package"`
+	Message string                 `json:"msg"`
+	Error   string                 `json:"error,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (e Entry) MarshalJSON() ([]byte, error) {
+	line := make(map[string]interface{}, len(e.Fields)+5)
+	for k, v := range e.Fields {
+		line[k] = v
+	}
+
+	line["time"] = e.Time.Format(time.RFC3339Nano)
+	line["level"] = e.Level.String()
+	line["package"] = e.Package
+	line["msg"] = e.Message
+	if e.Error != "" {
+		line["error"] = e.Error
+	}
+
+	return json.Marshal(line)
+}
+
+// Sink receives every entry that passed its package level.
+type Sink func(entry Entry)
+
+var (
+	muxLevels    sync.RWMutex
+	defaultLevel = InfoLevel
+	levels       = map[string]Level{}
+
+	muxOutput sync.Mutex
+	output    io.Writer = os.Stdout
+
+	muxSinks sync.RWMutex
+	sinks    []Sink
+)
+
+// SetLevel sets the level of a package, or the default of all packages
+// without their own when pkg is empty.
+func SetLevel(pkg string, level Level) {
+	muxLevels.Lock()
+	defer muxLevels.Unlock()
+
+	if pkg == "" {
+		defaultLevel = level
+	} else {
+		levels[pkg] = level
+	}
+}
+
+func GetLevel(pkg string) Level {
+	muxLevels.RLock()
+	defer muxLevels.RUnlock()
+
+	if level, found := levels[pkg]; found {
+		return level
+	}
+
+	return defaultLevel
+}
+
+// Levels lists the package levels, the default one under "".
+func Levels() map[string]string {
+	muxLevels.RLock()
+	defer muxLevels.RUnlock()
+
+	result := map[string]string{"": defaultLevel.String()}
+	for pkg, level := range levels {
+		result[pkg] = level.String()
+	}
+
+	return result
+}
+
+func SetOutput(w io.Writer) {
+	muxOutput.Lock()
+	defer muxOutput.Unlock()
+
+	output = w
+}
+
+func AddSink(sink Sink) {
+	muxSinks.Lock()
+	defer muxSinks.Unlock()
+
+	sinks = append(sinks, sink)
+}
+
+// Logger writes json lines for a package, with the fields given by With.
+type Logger struct {
+	pkg    string
+	fields map[string]interface{}
+}
+
+func New(pkg string) *Logger {
+	return &Logger{pkg: pkg}
+}
+
+// With returns a logger adding the key value pairs to every entry.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make(map[string]interface{}, len(l.fields)+len(kv)/2)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+
+	return &Logger{pkg: l.pkg, fields: fields}
+}
+
+func (l *Logger) Package() string {
+	return l.pkg
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= GetLevel(l.pkg)
+}
+
+func (l *Logger) Debug(msg string, v ...interface{}) {
+	l.log(DebugLevel, nil, msg, v...)
+}
+
+func (l *Logger) Info(msg string, v ...interface{}) {
+	l.log(InfoLevel, nil, msg, v...)
+}
+
+func (l *Logger) Warn(msg string, v ...interface{}) {
+	l.log(WarnLevel, nil, msg, v...)
+}
+
+func (l *Logger) Error(err error, msg string, v ...interface{}) {
+	l.log(ErrorLevel, err, msg, v...)
+}
+
+// StdLogger adapts l for apis that take a *log.Logger, like
+// http.Server.ErrorLog, writing each line at level.
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	return log.New(stdWriter{logger: l, level: level}, "", 0)
+}
+
+type stdWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.logger.log(w.level, nil, "%s", string(p))
+	return len(p), nil
+}
+
+func (l *Logger) log(level Level, err error, msg string, v ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Package: l.pkg,
+		Message: strings.TrimRight(fmt.Sprintf(msg, v...), "\n"),
+		Fields:  l.fields,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	line, jerr := json.Marshal(entry)
+	if jerr != nil {
+		line = []byte(fmt.Sprintf(`{"level":%q,"package":%q,"msg":%q}`,
+			level.String(), l.pkg, entry.Message))
+	}
+
+	muxOutput.Lock()
+	output.Write(append(line, '\n'))
+	muxOutput.Unlock()
+
+	muxSinks.RLock()
+	defer muxSinks.RUnlock()
+
+	for _, sink := range sinks {
+		sink(entry)
+	}
+}
+
+type Config struct {
+	// default level, info when empty
+	Level string
+	// levels by package, hub, bot, filter, web, tasks, streaming and so on
+	Packages map[string]string
+}
+
+func Configure(config Config) error {
+	if config.Level != "" {
+		level, err := ParseLevel(config.Level)
+		if err != nil {
+			return err
+		}
+		SetLevel("", level)
+	}
+
+	for pkg, name := range config.Packages {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return err
+		}
+		SetLevel(pkg, level)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -13,8 +14,12 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/replay"
+	"github.com/hawkwithwind/chat-bot-hub/server/simulator"
 	"github.com/hawkwithwind/chat-bot-hub/server/streaming"
 	"github.com/hawkwithwind/chat-bot-hub/server/tasks"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"github.com/hawkwithwind/chat-bot-hub/server/web"
 )
@@ -26,11 +31,16 @@ type MainConfig struct {
 	Fluent    utils.FluentConfig
 	Rabbitmq  utils.RabbitMQConfig
 	Streaming streaming.Config
+	Tasks     tasks.Config
+	Tracing   tracing.Config
+	Logging   logging.Config
+	Simulator simulator.Config
+	Replay    replay.Config
 }
 
 var (
 	configPath = flag.String("c", "/config/config.yml", "config file path")
-	startcmd   = flag.String("s", "", "start command: web/hub")
+	startcmd   = flag.String("s", "", "start command: web/hub/tasks/streaming/simulator/replay")
 	config     MainConfig
 )
 
@@ -80,6 +90,14 @@ func loadConfig(configPath string) (MainConfig, error) {
 	c.Streaming.WebBaseUrl = c.Web.Baseurl
 	c.Streaming.Oss = c.Hub.Oss
 
+	if c.Simulator.HubAddr == "" {
+		c.Simulator.HubAddr = fmt.Sprintf("hub:%s", c.Hub.Port)
+	}
+
+	c.Replay.Mongo = c.Web.Messagedb
+	c.Replay.Database = c.Web.Database
+	c.Replay.Fluent = c.Hub.Fluent
+
 	return c, nil
 }
 
@@ -99,6 +117,16 @@ func main() {
 
 	raven.SetDSN(config.Web.Sentry)
 
+	if err := logging.Configure(config.Logging); err != nil {
+		log.Printf("logging config failed %s, keep default levels", err)
+	}
+
+	shutdownTracing, err := tracing.Init(fmt.Sprintf("chat-bot-hub-%s", *startcmd), config.Tracing)
+	if err != nil {
+		log.Printf("tracing init failed %s, spans are dropped", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
 	if *startcmd == "web" {
 		wg.Add(1)
 
@@ -131,6 +159,7 @@ func main() {
 					Config:          config.Hub,
 					Webhost:         "web",
 					Webport:         config.Web.Port,
+					WebGrpcport:     config.Web.GrpcPort,
 					WebBaseUrl:      config.Web.Baseurl,
 					WebSecretPhrase: config.Web.SecretPhrase,
 				}
@@ -145,12 +174,16 @@ func main() {
 		go func() {
 			//defer wg.Done()
 			config.Web.Redis = config.Redis
+			config.Web.Rabbitmq = config.Rabbitmq
 
 			task := tasks.Tasks{
+				Config:     config.Tasks,
 				Webhost:    "web",
 				Webport:    config.Web.Port,
 				WebBaseUrl: config.Web.Baseurl,
 				WebConfig:  config.Web,
+				Hubhost:    "hub",
+				Hubport:    config.Hub.Port,
 			}
 
 			err := task.Serve()
@@ -177,7 +210,48 @@ func main() {
 		}()
 	}
 
+	if *startcmd == "simulator" {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sim := simulator.Simulator{
+				Config: config.Simulator,
+			}
+
+			err := sim.Serve()
+			if err != nil {
+				log.Printf("simulator start failed %s\n", err.Error())
+			}
+		}()
+	}
+
+	if *startcmd == "replay" {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			replayer := replay.Replayer{
+				Config: config.Replay,
+			}
+
+			err := replayer.Serve()
+			if err != nil {
+				log.Printf("replay failed %s\n", err.Error())
+			}
+		}()
+	}
+
 	time.Sleep(5 * time.Second)
 	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("tracing shutdown failed %s", err)
+	}
+
 	log.Printf("server ends.")
 }
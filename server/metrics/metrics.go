@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+)
+
+const (
+	Namespace string = "chatbothub"
+
+	Path string = "/metrics"
+)
+
+var (
+	// hub
+
+	TunnelEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "hub",
+		Name:      "tunnel_events_total",
+		Help:      "EventTunnel events, IN from clients and OUT to clients.",
+	}, []string{"direction", "event_type", "client_type"})
+
+	FilterFillSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "hub",
+		Name:      "filter_fill_seconds",
+		Help:      "Latency of filling a bot's filter chain, by source, MSG or MOMENT.",
+		Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"source"})
+
+	WebTriggers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "hub",
+		Name:      "webtrigger_total",
+		Help:      "WebTrigger calls by outcome.",
+	}, []string{"outcome"})
+
+	WebTriggerSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "hub",
+		Name:      "webtrigger_seconds",
+		Help:      "Latency of WebTrigger calls, retries included.",
+		Buckets:   []float64{.05, .1, .5, 1, 5, 10, 30},
+	})
+
+	// actions, counted by web and tasks, and by the hub queues
+
+	Actions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "actions_total",
+		Help:      "Bot actions by type and status, NEW when sent to the hub.",
+	}, []string{"action_type", "status"})
+
+	QuotaRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "action_quota_rejections_total",
+		Help:      "Bot actions rejected by a rate limit or a full queue, by type and limit.",
+	}, []string{"action_type", "limit"})
+
+	MqConsumeLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "mq",
+		Name:      "consume_lag_seconds",
+		Help:      "Time between the publish and the consume of RabbitMQ messages.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"queue"})
+
+	// alerting, in tasks
+
+	Alerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "alerting",
+		Name:      "alerts_total",
+		Help:      "Alerts fired and resolved, by kind.",
+	}, []string{"kind", "status"})
+
+	AlertDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "alerting",
+		Name:      "deliveries_total",
+		Help:      "Alert deliveries to webhooks and bot callbacks, by outcome.",
+	}, []string{"target", "ok"})
+
+	// storage
+
+	MysqlQuerySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "mysql_query_seconds",
+		Help:      "MySQL query latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"op"})
+
+	MongoQuerySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "mongo_query_seconds",
+		Help:      "Mongo query latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collection", "op"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		TunnelEvents,
+		FilterFillSeconds,
+		WebTriggers,
+		WebTriggerSeconds,
+		Actions,
+		QuotaRejections,
+		MqConsumeLag,
+		Alerts,
+		AlertDeliveries,
+		MysqlQuerySeconds,
+		MongoQuerySeconds,
+	)
+}
+
+// Since observes the seconds elapsed from start, for use in defer.
+func Since(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
+
+func ObserveMongo(collection string, op string, start time.Time) {
+	Since(MongoQuerySeconds.WithLabelValues(collection, op), start)
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+var logger = logging.New("metrics")
+
+// Serve serves /metrics, and /loglevels to adjust log levels at runtime, on
+// its own port, for services that have no http server of their own. It does
+// nothing when port is empty.
+func Serve(host string, port string) {
+	if port == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	mux.Handle(logging.LevelPath, logging.LevelHandler())
+
+	addr := fmt.Sprintf("%s:%s", host, port)
+	logger.Info("listening to %s%s", addr, Path)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error(err, "failed to serve")
+	}
+}
@@ -4,4 +4,15 @@ type MqEvent struct {
 	BotId     string `json:"botId"`
 	EventType string `json:"eventType"`
 	Body      string `json:"body"`
+	// trace context of the event, see tracing.Inject
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// BotStatusBody is the body of the events sent to the botStatus queue when
+// a bot logs in, fails to log in or logs out.
+type BotStatusBody struct {
+	Login      string `json:"login"`
+	ClientId   string `json:"clientId"`
+	ClientType string `json:"clientType"`
+	Message    string `json:"message,omitempty"`
 }
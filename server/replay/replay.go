@@ -0,0 +1,200 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+type ErrorHandler struct {
+	domains.ErrorHandler
+}
+
+// Config of a replay, the slice of the journal of one bot to feed back
+// through a sandboxed hub.
+type Config struct {
+	// where the hub journaled to, file or mongo, and for file the directory
+	Source string
+	Dir    string
+
+	// the bot replayed, by bot id or by client id
+	BotId    string
+	ClientId string
+
+	// RFC3339, the slice is from the whole journal when unset
+	From string
+	To   string
+
+	// message events replayed, all of MESSAGE, IMAGEMESSAGE and EMOJIMESSAGE
+	// when empty
+	EventTypes []string
+
+	// events replayed at most, 1000 when 0
+	Limit int
+
+	// file the results are written to as json lines, stdout when empty
+	Output string
+
+	// filled from the web config, the filters of the bot are built from the
+	// database when it connects, the journal is read from mongo
+	Mongo    utils.MongoConfig
+	Database utils.DatabaseConfig
+	Fluent   utils.FluentConfig
+}
+
+// Replayer feeds journaled message events of a bot to its filters in a hub
+// connected to nothing, and writes what every event went through: the
+// verified message, the filters filled, and the effects it would have had.
+type Replayer struct {
+	*logging.Logger
+
+	Config Config
+}
+
+func (r *Replayer) criteria() (domains.JournalCriteria, error) {
+	criteria := domains.JournalCriteria{
+		BotId:      r.Config.BotId,
+		ClientId:   r.Config.ClientId,
+		Direction:  domains.JOURNAL_IN,
+		EventTypes: r.Config.EventTypes,
+	}
+
+	if criteria.BotId == "" && criteria.ClientId == "" {
+		return criteria, fmt.Errorf("replay botId or clientId not set")
+	}
+
+	if len(criteria.EventTypes) == 0 {
+		criteria.EventTypes = []string{chatbothub.MESSAGE, chatbothub.IMAGEMESSAGE, chatbothub.EMOJIMESSAGE}
+	}
+
+	var err error
+	if r.Config.From != "" {
+		if criteria.From, err = time.Parse(time.RFC3339, r.Config.From); err != nil {
+			return criteria, fmt.Errorf("replay from %s invalid: %s", r.Config.From, err)
+		}
+	}
+	if r.Config.To != "" {
+		if criteria.To, err = time.Parse(time.RFC3339, r.Config.To); err != nil {
+			return criteria, fmt.Errorf("replay to %s invalid: %s", r.Config.To, err)
+		}
+	}
+
+	return criteria, nil
+}
+
+func (r *Replayer) load(criteria domains.JournalCriteria) ([]domains.JournalEntry, error) {
+	switch r.Config.Source {
+	case chatbothub.JOURNAL_FILE:
+		return chatbothub.ReadJournalDir(r.Config.Dir, criteria, r.Config.Limit)
+
+	case chatbothub.JOURNAL_MONGO:
+		o := &ErrorHandler{}
+		db := o.NewMongoConn(r.Config.Mongo.Host, r.Config.Mongo.Port, r.Config.Mongo.Database)
+		entries := o.QueryJournal(db, criteria, r.Config.Limit)
+		return entries, o.Err
+
+	default:
+		return nil, fmt.Errorf("replay source %s not supported, should be file or mongo", r.Config.Source)
+	}
+}
+
+// buildFilters sets the filters of the bot in the database on the sandbox
+// bot, the bot replays without filters when the database is unreachable.
+func (r *Replayer) buildFilters(hub *chatbothub.ChatHub, bot *chatbothub.ChatBot) {
+	if r.Config.Database.DataSourceName == "" {
+		return
+	}
+
+	o := &ErrorHandler{}
+	db := &dbx.Database{}
+	if o.Connect(db, "mysql", r.Config.Database.DataSourceName); o.Err != nil {
+		r.Error(o.Err, "connect database failed, replay without filters")
+		return
+	}
+	defer db.Conn.Close()
+
+	tx := o.Begin(db)
+	defer o.CommitOrRollback(tx)
+
+	thebot := o.GetBotById(tx, bot.BotId)
+	if o.Err != nil {
+		r.Error(o.Err, "get bot %s failed, replay without filters", bot.BotId)
+		return
+	}
+
+	if thebot == nil || !thebot.FilterId.Valid {
+		r.Info("bot %s has no filter", bot.BotId)
+		return
+	}
+
+	if err := hub.BuildBotFilters(tx, bot, thebot.FilterId.String); err != nil {
+		r.Error(err, "build filters %s failed, replay without filters", thebot.FilterId.String)
+		return
+	}
+
+	r.Info("bot %s filters from %s", bot.BotId, thebot.FilterId.String)
+}
+
+func (r *Replayer) Serve() error {
+	r.Logger = logging.New("replay")
+
+	if r.Config.Source == "" {
+		r.Config.Source = chatbothub.JOURNAL_FILE
+	}
+	if r.Config.Limit <= 0 {
+		r.Config.Limit = 1000
+	}
+
+	criteria, err := r.criteria()
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.load(criteria)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		r.Info("nothing to replay")
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	if r.Config.Output != "" {
+		f, err := os.Create(r.Config.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	first := entries[0]
+	hub := chatbothub.NewSandboxHub(chatbothub.ChatHubConfig{Fluent: r.Config.Fluent})
+	bot := hub.SandboxBot(first.BotId, first.Login, first.ClientId, first.ClientType)
+	r.buildFilters(hub, bot)
+
+	r.Info("replay %d events of bot %s login %s", len(entries), bot.BotId, bot.Login)
+
+	encoder := json.NewEncoder(out)
+	for i := range entries {
+		if !chatbothub.IsReplayable(entries[i].EventType) {
+			continue
+		}
+
+		if err := encoder.Encode(hub.Replay(bot, &entries[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
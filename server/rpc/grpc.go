@@ -1,18 +1,22 @@
 package rpc
 
 import (
+	"fmt"
 	"github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"time"
 )
 
 type GRPCWrapper struct {
 	conn *grpc.ClientConn
 
-	HubClient chatbothub.ChatBotHubClient
-	WebClient chatbotweb.ChatBotWebClient
+	HubClient      chatbothub.ChatBotHubClient
+	HubAdminClient chatbothub.ChatBotHubAdminClient
+	WebClient      chatbotweb.ChatBotWebClient
 
 	Context context.Context
 	cancel  context.CancelFunc
@@ -25,7 +29,7 @@ func CreateGRPCWrapper(addr string) *GRPCWrapper {
 	return &GRPCWrapper{
 		lastActive: time.Now(),
 		factory: func() (*grpc.ClientConn, error) {
-			return grpc.Dial(addr, grpc.WithInsecure())
+			return grpc.Dial(addr, grpc.WithInsecure(), tracing.DialOption())
 		},
 	}
 }
@@ -72,12 +76,35 @@ func (g *GRPCWrapper) Clone() (*GRPCWrapper, error) {
 	gctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 
 	return &GRPCWrapper{
-		conn:       g.conn,
-		HubClient:  chatbothub.NewChatBotHubClient(g.conn),
-		WebClient:  chatbotweb.NewChatBotWebClient(g.conn),
-		Context:    gctx,
-		cancel:     cancel,
-		lastActive: g.lastActive,
-		factory:    g.factory,
+		conn:           g.conn,
+		HubClient:      chatbothub.NewChatBotHubClient(g.conn),
+		HubAdminClient: chatbothub.NewChatBotHubAdminClient(g.conn),
+		WebClient:      chatbotweb.NewChatBotWebClient(g.conn),
+		Context:        gctx,
+		cancel:         cancel,
+		lastActive:     g.lastActive,
+		factory:        g.factory,
 	}, nil
 }
+
+// Health asks the server the wrapper dials for its grpc health status, nil
+// when it is SERVING.
+func (g *GRPCWrapper) Health(ctx context.Context) error {
+	w, err := g.Clone()
+	if err != nil {
+		return err
+	}
+
+	defer w.Cancel()
+
+	resp, err := healthpb.NewHealthClient(w.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+
+	return nil
+}
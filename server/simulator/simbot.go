@@ -0,0 +1,298 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/botclient"
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+)
+
+// simBot is one fake client, and the bot logged in on it.
+type simBot struct {
+	sim    *Simulator
+	index  int
+	client *botclient.Client
+
+	muxTraffic sync.Mutex
+	stop       chan struct{}
+}
+
+func (sim *Simulator) newSimBot(index int) *simBot {
+	bot := &simBot{
+		sim:   sim,
+		index: index,
+	}
+
+	bot.client = botclient.NewClient(botclient.Config{
+		Addr:       sim.Config.HubAddr,
+		ClientId:   fmt.Sprintf("%s-%d", sim.Config.ClientIdPrefix, index),
+		ClientType: sim.Config.ClientType,
+		Load:       sim.load,
+	})
+
+	bot.client.Handlers = botclient.Handlers{
+		OnLogin:    bot.onLogin,
+		OnLogout:   bot.onLogout,
+		OnShutdown: bot.onShutdown,
+	}
+
+	for _, actionType := range actionTypes {
+		bot.client.HandleAction(actionType, botclient.ActionHandlerFunc(bot.onAction))
+	}
+
+	return bot
+}
+
+func (bot *simBot) clientId() string {
+	return bot.client.Config.ClientId
+}
+
+func (bot *simBot) contact(k int) string {
+	return fmt.Sprintf("wxid_simcontact_%d_%d", bot.index, k)
+}
+
+func (bot *simBot) randomContact() string {
+	return bot.contact(bot.sim.intn(bot.sim.Config.Contacts))
+}
+
+func (bot *simBot) randomGroup() string {
+	return fmt.Sprintf("sim%d%02d@chatroom", bot.index, bot.sim.intn(5))
+}
+
+// onLogin plays a scan login: LOGINSCAN first, LOGINDONE after the login
+// delay, then the traffic starts.
+func (bot *simBot) onLogin(c *botclient.Client, body *botclient.LoginBody) {
+	login := body.Login
+	if login == "" {
+		login = fmt.Sprintf("wxid_simulator_%d", bot.index)
+	}
+
+	bot.sim.Info("c[%s] login b[%s] %s", bot.clientId(), body.BotId, login)
+
+	go func() {
+		if err := c.SendLoginScan(fmt.Sprintf("https://simulator.local/qrcode/%s", bot.clientId())); err != nil {
+			bot.sim.Error(err, "c[%s] send loginscan failed", bot.clientId())
+			return
+		}
+
+		time.Sleep(time.Duration(bot.sim.Config.LoginDelay) * time.Second)
+
+		err := c.SendLoginDone(botclient.LoginDoneBody{
+			BotId:    body.BotId,
+			UserName: login,
+			Alias:    fmt.Sprintf("simulator%d", bot.index),
+			Token:    bot.sim.randomId(),
+		})
+		if err != nil {
+			bot.sim.Error(err, "c[%s] send logindone failed", bot.clientId())
+			return
+		}
+
+		bot.startTraffic()
+	}()
+}
+
+func (bot *simBot) onLogout(c *botclient.Client) {
+	bot.stopTraffic()
+
+	if err := c.SendLogoutDone(""); err != nil {
+		bot.sim.Error(err, "c[%s] send logoutdone failed", bot.clientId())
+	}
+}
+
+func (bot *simBot) onShutdown(c *botclient.Client) {
+	bot.stopTraffic()
+}
+
+func (bot *simBot) startTraffic() {
+	bot.muxTraffic.Lock()
+	defer bot.muxTraffic.Unlock()
+
+	if bot.stop != nil {
+		close(bot.stop)
+	} else {
+		atomic.AddInt32(&bot.sim.loggedIn, 1)
+	}
+	bot.stop = make(chan struct{})
+
+	config := bot.sim.Config
+	bot.every(config.MessageInterval, bot.stop, bot.sendMessage)
+	bot.every(config.FriendRequestInterval, bot.stop, bot.sendFriendRequest)
+	bot.every(config.RoomJoinInterval, bot.stop, bot.sendRoomJoin)
+	bot.every(config.ContactInfoInterval, bot.stop, bot.sendContactInfo)
+}
+
+func (bot *simBot) stopTraffic() {
+	bot.muxTraffic.Lock()
+	defer bot.muxTraffic.Unlock()
+
+	if bot.stop != nil {
+		close(bot.stop)
+		bot.stop = nil
+		atomic.AddInt32(&bot.sim.loggedIn, -1)
+	}
+}
+
+func (bot *simBot) every(interval int, stop chan struct{}, send func() error) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := send(); err != nil {
+					bot.sim.Error(err, "c[%s] send traffic failed", bot.clientId())
+				}
+			}
+		}
+	}()
+}
+
+func (bot *simBot) sendMessage() error {
+	from := bot.randomContact()
+	groupId := ""
+	if bot.sim.chance(bot.sim.Config.GroupMessageRatio) {
+		groupId = bot.randomGroup()
+	}
+
+	return bot.client.SendMessage(botclient.MESSAGE, botclient.Message{
+		MsgId:     bot.sim.randomId(),
+		FromUser:  from,
+		ToUser:    bot.client.Login,
+		GroupId:   groupId,
+		Content:   fmt.Sprintf("simulated message %d from %s", bot.sim.intn(10000), from),
+		MType:     1,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// sendFriendRequest sends the xml a WECHATBOT client receives, the hub
+// parses it into a WechatFriendRequest, or the json of a WECHATMACPRO.
+func (bot *simBot) sendFriendRequest() error {
+	from := fmt.Sprintf("wxid_simstranger_%d_%d", bot.index, bot.sim.intn(100000))
+	if bot.sim.Config.ClientType == chatbothub.WECHATMACPRO {
+		return bot.client.Send(botclient.FRIENDREQUEST, map[string]interface{}{
+			"content": map[string]interface{}{
+				"fromUserName":    from,
+				"encryptUserName": fmt.Sprintf("v1_%s@stranger", bot.sim.randomId()),
+				"fromNickName":    fmt.Sprintf("stranger %s", from),
+				"content":         fmt.Sprintf("hello from %s", from),
+				"scene":           "30",
+				"sex":             "1",
+			},
+		})
+	}
+
+	content := fmt.Sprintf(
+		`<msg fromusername="%s" encryptusername="v1_%s@stranger" fromnickname="stranger %s" content="hello from %s" scene="30" sex="1" />`,
+		from, bot.sim.randomId(), from, from)
+
+	return bot.client.Send(botclient.FRIENDREQUEST, map[string]interface{}{
+		"content": content,
+	})
+}
+
+// sendRoomJoin is only scheduled for WECHATMACPRO, see Simulator.Serve.
+func (bot *simBot) sendRoomJoin() error {
+	return bot.client.Send(botclient.ROOMJOIN, map[string]interface{}{
+		"groupId":  bot.randomGroup(),
+		"inviter":  bot.randomContact(),
+		"members":  []string{bot.randomContact()},
+		"joinedAt": time.Now().Unix(),
+	})
+}
+
+func (bot *simBot) sendContactInfo() error {
+	k := bot.sim.intn(bot.sim.Config.Contacts)
+	return bot.client.Send(botclient.CONTACTINFO, map[string]interface{}{
+		"userName":  bot.contact(k),
+		"nickName":  fmt.Sprintf("contact %d", k),
+		"alias":     fmt.Sprintf("simcontact%d", k),
+		"sex":       k % 3,
+		"country":   "CN",
+		"provincia": "Shanghai",
+		"city":      "Shanghai",
+		"signature": "simulated contact",
+		"bigHead":   fmt.Sprintf("https://simulator.local/head/%d", k),
+		"smallHead": fmt.Sprintf("https://simulator.local/head/%d/small", k),
+	})
+}
+
+// onAction answers every action after the configured latency, failing some
+// of them on purpose.
+func (bot *simBot) onAction(c *botclient.Client, action *botclient.BotAction) (map[string]interface{}, error) {
+	time.Sleep(bot.sim.latency())
+
+	if bot.sim.chance(bot.sim.Config.FailureRate) {
+		return nil, &botclient.ActionError{
+			Status:  1,
+			Message: fmt.Sprintf("simulated failure of %s", action.ActionType),
+		}
+	}
+
+	params := map[string]interface{}{}
+	if action.Body != "" {
+		if err := json.Unmarshal([]byte(action.Body), &params); err != nil {
+			return nil, &botclient.ActionError{
+				Status:  -1,
+				Message: fmt.Sprintf("malformed action body %s", err),
+			}
+		}
+	}
+
+	data := map[string]interface{}{"status": 0}
+
+	switch action.ActionType {
+	case chatbothub.SendTextMessage, chatbothub.SendAppMessage:
+		data["msgId"] = bot.sim.randomId()
+		data["content"] = params["content"]
+
+	case chatbothub.SendImageMessage, chatbothub.SendImageResourceMessage:
+		data["msgId"] = bot.sim.randomId()
+		data["imageId"] = fmt.Sprintf("simimage%s", bot.sim.randomId())
+
+	case chatbothub.GetContact, chatbothub.SearchContact, chatbothub.CheckContact:
+		userId, _ := params["userId"].(string)
+		if userId == "" {
+			userId = bot.randomContact()
+		}
+		data["data"] = map[string]interface{}{
+			"userName": userId,
+			"nickName": fmt.Sprintf("nick of %s", userId),
+		}
+
+	case chatbothub.GetRoomMembers:
+		members := []map[string]interface{}{}
+		for i := 0; i < 3; i++ {
+			members = append(members, map[string]interface{}{"userName": bot.randomContact()})
+		}
+		data["data"] = members
+
+	case chatbothub.CreateRoom:
+		data["data"] = map[string]interface{}{"userName": bot.randomGroup()}
+
+	case chatbothub.GetRoomQRCode, chatbothub.GetContactQRCode:
+		data["data"] = map[string]interface{}{
+			"url": fmt.Sprintf("https://simulator.local/qrcode/%s", bot.sim.randomId()),
+		}
+
+	default:
+		if strings.HasPrefix(action.ActionType, "Sns") {
+			data["data"] = map[string]interface{}{"objectList": []interface{}{}}
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,194 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/botclient"
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+)
+
+// Config of the simulator. Intervals are in seconds, an interval of 0
+// turns that kind of traffic off.
+type Config struct {
+	// hub address, host:port
+	HubAddr        string
+	Clients        int
+	ClientIdPrefix string
+	// client type the clients register as, WECHATBOT when empty or
+	// WECHATMACPRO. The hub forwards ROOMJOIN of WECHATMACPRO only.
+	ClientType string
+	// region label the clients report with their load
+	Region string
+
+	// seconds between the LOGIN and the LOGINDONE of the scripted login
+	LoginDelay int
+
+	MessageInterval       int
+	GroupMessageRatio     float64
+	FriendRequestInterval int
+	RoomJoinInterval      int
+	ContactInfoInterval   int
+	Contacts              int
+
+	// every action reply is delayed between LatencyMin and LatencyMax
+	// milliseconds, and fails with the probability FailureRate
+	LatencyMin  int
+	LatencyMax  int
+	FailureRate float64
+}
+
+// Simulator runs fake WECHATBOT or WECHATMACPRO clients against a hub, for
+// local development. The clients register, log in when asked to, emit
+// synthetic traffic and answer every bot action.
+type Simulator struct {
+	*logging.Logger
+
+	Config Config
+
+	muxRand sync.Mutex
+	rand    *rand.Rand
+
+	// bots logged in on the simulated clients
+	loggedIn int32
+}
+
+func (sim *Simulator) init() {
+	sim.Logger = logging.New("simulator")
+
+	sim.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	if sim.Config.Clients <= 0 {
+		sim.Config.Clients = 1
+	}
+	if sim.Config.ClientIdPrefix == "" {
+		sim.Config.ClientIdPrefix = "simulator"
+	}
+	if sim.Config.ClientType == "" {
+		sim.Config.ClientType = chatbothub.WECHATBOT
+	}
+	if sim.Config.Contacts <= 0 {
+		sim.Config.Contacts = 20
+	}
+	if sim.Config.LatencyMax < sim.Config.LatencyMin {
+		sim.Config.LatencyMax = sim.Config.LatencyMin
+	}
+}
+
+func (sim *Simulator) Serve() error {
+	sim.init()
+
+	if sim.Config.HubAddr == "" {
+		return fmt.Errorf("simulator hub address not set")
+	}
+
+	switch sim.Config.ClientType {
+	case chatbothub.WECHATBOT:
+		// the hub drops the ROOMJOIN of WECHATBOT, none is sent
+		if sim.Config.RoomJoinInterval > 0 {
+			sim.Warn("room joins of %s are dropped by the hub, RoomJoinInterval ignored", sim.Config.ClientType)
+			sim.Config.RoomJoinInterval = 0
+		}
+	case chatbothub.WECHATMACPRO:
+	default:
+		return fmt.Errorf("simulator client type %s not supported, should be %s or %s",
+			sim.Config.ClientType, chatbothub.WECHATBOT, chatbothub.WECHATMACPRO)
+	}
+
+	sim.Info("start %d clients to %s", sim.Config.Clients, sim.Config.HubAddr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < sim.Config.Clients; i++ {
+		wg.Add(1)
+
+		bot := sim.newSimBot(i)
+		go func() {
+			defer wg.Done()
+			if err := bot.client.Run(context.Background()); err != nil {
+				sim.Error(err, "c[%s] stopped", bot.client.Config.ClientId)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (sim *Simulator) intn(n int) int {
+	sim.muxRand.Lock()
+	defer sim.muxRand.Unlock()
+
+	if n <= 0 {
+		return 0
+	}
+
+	return sim.rand.Intn(n)
+}
+
+func (sim *Simulator) chance(p float64) bool {
+	sim.muxRand.Lock()
+	defer sim.muxRand.Unlock()
+
+	return sim.rand.Float64() < p
+}
+
+func (sim *Simulator) latency() time.Duration {
+	ms := sim.Config.LatencyMin + sim.intn(sim.Config.LatencyMax-sim.Config.LatencyMin+1)
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (sim *Simulator) load() botclient.PingBody {
+	return botclient.PingBody{
+		Bots:   int(atomic.LoadInt32(&sim.loggedIn)),
+		Region: sim.Config.Region,
+	}
+}
+
+func (sim *Simulator) randomId() string {
+	return fmt.Sprintf("%d%06d", time.Now().UnixNano(), sim.intn(1000000))
+}
+
+// actionTypes the simulated clients declare at REGISTER. All of them are
+// answered, so that the web never waits on an unsupported action.
+var actionTypes = []string{
+	chatbothub.AddContact,
+	chatbothub.DeleteContact,
+	chatbothub.AcceptUser,
+	chatbothub.SendTextMessage,
+	chatbothub.SendAppMessage,
+	chatbothub.SendImageMessage,
+	chatbothub.SendImageResourceMessage,
+	chatbothub.CreateRoom,
+	chatbothub.AddRoomMember,
+	chatbothub.InviteRoomMember,
+	chatbothub.GetRoomMembers,
+	chatbothub.DeleteRoomMember,
+	chatbothub.SetRoomAnnouncement,
+	chatbothub.SetRoomName,
+	chatbothub.GetRoomQRCode,
+	chatbothub.GetContactQRCode,
+	chatbothub.GetContact,
+	chatbothub.CheckContact,
+	chatbothub.SearchContact,
+	chatbothub.SyncContact,
+	chatbothub.SnsTimeline,
+	chatbothub.SnsUserPage,
+	chatbothub.SnsGetObject,
+	chatbothub.SnsComment,
+	chatbothub.SnsLike,
+	chatbothub.SnsUpload,
+	chatbothub.SnsobjectOP,
+	chatbothub.SnsSendMoment,
+	chatbothub.GetLabelList,
+	chatbothub.AddLabel,
+	chatbothub.DeleteLabel,
+	chatbothub.SetLabel,
+	chatbothub.GetRequestToken,
+	chatbothub.RequestUrl,
+}
@@ -0,0 +1,15 @@
+package streaming
+
+import (
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
+)
+
+// newChecker checks the dependencies streaming relays through: mongo for
+// message history, the hub it streams from and web for bots and auth.
+func (server *Server) newChecker() *health.Checker {
+	return health.NewChecker(
+		health.Mongo("mongo", server.mongoDb),
+		health.Check{Name: "hub", Check: server.hubGRPCWrapper.Health},
+		health.Check{Name: "web", Check: server.webGRPCWrapper.Health},
+	)
+}
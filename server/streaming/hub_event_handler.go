@@ -2,7 +2,6 @@ package streaming
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/globalsign/mgo/bson"
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
@@ -36,7 +35,7 @@ func (server *Server) onHubEvent(event *pb.EventReply) {
 		wechatMessage := domains.WechatMessage{}
 		err := json.Unmarshal([]byte(event.Body), &wechatMessage)
 		if err != nil {
-			fmt.Printf("[on hub event] unmarshal json failed %s\n", event.Body)
+			server.Error(err, "[on hub event] unmarshal json failed %s", event.Body)
 			return
 		}
 
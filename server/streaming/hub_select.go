@@ -2,6 +2,7 @@ package streaming
 
 import (
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"io"
 	"time"
 
@@ -90,7 +91,7 @@ func (server *Server) _select() {
 	for _, addr := range server.Config.Chathubs {
 		go func(addr string) {
 			for {
-				conn, err := grpc.Dial(addr, grpc.WithInsecure())
+				conn, err := grpc.Dial(addr, grpc.WithInsecure(), tracing.DialOption())
 				defer conn.Close()
 
 				if err != nil {
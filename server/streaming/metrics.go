@@ -0,0 +1,37 @@
+package streaming
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+// registerMetrics exposes the websocket connections and subscriptions,
+// counted at every scrape.
+func (server *Server) registerMetrics() {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "streaming",
+		Name:      "websocket_connections",
+		Help:      "Websocket connections to this streaming server.",
+	}, func() float64 {
+		return float64(utils.MapLen(server.websocketConnections))
+	}))
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "streaming",
+		Name:      "websocket_subscriptions",
+		Help:      "Subscriptions of websocket connections to bot resources.",
+	}, func() float64 {
+		subs := 0
+		server.botAndWsConnectionSubInfo.Range(func(key, value interface{}) bool {
+			subs += utils.MapLen(value.(*sync.Map))
+			return true
+		})
+		return float64(subs)
+	}))
+}
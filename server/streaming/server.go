@@ -5,10 +5,11 @@ import (
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/globalsign/mgo"
 	chatbotweb "github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
-	"github.com/hawkwithwind/logger"
 	"net/http"
 	"sync"
 
@@ -23,6 +24,8 @@ type ErrorHandler struct {
 type Config struct {
 	Host string
 	Port string
+	// port serving /metrics and /loglevels, off when empty
+	MetricsPort string
 
 	Chathubs    []string
 	ChatWebGrpc string
@@ -37,7 +40,7 @@ type Config struct {
 }
 
 type Server struct {
-	*logger.Logger
+	*logging.Logger
 
 	Config Config
 	chmsg  chan *pb.EventReply
@@ -46,6 +49,7 @@ type Server struct {
 	botAndWsConnectionSubInfo *sync.Map
 
 	mongoDb *mgo.Database
+	usage   *domains.UsageMeter
 
 	restfulclient *http.Client
 
@@ -54,12 +58,12 @@ type Server struct {
 
 	hubGRPCWrapper *rpc.GRPCWrapper
 	webGRPCWrapper *rpc.GRPCWrapper
+
+	checker *health.Checker
 }
 
 func (server *Server) init() error {
-	server.Logger = logger.New()
-	server.Logger.SetPrefix("[STREAMING]")
-	_ = server.Logger.Init()
+	server.Logger = logging.New("streaming")
 
 	server.chmsg = make(chan *pb.EventReply, 1000)
 	server.websocketConnections = &sync.Map{}
@@ -67,7 +71,7 @@ func (server *Server) init() error {
 
 	o := &ErrorHandler{}
 
-	server.Debug("connecting to mongo, host:%s port:%s\n", server.Config.Mongo.Host, server.Config.Mongo.Port)
+	server.Debug("connecting to mongo, host:%s port:%s", server.Config.Mongo.Host, server.Config.Mongo.Port)
 	server.mongoDb = o.NewMongoConn(server.Config.Mongo.Host, server.Config.Mongo.Port, server.Config.Mongo.Database)
 	if o.Err != nil {
 		server.Error(o.Err, "connect to mongo failed %s", o.Err)
@@ -79,6 +83,12 @@ func (server *Server) init() error {
 		return o.Err
 	}
 
+	if o.EnsureUsageCounterIndexes(server.mongoDb); o.Err != nil {
+		server.Error(o.Err, "usage mongo ensure indexes fail")
+		return o.Err
+	}
+	server.usage = domains.NewUsageMeter()
+
 	server.restfulclient = httpx.NewHttpClient()
 
 	ossClient, err := oss.New(server.Config.Oss.Region, server.Config.Oss.Accesskeyid, server.Config.Oss.Accesskeysecret, oss.UseCname(true))
@@ -99,6 +109,8 @@ func (server *Server) init() error {
 	server.hubGRPCWrapper = rpc.CreateGRPCWrapper(server.Config.Chathubs[0])
 	server.webGRPCWrapper = rpc.CreateGRPCWrapper(server.Config.ChatWebGrpc)
 
+	server.checker = server.newChecker()
+
 	return nil
 }
 
@@ -108,6 +120,7 @@ func (server *Server) Serve() error {
 	}
 
 	server.StartHubClient()
+	go server.meterloop()
 
 	return server.ServeWebsocketServer()
 }
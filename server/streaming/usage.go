@@ -0,0 +1,70 @@
+package streaming
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+)
+
+// seconds between two flushes of the websocket time metered
+const usageFlushInterval = 10 * time.Second
+
+// meterUntil counts the whole seconds the connection was open since it
+// was last metered, for the account it is authorized as.
+func (wsConnection *WsConnection) meterUntil(now time.Time) {
+	usage := wsConnection.server.usage
+	if usage == nil || wsConnection.authUser == nil {
+		return
+	}
+
+	for {
+		last := atomic.LoadInt64(&wsConnection.meteredAt)
+		seconds := (now.UnixNano() - last) / int64(time.Second)
+		if seconds <= 0 {
+			return
+		}
+
+		if atomic.CompareAndSwapInt64(&wsConnection.meteredAt, last, last+seconds*int64(time.Second)) {
+			usage.Add(domains.USAGE_SCOPE_ACCOUNT, wsConnection.authUser.AccountName,
+				domains.USAGE_WEBSOCKET_SECONDS, "", seconds)
+			return
+		}
+	}
+}
+
+func (server *Server) flushUsage(now time.Time) {
+	server.websocketConnections.Range(func(key, value interface{}) bool {
+		key.(*WsConnection).meterUntil(now)
+		return true
+	})
+
+	o := &ErrorHandler{}
+	if o.FlushUsageMeter(server.mongoDb, server.usage); o.Err != nil {
+		server.Error(o.Err, "flush usage failed")
+	}
+}
+
+// meterloop flushes the usage every usageFlushInterval, and a last time
+// when streaming is stopped.
+func (server *Server) meterloop() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			server.flushUsage(now)
+		case sig := <-sigs:
+			server.Info("received %s, flush usage and exit", sig)
+			server.flushUsage(time.Now())
+			os.Exit(0)
+		}
+	}
+}
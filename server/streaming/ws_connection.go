@@ -29,6 +29,7 @@ type WsConnection struct {
 	authUser *utils.AuthUser
 
 	eventSeq          int64
+	meteredAt         int64
 	eventHandlers     *sync.Map
 	ackCallbacks      *sync.Map
 	eventsToWriteChan chan WsEvent
@@ -64,6 +65,8 @@ func (wsConnection *WsConnection) Close() error {
 
 	close(wsConnection.eventsToWriteChan)
 
+	wsConnection.meterUntil(time.Now())
+
 	result := wsConnection.conn.Close()
 
 	_, _ = wsConnection.emitEvent("close", nil)
@@ -85,6 +88,7 @@ func (server *Server) CreateWsConnection(wsConnection *websocket.Conn, token str
 	result.eventsToWriteChan = make(chan WsEvent, 128)
 	result.hubToken = token
 	result.authUser = user
+	result.meteredAt = time.Now().UnixNano()
 
 	return result
 }
@@ -231,7 +235,7 @@ func (wsConnection *WsConnection) invokeAckCallback(seq int64, payload interface
 	val, ok := wsConnection.ackCallbacks.Load(seq)
 	if !ok {
 		err := errors.New("ack not found for seq:" + strconv.FormatInt(seq, 10))
-		wsConnection.server.Error(err, "ack not found for seq: ", seq)
+		wsConnection.server.Error(err, "ack not found for seq: %v", seq)
 		return err
 	}
 
@@ -1,10 +1,13 @@
 package streaming
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/websocket"
 	chatbotweb "github.com/hawkwithwind/chat-bot-hub/proto/web"
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"github.com/pkg/errors"
 	"net/http"
@@ -89,6 +92,16 @@ func (server *Server) ServeWebsocketServer() error {
 		server.acceptWebsocketConnection(w, r)
 	})
 
+	server.registerMetrics()
+	http.Handle(metrics.Path, metrics.Handler())
+
+	// alive as long as it serves, ready while its dependencies are up
+	http.Handle(health.LivePath, health.LiveHandler(func() bool { return true }))
+	http.Handle(health.ReadyPath, health.ReadyHandler(server.checker))
+	go server.checker.Watch(context.Background())
+	// log levels are only adjustable on the internal metrics port
+	go metrics.Serve(server.Config.Host, server.Config.MetricsPort)
+
 	addr := fmt.Sprintf("%s:%s", server.Config.Host, server.Config.Port)
 	server.Info("websocket server listening to %s\n", addr)
 
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"fmt"
+	"time"
+)
+
+// windows rolled up when none are configured
+var DefaultActionStatsWindows = []string{"1h", "24h"}
+
+type ActionStatsConfig struct {
+	// seconds between two rollups, rollups are off when 0
+	Interval int
+	// rolling windows, as time.ParseDuration accepts, like 1h or 24h
+	Windows []string
+}
+
+// RollupActions computes and stores the action latency and success rate
+// rollups of every configured window.
+func (tasks *Tasks) RollupActions(windows []time.Duration) {
+	now := time.Now()
+
+	for _, window := range windows {
+		o := &ErrorHandler{}
+		start := time.Now()
+
+		rollups := o.ComputeActionRollups(tasks.apilogDb, window, now)
+		o.SaveActionRollups(tasks.apilogDb, rollups)
+		if o.Err != nil {
+			tasks.Error(o.Err, "rollup actions of %s failed", window)
+			continue
+		}
+
+		tasks.Info("rollup actions of %s, %d rollups in %s", window, len(rollups), time.Since(start))
+	}
+}
+
+func (tasks *Tasks) initActionStats() ([]time.Duration, error) {
+	names := tasks.Config.ActionStats.Windows
+	if len(names) == 0 {
+		names = DefaultActionStatsWindows
+	}
+
+	windows := []time.Duration{}
+	for _, name := range names {
+		window, err := time.ParseDuration(name)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("action stats window %s invalid", name)
+		}
+		windows = append(windows, window)
+	}
+
+	o := &ErrorHandler{}
+	if o.EnsureActionRollupIndexes(tasks.apilogDb); o.Err != nil {
+		return nil, o.Err
+	}
+
+	return windows, nil
+}
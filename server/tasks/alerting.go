@@ -0,0 +1,410 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/models"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	// seconds without ping before a bot is offline, when the rule has no threshold
+	DefaultOfflineThreshold float64 = 120
+	// seconds of actions to compute the failing rate of, when the rule has no window
+	DefaultFailingWindow int = 600
+	// the ARHOUR keys counting the actions live for an hour
+	MaxFailingWindow int = 3600
+
+	ALERT_EVENT string = "ALERT"
+
+	deliveryWebhook  string = "webhook"
+	deliveryCallback string = "callback"
+)
+
+type AlertingConfig struct {
+	// seconds between two evaluations of the rules, alerting is off when 0,
+	// status events need NotifyBotStatus of the hub on
+	Interval int
+	// urls every alert is posted to, besides the webhook of its rule
+	Webhooks []string
+}
+
+// Alerter evaluates the alert rules of the accounts against the bots, on
+// a timer for ping staleness and failing actions, and on the status events
+// the hub sends for login failures and logouts.
+type Alerter struct {
+	tasks  *Tasks
+	config AlertingConfig
+
+	// posts to the webhooks of the rules, set by users, public addresses only
+	publicclient *http.Client
+
+	// the last ping in ms of the bots seen, kept after they leave the hub
+	mux      sync.Mutex
+	lastPing map[string]int64
+}
+
+func (tasks *Tasks) NewAlerter() *Alerter {
+	return &Alerter{
+		tasks:        tasks,
+		config:       tasks.Config.Alerting,
+		publicclient: httpx.NewPublicHttpClient(),
+		lastPing:     make(map[string]int64),
+	}
+}
+
+func (al *Alerter) Serve() {
+	go al.consumeStatus()
+
+	ticker := time.NewTicker(time.Duration(al.config.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if err := al.evaluate(); err != nil {
+			al.tasks.Error(err, "evaluate alert rules failed")
+		}
+	}
+}
+
+func (al *Alerter) consumeStatus() {
+	ticker := time.NewTicker(5 * time.Second)
+
+	for ; ; <-ticker.C {
+		msgs, err := al.tasks.rabbitmq.Consume(
+			utils.CH_BotStatus,          // queue
+			utils.CONSU_TASKS_BotStatus, // consumer
+			false,                       // auto-ack
+			false,                       // exclusive
+			false,                       // no-local
+			false,                       // no-wait
+		)
+
+		if err != nil {
+			al.tasks.Error(err, "failed to register a consumer")
+			continue
+		}
+
+		for d := range msgs {
+			if !d.Timestamp.IsZero() {
+				metrics.MqConsumeLag.WithLabelValues(utils.CH_BotStatus).Observe(time.Since(d.Timestamp).Seconds())
+			}
+
+			mqEvent := models.MqEvent{}
+			if err := json.Unmarshal(d.Body, &mqEvent); err != nil {
+				al.tasks.Error(err, "unmarshal mqevent failed")
+				d.Ack(false)
+				continue
+			}
+
+			if err := al.processStatus(mqEvent); err != nil {
+				al.tasks.Error(err, "process bot status %s of b[%s] failed", mqEvent.EventType, mqEvent.BotId)
+			}
+
+			d.Ack(false)
+		}
+	}
+}
+
+// processStatus fires LOGINFAILED and LOGOUT alerts on the events, and
+// resolves both when the bot logs in again.
+func (al *Alerter) processStatus(event models.MqEvent) error {
+	o := &ErrorHandler{}
+
+	body := models.BotStatusBody{}
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		return err
+	}
+
+	bot := o.GetBotById(al.tasks.db.Conn, event.BotId)
+	rules := o.GetAlertRules(al.tasks.db.Conn)
+	if o.Err != nil {
+		return o.Err
+	}
+
+	if bot == nil {
+		return fmt.Errorf("bot %s not found", event.BotId)
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleMatches(rule, bot) {
+			continue
+		}
+
+		switch event.EventType {
+		case chatbothub.LOGINFAILED:
+			if rule.Kind == domains.ALERT_LOGINFAILED {
+				al.fire(rule, bot, body.Login, fmt.Sprintf("bot %s failed to login: %s", bot.BotName, body.Message))
+			}
+		case chatbothub.LOGOUTDONE:
+			if rule.Kind == domains.ALERT_LOGOUT {
+				al.fire(rule, bot, body.Login, fmt.Sprintf("bot %s logged out: %s", bot.BotName, body.Message))
+			}
+		case chatbothub.LOGINDONE:
+			if rule.Kind == domains.ALERT_LOGINFAILED || rule.Kind == domains.ALERT_LOGOUT {
+				al.resolve(rule, bot)
+			}
+		}
+	}
+
+	return nil
+}
+
+// evaluate checks the BOTOFFLINE and ACTIONFAILING rules against the bots
+// the hubs know and the failing actions kept in redis.
+func (al *Alerter) evaluate() error {
+	o := &ErrorHandler{}
+
+	pings, err := al.pings()
+	if err != nil {
+		return err
+	}
+
+	rules := o.GetAlertRules(al.tasks.db.Conn)
+	if o.Err != nil {
+		return o.Err
+	}
+
+	conn := al.tasks.redispool.Get()
+	defer conn.Close()
+
+	accountBots := map[string][]domains.Bot{}
+	now := time.Now().UnixNano() / 1e6
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind != domains.ALERT_BOTOFFLINE && rule.Kind != domains.ALERT_ACTIONFAILING {
+			continue
+		}
+
+		bots, ok := accountBots[rule.AccountId]
+		if !ok {
+			bots = o.GetBotsByAccountId(al.tasks.db.Conn, rule.AccountId)
+			if o.Err != nil {
+				return o.Err
+			}
+			accountBots[rule.AccountId] = bots
+		}
+
+		for j := range bots {
+			bot := &bots[j]
+			if !ruleMatches(rule, bot) {
+				continue
+			}
+
+			switch rule.Kind {
+			case domains.ALERT_BOTOFFLINE:
+				lastPing, seen := pings[bot.BotId]
+				if !seen {
+					// never seen since tasks started, cannot tell when it pinged
+					continue
+				}
+
+				threshold := rule.Threshold
+				if threshold <= 0 {
+					threshold = DefaultOfflineThreshold
+				}
+
+				silence := float64(now-lastPing) / 1000
+				if silence > threshold {
+					al.fire(rule, bot, bot.Login, fmt.Sprintf(
+						"bot %s has not pinged for %.0f seconds", bot.BotName, silence))
+				} else {
+					al.resolve(rule, bot)
+				}
+
+			case domains.ALERT_ACTIONFAILING:
+				if bot.Login == "" {
+					continue
+				}
+
+				window := rule.Window
+				if window <= 0 {
+					window = DefaultFailingWindow
+				} else if window > MaxFailingWindow {
+					window = MaxFailingWindow
+				}
+
+				failed, total := o.LoginActionCounts(conn, bot.Login, int64(window))
+				if o.Err != nil {
+					return o.Err
+				}
+
+				if total > 0 && failed > 0 && float64(failed)/float64(total) >= rule.Threshold {
+					al.fire(rule, bot, bot.Login, fmt.Sprintf(
+						"bot %s failed %d of %d actions in %d seconds", bot.BotName, failed, total, window))
+				} else {
+					al.resolve(rule, bot)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// pings returns the last ping of the bots, the ones logged in now from the
+// hubs, and the ones gone since from the last time they were seen.
+func (al *Alerter) pings() (map[string]int64, error) {
+	wrapper, err := al.tasks.NewHubGRPCWrapper()
+	if err != nil {
+		return nil, err
+	}
+	defer wrapper.Cancel()
+
+	reply, err := wrapper.HubClient.GetBots(wrapper.Context, &pb.BotsRequest{})
+	if err != nil {
+		return nil, err
+	} else if reply == nil {
+		return nil, fmt.Errorf("getbots reply is nil")
+	}
+
+	al.mux.Lock()
+	defer al.mux.Unlock()
+
+	for _, info := range reply.BotsInfo {
+		if info.BotId == "" || info.LastPing == 0 {
+			continue
+		}
+		if info.LastPing > al.lastPing[info.BotId] {
+			al.lastPing[info.BotId] = info.LastPing
+		}
+	}
+
+	pings := make(map[string]int64, len(al.lastPing))
+	for botId, lastPing := range al.lastPing {
+		pings[botId] = lastPing
+	}
+
+	return pings, nil
+}
+
+func ruleMatches(rule *domains.AlertRule, bot *domains.Bot) bool {
+	if rule.AccountId != bot.AccountId {
+		return false
+	}
+
+	return !rule.BotId.Valid || rule.BotId.String == bot.BotId
+}
+
+// fire saves and delivers an alert, unless the rule is already firing for
+// the bot.
+func (al *Alerter) fire(rule *domains.AlertRule, bot *domains.Bot, login string, message string) {
+	o := &ErrorHandler{}
+
+	if firing := o.GetFiringAlert(al.tasks.apilogDb, domains.AlertKey(rule.AlertRuleId, bot.BotId)); firing != nil {
+		return
+	}
+
+	alert := o.NewAlert(rule.AlertRuleId, rule.AccountId, bot.BotId, login, rule.Kind, message)
+	o.SaveAlert(al.tasks.apilogDb, alert)
+	if o.Err != nil {
+		al.tasks.Error(o.Err, "save alert %s failed", alert.AlertKey)
+		return
+	}
+
+	al.tasks.logger.With("botId", bot.BotId, "ruleId", rule.AlertRuleId).Warn("alert %s fired: %s", rule.Kind, message)
+	metrics.Alerts.WithLabelValues(rule.Kind, alert.Status).Inc()
+
+	go al.deliver(alert, rule, bot)
+}
+
+// resolve resolves and delivers the firing alert of the rule for the bot,
+// if there is one.
+func (al *Alerter) resolve(rule *domains.AlertRule, bot *domains.Bot) {
+	o := &ErrorHandler{}
+
+	alert := o.GetFiringAlert(al.tasks.apilogDb, domains.AlertKey(rule.AlertRuleId, bot.BotId))
+	if alert == nil {
+		if o.Err != nil {
+			al.tasks.Error(o.Err, "get firing alert failed")
+		}
+		return
+	}
+
+	o.ResolveAlert(al.tasks.apilogDb, alert)
+	if o.Err != nil {
+		al.tasks.Error(o.Err, "resolve alert %s failed", alert.AlertKey)
+		return
+	}
+
+	al.tasks.logger.With("botId", bot.BotId, "ruleId", rule.AlertRuleId).Info("alert %s resolved", rule.Kind)
+	metrics.Alerts.WithLabelValues(rule.Kind, alert.Status).Inc()
+
+	go al.deliver(alert, rule, bot)
+}
+
+// deliver posts the alert as json to the global webhooks and the webhook of
+// the rule, and as an ALERT event to the callback of the bot, recording
+// every delivery in the alert.
+func (al *Alerter) deliver(alert *domains.Alert, rule *domains.AlertRule, bot *domains.Bot) {
+	o := &ErrorHandler{}
+
+	requests := []*httpx.RestfulRequest{}
+	targets := []string{}
+	clients := []*http.Client{}
+
+	// the global webhooks are configured by the operators, and may be internal
+	webhooks := append([]string{}, al.config.Webhooks...)
+	if rule.Webhook.Valid && rule.Webhook.String != "" {
+		webhooks = append(webhooks, rule.Webhook.String)
+	}
+
+	for i, url := range webhooks {
+		rr := httpx.NewRestfulRequest("post", url)
+		if err := rr.SetBody(alert, "json", "utf-8"); err != nil {
+			al.tasks.Error(err, "marshal alert failed")
+			return
+		}
+		requests = append(requests, rr)
+		targets = append(targets, deliveryWebhook)
+		if i < len(al.config.Webhooks) {
+			clients = append(clients, al.tasks.restfulclient)
+		} else {
+			clients = append(clients, al.publicclient)
+		}
+	}
+
+	if rule.NotifyCallback && bot.Callback.Valid && bot.Callback.String != "" {
+		rr := httpx.NewRestfulRequest("post", bot.Callback.String)
+		rr.Params["event"] = ALERT_EVENT
+		rr.Params["body"] = o.ToJson(alert)
+		requests = append(requests, rr)
+		targets = append(targets, deliveryCallback)
+		clients = append(clients, al.tasks.restfulclient)
+	}
+
+	deliveries := []domains.AlertDelivery{}
+	for i, rr := range requests {
+		delivery := domains.AlertDelivery{
+			Target: targets[i],
+			Url:    rr.Uri,
+			Status: alert.Status,
+			At:     time.Now(),
+			Ok:     true,
+		}
+
+		if _, err := httpx.RestfulCallRetry(clients[i], rr, 3, 1); err != nil {
+			al.tasks.Error(err, "deliver alert %s to %s failed", alert.AlertKey, rr.Uri)
+			delivery.Ok = false
+			delivery.Error = err.Error()
+		}
+
+		metrics.AlertDeliveries.WithLabelValues(delivery.Target, fmt.Sprintf("%v", delivery.Ok)).Inc()
+		deliveries = append(deliveries, delivery)
+	}
+
+	o.AppendAlertDeliveries(al.tasks.apilogDb, alert, deliveries)
+	if o.Err != nil {
+		al.tasks.Error(o.Err, "save deliveries of alert %s failed", alert.AlertKey)
+	}
+}
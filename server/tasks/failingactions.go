@@ -10,6 +10,8 @@ import (
 	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/web"
 )
 
 type ActionRequestTimeoutListener struct {
@@ -45,20 +47,20 @@ func (artl *ActionRequestTimeoutListener) Serve() {
 			case redis.Message:
 				if strings.HasPrefix(string(v.Data), "ARTIMING:") &&
 					strings.HasSuffix(string(v.Channel), ":expired") {
-					fmt.Printf("[redis psub debug] %s| <%s>\n", v.Channel, v.Data)
+					artl.tasks.Debug("[redis psub] %s| <%s>", v.Channel, v.Data)
 					if err := artl.handle(string(v.Data)); err != nil {
-						fmt.Printf("artl handle failed %v\n", err)
+						artl.tasks.Error(err, "artl handle failed")
 						break
 					}
 				}
 			case redis.Subscription:
-				fmt.Printf("[redis psub debug] %s| %s %d\n", v.Channel, v.Kind, v.Count)
+				artl.tasks.Debug("[redis psub] %s| %s %d", v.Channel, v.Kind, v.Count)
 			case error:
-				fmt.Printf("[redis psub debug] error %v\n", v)
+				artl.tasks.Error(v, "[redis psub] receive failed")
 			}
 		}
 
-		fmt.Printf("[redis psub debug] connection error %v\n", c.Err())
+		artl.tasks.Error(c.Err(), "[redis psub] connection failed")
 		c.Close()
 	}
 }
@@ -81,18 +83,27 @@ func (artl *ActionRequestTimeoutListener) handle(key string) error {
 	}
 
 	if ar == nil {
-		fmt.Printf("[ar timeout debug] cannot get ar %s\n", arid)
+		artl.tasks.logger.With("arId", arid).Warn("[ar timeout] cannot get ar")
 		return fmt.Errorf("cannot get ar %s", arid)
 	}
 
 	if ar.Status == "NEW" {
 		ar.Status = "TIMEOUT"
+		metrics.Actions.WithLabelValues(ar.ActionType, ar.Status).Inc()
+
+		policy := web.GetRetryPolicy(&artl.tasks.WebConfig, ar.ActionType)
+		retrying := o.ScheduleActionRetry(conn, artl.apilogDb, ar, policy, true, 0, "action timeout")
+		if retrying {
+			artl.tasks.Info("ar %s attempt %d timeout, retry at %d",
+				ar.ActionRequestId, ar.CurrentAttempt(), ar.NextRetryAt)
+		}
+
 		o.UpdateActionRequest_(conn, artl.apilogDb, ar)
 		if o.Err != nil {
 			return o.Err
 		}
 
-		if ar.ActionType == chatbothub.AcceptUser {
+		if ar.ActionType == chatbothub.AcceptUser && !retrying {
 			rr := httpx.NewRestfulRequest("post", fmt.Sprintf("%s%s", artl.tasks.WebBaseUrl,
 				"/botactions/timeoutfriendrequest"))
 			o.Err = rr.SetBodyString(o.ToJson(ar), "json", "utf-8")
@@ -108,6 +119,20 @@ func (artl *ActionRequestTimeoutListener) handle(key string) error {
 		}
 
 		o.SaveFailingActionRequest(conn, ar, artl.ActionHealthCheck, artl.BotHealthCheck)
+
+		if transition := o.BreakerReport(conn, ar, false); transition != nil {
+			rr := httpx.NewRestfulRequest("post", fmt.Sprintf("%s%s", artl.tasks.WebBaseUrl,
+				"/botactions/breakertransition"))
+			rr.Headers[web.InternalTokenHeader] = web.InternalToken(artl.tasks.WebConfig.SecretPhrase)
+			o.Err = rr.SetBodyString(o.ToJson(transition), "json", "utf-8")
+			if o.Err != nil {
+				return o.Err
+			}
+
+			if _, err := httpx.RestfulCallRetry(artl.tasks.restfulclient, rr, 3, 1); err != nil {
+				artl.tasks.Error(err, "call %s failed", "/botactions/breakertransition")
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,88 @@
+package tasks
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	messageStatsDefaultBatch int = 5000
+	// batches computed at most in one run, the next run carries on
+	messageStatsMaxBatches int = 20
+	// messages saved within the delay are left to the next run, for the
+	// ones being saved at the same time to be counted together
+	messageStatsDelay time.Duration = 5 * time.Second
+)
+
+type MessageStatsConfig struct {
+	// seconds between two computations, message stats are off when 0
+	Interval int
+	// messages counted per batch, 5000 when 0
+	Batch int
+}
+
+// a run is skipped while the last one is still catching up
+var messageStatsRunning int32
+
+// ComputeMessageStats counts the messages of the bots saved since the last
+// run into the message stats, batch by batch until it has caught up.
+func (tasks *Tasks) ComputeMessageStats(batch int) {
+	if !atomic.CompareAndSwapInt32(&messageStatsRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&messageStatsRunning, 0)
+
+	start := time.Now()
+	until := start.Add(-messageStatsDelay)
+
+	o := &ErrorHandler{}
+	logins := func() []string {
+		tx := o.Begin(tasks.db)
+		defer o.CommitOrRollback(tx)
+		return o.GetBotLogins(tx)
+	}()
+
+	total := 0
+	for i := 0; i < messageStatsMaxBatches; i++ {
+		n := o.ComputeMessageStats(tasks.messageDb, logins, until, batch)
+		if o.Err != nil {
+			break
+		}
+		total += n
+		if n == 0 {
+			break
+		}
+	}
+
+	if o.Err != nil {
+		tasks.Error(o.Err, "compute message stats failed after %d messages", total)
+		return
+	}
+
+	if total > 0 {
+		tasks.Info("compute message stats of %d bots, %d messages in %s",
+			len(logins), total, time.Since(start))
+	}
+}
+
+func (tasks *Tasks) initMessageStats() (int, error) {
+	batch := tasks.Config.MessageStats.Batch
+	if batch <= 0 {
+		batch = messageStatsDefaultBatch
+	}
+
+	if err := tasks.connectDb(); err != nil {
+		return 0, err
+	}
+
+	if err := tasks.connectMessageDb(); err != nil {
+		return 0, err
+	}
+
+	o := &ErrorHandler{}
+	if o.EnsureMessageStatIndexes(tasks.messageDb); o.Err != nil {
+		return 0, o.Err
+	}
+
+	return batch, nil
+}
@@ -2,16 +2,18 @@ package tasks
 
 import (
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 
 	"github.com/globalsign/mgo"
 	"github.com/gomodule/redigo/redis"
 	"github.com/robfig/cron"
 
+	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"github.com/hawkwithwind/chat-bot-hub/server/web"
 )
@@ -20,27 +22,48 @@ type ErrorHandler struct {
 	domains.ErrorHandler
 }
 
+type Config struct {
+	Host string
+	// port serving /metrics and /loglevels, off when empty
+	MetricsPort  string
+	Alerting     AlertingConfig
+	ActionStats  ActionStatsConfig
+	Usage        UsageConfig
+	MessageStats MessageStatsConfig
+}
+
 type Tasks struct {
+	Config        Config
 	cron          *cron.Cron
 	Webhost       string
 	Webport       string
 	WebBaseUrl    string
 	WebConfig     web.WebConfig
+	Hubhost       string
+	Hubport       string
 	apilogDb      *mgo.Database
+	messageDb     *mgo.Database
 	redispool     *redis.Pool
-	logger        *log.Logger
+	db            *dbx.Database
+	rabbitmq      *utils.RabbitMQWrapper
+	hubWrapper    *rpc.GRPCWrapper
+	logger        *logging.Logger
 	restfulclient *http.Client
 
-	artl *ActionRequestTimeoutListener
+	artl    *ActionRequestTimeoutListener
+	alerter *Alerter
+}
+
+func (ctx *Tasks) Debug(msg string, v ...interface{}) {
+	ctx.logger.Debug(msg, v...)
 }
 
 func (ctx *Tasks) Info(msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
+	ctx.logger.Info(msg, v...)
 }
 
 func (ctx *Tasks) Error(err error, msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
-	ctx.logger.Printf("Error %v", err)
+	ctx.logger.Error(err, msg, v...)
 }
 
 func (tasks *Tasks) init() {
@@ -55,7 +78,7 @@ func (tasks *Tasks) init() {
 		tasks.WebConfig.Apilogdb.Database)
 
 	tasks.restfulclient = httpx.NewHttpClient()
-	tasks.logger = log.New(os.Stdout, "[TASKS] ", log.Ldate|log.Ltime)
+	tasks.logger = logging.New("tasks")
 	tasks.cron = cron.New()
 }
 
@@ -71,11 +94,114 @@ func (tasks *Tasks) Serve() error {
 	tasks.cron.AddFunc("0 */5 * * * *", func() { tasks.NotifyWebPost("/bots/wechatbots/notify/crawltimeline") })
 	//tasks.cron.AddFunc("0 */5 * * * *", func() { tasks.NotifyWebPost("/bots/wechatbots/notify/crawltimelinetail") })
 	tasks.cron.AddFunc("0 * * * * *", func() { tasks.NotifyWebPost("/bots/wechatbots/notify/recoverfailingactions") })
+	tasks.cron.AddFunc("*/10 * * * * *", func() { tasks.NotifyWebPost("/bots/wechatbots/notify/retryactions") })
+
+	if tasks.Config.ActionStats.Interval > 0 {
+		if windows, err := tasks.initActionStats(); err != nil {
+			tasks.Error(err, "init action stats failed, rollups are off")
+		} else {
+			tasks.cron.AddFunc(fmt.Sprintf("@every %ds", tasks.Config.ActionStats.Interval),
+				func() { tasks.RollupActions(windows) })
+		}
+	}
+
+	if tasks.Config.Usage.Interval > 0 {
+		if days, err := tasks.initUsage(); err != nil {
+			tasks.Error(err, "init usage failed, usage rollups are off")
+		} else {
+			tasks.cron.AddFunc(fmt.Sprintf("@every %ds", tasks.Config.Usage.Interval),
+				func() { tasks.RollupUsage(days) })
+		}
+	}
+
+	if tasks.Config.MessageStats.Interval > 0 {
+		if batch, err := tasks.initMessageStats(); err != nil {
+			tasks.Error(err, "init message stats failed, message stats are off")
+		} else {
+			tasks.cron.AddFunc(fmt.Sprintf("@every %ds", tasks.Config.MessageStats.Interval),
+				func() { tasks.ComputeMessageStats(batch) })
+		}
+	}
 
 	tasks.cron.Start()
+
+	if tasks.Config.Alerting.Interval > 0 {
+		if err := tasks.initAlerting(); err != nil {
+			tasks.Error(err, "init alerting failed, alerts are off")
+		} else {
+			tasks.alerter = tasks.NewAlerter()
+			go tasks.alerter.Serve()
+			tasks.Info("begin serve alerting every %d seconds ...", tasks.Config.Alerting.Interval)
+		}
+	}
+
+	go metrics.Serve(tasks.Config.Host, tasks.Config.MetricsPort)
 	return nil
 }
 
+// initAlerting connects what only alerting needs, the bots and alert rules
+// in mysql and the bot status queue.
+func (tasks *Tasks) initAlerting() error {
+	if err := tasks.connectDb(); err != nil {
+		return err
+	}
+
+	o := &ErrorHandler{}
+	if o.EnsureAlertIndexes(tasks.apilogDb); o.Err != nil {
+		return o.Err
+	}
+
+	tasks.rabbitmq = o.NewRabbitMQWrapper(tasks.WebConfig.Rabbitmq)
+	if err := tasks.rabbitmq.Reconnect(); err != nil {
+		return err
+	}
+
+	return tasks.rabbitmq.DeclareQueue(utils.CH_BotStatus, true, false, false, false)
+}
+
+// connectDb connects mysql once, for the features needing it.
+func (tasks *Tasks) connectDb() error {
+	if tasks.db != nil {
+		return nil
+	}
+
+	o := &ErrorHandler{}
+	db := &dbx.Database{}
+	if o.Connect(db, "mysql", tasks.WebConfig.Database.DataSourceName); o.Err != nil {
+		return o.Err
+	}
+
+	tasks.db = db
+	return nil
+}
+
+// connectMessageDb connects the mongo of the messages once.
+func (tasks *Tasks) connectMessageDb() error {
+	if tasks.messageDb != nil {
+		return nil
+	}
+
+	o := &ErrorHandler{}
+	messageDb := o.NewMongoConn(
+		tasks.WebConfig.Messagedb.Host,
+		tasks.WebConfig.Messagedb.Port,
+		tasks.WebConfig.Messagedb.Database)
+	if o.Err != nil {
+		return o.Err
+	}
+
+	tasks.messageDb = messageDb
+	return nil
+}
+
+func (tasks *Tasks) NewHubGRPCWrapper() (*rpc.GRPCWrapper, error) {
+	if tasks.hubWrapper == nil {
+		tasks.hubWrapper = rpc.CreateGRPCWrapper(fmt.Sprintf("%s:%s", tasks.Hubhost, tasks.Hubport))
+	}
+
+	return tasks.hubWrapper.Clone()
+}
+
 func (tasks Tasks) NotifyWebPost(notifypath string) {
 	baseurl := tasks.WebBaseUrl
 	tasks.Info("trigger %s", notifypath)
@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+)
+
+type UsageConfig struct {
+	// seconds between two rollups, rollups are off when 0
+	Interval int
+	// days rolled up back from today, the counters of a day keep growing
+	// until it ends, 2 when 0
+	Days int
+}
+
+// RollupUsage rolls the usage counters of the last days up per account
+// and bot, replacing the usages rolled up before.
+func (tasks *Tasks) RollupUsage(days int) {
+	now := time.Now()
+	start := time.Now()
+
+	names := []string{}
+	for i := 0; i < days; i++ {
+		names = append(names, domains.UsageDay(now.AddDate(0, 0, -i)))
+	}
+
+	o := &ErrorHandler{}
+	counters := o.GetUsageCounters(tasks.messageDb, names)
+	usages := tasks.dailyUsages(o, counters)
+	o.SaveDailyUsages(tasks.apilogDb, usages)
+	if o.Err != nil {
+		tasks.Error(o.Err, "rollup usage of %v failed", names)
+		return
+	}
+
+	tasks.Info("rollup usage of %v, %d counters to %d usages in %s",
+		names, len(counters), len(usages), time.Since(start))
+}
+
+// dailyUsages attributes the counters to the accounts of their bots,
+// filters, or accounts, the deleted ones included, and sums them up.
+func (tasks *Tasks) dailyUsages(o *ErrorHandler, counters []domains.UsageCounter) []*domains.DailyUsage {
+	if o.Err != nil {
+		return nil
+	}
+
+	tx := o.Begin(tasks.db)
+	defer o.CommitOrRollback(tx)
+
+	// subject id to account id, cached along the rollup
+	accountIds := map[string]string{}
+	accountIdOf := func(scope string, subjectId string) string {
+		key := scope + ":" + subjectId
+		if accountId, ok := accountIds[key]; ok {
+			return accountId
+		}
+
+		accountId := ""
+		switch scope {
+		case domains.USAGE_SCOPE_BOT:
+			if bot := o.GetBotByIdNull(tx, subjectId); bot != nil {
+				accountId = bot.AccountId
+			}
+		case domains.USAGE_SCOPE_FILTER:
+			if filter := o.GetFilterByIdNull(tx, subjectId); filter != nil {
+				accountId = filter.AccountId
+			}
+		case domains.USAGE_SCOPE_ACCOUNT:
+			if account := o.GetAccountByName(tx, subjectId); account != nil {
+				accountId = account.AccountId
+			}
+		}
+
+		accountIds[key] = accountId
+		return accountId
+	}
+
+	now := time.Now()
+	usages := map[string]*domains.DailyUsage{}
+	unknown := 0
+
+	for _, counter := range counters {
+		accountId := accountIdOf(counter.Scope, counter.SubjectId)
+		if o.Err != nil {
+			return nil
+		}
+		if accountId == "" {
+			unknown += 1
+			continue
+		}
+
+		botId := ""
+		if counter.Scope == domains.USAGE_SCOPE_BOT {
+			botId = counter.SubjectId
+		}
+
+		kind, value := counter.Kind, float64(counter.Value)
+		if kind == domains.USAGE_WEBSOCKET_SECONDS {
+			kind, value = domains.USAGE_WEBSOCKET_MINUTES, value/60
+		}
+
+		usage := domains.NewDailyUsage(counter.Day, accountId, botId, kind, counter.Type)
+		if existing, ok := usages[usage.Id]; ok {
+			usage = existing
+		} else {
+			usage.ComputedAt = now
+			usages[usage.Id] = usage
+		}
+		usage.Value += value
+	}
+
+	if unknown > 0 {
+		tasks.Info("rollup usage skipped %d counters of unknown subjects", unknown)
+	}
+
+	ret := make([]*domains.DailyUsage, 0, len(usages))
+	for _, usage := range usages {
+		ret = append(ret, usage)
+	}
+
+	return ret
+}
+
+func (tasks *Tasks) initUsage() (int, error) {
+	days := tasks.Config.Usage.Days
+	if days <= 0 {
+		days = 2
+	}
+
+	if err := tasks.connectDb(); err != nil {
+		return 0, err
+	}
+
+	if err := tasks.connectMessageDb(); err != nil {
+		return 0, err
+	}
+
+	o := &ErrorHandler{}
+	if o.EnsureDailyUsageIndexes(tasks.apilogDb); o.Err != nil {
+		return 0, o.Err
+	}
+
+	return days, nil
+}
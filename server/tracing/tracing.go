@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	TracerName string = "github.com/hawkwithwind/chat-bot-hub"
+
+	ActionRequestId = attribute.Key("chathub.action_request_id")
+	ActionType      = attribute.Key("chathub.action_type")
+	BotId           = attribute.Key("chathub.bot_id")
+	ClientId        = attribute.Key("chathub.client_id")
+	Login           = attribute.Key("chathub.login")
+	EventType       = attribute.Key("chathub.event_type")
+)
+
+type Config struct {
+	// OTLP grpc collector, host:port. Spans are dropped when empty.
+	Endpoint string
+	Insecure bool
+	// fraction of the traces started here that are sampled, 1 when 0.
+	// Traces started upstream follow the decision of their parent.
+	SampleRatio float64
+}
+
+// Init installs the tracer provider of the service. Without an endpoint the
+// provider stays the no-op default, but trace context is still propagated,
+// so a trace started upstream goes on downstream.
+func Init(service string, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := config.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Inject returns the trace context of ctx as a map, to carry it in event
+// bodies and mq events. It is nil when ctx is not traced.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+
+	return carrier
+}
+
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// ExtractFrom reads the carrier out of a decoded json body.
+func ExtractFrom(ctx context.Context, carrier interface{}) context.Context {
+	m, ok := carrier.(map[string]interface{})
+	if !ok {
+		return ctx
+	}
+
+	strs := map[string]string{}
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			strs[k] = s
+		}
+	}
+
+	return Extract(ctx, strs)
+}
+
+// WithSpanOf puts the span of src in dst, for calls made on a context that
+// was not derived from the traced one, like a rpc.GRPCWrapper's.
+func WithSpanOf(dst context.Context, src context.Context) context.Context {
+	return trace.ContextWithSpan(dst, trace.SpanFromContext(src))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// DialOption and ServerOption carry the trace context in grpc metadata.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"bytes"
-	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -47,8 +46,6 @@ func AliyunGetPassword(sk string) string {
 	buffer.WriteString(strings.ToUpper(HmacSha1(currentMillis, sk)))
 	buffer.WriteString(COLON)
 	buffer.WriteString(currentMillis)
-	fmt.Println(currentMillis)
-	fmt.Println(HmacSha1(sk, currentMillis))
 	return base64.StdEncoding.EncodeToString(buffer.Bytes())
 }
 
@@ -12,6 +12,7 @@ const (
 	RESOURCE_ACCESS_DENIED ClientErrorCode = 2002
 	RESOURCE_NOT_FOUND     ClientErrorCode = 2003
 	RESOURCE_QUOTA_LIMIT   ClientErrorCode = 2004
+	RESOURCE_CONFLICT      ClientErrorCode = 2005
 	STATUS_INCONSISTENT    ClientErrorCode = 3001
 	METHOD_UNSUPPORTED     ClientErrorCode = 3002
 )
@@ -9,8 +9,11 @@ import (
 	"time"
 
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
 )
 
+var logger = logging.New("utils")
+
 type ErrorHandler struct {
 	Err error
 }
@@ -227,7 +230,6 @@ func (ctx *ErrorHandler) GetResponseBody(resp *httpx.RestfulResponse) map[string
 
 func (o *ErrorHandler) Recover(name string) {
 	if r := recover(); r != nil {
-		fmt.Printf("recover from go[%s] with context: %v\n", name, r)
-		fmt.Println(string(debug.Stack()))
+		logger.Error(fmt.Errorf("%v", r), "recover from go[%s]\n%s", name, debug.Stack())
 	}
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"strconv"
 )
 
 type MessageType int
@@ -12,24 +13,44 @@ const (
 	MessageTypeEmoji
 )
 
-func genSignedURL(ossBucket *oss.Bucket, imageId string, messageType MessageType, options ...oss.Option) (string, error) {
+func ossImageKey(imageId string, messageType MessageType) (string, error) {
 	if imageId == "" {
 		return "", fmt.Errorf("imageId is required")
 	}
 
-	imageKey := ""
-
 	if messageType == MessageTypeImage {
-		imageKey = "chathub/images/" + imageId
+		return "chathub/images/" + imageId, nil
 	} else if messageType == MessageTypeEmoji {
-		imageKey = "chathub/emoji/" + imageId
+		return "chathub/emoji/" + imageId, nil
 	} else {
 		return "", fmt.Errorf("unkown message type to generate signed oss url for message type: %s\n", messageType)
 	}
+}
+
+func genSignedURL(ossBucket *oss.Bucket, imageId string, messageType MessageType, options ...oss.Option) (string, error) {
+	imageKey, err := ossImageKey(imageId, messageType)
+	if err != nil {
+		return "", err
+	}
 
 	return ossBucket.SignURL(imageKey, oss.HTTPGet, 60*30, options...)
 }
 
+// OssImageSize returns the bytes the image takes in oss.
+func OssImageSize(ossBucket *oss.Bucket, messageType MessageType, imageId string) (int64, error) {
+	imageKey, err := ossImageKey(imageId, messageType)
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := ossBucket.GetObjectDetailedMeta(imageKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+}
+
 // 生成 image 和 thumbnailImage url，如果 thumbnailId 不存在，那么用 imageId + image process 方式生成 thumb
 func GenSignedURLPair(ossBucket *oss.Bucket, messageType MessageType, imageId string, thumbImageId string) (string, string, error) {
 	if imageId == "" {
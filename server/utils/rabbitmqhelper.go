@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/streadway/amqp"
@@ -24,9 +26,11 @@ const (
 	CH_BotNotify          string = "botNotify"
 	CH_ContactInfo        string = "contactInfo"
 	CH_GetContact         string = "getContact"
+	CH_BotStatus          string = "botStatus"
 	CONSU_WEB_BotNotify   string = "webBotNotify"
 	CONSU_WEB_ContactInfo string = "webContactInfo"
 	CONSU_WEB_GetContact  string = "webGetContact"
+	CONSU_TASKS_BotStatus string = "tasksBotStatus"
 )
 
 const (
@@ -35,10 +39,16 @@ const (
 )
 
 type RabbitMQWrapper struct {
+	// guards mqConn and mqChannel, swapped by reconnects
+	mux sync.Mutex
+
 	mqConn     *amqp.Connection
 	mqChannel  *amqp.Channel
 	lastActive time.Time
 	config     RabbitMQConfig
+
+	// sends in progress, see Flush
+	pending int32
 }
 
 func (o *ErrorHandler) NewRabbitMQWrapper(config RabbitMQConfig) *RabbitMQWrapper {
@@ -52,6 +62,13 @@ func (o *ErrorHandler) NewRabbitMQWrapper(config RabbitMQConfig) *RabbitMQWrappe
 }
 
 func (w *RabbitMQWrapper) Reconnect() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	return w.reconnect()
+}
+
+func (w *RabbitMQWrapper) reconnect() error {
 	o := ErrorHandler{}
 
 	if w.mqConn != nil && w.mqConn.IsClosed() == false {
@@ -71,10 +88,33 @@ func (w *RabbitMQWrapper) Reconnect() error {
 	return nil
 }
 
+// Ping reconnects when the connection is closed, as the next send would,
+// holding the lock sends take, and tells whether the broker is reachable.
+func (w *RabbitMQWrapper) Ping() error {
+	if w == nil {
+		return fmt.Errorf("rabbitmq not connected")
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if err := w.reconnect(); err != nil {
+		return err
+	}
+
+	if w.mqConn == nil || w.mqConn.IsClosed() {
+		return fmt.Errorf("rabbitmq connection closed")
+	}
+
+	return nil
+}
+
 func (w *RabbitMQWrapper) DeclareQueue(queue string, durable bool, autodelete bool, exclusive bool, nowait bool) error {
 	o := &ErrorHandler{}
 
+	w.mux.Lock()
 	mqChannel := o.RabbitMQChannel(w.mqConn)
+	w.mux.Unlock()
 	if o.Err != nil {
 		return o.Err
 	}
@@ -95,7 +135,13 @@ func (w *RabbitMQWrapper) DeclareQueue(queue string, durable bool, autodelete bo
 }
 
 func (w *RabbitMQWrapper) Send(queue string, body string) error {
-	err := w.Reconnect()
+	atomic.AddInt32(&w.pending, 1)
+	defer atomic.AddInt32(&w.pending, -1)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	err := w.reconnect()
 	if err != nil {
 		return err
 	}
@@ -120,6 +166,7 @@ func (w *RabbitMQWrapper) Send(queue string, body string) error {
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
 			ContentType:  "text/plain",
+			Timestamp:    time.Now(),
 			Body:         []byte(body),
 		})
 	if err != nil {
@@ -135,8 +182,44 @@ func (w *RabbitMQWrapper) Send(queue string, body string) error {
 	return nil
 }
 
+func (w *RabbitMQWrapper) Pending() int {
+	return int(atomic.LoadInt32(&w.pending))
+}
+
+// Flush waits for the sends in progress, at most timeout, then closes the
+// channel and the connection, which hands the published messages to the broker.
+func (w *RabbitMQWrapper) Flush(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for w.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if pending := w.Pending(); pending > 0 {
+		return fmt.Errorf("rabbitmq flush timeout, %d sends pending", pending)
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.mqChannel != nil {
+		w.mqChannel.Close()
+		w.mqChannel = nil
+	}
+
+	if w.mqConn != nil {
+		err := w.mqConn.Close()
+		w.mqConn = nil
+		return err
+	}
+
+	return nil
+}
+
 func (w *RabbitMQWrapper) Consume(queue string, consumer string, autoack bool, exclusive bool, nolocal bool, nowait bool) (<-chan amqp.Delivery, error) {
-	err := w.Reconnect()
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	err := w.reconnect()
 	if err != nil {
 		return nil, err
 	}
@@ -156,11 +239,7 @@ func (w *RabbitMQWrapper) Consume(queue string, consumer string, autoack bool, e
 		w.mqChannel.Qos(100, 0, false)
 	}
 
-	if w.mqChannel == nil {
-		fmt.Println("mqchannel is nil")
-	} else {
-		fmt.Println("mqchannel is not nil")
-	}
+	logger.Debug("consume %s as %s, mqchannel nil %v", queue, consumer, w.mqChannel == nil)
 
 	msgs, err := w.mqChannel.Consume(
 		queue,
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	actionRequestsDefaultLimit int = 20
+	actionRequestsMaxLimit     int = 200
+)
+
+func (o *ErrorHandler) getTimeValue(r *http.Request, name string) time.Time {
+	if o.Err != nil {
+		return time.Time{}
+	}
+
+	v := o.getStringValueDefault(r.Form, name, "")
+	if v == "" {
+		return time.Time{}
+	}
+
+	var t time.Time
+	t, o.Err = time.Parse(time.RFC3339, v)
+	if o.Err != nil {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("%s should be RFC3339 time, got %s", name, v))
+	}
+
+	return t
+}
+
+func (web *WebServer) getActionRequests(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+	defer o.BackEndError(web)
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+
+	login := o.getStringValueDefault(r.Form, "login", "")
+	cursor := o.getStringValueDefault(r.Form, "cursor", "")
+	withCounts := o.getStringValueDefault(r.Form, "counts", "") == "true"
+
+	criteria := domains.ApiLogCriteria{
+		ActionType: o.getStringValueDefault(r.Form, "actionType", ""),
+		Status:     o.getStringValueDefault(r.Form, "status", ""),
+		Result:     o.getStringValueDefault(r.Form, "result", ""),
+		From:       o.getTimeValue(r, "from"),
+		To:         o.getTimeValue(r, "to"),
+	}
+
+	limit := actionRequestsDefaultLimit
+	if limitstr := o.getStringValueDefault(r.Form, "limit", ""); limitstr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitstr); err != nil || limit <= 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("limit should be a positive integer, got %s", limitstr))
+			return
+		}
+		if limit > actionRequestsMaxLimit {
+			limit = actionRequestsMaxLimit
+		}
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	if login != "" {
+		o.CheckBotOwner(tx, login, accountName)
+		criteria.Logins = []string{login}
+	} else {
+		bots := o.GetBotsByAccountName(tx, accountName)
+		criteria.Logins = []string{}
+		for _, bot := range bots {
+			if bot.Login != "" {
+				criteria.Logins = append(criteria.Logins, bot.Login)
+			}
+		}
+	}
+
+	if o.Err != nil {
+		return
+	}
+
+	apilogs, next := o.QueryApiLogs(web.apilogDb, criteria, cursor, limit)
+
+	body := map[string]interface{}{
+		"actionRequests": apilogs,
+		"nextCursor":     next,
+	}
+
+	if withCounts {
+		body["counts"] = o.CountApiLogsByStatus(web.apilogDb, criteria)
+	}
+
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", body)
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gomodule/redigo/redis"
 	"github.com/hawkwithwind/mux"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
@@ -15,8 +17,10 @@ import (
 	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/models"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -25,7 +29,7 @@ func webCallbackRequest(bot *domains.Bot, event string, body string) *httpx.Rest
 	rr.Params["event"] = event
 	rr.Params["body"] = body
 
-	fmt.Printf("[Web Callback] %s \n", bot.Callback.String)
+	webLogger.With("botId", bot.BotId).Debug("web callback %s", bot.Callback.String)
 	return rr
 }
 
@@ -242,6 +246,10 @@ func (ctx *WebServer) mqConsume(queue string, consumer string) {
 		}
 
 		for d := range msgs {
+			if !d.Timestamp.IsZero() {
+				metrics.MqConsumeLag.WithLabelValues(queue).Observe(time.Since(d.Timestamp).Seconds())
+			}
+
 			mqEvent := models.MqEvent{}
 
 			err := json.Unmarshal(d.Body, &mqEvent)
@@ -251,7 +259,12 @@ func (ctx *WebServer) mqConsume(queue string, consumer string) {
 				continue
 			}
 
+			_, span := tracing.Start(
+				tracing.Extract(context.Background(), mqEvent.Trace), "web.processBotNotify",
+				tracing.BotId.String(mqEvent.BotId),
+				tracing.EventType.String(mqEvent.EventType))
 			err = ctx.processBotNotify(mqEvent.BotId, mqEvent.EventType, mqEvent.Body)
+			tracing.End(span, err)
 			if err != nil {
 				ctx.Error(err, "process event failed")
 				d.Ack(false)
@@ -380,7 +393,7 @@ func (ctx *WebServer) processBotNotify(botId string, eventType string, bodystr s
 		// a_o.CreateAndRunAction(ctx, ar)
 
 		{
-			wrapper, err := ctx.NewGRPCWrapper()
+			wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByBot, botId)
 			if err != nil {
 				o.Err = err
 				return o.Err
@@ -473,6 +486,18 @@ func (ctx *WebServer) processBotNotify(botId string, eventType string, bodystr s
 			}
 		}()
 
+	case chatbothub.BOTMIGRATE:
+		ctx.Info("c[%s] %s", thebotinfo.ClientType, bodystr)
+
+		go func() {
+			if bot.Callback.Valid {
+				if resp, err := httpx.RestfulCallRetry(ctx.restfulclient, webCallbackRequest(
+					bot, eventType, bodystr), 5, 1); err != nil {
+					ctx.Error(err, "callback bot migrate failed\n%v\n", resp)
+				}
+			}
+		}()
+
 	case chatbothub.USERACCEPTED:
 		ctx.Info("c[%s] %s", thebotinfo.ClientType, bodystr)
 
@@ -646,12 +671,37 @@ func (ctx *WebServer) processBotNotify(botId string, eventType string, bodystr s
 			return o.Err
 		}
 
+		metrics.Actions.WithLabelValues(localar.ActionType, localar.Status).Inc()
+
 		if localar.Status == "Failed" {
 			conn := ctx.redispool.Get()
 			defer conn.Close()
 
 			o.SaveFailingActionRequest(conn, localar,
 				ctx.Config.ActionHealthCheck, ctx.Config.BotHealthCheck)
+
+			ctx.notifyBreakerTransition(botId, o.BreakerReport(conn, localar, false))
+
+			policy := GetRetryPolicy(&ctx.Config, localar.ActionType)
+			if o.ScheduleActionRetry(conn, ctx.apilogDb, localar, policy,
+				false, actionFailureCode(result), "") {
+				ctx.Info("ar %s attempt %d failed, retry at %d",
+					localar.ActionRequestId, localar.CurrentAttempt(), localar.NextRetryAt)
+			}
+		} else if localar.Status == "Done" {
+			conn := ctx.redispool.Get()
+			defer conn.Close()
+
+			ctx.notifyBreakerTransition(botId, o.BreakerReport(conn, localar, true))
+
+			if localar.CurrentAttempt() > 1 {
+				o.AppendApiLogAttempt(ctx.apilogDb, localar, domains.ActionAttempt{
+					Attempt:  localar.CurrentAttempt(),
+					Status:   localar.Status,
+					ClientId: localar.ClientId,
+					At:       time.Now(),
+				})
+			}
 		}
 
 		switch localar.ActionType {
@@ -949,7 +999,7 @@ func (ctx *WebServer) processBotNotify(botId string, eventType string, bodystr s
 						return o.Err
 					}
 
-					wrapper, err := ctx.NewGRPCWrapper()
+					wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByBot, thebotinfo.BotId)
 					if err != nil {
 						o.Err = err
 						return o.Err
@@ -1036,7 +1086,7 @@ func (ctx *WebServer) processBotNotify(botId string, eventType string, bodystr s
 					if resp.StatusCode == 200 {
 						ctx.Info("web short call returned %s", resp.Body)
 
-						wrapper, err := ctx.NewGRPCWrapper()
+						wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByBot, thebotinfo.BotId)
 						if err != nil {
 							o.Err = err
 							return
@@ -1157,6 +1207,12 @@ func (ctx *WebServer) botAction(w http.ResponseWriter, r *http.Request) {
 	actionType := o.FromMapString("actionType", bodym, "request json", false, "")
 	actionBody := o.FromMapString("actionBody", bodym, "request json", false, "")
 
+	// retries carrying the same key return the first result instead of dispatching again
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = o.FromMapString("idempotencyKey", bodym, "request json", true, "")
+	}
+
 	actionm := o.FromJson(actionBody)
 	if o.Err != nil {
 		return
@@ -1180,31 +1236,134 @@ func (ctx *WebServer) botAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	conn := ctx.redispool.Get()
+	defer conn.Close()
+
+	// the key is reserved once the request is known to be valid, so that
+	// a request rejected above can be fixed and sent again with it
+	var idem *domains.IdempotencyRecord
+	if idempotencyKey != "" {
+		idem = o.NewIdempotencyRecord(accountName, login, idempotencyKey, actionType, actionBody)
+		found := o.ReserveIdempotencyRecord(conn, idem, ctx.Config.ActionTimeout)
+		if o.Err != nil {
+			return
+		}
+
+		if found != nil {
+			o.CheckIdempotencyRecord(found, idem)
+			if o.Err != nil {
+				return
+			}
+
+			ctx.Info("idempotency key %s replayed ar %s", idempotencyKey, found.ActionRequestId)
+			w.Header().Set("Idempotent-Replayed", "true")
+
+			if o.Err = found.Failure(); o.Err != nil {
+				return
+			}
+
+			var replayed interface{}
+			o.Err = json.Unmarshal([]byte(found.Response), &replayed)
+			if o.Err != nil {
+				return
+			}
+
+			o.ok(w, "", replayed)
+			return
+		}
+	}
+
 	ctx.Info("ar is " + o.ToJson(ar))
 
-	actionReply := o.CreateAndRunAction(ctx, ar)
+	actionReply, dispatched := o.DispatchAction(ctx, ar)
 	if o.Err != nil {
 		ctx.Info("create and run action failed")
-		return
 	}
 
-	o.ok(w, "", map[string]interface{}{
+	response := map[string]interface{}{
 		"actionRequestId": ar.ActionRequestId,
 		"actionReply":     actionReply,
-	})
+	}
+
+	if idem != nil {
+		ctx.settleIdempotencyRecord(conn, idem, ar, response, o.Err, dispatched)
+	}
+
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", response)
+}
+
+// settleIdempotencyRecord releases the key of an action which never left
+// web, so that it can be retried. Once the action is dispatched the bot may
+// have run it, so its result is kept for the retries, failures included.
+func (ctx *WebServer) settleIdempotencyRecord(conn redis.Conn, idem *domains.IdempotencyRecord,
+	ar *domains.ActionRequest, response interface{}, err error, dispatched bool) {
+	o := &ErrorHandler{}
+
+	if !dispatched {
+		if o.ReleaseIdempotencyRecord(conn, idem); o.Err != nil {
+			ctx.Error(o.Err, "release idempotency key %s failed", idem.Key)
+		}
+		return
+	}
+
+	idem.ActionRequestId = ar.ActionRequestId
+	idem.SetFailure(err)
+	if err == nil {
+		idem.Response = o.ToJson(response)
+	}
+
+	if o.SaveIdempotencyRecord(conn, idem, ctx.Config.IdempotencyTTL); o.Err != nil {
+		ctx.Error(o.Err, "save idempotency key %s failed", idem.Key)
+	}
 }
 
 func (o *ErrorHandler) CreateAndRunAction(web *WebServer, ar *domains.ActionRequest) *pb.BotActionReply {
+	actionReply, _ := o.DispatchAction(web, ar)
+	return actionReply
+}
 
-	wrapper, err := web.NewGRPCWrapper()
+// hubRejected tells the codes the hub rejects an action with before it is
+// queued for any client, such an action was never run.
+func hubRejected(code utils.ClientErrorCode) bool {
+	switch code {
+	case utils.RESOURCE_INSUFFICIENT, // draining
+		utils.RESOURCE_QUOTA_LIMIT, // action queue full
+		utils.METHOD_UNSUPPORTED,
+		utils.STATUS_INCONSISTENT,
+		utils.PARAM_INVALID,
+		utils.PARAM_REQUIRED:
+		return true
+	default:
+		return false
+	}
+}
+
+// DispatchAction checks the action against the breaker and the limits and
+// sends it to the hub. dispatched tells whether it went as far as the hub,
+// an error after that does not mean the bot did not run it.
+func (o *ErrorHandler) DispatchAction(web *WebServer, ar *domains.ActionRequest) (actionReply *pb.BotActionReply, dispatched bool) {
+	spanCtx, span := tracing.Start(context.Background(), "web.action "+ar.ActionType,
+		tracing.ActionRequestId.String(ar.ActionRequestId),
+		tracing.ActionType.String(ar.ActionType),
+		tracing.Login.String(ar.Login))
+	defer func() { tracing.End(span, o.Err) }()
+
+	wrapper, err := web.NewGRPCWrapperForBot(domains.BotOwnerByLogin, ar.Login)
 	if err != nil {
 		o.Err = err
-		return nil
+		return nil, false
 	}
 
+	// the hub carries the trace on to the client, and back with the reply
+	wrapper.Context = tracing.WithSpanOf(wrapper.Context, spanCtx)
+
 	bot := o.getBotByLogin(wrapper, ar.Login)
 	if o.Err != nil {
-		return nil
+		return nil, false
 	}
 
 	ar.ClientType = bot.ClientType
@@ -1214,7 +1373,7 @@ func (o *ErrorHandler) CreateAndRunAction(web *WebServer, ar *domains.ActionRequ
 	defer conn.Close()
 
 	if !o.ActionIsHealthy(conn, ar) {
-		return nil
+		return nil, false
 	}
 
 	daylimit, hourlimit, minutelimit := o.GetRateLimit(ar.ActionType)
@@ -1236,42 +1395,51 @@ func (o *ErrorHandler) CreateAndRunAction(web *WebServer, ar *domains.ActionRequ
 
 	web.Info("action count %d, %d, %d", dayCount, hourCount, minuteCount)
 	if o.Err != nil {
-		return nil
+		return nil, false
 	}
 
 	if dayCount > daylimit {
+		metrics.QuotaRejections.WithLabelValues(ar.ActionType, "day").Inc()
 		o.Err = utils.NewClientError(utils.RESOURCE_QUOTA_LIMIT,
 			fmt.Errorf("%s:%s exceeds day limit %d", ar.Login, ar.ActionType, daylimit))
-		return nil
+		return nil, false
 	}
 
 	if hourCount > hourlimit {
+		metrics.QuotaRejections.WithLabelValues(ar.ActionType, "hour").Inc()
 		o.Err = utils.NewClientError(utils.RESOURCE_QUOTA_LIMIT,
 			fmt.Errorf("%s:%s exceeds hour limit %d", ar.Login, ar.ActionType, hourlimit))
-		return nil
+		return nil, false
 	}
 
 	if minuteCount > minutelimit {
+		metrics.QuotaRejections.WithLabelValues(ar.ActionType, "minute").Inc()
 		o.Err = utils.NewClientError(utils.RESOURCE_QUOTA_LIMIT,
 			fmt.Errorf("%s:%s exceeds minute limit %d", ar.Login, ar.ActionType, minutelimit))
-		return nil
+		return nil, false
+	}
+
+	// the last check before sending, a half-open breaker takes ar as its
+	// probe, which nothing is to reject afterwards
+	web.notifyBreakerTransition(bot.BotId,
+		o.BreakerAllow(conn, ar, web.Config.ActionHealthCheck))
+	if o.Err != nil {
+		return nil, false
 	}
 
 	web.Info("action request is " + o.ToJson(ar))
 
-	actionReply := o.BotAction(wrapper, ar.ToBotActionRequest())
+	actionReply = o.BotAction(wrapper, ar.ToBotActionRequest())
 	if o.Err != nil {
 		web.Error(o.Err, "ar is "+o.ToJson(ar))
-		return nil
+		return nil, true
 	}
 
 	if actionReply.ClientError != nil {
 		if actionReply.ClientError.Code != 0 {
-			o.Err = utils.NewClientError(
-				utils.ClientErrorCode(actionReply.ClientError.Code),
-				fmt.Errorf(actionReply.ClientError.Message),
-			)
-			return nil
+			code := utils.ClientErrorCode(actionReply.ClientError.Code)
+			o.Err = utils.NewClientError(code, fmt.Errorf(actionReply.ClientError.Message))
+			return nil, !hubRejected(code)
 		}
 	}
 
@@ -1280,7 +1448,10 @@ func (o *ErrorHandler) CreateAndRunAction(web *WebServer, ar *domains.ActionRequ
 
 	o.SaveActionRequestWLimit(conn, web.apilogDb, ar,
 		web.Config.ActionTimeout, daylimit, hourlimit, minutelimit)
-	return actionReply
+	if o.Err == nil {
+		metrics.Actions.WithLabelValues(ar.ActionType, ar.Status).Inc()
+	}
+	return actionReply, true
 }
 
 func (web *WebServer) rebuildMsgFiltersFromWeb(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const actionStatsDefaultWindow string = "1h"
+
+// getActionStats returns the action latency and success rate rollups of the
+// bots of the account, with the ones of all bots per client type to compare
+// against. Without from and to it returns the latest rollups of the window,
+// with them every rollup computed in between.
+func (web *WebServer) getActionStats(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+
+	windowstr := o.getStringValueDefault(r.Form, "window", actionStatsDefaultWindow)
+	login := o.getStringValueDefault(r.Form, "login", "")
+
+	window, err := time.ParseDuration(windowstr)
+	if err != nil || window <= 0 {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("window should be a duration like 1h or 24h, got %s", windowstr))
+		return
+	}
+
+	criteria := domains.ActionRollupCriteria{
+		Window:     window.String(),
+		ClientType: o.getStringValueDefault(r.Form, "clientType", ""),
+		ActionType: o.getStringValueDefault(r.Form, "actionType", ""),
+		From:       o.getTimeValue(r, "from"),
+		To:         o.getTimeValue(r, "to"),
+	}
+	if o.Err != nil {
+		return
+	}
+
+	if criteria.From.IsZero() && criteria.To.IsZero() {
+		latest := o.LatestActionRollupAt(web.apilogDb, criteria.Window)
+		if o.Err != nil {
+			return
+		}
+		if latest.IsZero() {
+			o.ok(w, "", map[string]interface{}{
+				"window":      criteria.Window,
+				"bots":        []domains.ActionRollup{},
+				"clientTypes": []domains.ActionRollup{},
+			})
+			return
+		}
+		criteria.From = latest
+		criteria.To = latest
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	logins := []string{}
+	if login != "" {
+		o.CheckBotOwner(tx, login, accountName)
+		logins = append(logins, login)
+	} else {
+		for _, bot := range o.GetBotsByAccountName(tx, accountName) {
+			if bot.Login != "" {
+				logins = append(logins, bot.Login)
+			}
+		}
+	}
+
+	if o.Err != nil {
+		return
+	}
+
+	// nil logins query the rollups over all bots
+	clientTypes := o.QueryActionRollups(web.apilogDb, criteria)
+
+	criteria.Logins = logins
+	bots := o.QueryActionRollups(web.apilogDb, criteria)
+
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"window":      criteria.Window,
+		"bots":        bots,
+		"clientTypes": clientTypes,
+	})
+}
@@ -0,0 +1,275 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hawkwithwind/mux"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+type AlertRuleVO struct {
+	AlertRuleId    string         `json:"alertRuleId"`
+	BotId          string         `json:"botId"`
+	Kind           string         `json:"kind"`
+	Threshold      float64        `json:"threshold"`
+	Window         int            `json:"window"`
+	Webhook        string         `json:"webhook"`
+	NotifyCallback bool           `json:"notifyCallback"`
+	CreateAt       utils.JSONTime `json:"createAt"`
+}
+
+func NewAlertRuleVO(rule *domains.AlertRule) AlertRuleVO {
+	return AlertRuleVO{
+		AlertRuleId:    rule.AlertRuleId,
+		BotId:          rule.BotId.String,
+		Kind:           rule.Kind,
+		Threshold:      rule.Threshold,
+		Window:         rule.Window,
+		Webhook:        rule.Webhook.String,
+		NotifyCallback: rule.NotifyCallback,
+		CreateAt:       utils.JSONTime{rule.CreateAt.Time},
+	}
+}
+
+// setAlertRuleValues sets the values given in the form to the rule, leaving
+// the others unchanged.
+func (o *ErrorHandler) setAlertRuleValues(form url.Values, rule *domains.AlertRule) {
+	if o.Err != nil {
+		return
+	}
+
+	if v := o.getStringValueDefault(form, "threshold", ""); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil || threshold < 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("threshold should be a non-negative number, got %s", v))
+			return
+		}
+		rule.Threshold = threshold
+	}
+
+	if v := o.getStringValueDefault(form, "window", ""); v != "" {
+		window, err := strconv.Atoi(v)
+		if err != nil || window < 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("window should be a non-negative integer, got %s", v))
+			return
+		}
+		rule.Window = window
+	}
+
+	if _, ok := form["webhook"]; ok {
+		webhook := o.getStringValueDefault(form, "webhook", "")
+		if webhook != "" {
+			if err := httpx.CheckPublicUrl(webhook); err != nil {
+				o.Err = utils.NewClientError(utils.PARAM_INVALID,
+					fmt.Errorf("webhook should be a public http or https url: %s", err))
+				return
+			}
+		}
+		rule.Webhook = sql.NullString{String: webhook, Valid: webhook != ""}
+	}
+
+	if v := o.getStringValueDefault(form, "notifyCallback", ""); v != "" {
+		notify, err := strconv.ParseBool(v)
+		if err != nil {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("notifyCallback should be true or false, got %s", v))
+			return
+		}
+		rule.NotifyCallback = notify
+	}
+
+	if rule.Kind == domains.ALERT_ACTIONFAILING && rule.Threshold > 1 {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("threshold of %s is a failing rate, should not exceed 1", rule.Kind))
+	}
+}
+
+func (web *WebServer) createAlertRule(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	kind := strings.ToUpper(o.getStringValue(r.Form, "kind"))
+	botId := o.getStringValueDefault(r.Form, "botId", "")
+	accountName := o.getAccountName(r)
+
+	o.CheckAlertKind(kind)
+	if o.Err != nil {
+		return
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	account := o.GetAccountByName(tx, accountName)
+	if o.Err != nil {
+		return
+	}
+	if account == nil {
+		o.Err = utils.NewClientError(utils.RESOURCE_ACCESS_DENIED, fmt.Errorf("account %s not found", accountName))
+		return
+	}
+
+	if botId != "" {
+		o.CheckBotOwnerById(tx, botId, accountName)
+	}
+
+	rule := o.NewAlertRule(account.AccountId, botId, kind)
+	o.setAlertRuleValues(r.Form, rule)
+	o.SaveAlertRule(tx, rule)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "success", NewAlertRuleVO(rule))
+}
+
+func (web *WebServer) getAlertRules(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	accountName := o.getAccountName(r)
+	if o.Err != nil {
+		return
+	}
+
+	rules := o.GetAlertRulesByAccountName(web.db.Conn, accountName)
+	if o.Err != nil {
+		return
+	}
+
+	rulevos := []AlertRuleVO{}
+	for i := range rules {
+		rulevos = append(rulevos, NewAlertRuleVO(&rules[i]))
+	}
+
+	o.ok(w, "success", rulevos)
+}
+
+func (web *WebServer) updateAlertRule(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	ruleId := vars["ruleId"]
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+	if o.Err != nil {
+		return
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	o.CheckAlertRuleOwner(tx, ruleId, accountName)
+	rule := o.GetAlertRuleById(tx, ruleId)
+	if o.Err != nil {
+		return
+	}
+
+	if rule == nil {
+		o.Err = utils.NewClientError(utils.RESOURCE_NOT_FOUND, fmt.Errorf("alert rule %s not found", ruleId))
+		return
+	}
+
+	o.setAlertRuleValues(r.Form, rule)
+	o.UpdateAlertRule(tx, rule)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "update alert rule success", NewAlertRuleVO(rule))
+}
+
+func (web *WebServer) deleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	ruleId := vars["ruleId"]
+
+	accountName := o.getAccountName(r)
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	o.CheckAlertRuleOwner(tx, ruleId, accountName)
+	if o.Err != nil {
+		return
+	}
+
+	o.DeleteAlertRule(tx, ruleId)
+	o.ok(w, "success", ruleId)
+}
+
+// getAlerts lists the alert history of the account, latest first, paged by
+// cursor as getActionRequests is.
+func (web *WebServer) getAlerts(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+
+	botId := o.getStringValueDefault(r.Form, "botId", "")
+	cursor := o.getStringValueDefault(r.Form, "cursor", "")
+
+	criteria := domains.AlertCriteria{
+		BotId:  botId,
+		Kind:   o.getStringValueDefault(r.Form, "kind", ""),
+		Status: o.getStringValueDefault(r.Form, "status", ""),
+		From:   o.getTimeValue(r, "from"),
+		To:     o.getTimeValue(r, "to"),
+	}
+
+	limit := actionRequestsDefaultLimit
+	if limitstr := o.getStringValueDefault(r.Form, "limit", ""); limitstr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitstr); err != nil || limit <= 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("limit should be a positive integer, got %s", limitstr))
+			return
+		}
+		if limit > actionRequestsMaxLimit {
+			limit = actionRequestsMaxLimit
+		}
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	account := o.GetAccountByName(tx, accountName)
+	if o.Err != nil {
+		return
+	}
+	if account == nil {
+		o.Err = utils.NewClientError(utils.RESOURCE_ACCESS_DENIED, fmt.Errorf("account %s not found", accountName))
+		return
+	}
+	criteria.AccountId = account.AccountId
+
+	if botId != "" {
+		o.CheckBotOwnerById(tx, botId, accountName)
+	}
+
+	alerts, next := o.QueryAlerts(web.apilogDb, criteria, cursor, limit)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"alerts":     alerts,
+		"nextCursor": next,
+	})
+}
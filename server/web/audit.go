@@ -0,0 +1,282 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/context"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+// auditTrail collects the audit logs of a request, validate sets it up
+// with who sends the request and writes what the handler recorded once it
+// returns.
+type auditTrail struct {
+	actor   domains.AuditActor
+	records []auditRecord
+}
+
+// auditRecord is an audit log, and the error handler of the handler that
+// recorded it, whose error once the handler returns, after its deferred
+// commit, tells whether the operation is done.
+type auditRecord struct {
+	o   *ErrorHandler
+	log *domains.AuditLog
+}
+
+// parseTrustedProxies parses the proxies given as addresses or cidrs.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %s is not an ip or a cidr", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func (web *WebServer) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range web.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIp is the address the request comes from. The forwarding headers
+// are only taken from the trusted proxies, the client is the last address
+// forwarded that is not one of them.
+func (web *WebServer) clientIp(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+
+	if !web.isTrustedProxy(remote) {
+		return remote
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && (i == 0 || !web.isTrustedProxy(hop)) {
+				return hop
+			}
+		}
+	}
+	if realIp := r.Header.Get("X-Real-IP"); realIp != "" {
+		return realIp
+	}
+	return remote
+}
+
+func (web *WebServer) newAuditActor(r *http.Request, accountName string, clientType string, child *utils.AuthChildUser) domains.AuditActor {
+	actor := domains.AuditActor{
+		AccountName: accountName,
+		ClientType:  clientType,
+		ClientIp:    web.clientIp(r),
+	}
+
+	if requestID, ok := r.Context().Value(requestIDKey).(string); ok {
+		actor.RequestId = requestID
+	}
+
+	// the cookie of the child stays out of the audit logs
+	if child != nil {
+		actor.ChildMetadata = child.Metadata
+		actor.ChildAuthUrl = child.AuthUrl
+	}
+
+	return actor
+}
+
+func (web *WebServer) beginAudit(r *http.Request, user *utils.AuthUser, clientType string) {
+	context.Set(r, "audit", &auditTrail{
+		actor: web.newAuditActor(r, user.AccountName, clientType, user.Child),
+	})
+}
+
+// endAudit writes the audit logs the handler recorded, but the ones of an
+// operation failing after it was recorded, as its commit. The operation is
+// done already, so a failed write is logged, not returned.
+func (web *WebServer) endAudit(r *http.Request) {
+	trail, ok := context.Get(r, "audit").(*auditTrail)
+	if !ok {
+		return
+	}
+
+	logs := []*domains.AuditLog{}
+	for _, record := range trail.records {
+		if record.o.Err == nil {
+			logs = append(logs, record.log)
+		}
+	}
+	if len(logs) == 0 {
+		return
+	}
+
+	o := &ErrorHandler{}
+	if o.SaveAuditLogs(web.apilogDb, logs); o.Err != nil {
+		web.Error(o.Err, "save %d audit logs of %s failed", len(logs), trail.actor.AccountName)
+	}
+}
+
+// audit records the operation of a request passing validate, if it
+// succeeded so far. It is written once the handler returns without error.
+func (o *ErrorHandler) audit(r *http.Request, action string, targetType string, targetId string, before interface{}, after interface{}) {
+	if o.Err != nil {
+		return
+	}
+
+	trail, ok := context.Get(r, "audit").(*auditTrail)
+	if !ok {
+		return
+	}
+
+	trail.records = append(trail.records, auditRecord{
+		o:   o,
+		log: o.NewAuditLog(trail.actor, action, targetType, targetId, auditSnapshot(before), auditSnapshot(after)),
+	})
+}
+
+// saveAudit writes the audit log of a request not passing validate, as
+// login and refreshtoken.
+func (web *WebServer) saveAudit(actor domains.AuditActor, action string, targetType string, targetId string, after interface{}) {
+	o := &ErrorHandler{}
+	log := o.NewAuditLog(actor, action, targetType, targetId, nil, auditSnapshot(after))
+	if o.SaveAuditLogs(web.apilogDb, []*domains.AuditLog{log}); o.Err != nil {
+		web.Error(o.Err, "save audit log %s of %s failed", action, actor.AccountName)
+	}
+}
+
+// auditSnapshot stores the target as its json, so that the snapshots read
+// back from mongo keep the field names of the api.
+func auditSnapshot(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var snapshot interface{}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil
+	}
+
+	return snapshot
+}
+
+// auditBot is the snapshot of a bot, the login info is a secret of the
+// client, only whether it is set is kept.
+func auditBot(bot *domains.Bot) interface{} {
+	if bot == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"botId":          bot.BotId,
+		"botName":        bot.BotName,
+		"login":          bot.Login,
+		"clientType":     bot.ChatbotType,
+		"callback":       bot.Callback.String,
+		"filterId":       bot.FilterId.String,
+		"momentFilterId": bot.MomentFilterId.String,
+		"wxaappId":       bot.WxaappId.String,
+		"hasLoginInfo":   bot.LoginInfo.Valid && bot.LoginInfo.String != "" && bot.LoginInfo.String != "{}",
+	}
+}
+
+func auditFilter(filter *domains.Filter) interface{} {
+	if filter == nil {
+		return nil
+	}
+
+	return FilterVO{
+		FilterId: filter.FilterId,
+		Name:     filter.FilterName,
+		Type:     filter.FilterType,
+		Body:     filter.Body.String,
+		Next:     filter.Next.String,
+		CreateAt: utils.JSONTime{filter.CreateAt.Time},
+	}
+}
+
+// getAuditLogs returns the audit logs of the account, latest first.
+func (web *WebServer) getAuditLogs(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+
+	cursor := o.getStringValueDefault(r.Form, "cursor", "")
+
+	criteria := domains.AuditLogCriteria{
+		AccountName:   accountName,
+		Action:        o.getStringValueDefault(r.Form, "action", ""),
+		TargetType:    o.getStringValueDefault(r.Form, "targetType", ""),
+		TargetId:      o.getStringValueDefault(r.Form, "targetId", ""),
+		ChildMetadata: o.getStringValueDefault(r.Form, "childMetadata", ""),
+		From:          o.getTimeValue(r, "from"),
+		To:            o.getTimeValue(r, "to"),
+	}
+
+	limit := actionRequestsDefaultLimit
+	if limitstr := o.getStringValueDefault(r.Form, "limit", ""); limitstr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitstr); err != nil || limit <= 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("limit should be a positive integer, got %s", limitstr))
+			return
+		}
+		if limit > actionRequestsMaxLimit {
+			limit = actionRequestsMaxLimit
+		}
+	}
+
+	if o.Err != nil {
+		return
+	}
+
+	logs, next := o.QueryAuditLogs(web.apilogDb, criteria, cursor, limit)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"auditLogs":  logs,
+		"nextCursor": next,
+	})
+}
@@ -1,6 +1,9 @@
 package web
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -141,7 +144,11 @@ func (ctx *WebServer) sdkToken(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	o.ok(w, "", o.genSdkToken(ctx, accountName, time.Hour*24*7, time.Hour*24*30, nil))
+	token := o.genSdkToken(ctx, accountName, time.Hour*24*7, time.Hour*24*30, nil)
+	o.audit(req, domains.AUDIT_TOKEN_SDK, domains.AUDIT_TARGET_ACCOUNT, accountName, nil, map[string]interface{}{
+		"expireAt": token["expireAt"],
+	})
+	o.ok(w, "", token)
 }
 
 func (web *WebServer) sdkTokenChild(w http.ResponseWriter, r *http.Request) {
@@ -197,11 +204,17 @@ func (web *WebServer) sdkTokenChild(w http.ResponseWriter, r *http.Request) {
 	expires := querym.ExpireAt.Sub(now)
 	refreshExpires := querym.ExpireAt.Sub(now) * 4
 
-	o.ok(w, "", o.genSdkToken(web, accountName, expires, refreshExpires, &utils.AuthChildUser{
+	token := o.genSdkToken(web, accountName, expires, refreshExpires, &utils.AuthChildUser{
 		Metadata: querym.Metadata,
 		AuthUrl:  querym.AuthUrl,
 		Cookie:   querym.Cookie,
-	}))
+	})
+	o.audit(r, domains.AUDIT_TOKEN_CHILD, domains.AUDIT_TARGET_ACCOUNT, accountName, nil, map[string]interface{}{
+		"metadata": querym.Metadata,
+		"authUrl":  querym.AuthUrl,
+		"expireAt": token["expireAt"],
+	})
+	o.ok(w, "", token)
 }
 
 func (ctx *WebServer) refreshToken(w http.ResponseWriter, req *http.Request) {
@@ -252,7 +265,14 @@ func (ctx *WebServer) refreshToken(w http.ResponseWriter, req *http.Request) {
 		}
 
 		// pass validate
-		o.ok(w, "", o.genSdkToken(ctx, user.AccountName, time.Hour*24*7, time.Hour*24*30, nil))
+		token := o.genSdkToken(ctx, user.AccountName, time.Hour*24*7, time.Hour*24*30, nil)
+		if o.Err == nil {
+			ctx.saveAudit(ctx.newAuditActor(req, user.AccountName, clientType, user.Child),
+				domains.AUDIT_TOKEN_REFRESH, domains.AUDIT_TARGET_ACCOUNT, user.AccountName, map[string]interface{}{
+					"expireAt": token["expireAt"],
+				})
+		}
+		o.ok(w, "", token)
 		return
 	} else {
 		o.Err = utils.NewAuthError(fmt.Errorf("身份令牌未验证通过"))
@@ -281,13 +301,10 @@ func (ctx *WebServer) login(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	fmt.Println(user.AccountName)
-	fmt.Println(utils.HexString(utils.CheckSum([]byte(user.Password))))
+	ctx.Debug("login %s", user.AccountName)
 
 	foundcount := 0
 	for _, acc := range ctx.accounts.accounts {
-		fmt.Println(acc.AccountName, acc.Secret)
-
 		secret := utils.HexString(utils.CheckSum([]byte(user.Password)))
 		if acc.AccountName == user.AccountName && acc.Secret == secret {
 			foundcount += 1
@@ -306,6 +323,8 @@ func (ctx *WebServer) login(w http.ResponseWriter, req *http.Request) {
 		session.Save(req, w)
 
 		if o.Err == nil {
+			ctx.saveAudit(ctx.newAuditActor(req, user.AccountName, USER, nil),
+				domains.AUDIT_ACCOUNT_LOGIN, domains.AUDIT_TARGET_ACCOUNT, user.AccountName, nil)
 			http.Redirect(w, req, "/", http.StatusFound)
 		} else {
 			o.Err = utils.NewAuthError(o.Err)
@@ -347,6 +366,32 @@ func (web *WebServer) streamingCtrl(w http.ResponseWriter, req *http.Request) {
 	o.ok(w, "", nil)
 }
 
+// the header carrying InternalToken, of the calls from tasks and the hub
+const InternalTokenHeader string = "X-INTERNAL-TOKEN"
+
+// InternalToken proves a call comes from a service sharing the secret
+// phrase of web, rather than from an account.
+func InternalToken(secretPhrase string) string {
+	mac := hmac.New(sha256.New, []byte(secretPhrase))
+	mac.Write([]byte("chatbothub internal"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// internal lets only the calls carrying InternalToken through.
+func (ctx *WebServer) internal(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := req.Header.Get(InternalTokenHeader)
+		if token == "" || !hmac.Equal([]byte(token), []byte(InternalToken(ctx.Config.SecretPhrase))) {
+			o := &ErrorHandler{}
+			defer o.WebError(w)
+			o.Err = utils.NewAuthError(fmt.Errorf("internal call only"))
+			return
+		}
+
+		next(w, req)
+	})
+}
+
 func (ctx *WebServer) validate(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		o := &ErrorHandler{}
@@ -412,7 +457,9 @@ func (ctx *WebServer) validate(next http.HandlerFunc) http.HandlerFunc {
 
 					// pass validate
 					context.Set(req, "login", user.AccountName)
+					ctx.beginAudit(req, user, clientType)
 					next(w, req)
+					ctx.endAudit(req)
 				}
 			} else {
 				o.Err = utils.NewAuthError(fmt.Errorf("身份令牌未验证通过"))
@@ -455,7 +502,7 @@ func (web *WebServer) UpdateAccounts() error {
 		return o.Err
 	}
 
-	web.Info("[cache accounts debug] updated %d accounts", len(accounts))
+	web.Debug("[cache accounts] updated %d accounts", len(accounts))
 
 	web.accounts.accounts = accounts
 	web.accounts.updateAt = time.Now()
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hawkwithwind/mux"
+
+	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+// getBotLogs returns the recent log lines the hub owning the bot kept of
+// it, optionally from a level up and only the latest limit ones.
+func (web *WebServer) getBotLogs(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	botId := vars["botId"]
+
+	accountName := o.getAccountName(r)
+	if o.Err != nil {
+		return
+	}
+
+	o.CheckBotOwnerById(web.db.Conn, botId, accountName)
+	if o.Err != nil {
+		return
+	}
+
+	r.ParseForm()
+	level := o.getStringValueDefault(r.Form, "level", "")
+	limit := 0
+	if limitstr := o.getStringValueDefault(r.Form, "limit", ""); limitstr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitstr); err != nil || limit <= 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("limit should be a positive integer, got %s", limitstr))
+			return
+		}
+	}
+
+	wrapper, err := web.NewGRPCWrapperForBot(domains.BotOwnerByBot, botId)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	defer wrapper.Cancel()
+
+	reply, err := wrapper.HubAdminClient.GetBotLogs(wrapper.Context, &pb.BotLogsRequest{
+		BotId: botId,
+		Level: level,
+		Limit: int32(limit),
+	})
+	if err != nil {
+		o.Err = err
+		return
+	} else if reply == nil {
+		o.Err = fmt.Errorf("botlogs reply is nil")
+		return
+	}
+
+	o.checkOperationReply(reply.ClientError)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", reply.Lines)
+}
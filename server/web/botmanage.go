@@ -10,6 +10,7 @@ import (
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
@@ -205,7 +206,7 @@ func (ctx *ErrorHandler) BotAction(w *rpc.GRPCWrapper, req *pb.BotActionRequest)
 	}
 
 	if actionreply, err := w.HubClient.BotAction(w.Context, req); err != nil {
-		fmt.Println("bot action err")
+		webLogger.Error(err, "bot action failed")
 		ctx.Err = err
 		return nil
 	} else if actionreply == nil {
@@ -454,6 +455,7 @@ func (ctx *WebServer) createBot(w http.ResponseWriter, r *http.Request) {
 	o.SaveBot(tx, bot)
 	o.CommitOrRollback(tx)
 
+	o.audit(r, domains.AUDIT_BOT_CREATE, domains.AUDIT_TARGET_BOT, bot.BotId, nil, auditBot(bot))
 	o.ok(w, "", bot)
 }
 
@@ -464,6 +466,7 @@ func (ctx *WebServer) scanCreateBot(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	botName := o.getStringValue(r.Form, "botName")
 	clientType := o.getStringValue(r.Form, "clientType")
+	region := o.getStringValueDefault(r.Form, "region", "")
 	accountName := o.getAccountName(r)
 	if o.Err != nil {
 		return
@@ -481,6 +484,8 @@ func (ctx *WebServer) scanCreateBot(w http.ResponseWriter, r *http.Request) {
 	o.SaveBot(tx, bot)
 	o.CommitOrRollback(tx)
 
+	o.audit(r, domains.AUDIT_BOT_CREATE, domains.AUDIT_TARGET_BOT, bot.BotId, nil, auditBot(bot))
+
 	wrapper, err := ctx.NewGRPCWrapper()
 	if err != nil {
 		o.Err = err
@@ -503,6 +508,7 @@ func (ctx *WebServer) scanCreateBot(w http.ResponseWriter, r *http.Request) {
 		Password:   "",
 		LoginInfo:  "",
 		BotId:      bot.BotId,
+		Region:     region,
 	})
 
 	if o.Err != nil {
@@ -516,6 +522,10 @@ func (ctx *WebServer) scanCreateBot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	o.audit(r, domains.AUDIT_BOT_LOGIN, domains.AUDIT_TARGET_BOT, bot.BotId, nil, map[string]interface{}{
+		"clientType": clientType,
+		"region":     region,
+	})
 	o.ok(w, "", loginreply)
 }
 
@@ -559,6 +569,7 @@ func (web *WebServer) botLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	o.audit(r, domains.AUDIT_BOT_LOGOUT, domains.AUDIT_TARGET_BOT, botId, nil, nil)
 	o.ok(w, "", opreply)
 }
 
@@ -634,6 +645,8 @@ func (web *WebServer) clearBotLoginInfo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before := auditBot(bot)
+
 	switch bot.ChatbotType {
 	case chatbothub.WECHATBOT:
 		if bot.LoginInfo.Valid {
@@ -671,6 +684,7 @@ func (web *WebServer) clearBotLoginInfo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	o.audit(r, domains.AUDIT_BOT_CLEARLOGININFO, domains.AUDIT_TARGET_BOT, botId, before, auditBot(bot))
 	o.ok(w, "", nil)
 }
 
@@ -683,38 +697,52 @@ func (web *WebServer) deleteBot(w http.ResponseWriter, r *http.Request) {
 
 	accountName := o.getAccountName(r)
 
-	tx := o.Begin(web.db)
-	defer o.CommitOrRollback(tx)
+	// committed before it is audited and answered
+	before := func() interface{} {
+		tx := o.Begin(web.db)
+		defer o.CommitOrRollback(tx)
 
-	o.CheckBotOwnerById(tx, botId, accountName)
-	if o.Err != nil {
-		return
-	}
+		o.CheckBotOwnerById(tx, botId, accountName)
+		if o.Err != nil {
+			return nil
+		}
 
-	wrapper, err := web.NewGRPCWrapper()
-	if err != nil {
-		o.Err = err
-		return
-	}
+		before := auditBot(o.GetBotById(tx, botId))
+		if o.Err != nil {
+			return nil
+		}
 
-	defer wrapper.Cancel()
+		wrapper, err := web.NewGRPCWrapper()
+		if err != nil {
+			o.Err = err
+			return nil
+		}
 
-	opreply := o.BotShutdown(wrapper, &pb.BotLogoutRequest{
-		BotId: botId,
-	})
+		defer wrapper.Cancel()
 
-	if o.Err != nil {
-		return
-	}
+		opreply := o.BotShutdown(wrapper, &pb.BotLogoutRequest{
+			BotId: botId,
+		})
 
-	if opreply.Code != 0 {
-		o.Err = utils.NewClientError(
-			utils.ClientErrorCode(opreply.Code),
-			fmt.Errorf(opreply.Message))
+		if o.Err != nil {
+			return nil
+		}
+
+		if opreply.Code != 0 {
+			o.Err = utils.NewClientError(
+				utils.ClientErrorCode(opreply.Code),
+				fmt.Errorf(opreply.Message))
+			return nil
+		}
+
+		o.DeleteBot(tx, botId)
+		return before
+	}()
+	if o.Err != nil {
 		return
 	}
 
-	o.DeleteBot(tx, botId)
+	o.audit(r, domains.AUDIT_BOT_DELETE, domains.AUDIT_TARGET_BOT, botId, before, nil)
 	o.ok(w, "", nil)
 }
 
@@ -744,6 +772,7 @@ func (ctx *WebServer) updateBot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	bot := o.GetBotById(tx, botId)
+	before := auditBot(bot)
 	if botName != "" {
 		bot.BotName = botName
 	}
@@ -791,6 +820,7 @@ func (ctx *WebServer) updateBot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	o.UpdateBot(tx, bot)
+	o.audit(r, domains.AUDIT_BOT_UPDATE, domains.AUDIT_TARGET_BOT, botId, before, auditBot(bot))
 	o.ok(w, "", nil)
 }
 
@@ -806,6 +836,7 @@ func (ctx *WebServer) botLogin(w http.ResponseWriter, r *http.Request) {
 	clientId := o.getStringValueDefault(r.Form, "clientId", "")
 	login := o.getStringValueDefault(r.Form, "login", "")
 	pass := o.getStringValueDefault(r.Form, "password", "")
+	region := o.getStringValueDefault(r.Form, "region", "")
 	if o.Err != nil {
 		return
 	}
@@ -851,7 +882,14 @@ func (ctx *WebServer) botLogin(w http.ResponseWriter, r *http.Request) {
 		Password:   pass,
 		LoginInfo:  logininfo,
 		BotId:      botId,
+		Region:     region,
 	})
 
+	o.audit(r, domains.AUDIT_BOT_LOGIN, domains.AUDIT_TARGET_BOT, botId, nil, map[string]interface{}{
+		"clientId":   clientId,
+		"clientType": clientType,
+		"login":      login,
+		"region":     region,
+	})
 	o.ok(w, "", loginreply)
 }
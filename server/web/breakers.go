@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+)
+
+// notifyBreakerTransition sends the state change of an action breaker to
+// the callback of the bot, as an ACTIONBREAKER event.
+func (web *WebServer) notifyBreakerTransition(botId string, transition *domains.BreakerTransition) {
+	if transition == nil {
+		return
+	}
+
+	web.Info("breaker %s:%s %s -> %s, %s", transition.Login, transition.ActionType,
+		transition.From, transition.To, transition.Reason)
+
+	go func() {
+		o := &ErrorHandler{}
+		bot := o.GetBotById(web.db.Conn, botId)
+		if o.Err != nil {
+			web.Error(o.Err, "get bot %s for breaker transition failed", botId)
+			return
+		}
+
+		if bot == nil || !bot.Callback.Valid {
+			return
+		}
+
+		if resp, err := httpx.RestfulCallRetry(web.restfulclient,
+			webCallbackRequest(bot, chatbothub.ACTIONBREAKER, o.ToJson(transition)), 5, 1); err != nil {
+			web.Error(err, "breaker transition callback failed")
+		} else {
+			web.Info("breaker transition callback resp [%d]", resp.StatusCode)
+		}
+	}()
+}
+
+func (web *WebServer) getActionBreakers(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+	defer o.BackEndError(web)
+
+	accountName := o.getAccountName(r)
+	bots := o.GetBotsByAccountName(web.db.Conn, accountName)
+	if o.Err != nil {
+		return
+	}
+
+	logins := []string{}
+	for _, bot := range bots {
+		if bot.Login != "" {
+			logins = append(logins, bot.Login)
+		}
+	}
+
+	conn := web.redispool.Get()
+	defer conn.Close()
+
+	breakers := o.GetActionBreakers(conn, logins)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", breakers)
+}
+
+// breakerTransition receives transitions decided outside web, e.g. by the
+// action timeout listener in tasks. It is internal, see internal.
+func (web *WebServer) breakerTransition(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+	defer o.BackEndError(web)
+
+	var b []byte
+	b, o.Err = ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if o.Err != nil {
+		return
+	}
+
+	transition := &domains.BreakerTransition{}
+	o.Err = json.Unmarshal(b, transition)
+	if o.Err != nil {
+		return
+	}
+
+	wrapper, err := web.NewGRPCWrapper()
+	if err != nil {
+		o.Err = err
+		return
+	}
+	defer wrapper.Cancel()
+
+	bot := o.getBotByLogin(wrapper, transition.Login)
+	if o.Err != nil {
+		return
+	}
+
+	web.notifyBreakerTransition(bot.BotId, transition)
+	o.ok(w, "", nil)
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/hawkwithwind/mux"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
@@ -59,3 +62,177 @@ func (ctx *WebServer) clientShutdown(w http.ResponseWriter, r *http.Request) {
 
 	o.ok(w, "", nil)
 }
+
+func (o *ErrorHandler) InspectClient(w *rpc.GRPCWrapper, clientId string) *pb.ClientInspection {
+	if o.Err != nil {
+		return nil
+	}
+
+	inspection, err := w.HubAdminClient.InspectClient(w.Context, &pb.ClientAdminRequest{ClientId: clientId})
+	if err != nil {
+		o.Err = err
+		return nil
+	} else if inspection == nil {
+		o.Err = fmt.Errorf("inspection is nil")
+		return nil
+	}
+
+	o.checkOperationReply(inspection.ClientError)
+	if o.Err != nil {
+		return nil
+	}
+
+	return inspection
+}
+
+func (o *ErrorHandler) checkOperationReply(opreply *pb.OperationReply) {
+	if o.Err != nil || opreply == nil {
+		return
+	}
+
+	if opreply.Code != 0 {
+		o.Err = utils.NewClientError(
+			utils.ClientErrorCode(opreply.Code),
+			fmt.Errorf(opreply.Message))
+	}
+}
+
+// clientInspect shows the live state of a client on the hub that hosts it:
+// its bot, ping latencies, tunnel errors, actions waiting for a reply and
+// the recent events.
+func (ctx *WebServer) clientInspect(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	clientId := vars["clientId"]
+
+	wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByClient, clientId)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	defer wrapper.Cancel()
+
+	inspection := o.InspectClient(wrapper, clientId)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", inspection)
+}
+
+func (ctx *WebServer) clientReconnect(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	clientId := vars["clientId"]
+
+	r.ParseForm()
+	target := o.getStringValueDefault(r.Form, "target", "")
+	if o.Err != nil {
+		return
+	}
+
+	wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByClient, clientId)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	defer wrapper.Cancel()
+
+	opreply, err := wrapper.HubAdminClient.ReconnectClient(wrapper.Context, &pb.ClientAdminRequest{
+		ClientId: clientId,
+		Target:   target,
+	})
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	o.checkOperationReply(opreply)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", nil)
+}
+
+func (ctx *WebServer) clientLogLevel(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	vars := mux.Vars(r)
+	clientId := vars["clientId"]
+
+	r.ParseForm()
+	level := o.getStringValue(r.Form, "level")
+	if o.Err != nil {
+		return
+	}
+
+	wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByClient, clientId)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	defer wrapper.Cancel()
+
+	opreply, err := wrapper.HubAdminClient.SetClientLogLevel(wrapper.Context, &pb.ClientAdminRequest{
+		ClientId: clientId,
+		LogLevel: level,
+	})
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	o.checkOperationReply(opreply)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", nil)
+}
+
+// clientSyncContacts forces a contact sync of the bot logged in on the
+// client.
+func (ctx *WebServer) clientSyncContacts(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+	defer o.BackEndError(ctx)
+
+	vars := mux.Vars(r)
+	clientId := vars["clientId"]
+
+	wrapper, err := ctx.NewGRPCWrapperForBot(domains.BotOwnerByClient, clientId)
+	if err != nil {
+		o.Err = err
+		return
+	}
+
+	defer wrapper.Cancel()
+
+	inspection := o.InspectClient(wrapper, clientId)
+	if o.Err != nil {
+		return
+	}
+
+	if inspection.BotsInfo == nil || inspection.BotsInfo.Login == "" {
+		o.Err = utils.NewClientError(utils.STATUS_INCONSISTENT,
+			fmt.Errorf("c[%s] has no bot logged in", clientId))
+		return
+	}
+
+	ar := o.NewActionRequest(inspection.BotsInfo.Login, chatbothub.SyncContact, "{}", "NEW")
+	actionReply := o.CreateAndRunAction(ctx, ar)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", actionReply)
+}
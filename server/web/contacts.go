@@ -42,7 +42,7 @@ func (cd *ContactInfoDispatcher) Notify(username string, chatuser domains.ChatUs
 		// send to channel maybe block, use go routine
 		go func() {
 			for ch := range pipe {
-				fmt.Printf("[sync get contact debug] notify %s", username)
+				webLogger.Debug("[sync get contact] notify %s", username)
 				ch <- chatuser
 			}
 		}()
@@ -171,7 +171,7 @@ func (web *WebServer) processUsers() {
 			//web.Info("[contacts debug] process %d", len(users))
 			err := web.saveChatUsers(users)
 			if err != nil {
-				web.Error(err, "[Contacts debug] save chatusers failed")
+				web.Error(err, "[Contacts] save chatusers failed")
 			}
 			users = []ProcessUserInfo{}
 		} else {
@@ -198,7 +198,7 @@ func (web *WebServer) saveChatUsers(users []ProcessUserInfo) error {
 		chatusers = append(chatusers, cc.chatuser)
 	}
 
-	web.Info("[Contacts debug] ready to save chatusers [%d]", len(chatusers))
+	web.Debug("[Contacts] ready to save chatusers [%d]", len(chatusers))
 	dbusers := o.FindOrCreateChatUsers(tx, chatusers)
 	findm := map[string]string{}
 	for _, dbu := range dbusers {
@@ -211,7 +211,7 @@ func (web *WebServer) saveChatUsers(users []ProcessUserInfo) error {
 
 	go func() {
 		if web.contactInfoDispatcher == nil {
-			web.Info("[sync get contact debug] web.contactInfoDispatcher is nil")
+			web.Debug("[sync get contact] web.contactInfoDispatcher is nil")
 			return
 		}
 
@@ -223,7 +223,7 @@ func (web *WebServer) saveChatUsers(users []ProcessUserInfo) error {
 	// ------- sync-get-contact notify end ------
 
 	if o.Err != nil {
-		web.Error(o.Err, "[Contacts debug] failed to save users [%d]", len(users))
+		web.Error(o.Err, "[Contacts] failed to save users [%d]", len(users))
 	} else {
 		ccs := []*domains.ChatContact{}
 		for _, uu := range users {
@@ -231,13 +231,13 @@ func (web *WebServer) saveChatUsers(users []ProcessUserInfo) error {
 				cc := o.NewChatContact(uu.botId, chatuserid)
 				ccs = append(ccs, cc)
 			} else {
-				web.Info("[Contacts debug] failed to save %s", uu.chatuser.UserName)
+				web.Debug("[Contacts] failed to save %s", uu.chatuser.UserName)
 			}
 		}
 
 		o.SaveIgnoreChatContacts(tx, ccs)
 		if o.Err != nil {
-			web.Error(o.Err, "[Contacts debug] failed to save chatcontacts[%d]", len(ccs))
+			web.Error(o.Err, "[Contacts] failed to save chatcontacts[%d]", len(ccs))
 		} else {
 			//web.Info("[Contacts debug] saved chatuser [%d]", len(users))
 		}
@@ -285,10 +285,10 @@ func (web *WebServer) processGroups() {
 		}
 
 		if (len(groups) > sectionLength) || (isTimeout && len(groups) > 0) {
-			web.Info("[contact groups debug] process %d", len(groups))
+			web.Debug("[contact groups] process %d", len(groups))
 			err := web.saveGroups(groups)
 			if err != nil {
-				web.Error(err, "[Contacts debug] save chatusers failed")
+				web.Error(err, "[Contacts] save chatusers failed")
 			}
 
 			groups = []ProcessGroupInfo{}
@@ -296,7 +296,7 @@ func (web *WebServer) processGroups() {
 			//web.Info("[contacts groups debug] isTimeout %v, groups[%d]", isTimeout, len(groups))
 
 			if len(groups) > 0 {
-				web.Info("[contact groups debug] stock group %d", len(groups))
+				web.Debug("[contact groups] stock group %d", len(groups))
 			}
 		}
 	}
@@ -7,12 +7,14 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 	"github.com/hawkwithwind/mux"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func (web *WebServer) NewGRPCWrapper() (*rpc.GRPCWrapper, error) {
@@ -23,6 +25,39 @@ func (web *WebServer) NewGRPCWrapper() (*rpc.GRPCWrapper, error) {
 	return web.wrapper.Clone()
 }
 
+// NewGRPCWrapperForBot connects to the hub instance that owns the bot, so the
+// call need not be forwarded between hubs. It falls back to the configured
+// hub when the owner is unknown.
+func (web *WebServer) NewGRPCWrapperForBot(kind string, id string) (*rpc.GRPCWrapper, error) {
+	o := &ErrorHandler{}
+	conn := web.redispool.Get()
+	owner := o.GetBotOwner(conn, kind, id)
+	conn.Close()
+
+	if o.Err != nil {
+		web.Error(o.Err, "get owner of %s %s failed", kind, id)
+	}
+
+	if owner == nil {
+		return web.NewGRPCWrapper()
+	}
+
+	web.muxHubWrappers.Lock()
+	defer web.muxHubWrappers.Unlock()
+
+	if web.hubWrappers == nil {
+		web.hubWrappers = make(map[string]*rpc.GRPCWrapper)
+	}
+
+	wrapper, ok := web.hubWrappers[owner.Addr]
+	if !ok {
+		wrapper = rpc.CreateGRPCWrapper(owner.Addr)
+		web.hubWrappers[owner.Addr] = wrapper
+	}
+
+	return wrapper.Clone()
+}
+
 func (ctx *WebServer) echo(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
@@ -429,23 +464,31 @@ func (web *WebServer) createFilter(w http.ResponseWriter, r *http.Request) {
 	filterbody := o.getStringValueDefault(r.Form, "body", "")
 	accountName := o.getAccountName(r)
 
-	tx := o.Begin(web.db)
-	defer o.CommitOrRollback(tx)
+	// committed before it is audited and answered
+	filter := func() *domains.Filter {
+		tx := o.Begin(web.db)
+		defer o.CommitOrRollback(tx)
 
-	account := o.GetAccountByName(tx, accountName)
+		account := o.GetAccountByName(tx, accountName)
+		if o.Err != nil {
+			return nil
+		}
+		if account == nil {
+			o.Err = fmt.Errorf("account %s not found", accountName)
+			return nil
+		}
+		filter := o.NewFilter(filtername, filtertype, "", account.AccountId)
+		if filterbody != "" {
+			filter.Body = sql.NullString{String: filterbody, Valid: true}
+		}
+		o.SaveFilter(tx, filter)
+		return filter
+	}()
 	if o.Err != nil {
 		return
 	}
-	if account == nil {
-		o.Err = fmt.Errorf("account %s not found", accountName)
-		return
-	}
-	filter := o.NewFilter(filtername, filtertype, "", account.AccountId)
-	if filterbody != "" {
-		filter.Body = sql.NullString{String: filterbody, Valid: true}
-	}
-	o.SaveFilter(tx, filter)
 
+	o.audit(r, domains.AUDIT_FILTER_CREATE, domains.AUDIT_TARGET_FILTER, filter.FilterId, nil, auditFilter(filter))
 	o.ok(w, "success", filter)
 }
 
@@ -530,6 +573,8 @@ func (web *WebServer) updateFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := auditFilter(filter)
+
 	if filtername != "" {
 		filter.FilterName = filtername
 	}
@@ -547,6 +592,7 @@ func (web *WebServer) updateFilter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	o.UpdateFilter(tx, filter)
+	o.audit(r, domains.AUDIT_FILTER_UPDATE, domains.AUDIT_TARGET_FILTER, filterId, before, auditFilter(filter))
 	o.ok(w, "update filter success", filter)
 }
 
@@ -579,9 +625,12 @@ func (web *WebServer) updateFilterNext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := auditFilter(filter)
+
 	filter.Next = sql.NullString{String: nextFilterId, Valid: true}
 	o.UpdateFilter(tx, filter)
 	o.CommitOrRollback(tx)
+	o.audit(r, domains.AUDIT_FILTER_UPDATE, domains.AUDIT_TARGET_FILTER, filterId, before, auditFilter(filter))
 	o.ok(w, "update filter next success", filter)
 }
 
@@ -637,7 +686,9 @@ func (web *WebServer) deleteFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := auditFilter(o.GetFilterById(tx, filterId))
 	o.DeleteFilter(tx, filterId)
+	o.audit(r, domains.AUDIT_FILTER_DELETE, domains.AUDIT_TARGET_FILTER, filterId, before, nil)
 	o.ok(w, "success", filterId)
 }
 
@@ -746,7 +797,7 @@ func (web *WebServer) Search(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	domain := vars["domain"]
-	web.Info("[SEARCH DEBUG] domains %s", domain)
+	web.Debug("[SEARCH] domains %s", domain)
 
 	r.ParseForm()
 	query := o.getStringValue(r.Form, "q")
@@ -1105,6 +1156,8 @@ func (web *WebServer) GetChatMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	defer metrics.ObserveMongo(domains.WechatMessageCollection, "find", time.Now())
+
 	wms := o.GetWechatMessages(web.messageDb.C(
 		domains.WechatMessageCollection,
 	).Find(criteria).Sort(
@@ -1166,7 +1219,7 @@ func (web *WebServer) SearchMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	web.Info("[MESSAGE SEARCH DEBUG] %s", mapkey)
+	web.Debug("[MESSAGE SEARCH] %s", mapkey)
 
 	r.ParseForm()
 	accountName := o.getAccountName(r)
@@ -1187,7 +1240,7 @@ func (web *WebServer) SearchMessage(w http.ResponseWriter, r *http.Request) {
 		query = string(b)
 	}
 
-	web.Info("[MESSAGE SEARCH DEBUG] %s", query)
+	web.Debug("[MESSAGE SEARCH] %s", query)
 
 	querym := o.FromJson(query)
 	if o.Err != nil {
@@ -1299,7 +1352,7 @@ func (web *WebServer) SearchMessage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	web.Info("[MESSAGE SEARCH DEBUG] CRITERIA \n %s", o.ToJson(criteria))
+	web.Debug("[MESSAGE SEARCH] CRITERIA \n %s", o.ToJson(criteria))
 
 	if o.Err != nil {
 		return
@@ -1373,6 +1426,8 @@ func (web *WebServer) SearchMessage(w http.ResponseWriter, r *http.Request) {
 		Value string
 	}
 	//var ret *mgo.MapReduceInfo
+	defer metrics.ObserveMongo(domains.WechatMessageCollection, "mapreduce", time.Now())
+
 	_, o.Err = web.messageDb.C(
 		domains.WechatMessageCollection).Find(criteria).MapReduce(job, &results)
 	if o.Err != nil {
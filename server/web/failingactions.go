@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 )
 
 var (
@@ -65,6 +67,26 @@ func (web *WebServer) recoverAction(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	o.RecoverAction(conn, key, action)
+
+	// key is FAILING:clientType:clientId:login
+	if t := strings.SplitN(key, ":", 4); len(t) == 4 {
+		transition := o.ResetActionBreaker(conn, t[3], action)
+		if transition != nil {
+			wrapper, err := web.NewGRPCWrapper()
+			if err != nil {
+				o.Err = err
+				return
+			}
+			defer wrapper.Cancel()
+
+			if bot := o.getBotByLogin(wrapper, t[3]); bot != nil {
+				web.notifyBreakerTransition(bot.BotId, transition)
+			}
+			// the bot may be offline, reset is done anyway
+			o.Err = nil
+		}
+	}
+
 	o.ok(w, "", nil)
 }
 
@@ -79,6 +101,7 @@ func (web *WebServer) recoverClient(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	o.RecoverClient(conn, key)
+	o.audit(r, domains.AUDIT_CLIENT_RECOVER, domains.AUDIT_TARGET_CLIENT, key, nil, nil)
 	o.ok(w, "", nil)
 }
 
@@ -135,3 +158,62 @@ func (web *WebServer) timeoutFriendRequest(w http.ResponseWriter, r *http.Reques
 
 	o.ok(w, "", nil)
 }
+
+func (web *WebServer) notifyRetryActions(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+	defer o.BackEndError(web)
+
+	conn := web.redispool.Get()
+	defer conn.Close()
+
+	arids := o.ClaimDueActionRetries(conn, 100)
+	if o.Err != nil {
+		return
+	}
+
+	retried := 0
+	for _, arid := range arids {
+		ar := o.GetActionRequest_(conn, arid)
+		if o.Err != nil || ar == nil {
+			web.Error(o.Err, "retry ar %s not found, or is expired", arid)
+			o.Err = nil
+			continue
+		}
+
+		// a late reply may have completed the action while it was waiting
+		if ar.Status == "Done" {
+			continue
+		}
+
+		ar.Attempt = ar.CurrentAttempt() + 1
+		ar.Status = "NEW"
+		ar.Result = ""
+		ar.ReplyAt = utils.JSONTime{}
+		ar.NextRetryAt = 0
+
+		web.Info("retry ar %s %s attempt %d", ar.ActionRequestId, ar.ActionType, ar.Attempt)
+
+		retryo := &ErrorHandler{}
+		retryo.CreateAndRunAction(web, ar)
+		if retryo.Err == nil {
+			retried += 1
+			continue
+		}
+
+		ar.Status = "Failed"
+		policy := GetRetryPolicy(&web.Config, ar.ActionType)
+		o.ScheduleRejectedActionRetry(conn, web.apilogDb, ar, policy,
+			transientDispatchError(retryo.Err), retryo.Err.Error())
+		o.UpdateActionRequest_(conn, web.apilogDb, ar)
+		if o.Err != nil {
+			web.Error(o.Err, "save retry ar %s failed", arid)
+			o.Err = nil
+		}
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"claimed": len(arids),
+		"retried": retried,
+	})
+}
@@ -50,7 +50,7 @@ func (server *WebServer) GetChatUserSync(_ context.Context, req *pb.GetChatUserS
 	}
 
 	if user != nil {
-		server.Info("[sync get chatuser debug] get chatuser by db")
+		server.Debug("[sync get chatuser] get chatuser by db")
 		response := &pb.GetChatUserResponse{}
 		payload, _ := json.Marshal(user)
 		response.Payload = payload
@@ -80,7 +80,7 @@ func (server *WebServer) GetChatUserSync(_ context.Context, req *pb.GetChatUserS
 		return nil, o.Err
 	}
 
-	server.Info("[sync get chatuser debug] call grpc")
+	server.Debug("[sync get chatuser] call grpc")
 	actionReply := o.CreateAndRunAction(server, ar)
 	if o.Err != nil {
 		return nil, o.Err
@@ -96,17 +96,17 @@ func (server *WebServer) GetChatUserSync(_ context.Context, req *pb.GetChatUserS
 	ch := make(chan domains.ChatUser)
 	go server.contactInfoDispatcher.Listen(req.UserName, ch)
 
-	server.Info("[sync get chatuser debug] wait for reply")
+	server.Debug("[sync get chatuser] wait for reply")
 
 	select {
 	case chatuser := <-ch:
-		server.Info("[sync get chatuser debug] get reply from channel")
+		server.Debug("[sync get chatuser] get reply from channel")
 		return &pb.GetChatUserResponse{
 			Payload: []byte(o.ToJson(chatuser)),
 		}, nil
 
 	case <-time.After(3 * time.Second):
-		server.Info("[sync get chatuser debug] wait reply timeout")
+		server.Debug("[sync get chatuser] wait reply timeout")
 		return nil, fmt.Errorf(
 			"cannot find user: %s %s %s, getcontact timeout",
 			req.UserName, req.Type, req.BotLogin)
@@ -189,3 +189,38 @@ func (server *WebServer) UpdateBotChatRoom(ctx context.Context, request *pb.Upda
 	response := &pb.UpdateBotChatRoomResponse{}
 	return response, nil
 }
+
+// RebuildBotFilters is called by a restarted hub, which has restored the bot
+// but lost its filter instances. Filters are rebuilt from the bot record, the
+// filter ids the hub remembers are only compared for logging.
+func (server *WebServer) RebuildBotFilters(ctx context.Context, req *pb.RebuildBotFiltersRequest) (*pb.RebuildBotFiltersResponse, error) {
+	o := &ErrorHandler{}
+
+	bot := o.GetBotById(server.db.Conn, req.BotId)
+	if o.Err != nil {
+		return nil, o.Err
+	}
+
+	if bot == nil {
+		return nil, fmt.Errorf("b[%s] not found", req.BotId)
+	}
+
+	if bot.FilterId.String != req.FilterId || bot.MomentFilterId.String != req.MomentFilterId {
+		server.Info("b[%s] hub filters [%s,%s] differ from db [%s,%s], rebuild from db",
+			req.BotId, req.FilterId, req.MomentFilterId, bot.FilterId.String, bot.MomentFilterId.String)
+	}
+
+	wrapper, err := server.NewGRPCWrapperForBot(domains.BotOwnerByBot, req.BotId)
+	if err != nil {
+		return nil, err
+	}
+	defer wrapper.Cancel()
+
+	o.rebuildMsgFilters(server, bot, server.db.Conn, wrapper)
+	o.rebuildMomentFilters(server, bot, server.db.Conn, wrapper)
+	if o.Err != nil {
+		return nil, o.Err
+	}
+
+	return &pb.RebuildBotFiltersResponse{}, nil
+}
@@ -0,0 +1,285 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	messageStatsDefaultDays int = 7
+	messageStatsMaxDays     int = 180
+	// hourly series are limited to a month
+	messageStatsMaxHourlyDays int = 31
+)
+
+// getMessageStatCriteria returns the criteria of the message stats of the
+// bots of the account, or of the bot of login, within from and to, the
+// last 7 days by default.
+func (web *WebServer) getMessageStatCriteria(o *ErrorHandler, r *http.Request) domains.MessageStatCriteria {
+	if o.Err != nil {
+		return domains.MessageStatCriteria{}
+	}
+
+	accountName := o.getAccountName(r)
+	login := o.getStringValueDefault(r.Form, "login", "")
+
+	criteria := domains.MessageStatCriteria{
+		Peer:     o.getStringValueDefault(r.Form, "peer", ""),
+		ChatType: o.getStringValueDefault(r.Form, "chatType", ""),
+		From:     o.getTimeValue(r, "from"),
+		To:       o.getTimeValue(r, "to"),
+	}
+	if o.Err != nil {
+		return criteria
+	}
+
+	switch criteria.ChatType {
+	case "", domains.MESSAGE_CHAT_GROUP, domains.MESSAGE_CHAT_PRIVATE:
+	default:
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("chatType %s not supported, should be group or private", criteria.ChatType))
+		return criteria
+	}
+
+	// the default range is rounded to the minute, for the requests in a row
+	// to hit the cache
+	if criteria.To.IsZero() {
+		criteria.To = time.Now().Truncate(time.Minute).Add(time.Minute)
+	}
+	if criteria.From.IsZero() {
+		criteria.From = criteria.To.AddDate(0, 0, -messageStatsDefaultDays)
+	}
+
+	if !criteria.From.Before(criteria.To) {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("from should be before to"))
+		return criteria
+	}
+	if criteria.To.Sub(criteria.From) > time.Duration(messageStatsMaxDays)*24*time.Hour {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("from and to should be at most %d days apart", messageStatsMaxDays))
+		return criteria
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	criteria.Logins = []string{}
+	if login != "" {
+		o.CheckBotOwner(tx, login, accountName)
+		criteria.Logins = append(criteria.Logins, login)
+	} else {
+		for _, bot := range o.GetBotsByAccountName(tx, accountName) {
+			if bot.Login != "" {
+				criteria.Logins = append(criteria.Logins, bot.Login)
+			}
+		}
+	}
+
+	return criteria
+}
+
+func (o *ErrorHandler) getMessageStatLimit(r *http.Request) int {
+	if o.Err != nil {
+		return 0
+	}
+
+	limit := actionRequestsDefaultLimit
+	if limitstr := o.getStringValueDefault(r.Form, "limit", ""); limitstr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitstr); err != nil || limit <= 0 {
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("limit should be a positive integer, got %s", limitstr))
+			return 0
+		}
+		if limit > actionRequestsMaxLimit {
+			limit = actionRequestsMaxLimit
+		}
+	}
+
+	return limit
+}
+
+// cachedMessageStat fills v with the aggregation cached under name and
+// args, or computes and caches it. The stats are computed by tasks every
+// so often, an aggregation is cached for as long.
+func (web *WebServer) cachedMessageStat(o *ErrorHandler, v interface{}, compute func(), name string, args ...interface{}) {
+	if o.Err != nil {
+		return
+	}
+
+	conn := web.redispool.Get()
+	defer conn.Close()
+
+	key := domains.MessageStatCacheKey(name, args...)
+	if o.GetCachedMessageStat(conn, key, v) || o.Err != nil {
+		return
+	}
+
+	compute()
+	o.CacheMessageStat(conn, key, v)
+}
+
+// getMessageTimeSeries counts the messages per day, or per hour with
+// interval=hour, per bot with groupBy=bot, per private chat with
+// groupBy=peer, per group with groupBy=group.
+func (web *WebServer) getMessageTimeSeries(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	interval := o.getStringValueDefault(r.Form, "interval", "day")
+	groupBy := o.getStringValueDefault(r.Form, "groupBy", "bot")
+
+	criteria := web.getMessageStatCriteria(o, r)
+	if o.Err != nil {
+		return
+	}
+
+	if interval != "day" && interval != "hour" {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("interval %s not supported, should be day or hour", interval))
+		return
+	}
+	hourly := interval == "hour"
+	if hourly && criteria.To.Sub(criteria.From) > time.Duration(messageStatsMaxHourlyDays)*24*time.Hour {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("from and to should be at most %d days apart by hour", messageStatsMaxHourlyDays))
+		return
+	}
+
+	byPeer := true
+	switch groupBy {
+	case "bot":
+		byPeer = false
+	case "peer":
+		criteria.ChatType = domains.MESSAGE_CHAT_PRIVATE
+	case "group":
+		criteria.ChatType = domains.MESSAGE_CHAT_GROUP
+	default:
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("groupBy %s not supported, should be bot, peer or group", groupBy))
+		return
+	}
+
+	points := []domains.MessageSeriesPoint{}
+	web.cachedMessageStat(o, &points, func() {
+		points = o.MessageTimeSeries(web.messageDb, criteria, hourly, byPeer)
+	}, "timeseries", criteria, hourly, byPeer)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"from":     criteria.From,
+		"to":       criteria.To,
+		"interval": interval,
+		"groupBy":  groupBy,
+		"series":   points,
+	})
+}
+
+// getTopMessageCounts returns the senders sending the most messages to the
+// bots with by=sender, the chats with the most messages with by=peer, and
+// the groups with by=group.
+func (web *WebServer) getTopMessageCounts(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	by := o.getStringValueDefault(r.Form, "by", "sender")
+	limit := o.getMessageStatLimit(r)
+
+	criteria := web.getMessageStatCriteria(o, r)
+	if o.Err != nil {
+		return
+	}
+
+	field := by
+	switch by {
+	case "sender", "peer":
+	case "group":
+		field = "peer"
+		criteria.ChatType = domains.MESSAGE_CHAT_GROUP
+	default:
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("by %s not supported, should be sender, peer or group", by))
+		return
+	}
+
+	counts := []domains.MessageCount{}
+	web.cachedMessageStat(o, &counts, func() {
+		counts = o.TopMessageCounts(web.messageDb, criteria, field, limit)
+	}, "top", criteria, field, limit)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"from": criteria.From,
+		"to":   criteria.To,
+		"by":   by,
+		"top":  counts,
+	})
+}
+
+// getMessageResponseTimes returns how fast the bots respond per chat, from
+// an inbound message to the next outbound one, of the chats responded the
+// most.
+func (web *WebServer) getMessageResponseTimes(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	limit := o.getMessageStatLimit(r)
+
+	criteria := web.getMessageStatCriteria(o, r)
+	if o.Err != nil {
+		return
+	}
+
+	times := []domains.MessageResponseTime{}
+	web.cachedMessageStat(o, &times, func() {
+		times = o.MessageResponseTimes(web.messageDb, criteria, limit)
+	}, "responsetimes", criteria, limit)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"from":          criteria.From,
+		"to":            criteria.To,
+		"responseTimes": times,
+	})
+}
+
+// getMessageTypes counts the messages per message type.
+func (web *WebServer) getMessageTypes(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+
+	criteria := web.getMessageStatCriteria(o, r)
+	if o.Err != nil {
+		return
+	}
+
+	counts := []domains.MessageTypeCount{}
+	web.cachedMessageStat(o, &counts, func() {
+		counts = o.MessageTypeDistribution(web.messageDb, criteria)
+	}, "types", criteria)
+	if o.Err != nil {
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"from":  criteria.From,
+		"to":    criteria.To,
+		"types": counts,
+	})
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"github.com/hawkwithwind/chat-bot-hub/server/chatbothub"
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+var (
+	defaultRetryPolicy domains.RetryPolicy = domains.RetryPolicy{
+		MaxAttempts:    3,
+		Backoff:        30,
+		BackoffFactor:  2,
+		MaxBackoff:     10 * 60,
+		RetryOnTimeout: true,
+	}
+
+	// actions that only read from the bot, safe to send more than once
+	idempotentActions map[string]bool = map[string]bool{
+		chatbothub.GetRoomMembers:   true,
+		chatbothub.GetRoomQRCode:    true,
+		chatbothub.GetContactQRCode: true,
+		chatbothub.GetContact:       true,
+		chatbothub.CheckContact:     true,
+		chatbothub.SearchContact:    true,
+		chatbothub.SyncContact:      true,
+		chatbothub.SnsTimeline:      true,
+		chatbothub.SnsUserPage:      true,
+		chatbothub.SnsGetObject:     true,
+		chatbothub.GetLabelList:     true,
+		chatbothub.GetRequestToken:  true,
+	}
+)
+
+// GetRetryPolicy looks up config.ActionRetry for the action type, falling
+// back to the default policy. Non idempotent actions get no retry unless
+// their configured policy opts in with allowNonIdempotent.
+func GetRetryPolicy(config *WebConfig, actionType string) domains.RetryPolicy {
+	policy := defaultRetryPolicy
+	if p, ok := config.ActionRetry[actionType]; ok {
+		policy = p
+	}
+
+	if !idempotentActions[actionType] && !policy.AllowNonIdempotent {
+		policy.MaxAttempts = 1
+	}
+
+	return policy
+}
+
+// actionFailureCode reads result.data.status of a failed action reply,
+// which is the code matched against retryableCodes.
+func actionFailureCode(result map[string]interface{}) int {
+	if result == nil {
+		return 0
+	}
+
+	switch data := result["data"].(type) {
+	case map[string]interface{}:
+		switch status := data["status"].(type) {
+		case float64:
+			return int(status)
+		}
+	}
+
+	return 0
+}
+
+// transientDispatchError tells whether an action turned down by web or the
+// hub may go through on a later attempt. Errors that are not client errors
+// come from grpc, redis or an open breaker, and pass with time.
+func transientDispatchError(err error) bool {
+	clientError, ok := err.(*utils.ClientError)
+	if !ok {
+		return true
+	}
+
+	switch clientError.ErrorCode() {
+	case utils.UNKNOWN,
+		utils.RESOURCE_INSUFFICIENT, // hub draining
+		utils.RESOURCE_QUOTA_LIMIT,  // rate limit or action queue full
+		utils.STATUS_INCONSISTENT:   // bot not logged in
+		return true
+	default:
+		return false
+	}
+}
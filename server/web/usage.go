@@ -0,0 +1,243 @@
+package web
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/utils"
+)
+
+const (
+	usageDefaultDays int    = 30
+	usageMaxDays     int    = 366
+	usageDefaultBy   string = "day,bot"
+)
+
+// UsageRow is the usage of a kind summed up over the dimensions not
+// grouped by, which are left empty.
+type UsageRow struct {
+	Day   string  `json:"day,omitempty"`
+	BotId string  `json:"botId,omitempty"`
+	Login string  `json:"login,omitempty"`
+	Kind  string  `json:"kind"`
+	Type  string  `json:"type,omitempty"`
+	Value float64 `json:"value"`
+}
+
+func (o *ErrorHandler) getUsageDay(r *http.Request, name string, defaultvalue time.Time) time.Time {
+	if o.Err != nil {
+		return time.Time{}
+	}
+
+	daystr := o.getStringValueDefault(r.Form, name, "")
+	if daystr == "" {
+		return defaultvalue
+	}
+
+	day, err := time.Parse(domains.UsageDayLayout, daystr)
+	if err != nil {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("%s should be a day like 2006-01-02, got %s", name, daystr))
+		return time.Time{}
+	}
+
+	return day
+}
+
+// getUsageGroupBy returns the dimensions the usage is grouped by, of day,
+// bot and type. The kinds are never summed up together.
+func (o *ErrorHandler) getUsageGroupBy(r *http.Request) map[string]bool {
+	if o.Err != nil {
+		return nil
+	}
+
+	groupBy := map[string]bool{}
+	for _, by := range strings.Split(o.getStringValueDefault(r.Form, "groupBy", usageDefaultBy), ",") {
+		by = strings.TrimSpace(by)
+		switch by {
+		case "day", "bot", "type":
+			groupBy[by] = true
+		case "kind", "":
+		default:
+			o.Err = utils.NewClientError(utils.PARAM_INVALID,
+				fmt.Errorf("groupBy %s not supported, should be of day, bot, type", by))
+			return nil
+		}
+	}
+
+	return groupBy
+}
+
+func groupUsages(usages []domains.DailyUsage, groupBy map[string]bool, logins map[string]string) []UsageRow {
+	rows := map[string]*UsageRow{}
+
+	for _, usage := range usages {
+		row := UsageRow{Kind: usage.Kind}
+		if groupBy["day"] {
+			row.Day = usage.Day
+		}
+		if groupBy["bot"] {
+			row.BotId = usage.BotId
+			row.Login = logins[usage.BotId]
+		}
+		if groupBy["type"] {
+			row.Type = usage.Type
+		}
+
+		key := strings.Join([]string{row.Day, row.BotId, row.Kind, row.Type}, "|")
+		if existing, ok := rows[key]; ok {
+			existing.Value += usage.Value
+		} else {
+			row.Value = usage.Value
+			rows[key] = &row
+		}
+	}
+
+	ret := make([]UsageRow, 0, len(rows))
+	for _, row := range rows {
+		ret = append(ret, *row)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.BotId != b.BotId {
+			return a.BotId < b.BotId
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Type < b.Type
+	})
+
+	return ret
+}
+
+func writeUsageCsv(w http.ResponseWriter, filename string, rows []UsageRow, groupBy map[string]bool) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	header := []string{}
+	if groupBy["day"] {
+		header = append(header, "day")
+	}
+	if groupBy["bot"] {
+		header = append(header, "botId", "login")
+	}
+	header = append(header, "kind")
+	if groupBy["type"] {
+		header = append(header, "type")
+	}
+	header = append(header, "value")
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+
+	for _, row := range rows {
+		record := []string{}
+		if groupBy["day"] {
+			record = append(record, row.Day)
+		}
+		if groupBy["bot"] {
+			record = append(record, row.BotId, row.Login)
+		}
+		record = append(record, row.Kind)
+		if groupBy["type"] {
+			record = append(record, row.Type)
+		}
+		record = append(record, strconv.FormatFloat(row.Value, 'f', -1, 64))
+		writer.Write(record)
+	}
+
+	writer.Flush()
+}
+
+// getUsage returns the daily usage of the account between from and to,
+// both included and in UTC, the last 30 days by default. With format=csv
+// it is exported as a csv file.
+func (web *WebServer) getUsage(w http.ResponseWriter, r *http.Request) {
+	o := &ErrorHandler{}
+	defer o.WebError(w)
+
+	r.ParseForm()
+	accountName := o.getAccountName(r)
+
+	today, _ := time.Parse(domains.UsageDayLayout, domains.UsageDay(time.Now()))
+	to := o.getUsageDay(r, "to", today)
+	from := o.getUsageDay(r, "from", to.AddDate(0, 0, 1-usageDefaultDays))
+	groupBy := o.getUsageGroupBy(r)
+	botId := o.getStringValueDefault(r.Form, "botId", "")
+	kind := o.getStringValueDefault(r.Form, "kind", "")
+	format := o.getStringValueDefault(r.Form, "format", "json")
+	if o.Err != nil {
+		return
+	}
+
+	if from.After(to) {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID, fmt.Errorf("from should not be after to"))
+		return
+	}
+	if to.Sub(from) >= time.Duration(usageMaxDays)*24*time.Hour {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("from and to should be at most %d days apart", usageMaxDays))
+		return
+	}
+	if format != "json" && format != "csv" {
+		o.Err = utils.NewClientError(utils.PARAM_INVALID,
+			fmt.Errorf("format %s not supported, should be json or csv", format))
+		return
+	}
+
+	tx := o.Begin(web.db)
+	defer o.CommitOrRollback(tx)
+
+	account := o.GetAccountByName(tx, accountName)
+	if o.Err != nil {
+		return
+	}
+	if account == nil {
+		o.Err = utils.NewClientError(utils.RESOURCE_ACCESS_DENIED, fmt.Errorf("account %s not found", accountName))
+		return
+	}
+
+	if botId != "" {
+		o.CheckBotOwnerById(tx, botId, accountName)
+	}
+
+	logins := map[string]string{}
+	for _, bot := range o.GetBotsByAccountName(tx, accountName) {
+		logins[bot.BotId] = bot.Login
+	}
+
+	usages := o.QueryDailyUsages(web.apilogDb, domains.DailyUsageCriteria{
+		AccountId: account.AccountId,
+		BotId:     botId,
+		Kind:      kind,
+		From:      domains.UsageDay(from),
+		To:        domains.UsageDay(to),
+	})
+	if o.Err != nil {
+		return
+	}
+
+	rows := groupUsages(usages, groupBy, logins)
+
+	if format == "csv" {
+		writeUsageCsv(w, fmt.Sprintf("usage-%s-%s.csv", domains.UsageDay(from), domains.UsageDay(to)), rows, groupBy)
+		return
+	}
+
+	o.ok(w, "", map[string]interface{}{
+		"from":  domains.UsageDay(from),
+		"to":    domains.UsageDay(to),
+		"usage": rows,
+	})
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+)
+
+func testDailyUsages() []domains.DailyUsage {
+	return []domains.DailyUsage{
+		{Day: "2019-06-01", BotId: "bot1", Kind: domains.USAGE_MESSAGES, Type: "TEXTMESSAGE", Value: 3},
+		{Day: "2019-06-01", BotId: "bot1", Kind: domains.USAGE_MESSAGES, Type: "IMAGEMESSAGE", Value: 2},
+		{Day: "2019-06-01", BotId: "bot2", Kind: domains.USAGE_MESSAGES, Type: "TEXTMESSAGE", Value: 5},
+		{Day: "2019-06-02", BotId: "bot1", Kind: domains.USAGE_MESSAGES, Type: "TEXTMESSAGE", Value: 7},
+		{Day: "2019-06-02", BotId: "bot1", Kind: domains.USAGE_ACTIONS, Type: "SendTextMessage", Value: 4},
+		{Day: "2019-06-02", BotId: "", Kind: domains.USAGE_WEBSOCKET_MINUTES, Value: 1.5},
+	}
+}
+
+func TestGroupUsagesByDayAndBot(t *testing.T) {
+	logins := map[string]string{"bot1": "login1", "bot2": "login2"}
+	rows := groupUsages(testDailyUsages(), map[string]bool{"day": true, "bot": true}, logins)
+
+	expected := []UsageRow{
+		{Day: "2019-06-01", BotId: "bot1", Login: "login1", Kind: domains.USAGE_MESSAGES, Value: 5},
+		{Day: "2019-06-01", BotId: "bot2", Login: "login2", Kind: domains.USAGE_MESSAGES, Value: 5},
+		{Day: "2019-06-02", Kind: domains.USAGE_WEBSOCKET_MINUTES, Value: 1.5},
+		{Day: "2019-06-02", BotId: "bot1", Login: "login1", Kind: domains.USAGE_ACTIONS, Value: 4},
+		{Day: "2019-06-02", BotId: "bot1", Login: "login1", Kind: domains.USAGE_MESSAGES, Value: 7},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("got %d rows %v, expected %v", len(rows), rows, expected)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Errorf("row %d is %+v, expected %+v", i, rows[i], expected[i])
+		}
+	}
+}
+
+func TestGroupUsagesKeepsKindsApart(t *testing.T) {
+	rows := groupUsages(testDailyUsages(), map[string]bool{}, nil)
+
+	expected := []UsageRow{
+		{Kind: domains.USAGE_ACTIONS, Value: 4},
+		{Kind: domains.USAGE_MESSAGES, Value: 17},
+		{Kind: domains.USAGE_WEBSOCKET_MINUTES, Value: 1.5},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("got %d rows %v, expected %v", len(rows), rows, expected)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Errorf("row %d is %+v, expected %+v", i, rows[i], expected[i])
+		}
+	}
+}
+
+func TestGroupUsagesByType(t *testing.T) {
+	rows := groupUsages(testDailyUsages(), map[string]bool{"type": true}, nil)
+
+	values := map[string]float64{}
+	for _, row := range rows {
+		if row.Day != "" || row.BotId != "" {
+			t.Errorf("row %+v should not be grouped by day or bot", row)
+		}
+		values[row.Kind+"|"+row.Type] = row.Value
+	}
+
+	if v := values[domains.USAGE_MESSAGES+"|TEXTMESSAGE"]; v != 15 {
+		t.Errorf("text messages sum up to %v, expected 15", v)
+	}
+	if v := values[domains.USAGE_MESSAGES+"|IMAGEMESSAGE"]; v != 2 {
+		t.Errorf("image messages sum up to %v, expected 2", v)
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"fmt"
 	"github.com/hawkwithwind/chat-bot-hub/server/rpc"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
-	"log"
 	"net"
 	"os/signal"
 	"sync"
@@ -31,7 +32,11 @@ import (
 
 	"github.com/hawkwithwind/chat-bot-hub/server/dbx"
 	"github.com/hawkwithwind/chat-bot-hub/server/domains"
+	"github.com/hawkwithwind/chat-bot-hub/server/health"
 	"github.com/hawkwithwind/chat-bot-hub/server/httpx"
+	"github.com/hawkwithwind/chat-bot-hub/server/logging"
+	"github.com/hawkwithwind/chat-bot-hub/server/metrics"
+	"github.com/hawkwithwind/chat-bot-hub/server/tracing"
 	"github.com/hawkwithwind/chat-bot-hub/server/utils"
 
 	pb "github.com/hawkwithwind/chat-bot-hub/proto/web"
@@ -41,6 +46,8 @@ type ErrorHandler struct {
 	domains.ErrorHandler
 }
 
+var webLogger = logging.New("web")
+
 type WebConfig struct {
 	Host      string
 	Port      string
@@ -52,15 +59,23 @@ type WebConfig struct {
 	Apilogdb  utils.MongoConfig
 	Rabbitmq  utils.RabbitMQConfig
 
+	// port serving /metrics and /loglevels, off when empty
+	MetricsPort string
+
 	SecretPhrase string
 	Database     utils.DatabaseConfig
 	Sentry       string
 	GithubOAuth  GithubOAuthConfig
 	AllowOrigin  []string
+	// addresses or cidrs of the proxies whose X-Forwarded-For and X-Real-IP
+	// are trusted for the client ip, none when empty
+	TrustedProxies []string
 
 	ActionHealthCheck domains.HealthCheckConfig
 	BotHealthCheck    domains.HealthCheckConfig
 	ActionTimeout     int
+	IdempotencyTTL    int
+	ActionRetry       map[string]domains.RetryPolicy
 }
 
 type WebServer struct {
@@ -71,7 +86,10 @@ type WebServer struct {
 	wrapper       *rpc.GRPCWrapper
 	restfulclient *http.Client
 
-	logger        *log.Logger
+	muxHubWrappers sync.Mutex
+	hubWrappers    map[string]*rpc.GRPCWrapper
+
+	logger        *logging.Logger
 	fluentLogger  *fluent.Fluent
 	redispool     *redis.Pool
 	db            *dbx.Database
@@ -84,19 +102,29 @@ type WebServer struct {
 	rabbitmq *utils.RabbitMQWrapper
 
 	contactInfoDispatcher *ContactInfoDispatcher
+
+	checker *health.Checker
+
+	trustedProxies []*net.IPNet
 }
 
 func (ctx *WebServer) init() error {
 	ctx.restfulclient = httpx.NewHttpClient()
 
-	ctx.logger = log.New(os.Stdout, "[WEB] ", log.Ldate|log.Ltime)
+	ctx.logger = webLogger
+
+	var err error
+	if ctx.trustedProxies, err = parseTrustedProxies(ctx.Config.TrustedProxies); err != nil {
+		ctx.Error(err, "parse trusted proxies failed")
+		return err
+	}
+
 	ctx.redispool = utils.NewRedisPool(
 		fmt.Sprintf("%s:%s", ctx.Config.Redis.Host, ctx.Config.Redis.Port),
 		ctx.Config.Redis.Db, ctx.Config.Redis.Password)
 	ctx.store = sessions.NewCookieStore([]byte(ctx.Config.SecretPhrase)[:64])
 	ctx.db = &dbx.Database{}
 
-	var err error
 	ctx.fluentLogger, err = fluent.New(fluent.Config{
 		FluentPort:   ctx.Config.Fluent.Port,
 		FluentHost:   ctx.Config.Fluent.Host,
@@ -131,9 +159,19 @@ func (ctx *WebServer) init() error {
 	ctx.apilogDb = o.NewMongoConn(ctx.Config.Apilogdb.Host, ctx.Config.Apilogdb.Port, ctx.Config.Apilogdb.Database)
 	ctx.Info("Apilog Mongo host: %s, port: %s, database: %s", ctx.Config.Apilogdb.Host, ctx.Config.Apilogdb.Port, ctx.Config.Apilogdb.Database)
 
+	// a failed connect or failed indexes are logged, web starts either way
 	if o.Err != nil {
 		ctx.Error(o.Err, "connect to mongo failed %s", o.Err)
+	} else {
+		if o.EnsureApiLogIndexes(ctx.apilogDb); o.Err != nil {
+			ctx.Error(o.Err, "apilog mongo ensure indexes fail")
+			o.Err = nil
+		}
+		if o.EnsureAuditLogIndexes(ctx.apilogDb); o.Err != nil {
+			ctx.Error(o.Err, "audit log mongo ensure indexes fail")
+		}
 	}
+	o.Err = nil
 
 	retryTimes := 7
 	gap := 2
@@ -160,7 +198,7 @@ func (ctx *WebServer) init() error {
 	go func(db *sqlx.DB) {
 		for {
 			time.Sleep(time.Duration(60) * time.Second)
-			fmt.Println("[WEB] database stats ", o.ToJson(db.Stats()))
+			ctx.Debug("database stats %s", o.ToJson(db.Stats()))
 		}
 	}(ctx.db.Conn)
 
@@ -211,16 +249,36 @@ func (ctx *WebServer) init() error {
 		pipes: make(map[string]chan chan domains.ChatUser),
 	}
 
+	ctx.checker = health.NewChecker(
+		health.SQL("mysql", ctx.db.Conn),
+		health.Mongo("mongo.message", ctx.messageDb),
+		health.Mongo("mongo.apilog", ctx.apilogDb),
+		health.Redis("redis", ctx.redispool),
+		health.Check{Name: "rabbitmq", Check: func(context.Context) error {
+			return ctx.rabbitmq.Ping()
+		}},
+		health.TCP("fluent", fmt.Sprintf("%s:%d", ctx.Config.Fluent.Host, ctx.Config.Fluent.Port)),
+		// the hub is not serving while draining, web still serves the rest
+		health.Check{Name: "hub", Check: ctx.wrapper.Health, Informational: true},
+	)
+
 	return nil
 }
 
+func (ctx *WebServer) Debug(msg string, v ...interface{}) {
+	ctx.logger.Debug(msg, v...)
+}
+
 func (ctx *WebServer) Info(msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
+	ctx.logger.Info(msg, v...)
+}
+
+func (ctx *WebServer) Warn(msg string, v ...interface{}) {
+	ctx.logger.Warn(msg, v...)
 }
 
 func (ctx *WebServer) Error(err error, msg string, v ...interface{}) {
-	ctx.logger.Printf(msg, v...)
-	ctx.logger.Printf("Error %v", err)
+	ctx.logger.Error(err, msg, v...)
 	raven.CaptureError(err, nil)
 }
 
@@ -248,6 +306,16 @@ func (ctx *ErrorHandler) complain(w http.ResponseWriter, code utils.ClientErrorC
 	})
 }
 
+func (ctx *ErrorHandler) conflict(w http.ResponseWriter, code utils.ClientErrorCode, msg string) {
+	// HTTP CODE 409
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(utils.CommonResponse{
+		Code:  int(code),
+		Ts:    time.Now().Unix(),
+		Error: utils.ErrorMessage{Message: msg},
+	})
+}
+
 func (ctx *ErrorHandler) ok(w http.ResponseWriter, msg string, body interface{}) {
 	if ctx.Err != nil {
 		return
@@ -290,7 +358,11 @@ func (ctx *ErrorHandler) fail(w http.ResponseWriter, msg string) {
 func (o *ErrorHandler) WebError(w http.ResponseWriter) {
 	switch err := o.Err.(type) {
 	case *utils.ClientError:
-		o.complain(w, err.ErrorCode(), err.Error())
+		if err.ErrorCode() == utils.RESOURCE_CONFLICT {
+			o.conflict(w, err.ErrorCode(), err.Error())
+		} else {
+			o.complain(w, err.ErrorCode(), err.Error())
+		}
 	case *utils.AuthError:
 		o.deny(w, err.Error())
 	case nil:
@@ -374,17 +446,7 @@ func sentryContext(next http.Handler) http.Handler {
 	})
 }
 
-func healthz() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if atomic.LoadInt32(&healthy) == 1 {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		w.WriteHeader(http.StatusServiceUnavailable)
-	})
-}
-
-func logging(logger *log.Logger) func(http.Handler) http.Handler {
+func logRequests(logger *logging.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
@@ -392,14 +454,15 @@ func logging(logger *log.Logger) func(http.Handler) http.Handler {
 				if !ok {
 					requestID = "unknown"
 				}
-				logger.Println(requestID, r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
+				logger.With("requestId", requestID, "remoteAddr", r.RemoteAddr, "userAgent", r.UserAgent()).
+					Info("%s %s", r.Method, r.URL.Path)
 			}()
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func tracing(nextRequestID func() string) func(http.Handler) http.Handler {
+func requestIDs(nextRequestID func() string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			requestID := r.Header.Get("X-Request-Id")
@@ -420,7 +483,11 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 
 	r := mux.NewRouter()
 
-	r.Handle("/healthz", healthz())
+	r.Handle(health.LivePath, health.LiveHandler(func() bool {
+		return atomic.LoadInt32(&healthy) == 1
+	}))
+	r.Handle(health.ReadyPath, health.ReadyHandler(server.checker))
+	r.Handle(metrics.Path, metrics.Handler())
 	r.HandleFunc("/echo", server.echo).Methods("Post")
 	r.HandleFunc("/hello", server.validate(server.hello)).Methods("GET")
 
@@ -451,6 +518,10 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 	// client control (clients.go)
 	r.HandleFunc("/clients", server.validate(server.getClients)).Methods("GET")
 	r.HandleFunc("/clients/{clientId}/shutdown", server.validate(server.clientShutdown)).Methods("POST")
+	r.HandleFunc("/clients/{clientId}/inspect", server.validate(server.clientInspect)).Methods("GET")
+	r.HandleFunc("/clients/{clientId}/reconnect", server.validate(server.clientReconnect)).Methods("POST")
+	r.HandleFunc("/clients/{clientId}/loglevel", server.validate(server.clientLogLevel)).Methods("POST")
+	r.HandleFunc("/clients/{clientId}/synccontacts", server.validate(server.clientSyncContacts)).Methods("POST")
 
 	// bot CURD and login (botmanage.go)
 	r.HandleFunc("/botlogin", server.validate(server.botLogin)).Methods("POST")
@@ -469,14 +540,43 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 	r.HandleFunc("/bots", server.validate(server.createBot)).Methods("POST")
 	r.HandleFunc("/bots/scancreate", server.validate(server.scanCreateBot)).Methods("POST")
 
+	// bot logs kept by the hub (botlogs.go)
+	r.HandleFunc("/bots/{botId}/logs", server.validate(server.getBotLogs)).Methods("GET")
+
+	// action latency and success rate rollups (actionstats.go)
+	r.HandleFunc("/stats/actions", server.validate(server.getActionStats)).Methods("GET")
+
+	// alert rules and alert history (alerts.go)
+	r.HandleFunc("/alertrules", server.validate(server.getAlertRules)).Methods("GET")
+	r.HandleFunc("/alertrules", server.validate(server.createAlertRule)).Methods("POST")
+	r.HandleFunc("/alertrules/{ruleId}", server.validate(server.updateAlertRule)).Methods("PUT")
+	r.HandleFunc("/alertrules/{ruleId}", server.validate(server.deleteAlertRule)).Methods("DELETE")
+	r.HandleFunc("/alerts", server.validate(server.getAlerts)).Methods("GET")
+
+	// audit logs of account operations (audit.go)
+	r.HandleFunc("/audit", server.validate(server.getAuditLogs)).Methods("GET")
+
+	// daily usage of the account (usage.go)
+	r.HandleFunc("/usage", server.validate(server.getUsage)).Methods("GET")
+
+	// aggregations of the stored messages, computed by tasks (messagestats.go)
+	r.HandleFunc("/stats/messages/timeseries", server.validate(server.getMessageTimeSeries)).Methods("GET")
+	r.HandleFunc("/stats/messages/top", server.validate(server.getTopMessageCounts)).Methods("GET")
+	r.HandleFunc("/stats/messages/responsetimes", server.validate(server.getMessageResponseTimes)).Methods("GET")
+	r.HandleFunc("/stats/messages/types", server.validate(server.getMessageTypes)).Methods("GET")
+
 	// bot action (actions.go)
 	r.HandleFunc("/botaction/{login}", server.validate(server.botAction)).Methods("POST")
 	r.HandleFunc("/bots/{login}/friendrequests", server.validate(server.getFriendRequests)).Methods("GET")
 	r.HandleFunc("/bots/{botId}/notify", server.botNotify).Methods("Post")
 	r.HandleFunc("/bots/wechatbots/notify/recoverfailingactions", server.notifyRecoverFailingActions).Methods("POST")
+	r.HandleFunc("/bots/wechatbots/notify/retryactions", server.notifyRetryActions).Methods("POST")
+	r.HandleFunc("/actionrequests", server.validate(server.getActionRequests)).Methods("GET")
 	r.HandleFunc("/botactions/failing", server.validate(server.getFailingBots)).Methods("GET")
 	r.HandleFunc("/botactions/recoveraction", server.validate(server.recoverAction)).Methods("POST")
 	r.HandleFunc("/botactions/recoverclient", server.validate(server.recoverClient)).Methods("POST")
+	r.HandleFunc("/botactions/breakers", server.validate(server.getActionBreakers)).Methods("GET")
+	r.HandleFunc("/botactions/breakertransition", server.internal(server.breakerTransition)).Methods("POST")
 	r.HandleFunc("/botactions/timeoutfriendrequest", server.timeoutFriendRequest).Methods("POST")
 
 	// timeline.go
@@ -502,10 +602,10 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 	r.Use(mux.CORSMethodMiddleware(r))
 	r.Use(handlers.CORS(
 		handlers.AllowCredentials(),
-		handlers.AllowedHeaders([]string{"Content-Type", "X-Requested-With"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "X-Requested-With", "Idempotency-Key"}),
 		handlers.AllowedOrigins(server.Config.AllowOrigin)))
-	r.Use(tracing(nextRequestID))
-	r.Use(logging(server.logger))
+	r.Use(requestIDs(nextRequestID))
+	r.Use(logRequests(server.logger))
 	r.Use(sentryContext)
 
 	addr := fmt.Sprintf("%s:%s", server.Config.Host, server.Config.Port)
@@ -513,7 +613,7 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 	httpServer := &http.Server{
 		Addr:         addr,
 		Handler:      r,
-		ErrorLog:     server.logger,
+		ErrorLog:     server.logger.StdLogger(logging.ErrorLevel),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -530,6 +630,7 @@ func (server *WebServer) serveHTTP(ctx context.Context) error {
 
 		server.Info("http server is shutting down")
 		atomic.StoreInt32(&healthy, 0)
+		server.checker.Stop()
 
 		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -566,10 +667,14 @@ func (server *WebServer) serverGRPC(ctx context.Context) error {
 		server.Error(err, "grpc server fail to listen")
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(tracing.ServerOption())
 	pb.RegisterChatBotWebServer(s, server)
 	reflection.Register(s)
 
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	server.checker.Serving(healthServer)
+
 	running := true
 
 	go func() {
@@ -606,6 +711,10 @@ func (server *WebServer) Serve() {
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
+	go server.checker.Watch(ctx)
+	// log levels are only adjustable on the internal metrics port
+	go metrics.Serve(server.Config.Host, server.Config.MetricsPort)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, os.Interrupt)